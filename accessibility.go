@@ -0,0 +1,254 @@
+package ios
+
+import "math"
+
+// Built-in accessibility filters, registered ahead of any app filters.
+func init() {
+	RegisterFrameFilter("deuteranopia", newColorMatrixFilter(daltonizeMatrix(deuteranopiaSim)))
+	RegisterFrameFilter("protanopia", newColorMatrixFilter(daltonizeMatrix(protanopiaSim)))
+	RegisterFrameFilter("tritanopia", newColorMatrixFilter(daltonizeMatrix(tritanopiaSim)))
+	RegisterFrameFilter("high-contrast", &highContrastFilter{})
+}
+
+// mat3 is a row-major 3x3 matrix over linear RGB.
+type mat3 [9]float64
+
+var identity3 = mat3{1, 0, 0, 0, 1, 0, 0, 0, 1}
+
+// Color vision deficiency simulation matrices in linear RGB, full severity
+// (Machado, Oliveira and Fernandes 2009).
+var (
+	protanopiaSim = mat3{
+		0.152286, 1.052583, -0.204868,
+		0.114503, 0.786281, 0.099216,
+		-0.003882, -0.048116, 1.051998,
+	}
+	deuteranopiaSim = mat3{
+		0.367322, 0.860646, -0.227968,
+		0.280085, 0.672501, 0.047413,
+		-0.011820, 0.042940, 0.968881,
+	}
+	tritanopiaSim = mat3{
+		1.255528, -0.076749, -0.178779,
+		-0.078411, 0.930809, 0.147602,
+		0.004733, 0.691367, 0.303900,
+	}
+)
+
+// daltonizeShift moves the color information lost to a deficiency into
+// channels that remain distinguishable.
+var daltonizeShift = mat3{
+	0, 0, 0,
+	0.7, 1, 0,
+	0.7, 0, 1,
+}
+
+func (a mat3) mul(b mat3) mat3 {
+	var m mat3
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			for k := 0; k < 3; k++ {
+				m[r*3+c] += a[r*3+k] * b[k*3+c]
+			}
+		}
+	}
+	return m
+}
+
+func (a mat3) sub(b mat3) mat3 {
+	var m mat3
+	for i := range a {
+		m[i] = a[i] - b[i]
+	}
+	return m
+}
+
+func (a mat3) add(b mat3) mat3 {
+	var m mat3
+	for i := range a {
+		m[i] = a[i] + b[i]
+	}
+	return m
+}
+
+// daltonizeMatrix folds simulation, error and shift into one transform:
+// c + shift*(c - sim*c) = (I + shift*(I - sim)) * c.
+func daltonizeMatrix(sim mat3) mat3 {
+	return identity3.add(daltonizeShift.mul(identity3.sub(sim)))
+}
+
+// sRGB transfer tables. Matrices apply in linear light so the transform
+// decodes and re-encodes gamma exactly once.
+const linearLevels = 4096
+
+var (
+	srgbToLinear [256]float32
+	linearToSRGB [linearLevels]uint8
+)
+
+func init() {
+	for i := range srgbToLinear {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		srgbToLinear[i] = float32(v)
+	}
+	for i := range linearToSRGB {
+		v := float64(i) / (linearLevels - 1)
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		linearToSRGB[i] = uint8(math.Round(v * 255))
+	}
+}
+
+// encodeLinear clamps a linear value and converts it to sRGB.
+func encodeLinear(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return linearToSRGB[int(v*(linearLevels-1)+0.5)]
+}
+
+// colorMatrixFilter applies a 3x3 matrix to every pixel in linear light.
+type colorMatrixFilter struct {
+	m [9]float32
+}
+
+func newColorMatrixFilter(m mat3) *colorMatrixFilter {
+	f := &colorMatrixFilter{}
+	for i, v := range m {
+		f.m[i] = float32(v)
+	}
+	return f
+}
+
+func (f *colorMatrixFilter) Apply(pixels []byte, width, height, stride int) {
+	m := &f.m
+	for y := 0; y < height; y++ {
+		row := pixels[y*stride : y*stride+width*4]
+		for x := 0; x < len(row); x += 4 {
+			r := srgbToLinear[row[x]]
+			g := srgbToLinear[row[x+1]]
+			b := srgbToLinear[row[x+2]]
+			row[x] = encodeLinear(m[0]*r + m[1]*g + m[2]*b)
+			row[x+1] = encodeLinear(m[3]*r + m[4]*g + m[5]*b)
+			row[x+2] = encodeLinear(m[6]*r + m[7]*g + m[8]*b)
+		}
+	}
+}
+
+// High-contrast tuning. The stretch maps the highContrastClip percentile
+// luminance to black and the 1-highContrastClip percentile to white.
+// Bounds are smoothed across frames with weight 1/highContrastSmoothing
+// and the applied stretch only follows them once they drift more than
+// highContrastDeadband levels, so alternating scenes settle instead of
+// pumping. Ranges narrower than highContrastMinRange are left alone.
+const (
+	highContrastClip      = 0.01
+	highContrastSmoothing = 32
+	highContrastDeadband  = 8
+	highContrastMinRange  = 16
+)
+
+// highContrastFilter stretches the per-frame luminance range to full scale.
+// It works on encoded values since the stretch is perceptual.
+type highContrastFilter struct {
+	started bool
+	// lo and hi are the smoothed bounds; appliedLo and appliedHi drive lut.
+	lo, hi               float64
+	appliedLo, appliedHi int
+	lut                  [256]uint8
+}
+
+func (f *highContrastFilter) Apply(pixels []byte, width, height, stride int) {
+	var hist [256]int
+	for y := 0; y < height; y++ {
+		row := pixels[y*stride : y*stride+width*4]
+		for x := 0; x < len(row); x += 4 {
+			hist[luma(row[x], row[x+1], row[x+2])]++
+		}
+	}
+	lo, hi := histogramBounds(&hist, width*height)
+	f.update(float64(lo), float64(hi))
+
+	if f.appliedHi-f.appliedLo < highContrastMinRange {
+		return
+	}
+	for y := 0; y < height; y++ {
+		row := pixels[y*stride : y*stride+width*4]
+		for x := 0; x < len(row); x += 4 {
+			row[x] = f.lut[row[x]]
+			row[x+1] = f.lut[row[x+1]]
+			row[x+2] = f.lut[row[x+2]]
+		}
+	}
+}
+
+// update folds a frame's bounds into the smoothed bounds and rebuilds the
+// lookup table when the applied bounds move.
+func (f *highContrastFilter) update(lo, hi float64) {
+	if !f.started {
+		f.started = true
+		f.lo, f.hi = lo, hi
+		f.setApplied(int(lo), int(hi))
+		return
+	}
+	f.lo += (lo - f.lo) / highContrastSmoothing
+	f.hi += (hi - f.hi) / highContrastSmoothing
+
+	nlo, nhi := f.appliedLo, f.appliedHi
+	if math.Abs(f.lo-float64(nlo)) > highContrastDeadband {
+		nlo = int(math.Round(f.lo))
+	}
+	if math.Abs(f.hi-float64(nhi)) > highContrastDeadband {
+		nhi = int(math.Round(f.hi))
+	}
+	if nlo != f.appliedLo || nhi != f.appliedHi {
+		f.setApplied(nlo, nhi)
+	}
+}
+
+func (f *highContrastFilter) setApplied(lo, hi int) {
+	f.appliedLo, f.appliedHi = lo, hi
+	if hi-lo < highContrastMinRange {
+		return
+	}
+	for i := range f.lut {
+		v := (i - lo) * 255 / (hi - lo)
+		f.lut[i] = uint8(max(0, min(255, v)))
+	}
+}
+
+// luma returns Rec. 601 luminance of an encoded pixel.
+func luma(r, g, b uint8) uint8 {
+	return uint8((299*int(r) + 587*int(g) + 114*int(b) + 500) / 1000)
+}
+
+// histogramBounds returns the luminance levels at the clip percentiles.
+func histogramBounds(hist *[256]int, total int) (lo, hi int) {
+	clip := int(float64(total) * highContrastClip)
+	n := 0
+	for lo = 0; lo < 255; lo++ {
+		n += hist[lo]
+		if n > clip {
+			break
+		}
+	}
+	n = 0
+	for hi = 255; hi > 0; hi-- {
+		n += hist[hi]
+		if n > clip {
+			break
+		}
+	}
+	return lo, hi
+}
@@ -0,0 +1,107 @@
+package ios
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDaltonizeMatrices(t *testing.T) {
+	tests := []struct {
+		name string
+		sim  mat3
+		want mat3
+	}{
+		{"deuteranopia", deuteranopiaSim, mat3{1, 0, 0, 0.16279, 0.725047, 0.112165, 0.454695, -0.645392, 1.190697}},
+		{"protanopia", protanopiaSim, mat3{1, 0, 0, 0.478897, 0.476911, 0.044192, 0.597282, -0.688692, 1.09141}},
+		{"tritanopia", tritanopiaSim, mat3{1, 0, 0, -0.100459, 1.122915, -0.022457, -0.183603, -0.637643, 1.821245}},
+	}
+	for _, tt := range tests {
+		got := daltonizeMatrix(tt.sim)
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-6 {
+				t.Errorf("%s: matrix[%d] = %f, want %f", tt.name, i, got[i], tt.want[i])
+			}
+		}
+		// Rows summing to one keep neutral grays neutral.
+		for r := 0; r < 3; r++ {
+			if sum := got[r*3] + got[r*3+1] + got[r*3+2]; math.Abs(sum-1) > 1e-5 {
+				t.Errorf("%s: row %d sums to %f", tt.name, r, sum)
+			}
+		}
+	}
+}
+
+func TestDaltonizeReferenceColors(t *testing.T) {
+	tests := []struct {
+		filter  string
+		in, out [3]byte
+	}{
+		{"deuteranopia", [3]byte{255, 0, 0}, [3]byte{255, 112, 180}},
+		{"deuteranopia", [3]byte{0, 255, 0}, [3]byte{0, 221, 0}},
+		{"deuteranopia", [3]byte{128, 64, 32}, [3]byte{128, 77, 81}},
+		{"protanopia", [3]byte{255, 0, 0}, [3]byte{255, 184, 203}},
+		{"protanopia", [3]byte{128, 64, 32}, [3]byte{128, 100, 93}},
+		{"tritanopia", [3]byte{128, 64, 32}, [3]byte{128, 53, 0}},
+		{"deuteranopia", [3]byte{128, 128, 128}, [3]byte{128, 128, 128}},
+	}
+	for _, tt := range tests {
+		f, ok := lookupFrameFilter(tt.filter)
+		if !ok {
+			t.Fatalf("%s not registered", tt.filter)
+		}
+		px := []byte{tt.in[0], tt.in[1], tt.in[2], 0x7F}
+		f.Apply(px, 1, 1, 4)
+		if [3]byte(px[:3]) != tt.out || px[3] != 0x7F {
+			t.Errorf("%s(%v) = %v, want %v with alpha kept", tt.filter, tt.in, px, tt.out)
+		}
+	}
+}
+
+func TestSRGBRoundTrip(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		if got := encodeLinear(srgbToLinear[i]); got != uint8(i) {
+			t.Errorf("round trip %d = %d", i, got)
+		}
+	}
+}
+
+// grayFrame returns an 8x8 frame split between two gray levels.
+func grayFrame(dark, light byte) []byte {
+	px := make([]byte, 8*8*4)
+	for i := 0; i < len(px); i += 4 {
+		v := dark
+		if i >= len(px)/2 {
+			v = light
+		}
+		px[i], px[i+1], px[i+2], px[i+3] = v, v, v, 0xFF
+	}
+	return px
+}
+
+func TestHighContrastStretches(t *testing.T) {
+	f := &highContrastFilter{}
+	px := grayFrame(64, 192)
+	f.Apply(px, 8, 8, 32)
+	if px[0] != 0 || px[len(px)-4] != 255 {
+		t.Errorf("stretched to %d..%d, want 0..255", px[0], px[len(px)-4])
+	}
+}
+
+func TestHighContrastNoOscillation(t *testing.T) {
+	f := &highContrastFilter{}
+	frames := [2][]byte{grayFrame(0, 255), grayFrame(100, 150)}
+
+	type bounds struct{ lo, hi int }
+	var history []bounds
+	for i := 0; i < 400; i++ {
+		px := append([]byte(nil), frames[i%2]...)
+		f.Apply(px, 8, 8, 32)
+		history = append(history, bounds{f.appliedLo, f.appliedHi})
+	}
+	settled := history[len(history)-100]
+	for i, b := range history[len(history)-100:] {
+		if b != settled {
+			t.Fatalf("applied bounds still moving at frame %d: %v vs %v", i, b, settled)
+		}
+	}
+}
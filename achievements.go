@@ -0,0 +1,437 @@
+package ios
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Achievements are evaluated in the bridge from RetroAchievements-style
+// condition strings, reading memory through the core's MemoryInspector.
+// The supported subset:
+//
+//	memory   0xH addr (8-bit), 0x addr (16-bit), 0xW (24-bit),
+//	         0xX (32-bit), 0xL/0xU (low/high nibble), 0xM-0xT (bit 0-7)
+//	modifier d (value last frame), p (value before its last change)
+//	literal  decimal, or hex with an h prefix
+//	compare  = != < <= > >=
+//	hits     trailing (N) or .N., the condition must be true on N frames
+//	flags    R: resets all hit counts, P: pauses its group
+//
+// Conditions are joined by "_"; "S" separates the core group from
+// alternates. An achievement unlocks when the core group and any
+// alternate are true, after it has been seen false at least once.
+
+// achievement is a loaded achievement and its evaluation state.
+type achievement struct {
+	id       int
+	title    string
+	groups   []condGroup
+	armed    bool
+	unlocked bool
+}
+
+type condGroup []*condition
+
+type condition struct {
+	flag      byte // 0, 'R' or 'P'
+	left      operand
+	op        string
+	right     operand
+	hitTarget int
+	hits      int
+}
+
+// Operand kinds.
+const (
+	operandValue = iota
+	operandMem
+	operandDelta
+	operandPrior
+)
+
+type operand struct {
+	kind  int
+	size  byte // memory size code: 'H', ' ', 'W', 'X', 'L', 'U', 'M'-'T'
+	addr  uint32
+	value uint32
+
+	// last and prior track memory values across frames for d and p.
+	last, prior uint32
+	seen        bool
+}
+
+// achievementEvent is an entry in PollAchievementEventsJSON.
+type achievementEvent struct {
+	Type  string `json:"type"`
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Frame int64  `json:"frame"`
+}
+
+// achievementState holds an instance's achievements and unpolled events.
+// eventsMu lets the app poll without waiting for a frame.
+type achievementState struct {
+	list     []*achievement
+	eventsMu sync.Mutex
+	events   []achievementEvent
+	scratch  [4]byte
+}
+
+// LoadAchievementsJSON replaces the loaded game's achievements with a
+// JSON array of {"id", "title", "memAddr"} objects, memAddr being the
+// condition string. Requires a core with MemoryInspector.
+// Returns false if the core has no memory access or a definition does not
+// parse (see LastError).
+func LoadAchievementsJSON(defs string) bool {
+	return def.loadAchievements(defs)
+}
+
+func (in *instance) loadAchievements(defs string) bool {
+	var parsed []struct {
+		ID      int    `json:"id"`
+		Title   string `json:"title"`
+		MemAddr string `json:"memAddr"`
+	}
+	if err := json.Unmarshal([]byte(defs), &parsed); err != nil {
+		setLastError("invalid achievements: %v", err)
+		return false
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.memInspector == nil {
+		setLastError("achievements need memory access")
+		return false
+	}
+	list := make([]*achievement, 0, len(parsed))
+	for _, p := range parsed {
+		groups, err := parseConditions(p.MemAddr)
+		if err != nil {
+			setLastError("achievement %d: %v", p.ID, err)
+			return false
+		}
+		list = append(list, &achievement{id: p.ID, title: p.Title, groups: groups})
+	}
+	in.achievements.list = list
+	return true
+}
+
+// PollAchievementEventsJSON drains achievement events, oldest first, as a
+// JSON array of {"type", "id", "title", "frame"} objects. type is
+// "unlocked".
+func PollAchievementEventsJSON() string {
+	a := &def.achievements
+	a.eventsMu.Lock()
+	events := a.events
+	a.events = nil
+	a.eventsMu.Unlock()
+	if len(events) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// SetHardcoreMode turns hardcore mode on or off. In hardcore mode states
+// cannot be loaded, cheats are suspended and rewind is unavailable.
+func SetHardcoreMode(enabled bool) {
+	def.setHardcoreMode(enabled)
+}
+
+func (in *instance) setHardcoreMode(enabled bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.hardcore == enabled {
+		return
+	}
+	in.hardcore = enabled
+	if enabled {
+		in.rewind = nil
+	}
+	in.reapplyCheats()
+}
+
+// IsHardcoreMode returns whether hardcore mode is on.
+func IsHardcoreMode() bool {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.hardcore
+}
+
+// evaluateAchievements checks every locked achievement against this
+// frame's memory.
+func (in *instance) evaluateAchievements() {
+	a := &in.achievements
+	for _, ach := range a.list {
+		if ach.unlocked {
+			continue
+		}
+		if !in.testAchievement(ach) {
+			ach.armed = true
+			continue
+		}
+		if !ach.armed {
+			continue
+		}
+		ach.unlocked = true
+		a.eventsMu.Lock()
+		a.events = append(a.events, achievementEvent{
+			Type:  "unlocked",
+			ID:    ach.id,
+			Title: ach.title,
+			Frame: in.frameCount,
+		})
+		a.eventsMu.Unlock()
+	}
+}
+
+// testAchievement evaluates ach's groups for this frame.
+func (in *instance) testAchievement(ach *achievement) bool {
+	reset := false
+	coreTrue := in.testGroup(ach.groups[0], &reset)
+	altTrue := len(ach.groups) == 1
+	for _, g := range ach.groups[1:] {
+		if in.testGroup(g, &reset) {
+			altTrue = true
+		}
+	}
+	if reset {
+		for _, g := range ach.groups {
+			for _, c := range g {
+				c.hits = 0
+			}
+		}
+		return false
+	}
+	return coreTrue && altTrue
+}
+
+// testGroup evaluates one condition group, setting *reset if a reset
+// condition is true.
+func (in *instance) testGroup(g condGroup, reset *bool) bool {
+	paused := false
+	for _, c := range g {
+		if c.flag == 'P' && in.compare(c) {
+			paused = true
+		}
+	}
+	result := true
+	for _, c := range g {
+		if c.flag == 'P' {
+			continue
+		}
+		ok := in.compare(c)
+		if c.flag == 'R' {
+			if ok {
+				*reset = true
+			}
+			continue
+		}
+		if paused {
+			result = false
+			continue
+		}
+		if c.hitTarget > 0 {
+			if ok && c.hits < c.hitTarget {
+				c.hits++
+			}
+			ok = c.hits >= c.hitTarget
+		}
+		if !ok {
+			result = false
+		}
+	}
+	return result && !paused
+}
+
+// compare evaluates a condition's comparison, advancing its operands'
+// frame history.
+func (in *instance) compare(c *condition) bool {
+	l, r := in.operandValue(&c.left), in.operandValue(&c.right)
+	switch c.op {
+	case "=":
+		return l == r
+	case "!=":
+		return l != r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+// operandValue returns o's value this frame. Memory operands remember
+// the value for the next frame's delta and prior.
+func (in *instance) operandValue(o *operand) uint32 {
+	if o.kind == operandValue {
+		return o.value
+	}
+	v := in.peek(o.size, o.addr)
+	if !o.seen {
+		o.last, o.prior, o.seen = v, v, true
+	}
+	delta := o.last
+	if v != o.last {
+		o.prior = o.last
+	}
+	o.last = v
+	switch o.kind {
+	case operandDelta:
+		return delta
+	case operandPrior:
+		return o.prior
+	}
+	return v
+}
+
+// peek reads a value of the given size code at addr.
+func (in *instance) peek(size byte, addr uint32) uint32 {
+	n := 1
+	switch size {
+	case ' ':
+		n = 2
+	case 'W':
+		n = 3
+	case 'X':
+		n = 4
+	}
+	buf := in.achievements.scratch[:n]
+	clear(buf)
+	in.memInspector.ReadMemory(addr, buf)
+	var v uint32
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint32(buf[i])
+	}
+	switch {
+	case size == 'L':
+		v &= 0x0F
+	case size == 'U':
+		v >>= 4
+	case size >= 'M' && size <= 'T':
+		v = v >> (size - 'M') & 1
+	}
+	return v
+}
+
+// parseConditions parses a condition string into its core group and
+// alternates.
+func parseConditions(memAddr string) ([]condGroup, error) {
+	var groups []condGroup
+	for i, part := range splitGroups(memAddr) {
+		if part == "" {
+			return nil, fmt.Errorf("empty condition group %d", i)
+		}
+		var g condGroup
+		for _, s := range strings.Split(part, "_") {
+			c, err := parseCondition(s)
+			if err != nil {
+				return nil, fmt.Errorf("condition %q: %w", s, err)
+			}
+			g = append(g, c)
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// splitGroups splits a condition string at its "S" group separators,
+// leaving the 0xS bit size alone.
+func splitGroups(memAddr string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(memAddr); i++ {
+		if memAddr[i] == 'S' && !strings.HasSuffix(memAddr[:i], "0x") {
+			parts = append(parts, memAddr[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, memAddr[start:])
+}
+
+func parseCondition(s string) (*condition, error) {
+	c := &condition{}
+	if len(s) > 2 && s[1] == ':' {
+		if s[0] != 'R' && s[0] != 'P' {
+			return nil, fmt.Errorf("unsupported flag %c", s[0])
+		}
+		c.flag, s = s[0], s[2:]
+	}
+	if open := strings.IndexByte(s, '('); open >= 0 && strings.HasSuffix(s, ")") {
+		n, err := strconv.Atoi(s[open+1 : len(s)-1])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("bad hit count")
+		}
+		c.hitTarget, s = n, s[:open]
+	} else if strings.HasSuffix(s, ".") {
+		open := strings.LastIndexByte(s[:len(s)-1], '.')
+		n, err := strconv.Atoi(s[open+1 : len(s)-1])
+		if open < 0 || err != nil || n < 0 {
+			return nil, fmt.Errorf("bad hit count")
+		}
+		c.hitTarget, s = n, s[:open]
+	}
+
+	// Two-character operators first so "<=" is not read as "<".
+	for _, op := range []string{"!=", "<=", ">=", "=", "<", ">"} {
+		if i := strings.Index(s, op); i > 0 {
+			left, err := parseOperand(s[:i])
+			if err != nil {
+				return nil, err
+			}
+			right, err := parseOperand(s[i+len(op):])
+			if err != nil {
+				return nil, err
+			}
+			c.left, c.op, c.right = left, op, right
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("missing comparison")
+}
+
+func parseOperand(s string) (operand, error) {
+	o := operand{kind: operandMem}
+	switch {
+	case strings.HasPrefix(s, "d0x"):
+		o.kind, s = operandDelta, s[1:]
+	case strings.HasPrefix(s, "p0x"):
+		o.kind, s = operandPrior, s[1:]
+	}
+	if !strings.HasPrefix(s, "0x") {
+		if o.kind != operandMem {
+			return operand{}, fmt.Errorf("bad operand %q", s)
+		}
+		base := 10
+		if strings.HasPrefix(s, "h") {
+			base, s = 16, s[1:]
+		}
+		v, err := strconv.ParseUint(s, base, 32)
+		if err != nil {
+			return operand{}, fmt.Errorf("bad value %q", s)
+		}
+		return operand{kind: operandValue, value: uint32(v)}, nil
+	}
+
+	s = s[2:]
+	o.size = ' '
+	if s != "" && strings.IndexByte("HWXLUMNOPQRST ", s[0]) >= 0 {
+		o.size, s = s[0], s[1:]
+	}
+	addr, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return operand{}, fmt.Errorf("bad address %q", s)
+	}
+	o.addr = uint32(addr)
+	return o, nil
+}
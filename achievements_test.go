@@ -0,0 +1,169 @@
+package ios
+
+import (
+	"encoding/json"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockInspectEmulator exposes flat memory through MemoryInspector and
+// supports save states.
+type mockInspectEmulator struct {
+	*mockCheatEmulator
+	mem []byte
+}
+
+func (m *mockInspectEmulator) ReadMemory(addr uint32, buf []byte) uint32 {
+	if int(addr) >= len(m.mem) {
+		return 0
+	}
+	return uint32(copy(buf, m.mem[addr:]))
+}
+
+func useInspectEmulator(t *testing.T) *mockInspectEmulator {
+	t.Helper()
+	var e *mockInspectEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockInspectEmulator{
+				mockCheatEmulator: &mockCheatEmulator{mockEmulator: newMockEmulator(rom, region)},
+				mem:               make([]byte, 16),
+			}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func pollAchievements(t *testing.T) []achievementEvent {
+	t.Helper()
+	var events []achievementEvent
+	if err := json.Unmarshal([]byte(PollAchievementEventsJSON()), &events); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestAchievementUnlock(t *testing.T) {
+	e := useInspectEmulator(t)
+	defs := `[
+		{"id": 1, "title": "Ten lives", "memAddr": "0xH0002=10"},
+		{"id": 2, "title": "Level up", "memAddr": "0xH0003>d0xH0003_0xM0004=1"},
+		{"id": 3, "title": "Hold on", "memAddr": "0xH0005=1(3)_R:0xH0006=1"},
+		{"id": 4, "title": "Either", "memAddr": "0x0008=h1234S0xH0000=1S0xH0001=1"}
+	]`
+	if !LoadAchievementsJSON(defs) {
+		t.Fatalf("LoadAchievementsJSON failed: %s", LastError())
+	}
+
+	RunFrame()
+	if events := pollAchievements(t); len(events) != 0 {
+		t.Fatalf("unlocked with nothing set: %+v", events)
+	}
+
+	e.mem[2] = 10
+	e.mem[3] = 1
+	e.mem[4] = 0x01
+	e.mem[5] = 1
+	RunFrame()
+	events := pollAchievements(t)
+	if len(events) != 2 || events[0].ID != 1 || events[1].ID != 2 || events[0].Frame != 2 {
+		t.Fatalf("events = %+v, want 1 and 2 at frame 2", events)
+	}
+
+	// Achievement 3 needs three hits; a reset condition clears them.
+	RunFrame()
+	e.mem[6] = 1
+	RunFrame()
+	e.mem[6] = 0
+	RunFrame()
+	RunFrame()
+	if events := pollAchievements(t); len(events) != 0 {
+		t.Fatalf("hit count not reset: %+v", events)
+	}
+	RunFrame()
+	if events := pollAchievements(t); len(events) != 1 || events[0].ID != 3 {
+		t.Fatalf("events = %+v, want 3", events)
+	}
+
+	// The core group needs 0x1234 little-endian and any alternate.
+	e.mem[8], e.mem[9] = 0x34, 0x12
+	RunFrame()
+	if events := pollAchievements(t); len(events) != 0 {
+		t.Fatal("unlocked without an alternate")
+	}
+	e.mem[1] = 1
+	RunFrame()
+	if events := pollAchievements(t); len(events) != 1 || events[0].ID != 4 || events[0].Title != "Either" {
+		t.Fatalf("events = %+v, want 4", events)
+	}
+
+	RunFrame()
+	if events := pollAchievements(t); len(events) != 0 {
+		t.Error("achievement unlocked twice")
+	}
+}
+
+func TestAchievementNeedsFalseFirst(t *testing.T) {
+	e := useInspectEmulator(t)
+	e.mem[0] = 1
+	LoadAchievementsJSON(`[{"id": 1, "title": "t", "memAddr": "0xH0000=1"}]`)
+	RunFrame()
+	RunFrame()
+	if events := pollAchievements(t); len(events) != 0 {
+		t.Error("unlocked when already true at load")
+	}
+	e.mem[0] = 0
+	RunFrame()
+	e.mem[0] = 1
+	RunFrame()
+	if events := pollAchievements(t); len(events) != 1 {
+		t.Error("not unlocked after a true edge")
+	}
+}
+
+func TestParseConditionsErrors(t *testing.T) {
+	for _, s := range []string{"", "0xH0000", "0xHzz=1", "Q:0xH00=1", "0xH00=1(x)", "d5=1", "0xH00=1S"} {
+		if _, err := parseConditions(s); err == nil {
+			t.Errorf("parseConditions(%q) succeeded", s)
+		}
+	}
+	g, err := parseConditions("0xS0001=1_0xH0002=1.5.")
+	if err != nil || len(g) != 1 || g[0][0].left.size != 'S' || g[0][1].hitTarget != 5 {
+		t.Errorf("parseConditions = %+v, %v", g, err)
+	}
+}
+
+func TestHardcoreMode(t *testing.T) {
+	e := useInspectEmulator(t)
+	if !AddCheat("OK1") {
+		t.Fatal("AddCheat failed")
+	}
+	SaveState()
+	state := append([]byte(nil), def.stateData...)
+
+	SetHardcoreMode(true)
+	if !IsHardcoreMode() || len(e.applied) != 0 {
+		t.Errorf("hardcore left cheats applied: %v", e.applied)
+	}
+	if LoadState(state) {
+		t.Error("LoadState allowed in hardcore mode")
+	}
+	if AddCheat("OK2") {
+		t.Error("AddCheat allowed in hardcore mode")
+	}
+
+	SetHardcoreMode(false)
+	if len(e.applied) != 1 || !LoadState(state) {
+		t.Errorf("leaving hardcore: cheats %v", e.applied)
+	}
+
+	useMockEmulator(t)
+	if LoadAchievementsJSON(`[]`) {
+		t.Error("achievements loaded without memory access")
+	}
+}
@@ -0,0 +1,327 @@
+package ios
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+	"github.com/user-none/eblitui/romloader"
+)
+
+// maxEntrySize matches romloader's ROM size limit.
+const maxEntrySize = 8 * 1024 * 1024
+
+var zipMagic = []byte{0x50, 0x4B, 0x03, 0x04}
+
+// errEntryNotFound is returned when a named archive entry does not exist.
+var errEntryNotFound = errors.New("entry not found in archive")
+
+// archiveEntry describes a ROM candidate inside an archive.
+type archiveEntry struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	CRC32 string `json:"crc32"`
+}
+
+// hasROMExtension reports whether name ends in one of extensions,
+// ignoring case.
+func hasROMExtension(name string, extensions []string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range extensions {
+		if strings.HasSuffix(lower, strings.ToLower(ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Archive formats that can list and select entries.
+const (
+	archiveNone = iota
+	archiveZip
+	archive7z
+	archiveRAR
+)
+
+// archiveFormat identifies the archive at path from its magic.
+func archiveFormat(path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return archiveNone
+	}
+	defer f.Close()
+	header := make([]byte, len(sevenZMagic))
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return archiveZip
+	case bytes.HasPrefix(header, sevenZMagic):
+		return archive7z
+	case bytes.HasPrefix(header, rarMagic):
+		return archiveRAR
+	}
+	return archiveNone
+}
+
+// listROMEntries lists the ROM candidates at path. ZIP, 7z and RAR
+// archives list every matching entry; other files yield the single ROM
+// romloader would load.
+func listROMEntries(path string, extensions []string) ([]archiveEntry, error) {
+	switch archiveFormat(path) {
+	case archiveZip:
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+		defer r.Close()
+
+		var entries []archiveEntry
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() || !hasROMExtension(f.Name, extensions) {
+				continue
+			}
+			entries = append(entries, archiveEntry{
+				Name:  f.Name,
+				Size:  int64(f.UncompressedSize64),
+				CRC32: fmt.Sprintf("%08X", f.CRC32),
+			})
+		}
+		return entries, nil
+
+	case archive7z:
+		r, err := sevenzip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open 7z: %w", err)
+		}
+		defer r.Close()
+
+		var entries []archiveEntry
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() || !hasROMExtension(f.Name, extensions) {
+				continue
+			}
+			crc := f.CRC32
+			if crc == 0 && f.UncompressedSize > 0 {
+				// Solid archives may omit per-file CRCs.
+				rc, err := f.Open()
+				if err != nil {
+					return nil, fmt.Errorf("failed to open %s in archive: %w", f.Name, err)
+				}
+				data, _, err := readEntry(rc, f.Name)
+				rc.Close()
+				if err != nil {
+					return nil, err
+				}
+				crc = crc32.ChecksumIEEE(data)
+			}
+			entries = append(entries, archiveEntry{
+				Name:  f.Name,
+				Size:  int64(f.UncompressedSize),
+				CRC32: fmt.Sprintf("%08X", crc),
+			})
+		}
+		return entries, nil
+
+	case archiveRAR:
+		// RAR headers carry no usable CRC32, so entries are read.
+		var entries []archiveEntry
+		err := walkRAR(path, extensions, func(name string, r io.Reader) (bool, error) {
+			data, _, err := readEntry(r, name)
+			if err != nil {
+				return false, err
+			}
+			entries = append(entries, archiveEntry{
+				Name:  name,
+				Size:  int64(len(data)),
+				CRC32: fmt.Sprintf("%08X", crc32.ChecksumIEEE(data)),
+			})
+			return true, nil
+		})
+		return entries, err
+	}
+
+	rom, name, err := romloader.Load(path, extensions)
+	if err != nil {
+		return nil, err
+	}
+	return []archiveEntry{{
+		Name:  name,
+		Size:  int64(len(rom)),
+		CRC32: fmt.Sprintf("%08X", crc32.ChecksumIEEE(rom)),
+	}}, nil
+}
+
+// walkRAR calls fn for each ROM candidate in the RAR archive at path,
+// with a reader for its contents, until fn returns false or an error.
+func walkRAR(path string, extensions []string, fn func(name string, r io.Reader) (bool, error)) error {
+	r, err := rardecode.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open rar: %w", err)
+	}
+	defer r.Close()
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read rar entry: %w", err)
+		}
+		if header.IsDir || !hasROMExtension(header.Name, extensions) {
+			continue
+		}
+		more, err := fn(header.Name, r)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// loadROMEntry loads the entry named entryName from the archive at path.
+// The name must match exactly; there is no fallback to another entry.
+// For non-archive files the name is the one listROMEntries reports.
+// Returns the ROM data and the entry's base filename.
+func loadROMEntry(path, entryName string, extensions []string) ([]byte, string, error) {
+	switch archiveFormat(path) {
+	case archiveZip:
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open zip: %w", err)
+		}
+		defer r.Close()
+
+		for _, f := range r.File {
+			if f.Name != entryName || f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to open %s in archive: %w", f.Name, err)
+			}
+			defer rc.Close()
+			return readEntry(rc, f.Name)
+		}
+
+	case archive7z:
+		r, err := sevenzip.OpenReader(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open 7z: %w", err)
+		}
+		defer r.Close()
+
+		for _, f := range r.File {
+			if f.Name != entryName || f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to open %s in archive: %w", f.Name, err)
+			}
+			defer rc.Close()
+			return readEntry(rc, f.Name)
+		}
+
+	case archiveRAR:
+		var (
+			rom  []byte
+			name string
+		)
+		err := walkRAR(path, extensions, func(n string, r io.Reader) (bool, error) {
+			if n != entryName {
+				return true, nil
+			}
+			var err error
+			rom, name, err = readEntry(r, n)
+			return false, err
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		if rom != nil {
+			return rom, name, nil
+		}
+
+	default:
+		rom, name, err := romloader.Load(path, extensions)
+		if err != nil {
+			return nil, "", err
+		}
+		if name != entryName {
+			return nil, "", fmt.Errorf("%w: %s", errEntryNotFound, entryName)
+		}
+		return rom, name, nil
+	}
+	return nil, "", fmt.Errorf("%w: %s", errEntryNotFound, entryName)
+}
+
+// ListROMsInArchive returns the ROM candidates in an archive as a JSON
+// array of {"name", "size", "crc32"} objects. name is the full path
+// inside the archive, as accepted by ExtractAndStoreROMEntry and
+// InitWithEntry. ZIP, 7z and RAR archives list every ROM; other files,
+// including gzip, list a single entry.
+// Returns "[]" on error (see LastError).
+func ListROMsInArchive(path string) string {
+	f := activeFactory()
+	if f == nil {
+		setLastError("no factory registered")
+		return "[]"
+	}
+	entries, err := listROMEntries(path, f.SystemInfo().Extensions)
+	if err != nil {
+		setLastError("failed to list archive: %v", err)
+		return "[]"
+	}
+	if len(entries) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// ExtractAndStoreROMEntry is ExtractAndStoreROM for a specific archive
+// entry. It fails if the entry does not exist.
+func ExtractAndStoreROMEntry(srcPath, entryName, destDir string, overwrite bool) (string, error) {
+	f := activeFactory()
+	if f == nil {
+		return "", fmt.Errorf("no factory registered")
+	}
+
+	info := f.SystemInfo()
+	if len(info.Extensions) == 0 {
+		return "", fmt.Errorf("no extensions configured")
+	}
+
+	rom, romFilename, err := loadROMEntry(srcPath, entryName, info.Extensions)
+	if err != nil {
+		return "", fmt.Errorf("failed to load ROM: %w", err)
+	}
+
+	return storeROM(f, rom, romFilename, destDir, overwrite)
+}
+
+// InitWithEntry is Init for a specific archive entry.
+// Returns false if the entry does not exist (see LastError).
+func InitWithEntry(path, entryName string, regionCode int) bool {
+	f := activeFactory()
+	if f == nil {
+		return false
+	}
+	rom, _, err := loadROMEntry(path, entryName, f.SystemInfo().Extensions)
+	if err != nil {
+		setLastError("failed to load ROM: %v", err)
+		return false
+	}
+	return def.initROM(f, rom, regionCode)
+}
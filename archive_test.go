@@ -0,0 +1,211 @@
+package ios
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+type zipFile struct {
+	name string
+	data []byte
+}
+
+func writeZip(t *testing.T, files ...zipFile) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "roms.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for _, zf := range files {
+		fw, err := w.Create(zf.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(zf.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var (
+	romA = []byte("first rom")
+	romB = []byte("second rom, translated")
+)
+
+func writeTwoROMZip(t *testing.T) string {
+	return writeZip(t,
+		zipFile{"readme.txt", []byte("hello")},
+		zipFile{"Game (USA).bin", romA},
+		zipFile{"hacks/Game (T-En).bin", romB},
+	)
+}
+
+func TestListROMsInArchive(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	path := writeTwoROMZip(t)
+
+	var entries []archiveEntry
+	if err := json.Unmarshal([]byte(ListROMsInArchive(path)), &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := []archiveEntry{
+		{Name: "Game (USA).bin", Size: int64(len(romA)), CRC32: fmt.Sprintf("%08X", crc32.ChecksumIEEE(romA))},
+		{Name: "hacks/Game (T-En).bin", Size: int64(len(romB)), CRC32: fmt.Sprintf("%08X", crc32.ChecksumIEEE(romB))},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestListROMsInArchiveRawFile(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	path := writeROM(t, "single.bin", romA)
+
+	var entries []archiveEntry
+	if err := json.Unmarshal([]byte(ListROMsInArchive(path)), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "single.bin" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestExtractAndStoreROMEntry(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	path := writeTwoROMZip(t)
+	dest := t.TempDir()
+
+	result, err := ExtractAndStoreROMEntry(path, "hacks/Game (T-En).bin", dest, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct{ CRC, Name string }
+	json.Unmarshal([]byte(result), &parsed)
+	wantCRC := fmt.Sprintf("%08X", crc32.ChecksumIEEE(romB))
+	if parsed.CRC != wantCRC || parsed.Name != "Game (T-En)" {
+		t.Errorf("result = %s", result)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, wantCRC+".bin"))
+	if err != nil || !bytes.Equal(data, romB) {
+		t.Errorf("stored ROM = %q, %v", data, err)
+	}
+
+	if _, err := ExtractAndStoreROMEntry(path, "Game (T-En).bin", dest, false); err == nil {
+		t.Error("expected error for non-exact entry name")
+	}
+
+	// The single-entry path is unchanged: first matching ROM wins.
+	result, err = ExtractAndStoreROM(path, dest, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal([]byte(result), &parsed)
+	if parsed.CRC != fmt.Sprintf("%08X", crc32.ChecksumIEEE(romA)) {
+		t.Errorf("ExtractAndStoreROM picked %s", result)
+	}
+}
+
+func TestInitWithEntry(t *testing.T) {
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			return e, nil
+		},
+	})
+	path := writeTwoROMZip(t)
+
+	if !InitWithEntry(path, "hacks/Game (T-En).bin", 0) {
+		t.Fatalf("InitWithEntry failed: %s", LastError())
+	}
+	if !bytes.Equal(e.rom, romB) {
+		t.Errorf("loaded %q, want %q", e.rom, romB)
+	}
+	e = nil
+	if InitWithEntry(path, "missing.bin", 0) || e != nil {
+		t.Error("InitWithEntry fell back for a missing entry")
+	}
+}
+
+// sevenZipArchive holds something.txt ("123\n") and two empty .txt files.
+const sevenZipArchive = "N3q8ryccAANRVvxwggAAAAAAAAAhAAAAAAAAAHOl1r4AGIyCszp3oAAAAIEzB64P4OxyY2LTejwpdVWMXE1hgnpN3FKCAp2QGqqPKCpTeHPlMCsyz+WyH65QfBrS/AYEosXxvkK404c3rWok9gD6f0bkPCV9fZZ1EP1I59puXmTPd8N99rAtUEXQcElZ9TFXP9m1FehTii3d0JG8ejrIAAAAFwYJAQl5AAcLAQABIwMBAQVdABAAAAyAqAoBUg1anwAA"
+
+func TestListAndSelect7zEntries(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		modify: func(info *emucore.SystemInfo) { info.Extensions = []string{".txt"} },
+	})
+	data, err := base64.StdEncoding.DecodeString(sevenZipArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "roms.7z")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []archiveEntry
+	if err := json.Unmarshal([]byte(ListROMsInArchive(path)), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0] != (archiveEntry{Name: "something.txt", Size: 4, CRC32: "5A82FD08"}) {
+		t.Fatalf("entries = %+v", entries)
+	}
+
+	rom, name, err := loadROMEntry(path, "something.txt", []string{".txt"})
+	if err != nil || string(rom) != "123\n" || name != "something.txt" {
+		t.Errorf("loadROMEntry = %q, %q, %v", rom, name, err)
+	}
+	if _, _, err := loadROMEntry(path, "missing.txt", []string{".txt"}); err == nil {
+		t.Error("loaded a missing 7z entry")
+	}
+}
+
+func TestExtractAndStoreROMFrom7z(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		modify: func(info *emucore.SystemInfo) { info.Extensions = []string{".txt"} },
+	})
+	data, err := base64.StdEncoding.DecodeString(sevenZipArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(t.TempDir(), "roms.7z")
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := extractAndStore(t, src, t.TempDir(), false)
+	if got.Status != "written" || got.CRC != "5A82FD08" || got.Size != 4 {
+		t.Errorf("7z store = %+v", got)
+	}
+
+	var info struct {
+		ArchiveExtensions []string `json:"ArchiveExtensions"`
+	}
+	if err := json.Unmarshal([]byte(SystemInfoJSON()), &info); err != nil {
+		t.Fatal(err)
+	}
+	if !hasROMExtension("roms.7z", info.ArchiveExtensions) || !hasROMExtension("roms.rar", info.ArchiveExtensions) {
+		t.Errorf("ArchiveExtensions = %v", info.ArchiveExtensions)
+	}
+}
@@ -0,0 +1,89 @@
+package ios
+
+// AspectReporter is an optional emulator interface for cores whose display
+// aspect ratio depends on the video mode, such as a console that switches
+// between 256 and 320 pixel wide modes on the same 4:3 screen.
+type AspectReporter interface {
+	// DisplayAspectRatio returns the width/height the current frame is
+	// meant to be shown at, or 0 to use SystemInfo.AspectRatio.
+	DisplayAspectRatio() float64
+}
+
+// DisplayAspectRatio returns the width/height the current frame should be
+// shown at. It comes from the core for the current video mode, falling
+// back to SystemInfo.AspectRatio and then to square pixels, and is
+// adjusted for any SetCrop crop and SetRotateFrames rotation. Returns 0
+// if unknown.
+func DisplayAspectRatio() float64 {
+	return def.displayAspectRatio()
+}
+
+func (in *instance) displayAspectRatio() float64 {
+	w, h := in.displaySize()
+	par := in.pixelAspectRatio()
+	if par == 0 || h == 0 {
+		return 0
+	}
+	return par * float64(w) / float64(h)
+}
+
+// PixelAspectRatio returns the width/height of one pixel of the current
+// frame when shown at DisplayAspectRatio: 1 for square pixels, about 1.17
+// for 256x224 shown at 4:3. Returns 0 if unknown.
+func PixelAspectRatio() float64 {
+	return def.pixelAspectRatio()
+}
+
+func (in *instance) pixelAspectRatio() float64 {
+	w, h := in.uncroppedSize()
+	par := pixelAspect(in.uncroppedAspectRatio(w, h), w, h)
+	if par > 0 && in.quarterTurned() {
+		return 1 / par
+	}
+	return par
+}
+
+// uncroppedAspectRatio returns the display aspect ratio of the uncropped
+// w x h frame.
+func (in *instance) uncroppedAspectRatio(w, h int) float64 {
+	if r, ok := in.emu.(AspectReporter); ok {
+		if dar := r.DisplayAspectRatio(); dar > 0 {
+			return dar
+		}
+	}
+	if f := in.coreFactory(); f != nil {
+		if dar := f.SystemInfo().AspectRatio; dar > 0 {
+			return dar
+		}
+	}
+	if w == 0 || h == 0 {
+		return 0
+	}
+	return float64(w) / float64(h)
+}
+
+// displaySize returns the visible frame size, or the system's screen size
+// before a game is loaded.
+func (in *instance) displaySize() (w, h int) {
+	if in.emu == nil {
+		return in.frameWidthLocked(), in.frameHeightLocked()
+	}
+	return in.visibleWidth(), in.frameHeightLocked()
+}
+
+// uncroppedSize is displaySize before any crop or rotation.
+func (in *instance) uncroppedSize() (w, h int) {
+	if in.emu == nil {
+		return in.frameWidthLocked(), in.frameHeightLocked()
+	}
+	return in.uncroppedWidth(), in.emu.GetActiveHeight()
+}
+
+// pixelAspect returns the pixel aspect ratio of a w x h frame shown at
+// dar, or 0 if any is unknown.
+func pixelAspect(dar float64, w, h int) float64 {
+	if dar <= 0 || w <= 0 || h <= 0 {
+		return 0
+	}
+	return dar * float64(h) / float64(w)
+}
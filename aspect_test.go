@@ -0,0 +1,67 @@
+package ios
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockAspectEmulator reports a video-mode dependent aspect ratio.
+type mockAspectEmulator struct {
+	*mockEmulator
+	dar float64
+}
+
+func (m *mockAspectEmulator) DisplayAspectRatio() float64 { return m.dar }
+
+func TestAspectRatios(t *testing.T) {
+	var e *mockAspectEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockAspectEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+		modify: func(info *emucore.SystemInfo) {
+			info.ScreenWidth = 256
+			info.MaxScreenHeight = 224
+			info.AspectRatio = 4.0 / 3
+		},
+	})
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+	if !near(DisplayAspectRatio(), 4.0/3) || !near(PixelAspectRatio(), 4.0/3*224/256) {
+		t.Errorf("before load: DAR %v, PAR %v", DisplayAspectRatio(), PixelAspectRatio())
+	}
+
+	var info struct {
+		AspectRatio      float64
+		PixelAspectRatio float64
+	}
+	if err := json.Unmarshal([]byte(SystemInfoJSON()), &info); err != nil {
+		t.Fatal(err)
+	}
+	if !near(info.AspectRatio, 4.0/3) || !near(info.PixelAspectRatio, 4.0/3*224/256) {
+		t.Errorf("SystemInfoJSON aspect = %+v", info)
+	}
+
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	// The mock frame is 4x4 pixels.
+	if !near(DisplayAspectRatio(), 4.0/3) || !near(PixelAspectRatio(), 4.0/3) {
+		t.Errorf("system aspect: DAR %v, PAR %v", DisplayAspectRatio(), PixelAspectRatio())
+	}
+	e.dar = 2
+	if !near(DisplayAspectRatio(), 2) || !near(PixelAspectRatio(), 2) {
+		t.Errorf("core aspect: DAR %v, PAR %v", DisplayAspectRatio(), PixelAspectRatio())
+	}
+}
+
+func TestAspectRatioSquarePixelsFallback(t *testing.T) {
+	useMockEmulator(t)
+	if DisplayAspectRatio() != 1 || PixelAspectRatio() != 1 {
+		t.Errorf("DAR %v, PAR %v, want 1, 1", DisplayAspectRatio(), PixelAspectRatio())
+	}
+}
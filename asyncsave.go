@@ -0,0 +1,120 @@
+package ios
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// asyncResult is the outcome of a background save, as reported by
+// PollAsyncResultJSON.
+type asyncResult struct {
+	Ticket int    `json:"ticket"`
+	Status string `json:"status"`
+	Path   string `json:"path,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Async job statuses.
+const (
+	asyncPending = "pending"
+	asyncDone    = "done"
+	asyncFailed  = "failed"
+	asyncUnknown = "unknown"
+)
+
+// asyncJobs tracks background saves until their results are polled.
+var asyncJobs struct {
+	mu      sync.Mutex
+	next    int
+	results map[int]*asyncResult
+	wg      sync.WaitGroup
+}
+
+// startAsyncJob registers a pending job for path and returns its ticket.
+func startAsyncJob(path string) int {
+	asyncJobs.mu.Lock()
+	defer asyncJobs.mu.Unlock()
+	if asyncJobs.results == nil {
+		asyncJobs.results = map[int]*asyncResult{}
+	}
+	asyncJobs.next++
+	t := asyncJobs.next
+	asyncJobs.results[t] = &asyncResult{Ticket: t, Status: asyncPending, Path: path}
+	asyncJobs.wg.Add(1)
+	return t
+}
+
+// finishAsyncJob records a job's outcome.
+func finishAsyncJob(ticket int, err error) {
+	asyncJobs.mu.Lock()
+	defer asyncJobs.mu.Unlock()
+	defer asyncJobs.wg.Done()
+	r := asyncJobs.results[ticket]
+	if err != nil {
+		r.Status, r.Error = asyncFailed, err.Error()
+		journalf("warning", "async save to %s failed: %v", r.Path, err)
+		return
+	}
+	r.Status = asyncDone
+}
+
+// SaveStateAsync saves a state file to path like WriteStateWithMetadata
+// without holding up the frame loop: only the core's state and the
+// current frame are captured before it returns; compression, the
+// thumbnail and the write run in the background. Returns a ticket for
+// PollAsyncResultJSON, or 0 if the state could not be captured (see
+// LastError).
+func SaveStateAsync(path string) int {
+	return def.saveStateAsync(path)
+}
+
+func (in *instance) saveStateAsync(path string) int {
+	in.mu.Lock()
+	state, err := in.serializeState()
+	if err != nil {
+		in.mu.Unlock()
+		setLastError("save state failed: %v", err)
+		return 0
+	}
+	f := in.newStateFile(state, nil)
+	img := in.frameImage()
+	in.mu.Unlock()
+
+	ticket := startAsyncJob(path)
+	go func() {
+		f.thumbnail = encodePNG(img, stateThumbnailSize)
+		finishAsyncJob(ticket, writeFileAtomic(path, f.encode()))
+	}()
+	return ticket
+}
+
+// PollAsyncResultJSON reports a SaveStateAsync ticket:
+// {"ticket", "status", "path", "error"}, where status is "pending",
+// "done", "failed" (with error) or "unknown". A finished result is
+// reported once and then forgotten.
+func PollAsyncResultJSON(ticket int) string {
+	asyncJobs.mu.Lock()
+	r, ok := asyncJobs.results[ticket]
+	var result asyncResult
+	if ok {
+		result = *r
+		if r.Status != asyncPending {
+			delete(asyncJobs.results, ticket)
+		}
+	} else {
+		result = asyncResult{Ticket: ticket, Status: asyncUnknown}
+	}
+	asyncJobs.mu.Unlock()
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// WaitAsyncSaves blocks until every background save has finished, for
+// the app-termination path.
+func WaitAsyncSaves() {
+	asyncJobs.wg.Wait()
+}
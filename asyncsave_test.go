@@ -0,0 +1,54 @@
+package ios
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func pollAsync(t *testing.T, ticket int) asyncResult {
+	t.Helper()
+	var r asyncResult
+	if err := json.Unmarshal([]byte(PollAsyncResultJSON(ticket)), &r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestSaveStateAsync(t *testing.T) {
+	e := useStateEmulator(t, []byte{0x01})
+	RunFrame()
+	path := filepath.Join(t.TempDir(), "game.state")
+
+	e.value = 0x33
+	ticket := SaveStateAsync(path)
+	if ticket == 0 {
+		t.Fatalf("SaveStateAsync failed: %s", LastError())
+	}
+	// The state is captured before SaveStateAsync returns.
+	e.value = 0
+	WaitAsyncSaves()
+
+	if r := pollAsync(t, ticket); r.Status != asyncDone || r.Path != path {
+		t.Fatalf("result = %+v", r)
+	}
+	if r := pollAsync(t, ticket); r.Status != asyncUnknown {
+		t.Errorf("result reported twice: %+v", r)
+	}
+	if !LoadStateFromFile(path) || e.value != 0x33 {
+		t.Errorf("loaded value = %#x, want 0x33 (%s)", e.value, LastError())
+	}
+
+	bad := SaveStateAsync(filepath.Join(t.TempDir(), "missing", "game.state"))
+	WaitAsyncSaves()
+	if r := pollAsync(t, bad); r.Status != asyncFailed || r.Error == "" {
+		t.Errorf("failed save result = %+v", r)
+	}
+}
+
+func TestSaveStateAsyncWithoutStates(t *testing.T) {
+	useMockEmulator(t)
+	if SaveStateAsync(filepath.Join(t.TempDir(), "x.state")) != 0 {
+		t.Error("ticket issued for a core without save states")
+	}
+}
@@ -0,0 +1,217 @@
+package ios
+
+import (
+	"cmp"
+	"math"
+)
+
+// maxRateSkew is the largest fraction dynamic rate control stretches or
+// shrinks the output audio by.
+const maxRateSkew = 0.005
+
+// AudioFormatReporter is an optional emulator interface for cores whose
+// audio format differs from SystemInfo, such as a sample rate that
+// follows the region, or mono output.
+type AudioFormatReporter interface {
+	// AudioSampleRate returns the rate GetAudioSamples is produced at.
+	AudioSampleRate() int
+	// AudioChannels returns the number of interleaved channels.
+	AudioChannels() int
+}
+
+// GetAudioSampleRate returns the core's native audio sample rate in Hz,
+// or 0 if unknown.
+func GetAudioSampleRate() int {
+	rate, _ := def.sourceAudioFormat()
+	return rate
+}
+
+// GetAudioChannels returns the core's native channel count.
+func GetAudioChannels() int {
+	_, channels := def.sourceAudioFormat()
+	return channels
+}
+
+// sourceAudioFormat returns the rate and channel count of the core's
+// samples. Cores without an AudioFormatReporter produce stereo at the
+// SystemInfo sample rate.
+func (in *instance) sourceAudioFormat() (rate, channels int) {
+	if in.audioFormat != nil {
+		return in.audioFormat.AudioSampleRate(), max(in.audioFormat.AudioChannels(), 1)
+	}
+	f := in.coreFactory()
+	if f == nil {
+		return 0, 2
+	}
+	return f.SystemInfo().SampleRate, 2
+}
+
+// AudioSampleRate returns the rate in Hz GetAudioData is delivered at:
+// the SetOutputSampleRate rate, or the core's native rate. Returns 0 if
+// unknown.
+func AudioSampleRate() int {
+	return def.outputSampleRate()
+}
+
+// AudioChannels returns the number of interleaved channels in
+// GetAudioData.
+func AudioChannels() int {
+	return def.outputChannels()
+}
+
+// AudioSamplesPerFrame returns the number of sample frames (one sample
+// per channel) GetAudioData holds after a single RunFrame at normal speed,
+// rounded to the nearest whole frame. Returns 0 if unknown.
+func AudioSamplesPerFrame() int {
+	fps := def.fpsFloat()
+	if fps <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(def.outputSampleRate()) / fps))
+}
+
+func (in *instance) outputChannels() int {
+	_, channels := in.sourceAudioFormat()
+	return cmp.Or(in.audioOut.channels, channels)
+}
+
+// SetOutputSampleRate makes GetAudioData return audio resampled to rate
+// Hz. A rate of 0 returns audio at the core's native rate.
+func SetOutputSampleRate(rate int) {
+	def.setOutputSampleRate(rate)
+}
+
+func (in *instance) setOutputSampleRate(rate int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.audioOut.rate = max(rate, 0)
+	in.audioOut.reset()
+}
+
+// SetOutputChannels makes GetAudioData return audio with the given number
+// of interleaved channels: 1 mixes down to mono and 2 expands mono to
+// stereo. Any other value returns the core's native channels.
+func SetOutputChannels(channels int) {
+	def.setOutputChannels(channels)
+}
+
+func (in *instance) setOutputChannels(channels int) {
+	if channels != 1 && channels != 2 {
+		channels = 0
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.audioOut.channels = channels
+	in.audioOut.reset()
+}
+
+// ReportAudioBufferFill tells the bridge how full the frontend's audio
+// ring buffer is, in percent. Audio from later frames is stretched or
+// shrunk by up to 0.5% to move the fill back towards 50%, absorbing clock
+// drift between the emulated console and the device. Call after each
+// buffer enqueue; it is safe to call from the audio thread.
+func ReportAudioBufferFill(pct float64) {
+	def.reportAudioBufferFill(pct)
+}
+
+func (in *instance) reportAudioBufferFill(pct float64) {
+	if math.IsNaN(pct) {
+		return
+	}
+	fill := min(max(pct/100, 0), 1)
+	skew := (0.5 - fill) * 2 * maxRateSkew
+	in.rateSkew.Store(math.Float64bits(skew))
+}
+
+// audioConverter resamples and remaps channels for GetAudioData. It
+// carries the last input frame and the fractional read position across
+// frames so the output is continuous.
+type audioConverter struct {
+	// rate and channels are the requested output format; 0 means native.
+	rate     int
+	channels int
+	// skew is the dynamic rate control adjustment to the output rate.
+	skew float64
+
+	// pos is the read position in input frames relative to the start of
+	// the next batch; -1 <= pos < 0 interpolates from last.
+	pos  float64
+	last []int16
+
+	frame []int16
+	out   []int16
+}
+
+func (c *audioConverter) reset() {
+	c.pos = 0
+	c.last = nil
+}
+
+// convert returns samples in the requested output format. The result may
+// alias samples or the converter's buffer and is only valid until the
+// next call.
+func (c *audioConverter) convert(samples []int16, inRate, inChannels int) []int16 {
+	outChannels := cmp.Or(c.channels, inChannels)
+	outRate := float64(cmp.Or(c.rate, inRate)) * (1 + c.skew)
+	resample := inRate > 0 && outRate > 0 && (c.rate > 0 && c.rate != inRate || c.skew != 0)
+	if !resample && outChannels == inChannels {
+		c.reset()
+		return samples
+	}
+
+	frames := len(samples) / inChannels
+	c.out = c.out[:0]
+	if !resample {
+		for f := 0; f < frames; f++ {
+			c.emit(samples[f*inChannels:(f+1)*inChannels], outChannels)
+		}
+		return c.out
+	}
+
+	if c.last != nil && len(c.last) != inChannels {
+		c.reset()
+	}
+	if len(c.frame) != inChannels {
+		c.frame = make([]int16, inChannels)
+	}
+	at := func(i, ch int) float64 {
+		if i < 0 {
+			return float64(c.last[ch])
+		}
+		return float64(samples[i*inChannels+ch])
+	}
+
+	step := float64(inRate) / outRate
+	for ; c.pos < float64(frames-1); c.pos += step {
+		i := int(math.Floor(c.pos))
+		frac := c.pos - float64(i)
+		for ch := range c.frame {
+			a, b := at(i, ch), at(i+1, ch)
+			c.frame[ch] = int16(math.Round(a + (b-a)*frac))
+		}
+		c.emit(c.frame, outChannels)
+	}
+	if frames > 0 {
+		c.last = append(c.last[:0], samples[(frames-1)*inChannels:frames*inChannels]...)
+		c.pos -= float64(frames)
+	}
+	return c.out
+}
+
+// emit appends one frame to out, mapped to outChannels.
+func (c *audioConverter) emit(frame []int16, outChannels int) {
+	switch {
+	case len(frame) == outChannels:
+		c.out = append(c.out, frame...)
+	case outChannels == 1:
+		sum := 0
+		for _, s := range frame {
+			sum += int(s)
+		}
+		c.out = append(c.out, int16(sum/len(frame)))
+	default:
+		for ch := 0; ch < outChannels; ch++ {
+			c.out = append(c.out, frame[min(ch, len(frame)-1)])
+		}
+	}
+}
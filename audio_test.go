@@ -0,0 +1,139 @@
+package ios
+
+import (
+	"encoding/binary"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockMonoEmulator reports mono audio at a fixed rate.
+type mockMonoEmulator struct {
+	*mockEmulator
+	rate int
+}
+
+func (m *mockMonoEmulator) AudioSampleRate() int { return m.rate }
+func (m *mockMonoEmulator) AudioChannels() int   { return 1 }
+
+func decodePCM(data []byte) []int16 {
+	out := make([]int16, len(data)/2)
+	for i := range out {
+		out[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+	return out
+}
+
+func TestAudioFormatFromSystemInfo(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		modify: func(info *emucore.SystemInfo) { info.SampleRate = 32040 },
+	})
+	if GetAudioSampleRate() != 32040 || GetAudioChannels() != 2 {
+		t.Errorf("format = %d Hz x%d, want 32040 Hz x2", GetAudioSampleRate(), GetAudioChannels())
+	}
+}
+
+func TestResampleCountsAndContinuity(t *testing.T) {
+	var e *mockMonoEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockMonoEmulator{mockEmulator: newMockEmulator(rom, region), rate: 32000}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	if AudioSampleRate() != 32000 || AudioChannels() != 1 || AudioSamplesPerFrame() != 533 {
+		t.Errorf("native output = %d Hz x%d, %d per frame", AudioSampleRate(), AudioChannels(), AudioSamplesPerFrame())
+	}
+	SetOutputSampleRate(48000)
+	SetOutputChannels(2)
+	t.Cleanup(func() {
+		SetOutputSampleRate(0)
+		SetOutputChannels(0)
+	})
+	if AudioSampleRate() != 48000 || AudioChannels() != 2 || AudioSamplesPerFrame() != 800 {
+		t.Errorf("output = %d Hz x%d, %d per frame", AudioSampleRate(), AudioChannels(), AudioSamplesPerFrame())
+	}
+
+	// A ramp across frames: any discontinuity at a frame boundary shows
+	// up as a step larger than the interpolated slope.
+	const perFrame = 533
+	var out []int16
+	next := 0
+	for f := 0; f < 60; f++ {
+		e.samples = make([]int16, perFrame)
+		for i := range e.samples {
+			e.samples[i] = int16(next)
+			next++
+		}
+		RunFrame()
+		out = append(out, decodePCM(GetAudioData())...)
+	}
+
+	frames := len(out) / 2
+	want := 60 * perFrame * 48000 / 32000
+	if frames < want-2 || frames > want+2 {
+		t.Errorf("output frames = %d, want about %d", frames, want)
+	}
+	for i := 0; i < frames; i++ {
+		if out[i*2] != out[i*2+1] {
+			t.Fatalf("frame %d: mono not duplicated: %d, %d", i, out[i*2], out[i*2+1])
+		}
+		if i == 0 {
+			continue
+		}
+		if d := out[i*2] - out[(i-1)*2]; d < 0 || d > 1 {
+			t.Fatalf("discontinuity at output frame %d: %d -> %d", i, out[(i-1)*2], out[i*2])
+		}
+	}
+}
+
+func TestAudioPassthroughByDefault(t *testing.T) {
+	e := useMockEmulator(t)
+	e.samples = []int16{1, -1, 2, -2}
+	RunFrame()
+	got := decodePCM(GetAudioData())
+	if len(got) != 4 || got[1] != -1 || got[3] != -2 {
+		t.Errorf("audio = %v, want samples unchanged", got)
+	}
+}
+
+func TestReportAudioBufferFillSkewsRate(t *testing.T) {
+	var e *mockMonoEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockMonoEmulator{mockEmulator: newMockEmulator(rom, region), rate: 32000}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	e.samples = make([]int16, 1000)
+
+	run := func(pct float64) int {
+		ReportAudioBufferFill(pct)
+		n := 0
+		for f := 0; f < 100; f++ {
+			RunFrame()
+			n += len(GetAudioData()) / 2
+		}
+		return n
+	}
+	tests := []struct {
+		pct  float64
+		want int
+	}{
+		{0, 100500},
+		{100, 99500},
+		{50, 100000},
+		{-20, 100500},
+	}
+	for _, tt := range tests {
+		if got := run(tt.pct); got < tt.want-2 || got > tt.want+2 {
+			t.Errorf("fill %v%%: %d samples, want about %d", tt.pct, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package ios
+
+// Frame blend modes for SetFrameBlend.
+const (
+	// FrameBlendOff passes frames through unchanged.
+	FrameBlendOff = 0
+	// FrameBlendMix averages each frame with the previous one.
+	FrameBlendMix = 1
+	// FrameBlendAccumulate keeps the brighter of each channel of the
+	// current and previous frame.
+	FrameBlendAccumulate = 2
+)
+
+// SetFrameBlend sets how each frame is blended with the one before it,
+// smoothing games that use flicker for transparency. Returns false,
+// leaving the mode unchanged, if mode is unknown.
+func SetFrameBlend(mode int) bool {
+	return def.setFrameBlend(mode)
+}
+
+func (in *instance) setFrameBlend(mode int) bool {
+	if mode < FrameBlendOff || mode > FrameBlendAccumulate {
+		setLastError("unknown frame blend mode %d", mode)
+		return false
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.frameBlend = mode
+	if mode == FrameBlendOff {
+		in.blendPrev = nil
+		in.blended = nil
+	}
+	return true
+}
+
+// FrameBlendMode returns the mode set with SetFrameBlend.
+func FrameBlendMode() int {
+	return def.frameBlend
+}
+
+// resetBlend forgets the previous frame so the next one is shown as is.
+func (in *instance) resetBlend() {
+	in.blendPrev = in.blendPrev[:0]
+}
+
+// blendFrame blends frameData with the previous core frame into a
+// bridge-owned buffer. The first frame after a reset, or after the
+// frame size changes, passes through unchanged.
+func (in *instance) blendFrame() {
+	cur := in.frameData
+	if len(cur) == 0 {
+		return
+	}
+	if len(in.blendPrev) != len(cur) {
+		in.blendPrev = append(in.blendPrev[:0], cur...)
+		return
+	}
+
+	if cap(in.blended) < len(cur) {
+		in.blended = make([]byte, len(cur))
+	}
+	out := in.blended[:len(cur)]
+	prev := in.blendPrev
+
+	switch in.frameBlend {
+	case FrameBlendMix:
+		for i := range out {
+			out[i] = byte((uint16(cur[i]) + uint16(prev[i])) >> 1)
+		}
+	case FrameBlendAccumulate:
+		for i := range out {
+			out[i] = max(cur[i], prev[i])
+		}
+	}
+
+	copy(prev, cur)
+	in.blended = out
+	in.frameData = out
+}
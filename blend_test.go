@@ -0,0 +1,78 @@
+package ios
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFrameBlendModes(t *testing.T) {
+	first := []byte{0x00, 0x10, 0xFF, 0xFF}
+	second := []byte{0x80, 0x11, 0x01, 0xFF}
+
+	tests := []struct {
+		mode int
+		want []byte
+	}{
+		{FrameBlendOff, second},
+		{FrameBlendMix, []byte{0x40, 0x10, 0x80, 0xFF}},
+		{FrameBlendAccumulate, []byte{0x80, 0x11, 0xFF, 0xFF}},
+	}
+	for _, tt := range tests {
+		e := useMockEmulator(t)
+		if !SetFrameBlend(tt.mode) || FrameBlendMode() != tt.mode {
+			t.Fatalf("SetFrameBlend(%d) failed", tt.mode)
+		}
+		fill := func(px []byte) {
+			for i := 0; i < len(e.framebuffer); i += 4 {
+				copy(e.framebuffer[i:], px)
+			}
+		}
+
+		fill(first)
+		RunFrame()
+		if got := GetFrameData()[:4]; !bytes.Equal(got, first) {
+			t.Errorf("mode %d: first frame = % x, want % x", tt.mode, got, first)
+		}
+
+		fill(second)
+		RunFrame()
+		if got := GetFrameData()[:4]; !bytes.Equal(got, tt.want) {
+			t.Errorf("mode %d: blended = % x, want % x", tt.mode, got, tt.want)
+		}
+		if !bytes.Equal(e.framebuffer[:4], second) {
+			t.Errorf("mode %d: core framebuffer modified", tt.mode)
+		}
+	}
+}
+
+func TestFrameBlendReset(t *testing.T) {
+	e := useMockEmulator(t)
+	SetFrameBlend(FrameBlendMix)
+	RunFrame()
+
+	for i := range e.framebuffer {
+		e.framebuffer[i] = 0xFE
+	}
+	if !Reset(true) {
+		t.Fatal("Reset failed")
+	}
+	e = def.emu.(*mockEmulator)
+	for i := range e.framebuffer {
+		e.framebuffer[i] = 0xFE
+	}
+	RunFrame()
+	if got := GetFrameData()[0]; got != 0xFE {
+		t.Errorf("frame after reset = %#x, want unblended 0xfe", got)
+	}
+}
+
+func TestFrameBlendOffNoCopy(t *testing.T) {
+	e := useMockEmulator(t)
+	RunFrame()
+	if &def.frameData[0] != &e.framebuffer[0] || def.blendPrev != nil {
+		t.Error("blend off copied the frame")
+	}
+	if SetFrameBlend(3) || FrameBlendMode() != FrameBlendOff {
+		t.Error("unknown mode accepted or changed the mode")
+	}
+}
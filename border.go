@@ -0,0 +1,157 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/draw"
+	"image/png"
+)
+
+// border is a decoded border image and the rect the frame is drawn into.
+type border struct {
+	img  *image.NRGBA
+	rect image.Rectangle
+
+	// out is the composited frame, reused across frames. Getters read
+	// the copy publishBorder makes of it.
+	out []byte
+}
+
+// borderLayout is the layoutJSON accepted by SetBorderImage.
+type borderLayout struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// SetBorderImage sets a PNG border composited around the frame. layoutJSON
+// gives the rect, in border pixels, that the frame is drawn into:
+//
+//	{"x": 40, "y": 30, "w": 320, "h": 288}
+//
+// The frame is scaled by the largest integer factor that fits the rect,
+// nearest-neighbor, and centered in it. Passing empty pngBytes disables
+// the border. Returns false if the image or layout is invalid.
+func SetBorderImage(pngBytes []byte, layoutJSON string) bool {
+	return def.setBorderImage(pngBytes, layoutJSON)
+}
+
+func (in *instance) setBorderImage(pngBytes []byte, layoutJSON string) bool {
+	if len(pngBytes) == 0 {
+		in.mu.Lock()
+		in.border = nil
+		in.publishBorder()
+		in.mu.Unlock()
+		return true
+	}
+
+	var layout borderLayout
+	if err := json.Unmarshal([]byte(layoutJSON), &layout); err != nil {
+		return false
+	}
+	src, err := png.Decode(bytes.NewReader(pngBytes))
+	if err != nil {
+		return false
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(img, img.Rect, src, src.Bounds().Min, draw.Src)
+
+	rect := image.Rect(layout.X, layout.Y, layout.X+layout.W, layout.Y+layout.H)
+	if rect.Empty() || !rect.In(img.Rect) {
+		return false
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.border = &border{img: img, rect: rect}
+	if len(in.frameData) > 0 {
+		in.compositeBorder()
+	}
+	in.publishBorder()
+	return true
+}
+
+// compositeBorder draws the cached frame into the border image.
+// in.mu must be held.
+func (in *instance) compositeBorder() {
+	b := in.border
+	if b == nil || len(in.frameData) == 0 {
+		return
+	}
+	if len(b.out) != len(b.img.Pix) {
+		b.out = make([]byte, len(b.img.Pix))
+	}
+	copy(b.out, b.img.Pix)
+
+	stride := in.dataStride()
+	w := in.visibleWidth()
+	h := len(in.frameData) / stride
+	if w == 0 || h == 0 {
+		return
+	}
+	scale := max(min(b.rect.Dx()/w, b.rect.Dy()/h), 1)
+	dw, dh := min(w*scale, b.rect.Dx()), min(h*scale, b.rect.Dy())
+	x0 := b.rect.Min.X + (b.rect.Dx()-dw)/2
+	y0 := b.rect.Min.Y + (b.rect.Dy()-dh)/2
+
+	for dy := 0; dy < dh; dy++ {
+		src := in.frameData[(dy/scale)*stride:]
+		dst := b.out[(y0+dy)*b.img.Stride+x0*4:]
+		for dx := 0; dx < dw; dx++ {
+			p := src[(dx/scale)*4 : (dx/scale)*4+4]
+			copy(dst[dx*4:], p[:3])
+			dst[dx*4+3] = 0xFF
+		}
+	}
+}
+
+// HasBorder returns whether a border image is set.
+func HasBorder() bool {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.border != nil
+}
+
+// GetBorderedFrameData returns the last frame composited into the border
+// image as RGBA, or nil if no border is set or no frame has been run.
+// Like GetFrameData, the buffer is a published snapshot that later
+// frames do not overwrite.
+func GetBorderedFrameData() []byte {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	if def.border == nil {
+		return nil
+	}
+	return def.latestBorder()
+}
+
+// borderBounds returns the border image's bounds and stride, or zeros if
+// no border is set.
+func (in *instance) borderBounds() (image.Rectangle, int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.border == nil {
+		return image.Rectangle{}, 0
+	}
+	return in.border.img.Rect, in.border.img.Stride
+}
+
+// BorderedFrameWidth returns the width of the bordered frame in pixels.
+func BorderedFrameWidth() int {
+	r, _ := def.borderBounds()
+	return r.Dx()
+}
+
+// BorderedFrameHeight returns the height of the bordered frame in pixels.
+func BorderedFrameHeight() int {
+	r, _ := def.borderBounds()
+	return r.Dy()
+}
+
+// BorderedFrameStride returns the bordered frame stride in bytes per row.
+func BorderedFrameStride() int {
+	_, stride := def.borderBounds()
+	return stride
+}
@@ -0,0 +1,163 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"testing"
+)
+
+func borderPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0x11
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBorderComposite(t *testing.T) {
+	e := useMockEmulator(t)
+	// 2x2 frame in an 8 byte stride: red channel = 1..4
+	e.stride = 8
+	e.activeHeight = 2
+	e.framebuffer = []byte{
+		1, 0, 0, 0, 2, 0, 0, 0,
+		3, 0, 0, 0, 4, 0, 0, 0,
+	}
+
+	// 8x7 border, frame rect 5x5 at (1,1): scale 2, centered -> 4x4 at (1,1)
+	if !SetBorderImage(borderPNG(t, 8, 7), `{"x":1,"y":1,"w":5,"h":5}`) {
+		t.Fatal("SetBorderImage failed")
+	}
+	RunFrame()
+
+	out := GetBorderedFrameData()
+	if len(out) != 8*7*4 || BorderedFrameWidth() != 8 || BorderedFrameHeight() != 7 {
+		t.Fatalf("bordered frame len %d size %dx%d", len(out), BorderedFrameWidth(), BorderedFrameHeight())
+	}
+	at := func(x, y int) color.NRGBA {
+		p := out[y*BorderedFrameStride()+x*4:]
+		return color.NRGBA{p[0], p[1], p[2], p[3]}
+	}
+
+	if got := at(0, 0); got != (color.NRGBA{0x11, 0x11, 0x11, 0x11}) {
+		t.Errorf("border pixel = %v", got)
+	}
+	want := map[[2]int]byte{
+		{1, 1}: 1, {2, 2}: 1, {3, 1}: 2, {4, 2}: 2,
+		{1, 3}: 3, {2, 4}: 3, {3, 3}: 4, {4, 4}: 4,
+	}
+	for pos, r := range want {
+		if got := at(pos[0], pos[1]); got != (color.NRGBA{R: r, A: 0xFF}) {
+			t.Errorf("pixel %v = %v, want red %d", pos, got, r)
+		}
+	}
+	if got := at(5, 1); got.R != 0x11 {
+		t.Errorf("pixel right of scaled frame = %v, want border", got)
+	}
+
+	var usage map[string]int
+	if err := json.Unmarshal([]byte(MemoryUsageJSON()), &usage); err != nil {
+		t.Fatal(err)
+	}
+	if usage["border"] != 2*8*7*4 {
+		t.Errorf("border memory = %d, want %d", usage["border"], 2*8*7*4)
+	}
+}
+
+func TestBorderDisabledByDefault(t *testing.T) {
+	useMockEmulator(t)
+	RunFrame()
+	if HasBorder() || GetBorderedFrameData() != nil {
+		t.Error("border active by default")
+	}
+}
+
+func TestSetBorderImageValidation(t *testing.T) {
+	useMockEmulator(t)
+	img := borderPNG(t, 4, 4)
+
+	if SetBorderImage([]byte("not a png"), `{"x":0,"y":0,"w":2,"h":2}`) {
+		t.Error("accepted invalid PNG")
+	}
+	if SetBorderImage(img, `{"x":3,"y":3,"w":2,"h":2}`) {
+		t.Error("accepted rect outside border")
+	}
+	if SetBorderImage(img, `{bad`) {
+		t.Error("accepted invalid layout")
+	}
+	if !SetBorderImage(img, `{"x":0,"y":0,"w":4,"h":4}`) || !HasBorder() {
+		t.Fatal("SetBorderImage failed")
+	}
+	if !SetBorderImage(nil, "") || HasBorder() {
+		t.Error("border not disabled")
+	}
+}
+
+func TestBorderedFrameIsSnapshot(t *testing.T) {
+	e := useMockEmulator(t)
+	e.framebuffer[0] = 1
+	if !SetBorderImage(borderPNG(t, 4, 4), `{"x":0,"y":0,"w":4,"h":4}`) {
+		t.Fatal("SetBorderImage failed")
+	}
+	RunFrame()
+	out := GetBorderedFrameData()
+	first := append([]byte(nil), out...)
+
+	e.framebuffer[0] = 9
+	RunFrame()
+	if !bytes.Equal(out, first) {
+		t.Error("RunFrame overwrote a fetched bordered frame")
+	}
+	if got := GetBorderedFrameData(); got[0] != 9 {
+		t.Errorf("bordered frame red = %d, want 9", got[0])
+	}
+}
+
+// TestBorderRace sets and reads the border while frames run; run with
+// -race.
+func TestBorderRace(t *testing.T) {
+	useMockEmulator(t)
+	img := borderPNG(t, 4, 4)
+	const iterations = 500
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() { // display link
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			RunFrame()
+		}
+	}()
+	go func() { // settings
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			if i%2 == 0 {
+				SetBorderImage(img, `{"x":0,"y":0,"w":4,"h":4}`)
+			} else {
+				SetBorderImage(nil, "")
+			}
+		}
+	}()
+	go func() { // renderer
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			if HasBorder() {
+				GetBorderedFrameData()
+			}
+			BorderedFrameWidth()
+			BorderedFrameHeight()
+			BorderedFrameStride()
+			MemoryUsageJSON()
+		}
+	}()
+	wg.Wait()
+}
@@ -2,161 +2,628 @@ package ios
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	emucore "github.com/user-none/eblitui/api"
 	"github.com/user-none/eblitui/romloader"
 	"hash/crc32"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
-	factory      emucore.CoreFactory
+	factory emucore.CoreFactory
+
+	// def is the instance behind the package-level API.
+	def = &instance{}
+)
+
+// instance holds the state of a single emulation session.
+type instance struct {
+	// mu serializes frame execution against loading, closing, resets
+	// and the pause lifecycle, which may run on different threads.
+	mu sync.Mutex
+
+	// factory is the core emu was created with. It is kept after Close
+	// so the instance keeps describing that core.
+	factory emucore.CoreFactory
+
 	emu          emucore.Emulator
 	saveStater   emucore.SaveStater
 	batterySaver emucore.BatterySaver
+	memInspector emucore.MemoryInspector
+	memMapper    emucore.MemoryMapper
+	cheater      Cheater
+	idleSkipper  IdleSkipper
+	resetter     Resetter
+	audioFormat  AudioFormatReporter
+	discControl  DiscControl
+	analog       AnalogInput
+	pointer      PointerInput
+	rumble       RumbleProvider
+	rumbleLevels rumbleState
+	multitap     Multitap
+	eventSource  EventSource
+	overscan     OverscanReporter
+
+	optionReporter OptionReporter
+	deviceSelector InputDeviceSelector
+	// inputDevices holds the SetInputDevice device per player.
+	inputDevices map[int]string
+
+	// rom is the ROM the emulator was created from, kept for resets.
+	rom []byte
+	// romPath is the file Init loaded rom from, or empty if it was
+	// loaded from memory.
+	romPath string
+	// patchPath is the patch InitWithPatch applied to rom, if any.
+	patchPath string
+	// romWarnings lists the dump problems found in rom.
+	romWarnings []string
+	// discs holds every image of a multi-disc game; disc is the index
+	// of the inserted one.
+	discs [][]byte
+	disc  int
+
+	// options holds the core options set since load, in the order
+	// they were first set.
+	options []optionValue
+
+	// cheats holds the cheats added since load, keyed by their code.
+	cheats []cheat
+
+	// inputs holds the buttons last presented to the core per player.
+	inputs map[int]uint32
+	// pendingInputs holds SetInput calls not yet latched by a frame,
+	// already translated by buttonMaps.
+	inputMu       sync.Mutex
+	pendingInputs map[int]uint32
+	buttonMaps    map[int]buttonMapping
+	// connectedPlayers is the SetConnectedPlayers count, or 0 if every
+	// player is connected.
+	connectedPlayers int
+	// heldInputs holds the buttons last latched per player, before
+	// turbo is applied.
+	heldInputs map[int]uint32
+	turbo      turbo
+	// movie is the input recording or playback in progress.
+	movie *inputMovie
+	// movieVerify is the SetMovieRecordingVerify setting.
+	movieVerify bool
+
+	// events queues core events for PollEventsJSON.
+	events eventQueue
+	// restartRequired is set by option changes that need the emulator
+	// re-created.
+	restartRequired bool
+
+	// rewind holds recent states for RewindStep; nil while disabled.
+	rewind *rewindBuffer
+
+	achievements achievementState
+	// hardcore blocks state loading, cheats and rewind.
+	hardcore bool
+
+	idleSkip  bool
+	idle      bool
+	idleCount int
+
+	// paused makes RunFrame a no-op; fadeIn ramps up the audio of the
+	// first frame after Resume.
+	paused bool
+	fadeIn bool
+
+	// frameBlend is the SetFrameBlend mode; blendPrev holds the last
+	// core frame and blended the bridge-owned blended output.
+	frameBlend int
+	blendPrev  []byte
+	blended    []byte
+
+	border *border
+
+	// display holds the loaded game's display preference overrides;
+	// displayFilter is the video filter they enabled.
+	display       displayPrefs
+	displayFilter string
+
+	// videoFilters holds the enabled filter names and their timings.
+	videoFilters map[string]*filterState
+	// filtered is the bridge-owned frame copy filters run on; scaled
+	// holds the output of a FrameScaler.
+	filtered []byte
+	scaled   []byte
+
+	// crop is the SetCrop crop, or nil for the core's default; cropped
+	// holds the packed cropped frame.
+	crop    *cropRect
+	cropped []byte
+
+	// rotateFrames is the SetRotateFrames setting; rotated holds the
+	// rotated frame.
+	rotateFrames bool
+	rotated      []byte
+
+	// pixelFormat is the app's frame format; converted holds the frame
+	// in that format when it is not RGBA8888.
+	pixelFormat int
+	converted   []byte
+
+	// stateCompression is the SetStateCompression zlib level.
+	stateCompression int
+
+	sramTracker sramTracker
+
+	frameLeases frameLeases
+
+	// audioOut converts audio to the app's requested output format.
+	audioOut audioConverter
+	// rateSkew holds the float64 bits of the ReportAudioBufferFill rate
+	// adjustment; it is written from the audio thread.
+	rateSkew atomic.Uint64
+
+	// fastForwardAudio is the SetFastForwardAudioMode mode;
+	// fastForward the SetFastForward multiplier, 0 or 1 when off.
+	fastForwardAudio int
+	fastForward      int
+
+	// runAhead is the SetRunAhead frame count; speculating is set while
+	// run-ahead frames that will be rolled back are running.
+	runAhead    int
+	speculating bool
+
+	stats sessionStats
+	// frameCount is the number of frames emulated, for FrameCount.
+	frameCount int64
+	// stateScratch is reused by StateHash for cores that can serialize
+	// into it.
+	stateScratch []byte
+	// stageTimes holds each framePipeline stage's last run time.
+	stageTimes []time.Duration
+
+	gate shutdownGate
+
+	// published holds the last completed frame's output for getters on
+	// other threads.
+	published publisher
 
 	// cached data
 	frameData []byte
 	audioData []byte
 	stateData []byte
 	sramData  []byte
-)
+}
 
-// RegisterFactory sets the CoreFactory. Called by core's init().
+// RegisterFactory registers the CoreFactory under its system name and
+// makes it active. Called by core's init().
 func RegisterFactory(f emucore.CoreFactory) {
-	factory = f
+	RegisterFactoryFor(f.SystemInfo().Name, f)
+	setActiveFactory(f)
 }
 
-// Init creates an emulator from a ROM file path.
+// Init creates an emulator from a ROM file path. When several cores are
+// registered, the first whose extensions match the file (or an archive
+// entry) is used, preferring the active core, and is made active.
 // regionCode: 0=NTSC, 1=PAL
-// Returns true on success.
+// Returns true on success (see LastError on failure).
 func Init(path string, regionCode int) bool {
-	if factory == nil {
+	return def.init(path, regionCode)
+}
+
+// InitWithError is Init returning JSON {"code", "message", "warnings"}
+// describing the outcome. code is one of the InitError constants;
+// InitErrorNone means success. warnings lists dump problems in a ROM
+// that loaded, as in ROMInfoJSON.
+func InitWithError(path string, regionCode int) string {
+	result := &initError{Code: InitErrorNone}
+	if err := def.initPath(path, regionCode); err != nil {
+		setLastError("%v", err)
+		result = asInitError(err)
+	} else {
+		def.mu.Lock()
+		result.Warnings = def.romWarnings
+		def.mu.Unlock()
+	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+// init loads the ROM at path and creates the instance's emulator,
+// replacing any emulator it already holds. Failures are recorded for
+// LastError.
+func (in *instance) init(path string, regionCode int) bool {
+	if err := in.initPath(path, regionCode); err != nil {
+		setLastError("%v", err)
 		return false
 	}
+	return true
+}
+
+// initPath is init returning an *initError on failure.
+func (in *instance) initPath(path string, regionCode int) error {
+	if activeFactory() == nil {
+		return errNoFactory
+	}
 
-	info := factory.SystemInfo()
-	rom, _, err := romloader.Load(path, info.Extensions)
+	f, rom, err := loadSystemROM(path)
 	if err != nil {
+		return loadError(err)
+	}
+
+	if err := in.initROMData(f, rom, regionCode); err != nil {
+		return err
+	}
+	in.romPath = path
+	return nil
+}
+
+// initROM creates the instance's emulator from ROM data loaded for f,
+// replacing any emulator it already holds. Failures are recorded for
+// LastError.
+func (in *instance) initROM(f emucore.CoreFactory, rom []byte, regionCode int) bool {
+	if err := in.initROMData(f, rom, regionCode); err != nil {
+		setLastError("%v", err)
 		return false
 	}
+	return true
+}
+
+// initROMData is initROM returning an *initError on failure. Loading a
+// game into the default instance makes f the active core.
+func (in *instance) initROMData(f emucore.CoreFactory, rom []byte, regionCode int) error {
+	if f == nil {
+		return errNoFactory
+	}
+
+	if err := loadFirmware(f); err != nil {
+		return &initError{Code: InitErrorFirmware, Message: err.Error()}
+	}
 
 	region := emucore.Region(regionCode)
-	e, err := factory.CreateEmulator(rom, region)
+	e, err := f.CreateEmulator(rom, region)
 	if err != nil {
-		return false
+		return &initError{Code: InitErrorCore, Message: fmt.Sprintf("failed to create emulator: %v", err)}
 	}
 
-	emu = e
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.release()
+	in.factory = f
+	if in == def {
+		setActiveFactory(f)
+	}
+	in.rom = rom
+	in.romWarnings = romDumpWarnings(f, rom)
+	for _, w := range in.romWarnings {
+		journalf("warning", "ROM %08X: %s", crc32.ChecksumIEEE(rom), w)
+	}
+	in.attach(e)
+	in.gate.reopen()
+	in.resetSRAMTracking()
+	in.loadDisplayPreferences()
+	in.loadButtonMappings()
 
-	// Detect optional interfaces
-	saveStater, _ = e.(emucore.SaveStater)
-	batterySaver, _ = e.(emucore.BatterySaver)
+	return nil
+}
 
-	return true
+// attach makes e the instance's emulator and detects its optional
+// interfaces. attach(nil) clears them.
+func (in *instance) attach(e emucore.Emulator) {
+	in.emu = e
+
+	// Detect optional interfaces
+	in.saveStater, _ = e.(emucore.SaveStater)
+	in.batterySaver, _ = e.(emucore.BatterySaver)
+	in.memInspector, _ = e.(emucore.MemoryInspector)
+	in.memMapper, _ = e.(emucore.MemoryMapper)
+	in.cheater, _ = e.(Cheater)
+	in.idleSkipper, _ = e.(IdleSkipper)
+	in.resetter, _ = e.(Resetter)
+	in.audioFormat, _ = e.(AudioFormatReporter)
+	in.discControl, _ = e.(DiscControl)
+	in.analog, _ = e.(AnalogInput)
+	in.pointer, _ = e.(PointerInput)
+	in.rumble, _ = e.(RumbleProvider)
+	in.multitap, _ = e.(Multitap)
+	in.eventSource, _ = e.(EventSource)
+	in.overscan, _ = e.(OverscanReporter)
+	in.deviceSelector, _ = e.(InputDeviceSelector)
+	in.optionReporter, _ = e.(OptionReporter)
 }
 
 // Close releases the emulator.
 func Close() {
-	if emu != nil {
-		emu.Close()
+	def.close()
+}
+
+func (in *instance) close() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.release()
+}
+
+// release closes the emulator and drops all per-game state. in.mu must
+// be held.
+func (in *instance) release() {
+	if !in.gate.closing.Load() {
+		in.gate.begin()
+	}
+	if in.sramTracker.autoPath != "" {
+		if !in.flushSRAMLocked(in.sramTracker.autoPath) {
+			journalf("warning", "SRAM auto-save failed at close: %s", LastError())
+		}
+		in.sramTracker.autoPath = ""
 	}
-	emu = nil
-	saveStater = nil
-	batterySaver = nil
-	frameData = nil
-	audioData = nil
-	stateData = nil
-	sramData = nil
+	if in.emu != nil {
+		in.emu.Close()
+	}
+	in.attach(nil)
+	in.reclaimFrameLeases()
+	in.rom = nil
+	in.romPath = ""
+	in.patchPath = ""
+	in.romWarnings = nil
+	in.discs = nil
+	in.disc = 0
+	in.options = nil
+	in.cheats = nil
+	in.inputs = nil
+	in.inputMu.Lock()
+	in.pendingInputs = nil
+	in.buttonMaps = nil
+	in.connectedPlayers = 0
+	in.inputMu.Unlock()
+	in.heldInputs = nil
+	in.inputDevices = nil
+	in.turbo = turbo{on: in.turbo.on, off: in.turbo.off}
+	in.movie = nil
+	in.rewind = nil
+	in.achievements.list = nil
+	in.events.reset()
+	in.restartRequired = false
+	in.rumbleLevels.reset()
+	in.audioOut.reset()
+	in.rateSkew.Store(0)
+	in.idle = false
+	in.idleCount = 0
+	in.paused = false
+	in.fadeIn = false
+	in.stats = sessionStats{}
+	in.frameCount = 0
+	in.stateScratch = nil
+	in.stageTimes = nil
+	in.resetBlend()
+	in.frameData = nil
+	in.converted = nil
+	in.cropped = nil
+	in.rotated = nil
+	in.scaled = nil
+	in.clearPublished()
+	in.audioData = nil
+	in.stateData = nil
+	in.sramData = nil
 }
 
 // RunFrame executes one frame of emulation.
 func RunFrame() {
-	if emu == nil {
+	def.runFrame()
+}
+
+func (in *instance) runFrame() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil || in.paused {
+		return
+	}
+	in.latchInputs()
+	if in.fastForward > 1 {
+		in.runFramesLocked(in.fastForward, true)
+		return
+	}
+	if in.skipIdleFrame() {
+		in.publishAudio()
 		return
 	}
 
-	emu.RunFrame()
+	in.audioData = in.audioData[:0]
+	if in.runAhead > 0 && in.saveStater != nil {
+		in.runAheadFrame()
+	} else {
+		in.runPipeline(framePass{render: true, last: true})
+	}
+	in.finishAudio()
+	in.publishFrame()
+	in.publishAudio()
+}
 
-	// Cache frame buffer - only the active display area
-	fullBuffer := emu.GetFramebuffer()
-	activeHeight := emu.GetActiveHeight()
-	stride := emu.GetFramebufferStride()
+// cacheFrame caches the frame buffer - only the active display area.
+func (in *instance) cacheFrame() {
+	fullBuffer := in.emu.GetFramebuffer()
+	activeHeight := in.emu.GetActiveHeight()
+	stride := in.emu.GetFramebufferStride()
 	activeBytes := stride * activeHeight
 	if activeBytes <= len(fullBuffer) {
-		frameData = fullBuffer[:activeBytes]
+		in.frameData = fullBuffer[:activeBytes]
 	} else {
-		frameData = fullBuffer
+		in.frameData = fullBuffer
 	}
+	in.cropFrame()
+	in.rotateFrame()
+}
 
-	// Convert audio samples to little-endian bytes
-	samples := emu.GetAudioSamples()
-	if len(samples) > 0 {
-		needed := len(samples) * 2
-		if cap(audioData) < needed {
-			audioData = make([]byte, needed)
-		} else {
-			audioData = audioData[:needed]
-		}
-		for i, s := range samples {
-			audioData[i*2] = byte(s)
-			audioData[i*2+1] = byte(s >> 8)
-		}
+// finishAudio drops the audio buffer if no frame produced samples.
+func (in *instance) finishAudio() {
+	if len(in.audioData) == 0 {
+		in.audioData = nil
+	}
+}
+
+// appendAudio appends audio samples to audioData as little-endian bytes,
+// reusing its capacity.
+func (in *instance) appendAudio(samples []int16) {
+	in.audioOut.skew = math.Float64frombits(in.rateSkew.Load())
+	if in.audioOut.rate != 0 || in.audioOut.channels != 0 || in.audioOut.skew != 0 {
+		rate, channels := in.sourceAudioFormat()
+		samples = in.audioOut.convert(samples, rate, channels)
+	}
+	if len(samples) == 0 {
+		return
+	}
+	n := len(in.audioData)
+	needed := n + len(samples)*2
+	if cap(in.audioData) < needed {
+		grown := make([]byte, needed, max(needed, 2*cap(in.audioData)))
+		copy(grown, in.audioData)
+		in.audioData = grown
 	} else {
-		audioData = nil
+		in.audioData = in.audioData[:needed]
+	}
+	for i, s := range samples {
+		in.audioData[n+i*2] = byte(s)
+		in.audioData[n+i*2+1] = byte(s >> 8)
 	}
 }
 
-// GetFrameData returns the frame buffer for the active display area.
+// GetFrameData returns the frame buffer for the active display area as of
+// the last completed frame. The returned buffer is never modified, so it
+// can be read on any thread while RunFrame continues.
 func GetFrameData() []byte {
-	return frameData
+	return def.fetchFrame()
 }
 
-// GetAudioData returns audio as int16 stereo PCM little-endian bytes.
+// GetAudioData returns audio as int16 PCM little-endian bytes, stereo at
+// the core's native rate unless changed with SetOutputSampleRate and
+// SetOutputChannels. Like GetFrameData it returns the last completed
+// frame's buffer, which is never modified.
 func GetAudioData() []byte {
-	return audioData
+	return def.fetchAudio()
 }
 
-// SetInput sets controller state as a button bitmask for the given player.
+// SetInput sets controller state as a button bitmask for the given player,
+// translated by any SetButtonMapping. It is safe to call from any thread;
+// the state is latched and reaches the core at the start of the next
+// RunFrame.
 func SetInput(player int, buttons int) {
-	if emu != nil {
-		emu.SetInput(player, uint32(buttons))
+	def.setInput(player, buttons)
+}
+
+func (in *instance) setInput(player int, buttons int) {
+	in.inputMu.Lock()
+	defer in.inputMu.Unlock()
+	if in.pendingInputs == nil {
+		in.pendingInputs = map[int]uint32{}
+	}
+	if in.connectedPlayers > 0 && player >= in.connectedPlayers {
+		return
+	}
+	mask := uint32(buttons)
+	if m, ok := in.buttonMaps[player]; ok {
+		mask = m.apply(mask)
 	}
+	in.pendingInputs[player] = mask
+}
+
+// latchInputs presents the input set since the last frame. SetInput may
+// run on another thread, so it only records; the frame thread applies it
+// here before running the core. Input is dropped while a movie plays.
+func (in *instance) latchInputs() {
+	in.inputMu.Lock()
+	defer in.inputMu.Unlock()
+	if in.movie == nil || !in.movie.playing {
+		if in.heldInputs == nil && len(in.pendingInputs) > 0 {
+			in.heldInputs = map[int]uint32{}
+		}
+		for player, buttons := range in.pendingInputs {
+			in.heldInputs[player] = buttons
+			in.presentInput(player, buttons)
+		}
+	}
+	clear(in.pendingInputs)
+}
+
+// presentInput hands a player's final button mask to the core. Every
+// input the core sees goes through here, so inputs holds exactly what
+// the core was given.
+func (in *instance) presentInput(player int, buttons uint32) {
+	if in.inputs == nil {
+		in.inputs = map[int]uint32{}
+	}
+	if prev, ok := in.inputs[player]; !ok || prev != buttons {
+		in.wakeIdle()
+	}
+	in.inputs[player] = buttons
+	in.emu.SetInput(player, buttons)
 }
 
 // FrameWidth returns the display width in pixels.
 func FrameWidth() int {
-	if emu == nil {
-		if factory != nil {
-			return factory.SystemInfo().ScreenWidth
+	return def.frameWidth()
+}
+
+func (in *instance) frameWidth() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.frameWidthLocked()
+}
+
+// frameWidthLocked is frameWidth with in.mu held.
+func (in *instance) frameWidthLocked() int {
+	if in.emu == nil {
+		if f := in.coreFactory(); f != nil {
+			return f.SystemInfo().ScreenWidth
 		}
 		return 0
 	}
-	return emu.GetFramebufferStride() / 4
+	return in.dataStride() / 4
 }
 
 // FrameStride returns the framebuffer stride in bytes per row.
 func FrameStride() int {
-	if emu == nil {
-		if factory != nil {
-			return factory.SystemInfo().ScreenWidth * 4
+	return def.frameStride()
+}
+
+func (in *instance) frameStride() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.frameStrideLocked()
+}
+
+// frameStrideLocked is frameStride with in.mu held.
+func (in *instance) frameStrideLocked() int {
+	if in.emu == nil {
+		if f := in.coreFactory(); f != nil {
+			return f.SystemInfo().ScreenWidth * in.bytesPerPixel()
 		}
 		return 0
 	}
-	return emu.GetFramebufferStride()
+	return in.outputStride(in.dataStride())
 }
 
 // FrameHeight returns the active display height.
 func FrameHeight() int {
-	if emu == nil {
-		if factory != nil {
-			return factory.SystemInfo().MaxScreenHeight
+	return def.frameHeight()
+}
+
+func (in *instance) frameHeight() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.frameHeightLocked()
+}
+
+// frameHeightLocked is frameHeight with in.mu held.
+func (in *instance) frameHeightLocked() int {
+	if in.emu == nil {
+		if f := in.coreFactory(); f != nil {
+			return f.SystemInfo().MaxScreenHeight
 		}
 		return 0
 	}
-	return emu.GetActiveHeight()
+	return in.rotatedHeight() * in.filterScale()
 }
 
 // categoryString converts a CoreOptionCategory to its display name for iOS.
@@ -192,11 +659,12 @@ type jsonCoreOption struct {
 // SystemInfoJSON returns the system info as a JSON string.
 // CoreOptionCategory values are serialized as display strings.
 func SystemInfoJSON() string {
-	if factory == nil {
+	f := activeFactory()
+	if f == nil {
 		return "{}"
 	}
 
-	info := factory.SystemInfo()
+	info := f.SystemInfo()
 
 	options := make([]jsonCoreOption, len(info.CoreOptions))
 	for i, opt := range info.CoreOptions {
@@ -216,12 +684,22 @@ func SystemInfoJSON() string {
 	}
 
 	// Embed SystemInfo and override CoreOptions with string categories.
+	// AspectRatio is the display aspect ratio; PixelAspectRatio is the
+	// matching pixel shape at ScreenWidth x MaxScreenHeight. InputDevices
+	// lists the SetInputDevice device types. ArchiveExtensions lists the
+	// containers the import functions unpack, for the document picker.
 	data, err := json.Marshal(struct {
 		emucore.SystemInfo
-		CoreOptions []jsonCoreOption `json:"CoreOptions"`
+		CoreOptions       []jsonCoreOption `json:"CoreOptions"`
+		PixelAspectRatio  float64          `json:"PixelAspectRatio"`
+		InputDevices      []string         `json:"InputDevices"`
+		ArchiveExtensions []string         `json:"ArchiveExtensions"`
 	}{
-		SystemInfo:  info,
-		CoreOptions: options,
+		SystemInfo:        info,
+		CoreOptions:       options,
+		PixelAspectRatio:  pixelAspect(info.AspectRatio, info.ScreenWidth, info.MaxScreenHeight),
+		InputDevices:      systemInputDevices(f),
+		ArchiveExtensions: archiveExtensions,
 	})
 	if err != nil {
 		return "{}"
@@ -231,166 +709,393 @@ func SystemInfoJSON() string {
 
 // Region returns the current region (0=NTSC, 1=PAL).
 func Region() int {
-	if emu == nil {
+	return def.region()
+}
+
+func (in *instance) region() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.regionLocked()
+}
+
+// regionLocked is region with in.mu held.
+func (in *instance) regionLocked() int {
+	if in.emu == nil {
 		return 0
 	}
-	return int(emu.GetRegion())
+	return int(in.emu.GetRegion())
 }
 
-// GetFPS returns the frames per second for the current emulator state.
+// GetFPS returns the frames per second for the current emulator state,
+// rounded to the nearest whole frame. See GetFPSFloat.
 func GetFPS() int {
-	if emu == nil {
-		return 60
-	}
-	return emu.GetTiming().FPS
+	return def.fps()
 }
 
-// DetectRegionFromPath detects the region for a ROM file (0=NTSC, 1=PAL).
-func DetectRegionFromPath(path string) int {
-	if factory == nil {
-		return 0
-	}
+func (in *instance) fps() int {
+	return int(math.Round(in.fpsFloat()))
+}
 
-	info := factory.SystemInfo()
-	rom, _, err := romloader.Load(path, info.Extensions)
+// DetectRegionFromPath detects the region for a ROM file (0=NTSC, 1=PAL),
+// using the core Init would pick for it.
+func DetectRegionFromPath(path string) int {
+	f, rom, err := loadSystemROM(path)
 	if err != nil {
 		return 0
 	}
-
-	region, _ := factory.DetectRegion(rom)
+	region, _ := f.DetectRegion(rom)
 	return int(region)
 }
 
 // HasSaveStates returns whether the emulator supports save states.
 func HasSaveStates() bool {
-	return saveStater != nil
+	return def.hasSaveStates()
+}
+
+func (in *instance) hasSaveStates() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.saveStater != nil
 }
 
-// SaveState creates a save state. Returns true on success.
+// SaveState creates a save state, wrapped in a header naming the ROM,
+// core and core version so LoadState can refuse states from another
+// game or core. Returns true on success.
 func SaveState() bool {
-	if saveStater == nil {
-		return false
+	_, ok := def.saveStateContainer()
+	return ok
+}
+
+// serializeState returns the core's state with the bridge's trailers, as
+// SaveState stores it before wrapping. in.mu must be held.
+func (in *instance) serializeState() ([]byte, error) {
+	if in.saveStater == nil {
+		return nil, errors.New("core does not support save states")
 	}
-	data, err := saveStater.Serialize()
+	data, err := in.saveStater.Serialize()
 	if err != nil {
-		stateData = nil
-		return false
+		return nil, err
 	}
-	stateData = data
-	return true
+	return in.appendFrameTrailer(in.appendDiscTrailer(data)), nil
 }
 
 // StateLen returns the length of the last saved state.
 func StateLen() int {
-	return len(stateData)
+	return def.stateLen()
+}
+
+func (in *instance) stateLen() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.stateData)
 }
 
 // StateByte returns a single byte from the saved state at index i.
 func StateByte(i int) int {
-	if i < 0 || i >= len(stateData) {
+	return def.stateByte(i)
+}
+
+func (in *instance) stateByte(i int) int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if i < 0 || i >= len(in.stateData) {
 		return 0
 	}
-	return int(stateData[i])
+	return int(in.stateData[i])
+}
+
+// StateBytes returns up to length bytes of the saved state starting at
+// offset, for copying it in chunks. Returns nil if offset is out of range.
+func StateBytes(offset, length int) []byte {
+	return def.stateBytes(offset, length)
+}
+
+// GetStateData returns a copy of the whole saved state.
+func GetStateData() []byte {
+	return def.stateBytes(0, math.MaxInt)
+}
+
+func (in *instance) stateBytes(offset, length int) []byte {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return bufferRange(in.stateData, offset, length)
+}
+
+// bufferRange returns a copy of up to length bytes of buf from offset.
+func bufferRange(buf []byte, offset, length int) []byte {
+	if offset < 0 || length <= 0 || offset >= len(buf) {
+		return nil
+	}
+	end := offset + min(length, len(buf)-offset)
+	return append([]byte(nil), buf[offset:end]...)
 }
 
-// LoadState loads a save state. Returns true on success.
+// LoadState loads a save state from SaveState, refusing states made from
+// another ROM, core or core version. States without a header, from
+// older versions, are loaded as is. Returns true on success (see
+// LastError on failure).
 func LoadState(data []byte) bool {
-	if saveStater == nil {
+	return def.loadStateContainer(data)
+}
+
+func (in *instance) loadState(data []byte) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.loadStateLocked(data)
+}
+
+// loadStateLocked is loadState with in.mu held.
+func (in *instance) loadStateLocked(data []byte) bool {
+	if in.saveStater == nil {
+		return false
+	}
+	if in.hardcore {
+		setLastError("states cannot be loaded in hardcore mode")
 		return false
 	}
-	return saveStater.Deserialize(data) == nil
+	data, frames, _ := splitFrameTrailer(data)
+	data, disc, hasDisc := splitDiscTrailer(data)
+	if err := in.saveStater.Deserialize(data); err != nil {
+		return false
+	}
+	in.frameCount = frames
+	if in.rewind != nil {
+		in.rewind.clear()
+	}
+	if hasDisc && disc != in.disc {
+		in.setDiscLocked(disc)
+	}
+	in.resetBlend()
+	in.reapplyCheats()
+	return true
 }
 
 // HasSRAM returns whether the current ROM uses battery-backed save.
 func HasSRAM() bool {
-	return batterySaver != nil && batterySaver.HasSRAM()
+	return def.hasSRAM()
+}
+
+func (in *instance) hasSRAM() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.hasSRAMLocked()
+}
+
+// hasSRAMLocked is hasSRAM with in.mu held.
+func (in *instance) hasSRAMLocked() bool {
+	return in.batterySaver != nil && in.batterySaver.HasSRAM()
 }
 
 // PrepareSRAM copies SRAM to internal buffer.
 func PrepareSRAM() {
-	if batterySaver == nil {
+	def.prepareSRAM()
+}
+
+func (in *instance) prepareSRAM() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.batterySaver == nil {
 		return
 	}
-	sramData = batterySaver.GetSRAM()
+	in.sramData = in.batterySaver.GetSRAM()
 }
 
 // SRAMLen returns the SRAM length.
 func SRAMLen() int {
-	return len(sramData)
+	return def.sramLen()
+}
+
+func (in *instance) sramLen() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.sramData)
 }
 
 // SRAMByte returns a single byte from SRAM at index i.
 func SRAMByte(i int) int {
-	if i < 0 || i >= len(sramData) {
+	return def.sramByte(i)
+}
+
+func (in *instance) sramByte(i int) int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if i < 0 || i >= len(in.sramData) {
 		return 0
 	}
-	return int(sramData[i])
+	return int(in.sramData[i])
+}
+
+// SRAMBytes returns up to length bytes of the prepared SRAM starting at
+// offset. Returns nil if offset is out of range.
+func SRAMBytes(offset, length int) []byte {
+	return def.sramBytes(offset, length)
+}
+
+// GetSRAMData returns a copy of the whole prepared SRAM.
+func GetSRAMData() []byte {
+	return def.sramBytes(0, math.MaxInt)
+}
+
+func (in *instance) sramBytes(offset, length int) []byte {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return bufferRange(in.sramData, offset, length)
 }
 
 // LoadSRAM loads SRAM data into the emulator.
 func LoadSRAM(data []byte) {
-	if batterySaver != nil {
-		batterySaver.SetSRAM(data)
+	def.loadSRAM(data)
+}
+
+func (in *instance) loadSRAM(data []byte) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.batterySaver != nil {
+		in.batterySaver.SetSRAM(data)
+		in.markSRAMClean(crc32.ChecksumIEEE(data))
 	}
 }
 
 // ExtractAndStoreROM extracts a ROM from an archive, calculates its CRC32,
-// and stores it as {CRC32}.{first extension} in destDir.
-// Returns JSON with "crc" (hex string) and "name" (ROM filename without extension).
-func ExtractAndStoreROM(srcPath, destDir string) (string, error) {
-	if factory == nil {
+// and stores it as {CRC32}.{first extension} in destDir. An existing file
+// with that name is kept only if its contents match, unless overwrite is
+// set.
+// Returns JSON with "crc" (hex string), "name" (ROM filename without
+// extension), "title" (the LoadROMDatabase title, if known), "filename"
+// (the original ROM or archive entry filename), "size" (bytes),
+// "status": "written", "exists" when a matching file was kept, or
+// "replaced" when a mismatched file was rewritten, and "warnings" (dump
+// problems, as in ROMInfoJSON).
+// The ROM is stored for the first registered core that accepts it, as
+// Init picks one.
+func ExtractAndStoreROM(srcPath, destDir string, overwrite bool) (string, error) {
+	f := activeFactory()
+	if f == nil {
 		return "", fmt.Errorf("no factory registered")
 	}
-
-	info := factory.SystemInfo()
-	if len(info.Extensions) == 0 {
+	if len(f.SystemInfo().Extensions) == 0 {
 		return "", fmt.Errorf("no extensions configured")
 	}
 
-	rom, romFilename, err := romloader.Load(srcPath, info.Extensions)
+	f, rom, romFilename, err := loadWithSystems(func(exts []string) ([]byte, string, error) {
+		return romloader.Load(srcPath, exts)
+	})
+	if errors.Is(err, romloader.ErrUnsupportedFormat) {
+		// Misnamed file: accept it if the contents identify the system.
+		f = activeFactory()
+		rom, romFilename, err = loadROMAnyExtension(srcPath, f.SystemInfo().Extensions)
+		if err == nil && sniffROMWith(f, rom).Confidence < sniffAcceptConfidence {
+			err = fmt.Errorf("%w: %s", romloader.ErrUnsupportedFormat, srcPath)
+		}
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to load ROM: %w", err)
 	}
 
+	return storeROM(f, rom, romFilename, destDir, overwrite)
+}
+
+// Store statuses reported by ExtractAndStoreROM.
+const (
+	storeWritten  = "written"
+	storeExists   = "exists"
+	storeReplaced = "replaced"
+)
+
+// storeROM stores ROM data loaded for f as {CRC32}.{f's first extension}
+// in destDir and returns the ExtractAndStoreROM result JSON.
+func storeROM(f emucore.CoreFactory, rom []byte, romFilename, destDir string, overwrite bool) (string, error) {
 	crc := crc32.ChecksumIEEE(rom)
 	crcHex := fmt.Sprintf("%08X", crc)
+	destPath := storedROMPath(f, destDir, crcHex)
 
-	// Strip extension from ROM filename for display name
-	romName := strings.TrimSuffix(romFilename, filepath.Ext(romFilename))
+	status := storeWritten
+	if existing, err := os.ReadFile(destPath); err == nil {
+		// Keep the file only if it really holds this ROM; a partial
+		// earlier write or a corrupted file is replaced.
+		status = storeReplaced
+		if !overwrite && len(existing) == len(rom) && crc32.ChecksumIEEE(existing) == crc {
+			status = storeExists
+		}
+	}
 
-	ext := info.Extensions[0]
-	destPath := filepath.Join(destDir, crcHex+ext)
+	if status != storeExists {
+		if err := writeFileAtomic(destPath, rom); err != nil {
+			return "", fmt.Errorf("failed to write ROM: %w", err)
+		}
+	}
 
-	// Skip write if file already exists
-	if _, err := os.Stat(destPath); err == nil {
-		return extractResultJSON(crcHex, romName), nil
+	result := struct {
+		CRC      string `json:"crc"`
+		Name     string `json:"name"`
+		Title    string `json:"title,omitempty"`
+		Filename string `json:"filename"`
+		Size     int    `json:"size"`
+		Status   string `json:"status"`
+		// Warnings lists dump problems, as in ROMInfoJSON.
+		Warnings []string `json:"warnings,omitempty"`
+	}{
+		CRC:      crcHex,
+		Name:     strings.TrimSuffix(romFilename, filepath.Ext(romFilename)),
+		Title:    romTitle(crc),
+		Filename: romFilename,
+		Size:     len(rom),
+		Status:   status,
+		Warnings: romDumpWarnings(f, rom),
 	}
+	data, _ := json.Marshal(result)
+	return string(data), nil
+}
 
-	if err := os.WriteFile(destPath, rom, 0644); err != nil {
-		return "", fmt.Errorf("failed to write ROM: %w", err)
+// storedROMPath returns where storeROM keeps f's ROM with crcHex.
+func storedROMPath(f emucore.CoreFactory, destDir, crcHex string) string {
+	return filepath.Join(destDir, crcHex+f.SystemInfo().Extensions[0])
+}
+
+// storedROMLookup validates a CRC32 hex string and returns the path the
+// active core's stored ROM would have.
+func storedROMLookup(destDir, crc string) (string, bool) {
+	f := activeFactory()
+	if f == nil || len(f.SystemInfo().Extensions) == 0 {
+		return "", false
 	}
+	v, err := strconv.ParseUint(crc, 16, 32)
+	if err != nil {
+		setLastError("invalid CRC %q", crc)
+		return "", false
+	}
+	return storedROMPath(f, destDir, fmt.Sprintf("%08X", v)), true
+}
 
-	return extractResultJSON(crcHex, romName), nil
+// StoredROMExists returns whether ExtractAndStoreROM has stored the ROM
+// with the given CRC32 hex string in destDir.
+func StoredROMExists(destDir, crc string) bool {
+	path, ok := storedROMLookup(destDir, crc)
+	if !ok {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
 }
 
-func extractResultJSON(crc, name string) string {
-	result := struct {
-		CRC  string `json:"crc"`
-		Name string `json:"name"`
-	}{CRC: crc, Name: name}
-	data, _ := json.Marshal(result)
-	return string(data)
+// RemoveStoredROM deletes the ROM with the given CRC32 hex string from
+// destDir. Returns false if it was not stored or could not be removed.
+func RemoveStoredROM(destDir, crc string) bool {
+	path, ok := storedROMLookup(destDir, crc)
+	if !ok {
+		return false
+	}
+	if err := os.Remove(path); err != nil {
+		setLastError("failed to remove ROM: %v", err)
+		return false
+	}
+	return true
 }
 
 // GetCRC32FromPath calculates the CRC32 checksum of a ROM file.
 // Returns -1 on error.
 func GetCRC32FromPath(path string) int64 {
-	if factory == nil {
-		return -1
-	}
-
-	info := factory.SystemInfo()
-	rom, _, err := romloader.Load(path, info.Extensions)
+	_, rom, err := loadSystemROM(path)
 	if err != nil {
 		return -1
 	}
@@ -398,9 +1103,53 @@ func GetCRC32FromPath(path string) int64 {
 	return int64(crc32.ChecksumIEEE(rom))
 }
 
-// SetOption applies a core option change to the emulator.
+// SetOption applies a core option change to the emulator. Values of
+// declared options are checked against the declaration: range values are
+// clamped to Min and Max and snapped to Step, and invalid bool or select
+// values are dropped (see LastError and ValidateOption). Undeclared keys
+// are passed through. Changes an OptionRestarter core cannot apply live
+// set RestartRequired; see ReinitializeWithCurrentROM.
 func SetOption(key string, value string) {
-	if emu != nil {
-		emu.SetOption(key, value)
+	def.setOption(key, value)
+}
+
+func (in *instance) setOption(key string, value string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil {
+		return
+	}
+	if opt, ok := declaredOption(in.factory, key); ok {
+		v, err := coerceOptionValue(opt, value)
+		if err != nil {
+			setLastError("option %s rejected: %v", key, err)
+			return
+		}
+		if v != value {
+			journalf("warning", "option %s=%q adjusted to %q", key, value, v)
+		}
+		value = v
+	}
+	old := in.effectiveOptions()[key]
+	in.emu.SetOption(key, value)
+	in.recordOption(key, value)
+	in.noteOptionRestart(key, old, value)
+}
+
+// optionValue is a core option value set through SetOption.
+type optionValue struct {
+	key   string
+	value string
+}
+
+// recordOption remembers an option so it can be re-applied when the
+// emulator is re-created.
+func (in *instance) recordOption(key string, value string) {
+	for i := range in.options {
+		if in.options[i].key == key {
+			in.options[i].value = value
+			return
+		}
 	}
+	in.options = append(in.options, optionValue{key: key, value: value})
 }
@@ -1,7 +1,11 @@
 package ios
 
 import (
+	"bytes"
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	emucore "github.com/user-none/eblitui/api"
@@ -27,10 +31,15 @@ func TestCategoryString(t *testing.T) {
 	}
 }
 
-type mockFactory struct{}
+type mockFactory struct {
+	// create, when set, builds the emulator returned by CreateEmulator.
+	create func(rom []byte, region emucore.Region) (emucore.Emulator, error)
+	// modify, when set, adjusts the SystemInfo returned by SystemInfo.
+	modify func(info *emucore.SystemInfo)
+}
 
 func (f *mockFactory) SystemInfo() emucore.SystemInfo {
-	return emucore.SystemInfo{
+	info := emucore.SystemInfo{
 		Name:        "test",
 		ConsoleName: "Test Console",
 		Extensions:  []string{".bin"},
@@ -57,9 +66,16 @@ func (f *mockFactory) SystemInfo() emucore.SystemInfo {
 			},
 		},
 	}
+	if f.modify != nil {
+		f.modify(&info)
+	}
+	return info
 }
 
 func (f *mockFactory) CreateEmulator(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+	if f.create != nil {
+		return f.create(rom, region)
+	}
 	return nil, nil
 }
 
@@ -107,3 +123,204 @@ func TestSystemInfoJSONCategoryStrings(t *testing.T) {
 		}
 	}
 }
+
+// mockEmulator is a minimal emucore.Emulator with a fixed framebuffer.
+type mockEmulator struct {
+	rom    []byte
+	region emucore.Region
+
+	frames       int
+	framebuffer  []byte
+	stride       int
+	activeHeight int
+	samples      []int16
+
+	inputs  map[int]uint32
+	options map[string]string
+	closed  bool
+}
+
+func newMockEmulator(rom []byte, region emucore.Region) *mockEmulator {
+	return &mockEmulator{
+		rom:          rom,
+		region:       region,
+		framebuffer:  make([]byte, 4*4*4),
+		stride:       4 * 4,
+		activeHeight: 4,
+		inputs:       map[int]uint32{},
+		options:      map[string]string{},
+	}
+}
+
+func (m *mockEmulator) RunFrame()                  { m.frames++ }
+func (m *mockEmulator) GetFramebuffer() []byte     { return m.framebuffer }
+func (m *mockEmulator) GetFramebufferStride() int  { return m.stride }
+func (m *mockEmulator) GetActiveHeight() int       { return m.activeHeight }
+func (m *mockEmulator) GetAudioSamples() []int16   { return m.samples }
+func (m *mockEmulator) GetRegion() emucore.Region  { return m.region }
+func (m *mockEmulator) SetRegion(r emucore.Region) { m.region = r }
+func (m *mockEmulator) Close()                     { m.closed = true }
+
+func (m *mockEmulator) SetInput(player int, buttons uint32) {
+	m.inputs[player] = buttons
+}
+
+func (m *mockEmulator) GetTiming() emucore.Timing {
+	if m.region == emucore.RegionPAL {
+		return emucore.Timing{FPS: 50, Scanlines: 313}
+	}
+	return emucore.Timing{FPS: 60, Scanlines: 262}
+}
+
+func (m *mockEmulator) SetOption(key string, value string) {
+	m.options[key] = value
+}
+
+// mockSRAMEmulator adds battery-backed SRAM to mockEmulator.
+type mockSRAMEmulator struct {
+	*mockEmulator
+	sram []byte
+}
+
+func (m *mockSRAMEmulator) HasSRAM() bool       { return true }
+func (m *mockSRAMEmulator) GetSRAM() []byte     { return append([]byte(nil), m.sram...) }
+func (m *mockSRAMEmulator) SetSRAM(data []byte) { m.sram = append([]byte(nil), data...) }
+
+// useMockFactory registers f for the duration of the test and restores
+// the previous factory and default instance afterwards.
+func useMockFactory(t *testing.T, f *mockFactory) {
+	t.Helper()
+	oldFactory, oldDef := factory, def
+	factory = f
+	def = &instance{}
+	t.Cleanup(func() {
+		def.close()
+		factory, def = oldFactory, oldDef
+	})
+}
+
+// writeROM writes data to a .bin file in a temp dir and returns its path.
+func writeROM(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write ROM: %v", err)
+	}
+	return path
+}
+
+// useMockEmulator registers a factory creating a single mockEmulator,
+// initializes the default instance from a dummy ROM, and returns it.
+func useMockEmulator(t *testing.T) *mockEmulator {
+	t.Helper()
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+// storeResult is the parsed ExtractAndStoreROM result.
+type storeResult struct {
+	CRC      string `json:"crc"`
+	Name     string `json:"name"`
+	Filename string `json:"filename"`
+	Size     int    `json:"size"`
+	Status   string `json:"status"`
+}
+
+func extractAndStore(t *testing.T, src, dest string, overwrite bool) storeResult {
+	t.Helper()
+	result, err := ExtractAndStoreROM(src, dest, overwrite)
+	if err != nil {
+		t.Fatalf("ExtractAndStoreROM: %v", err)
+	}
+	var parsed storeResult
+	if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+	return parsed
+}
+
+func TestExtractAndStoreROMVerifiesExisting(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	rom := []byte{0x10, 0x20, 0x30}
+	src := writeROM(t, "Game (USA).bin", rom)
+	dest := t.TempDir()
+
+	got := extractAndStore(t, src, dest, false)
+	if got.Status != "written" || got.Name != "Game (USA)" || got.Filename != "Game (USA).bin" || got.Size != 3 {
+		t.Fatalf("first store = %+v", got)
+	}
+	if !StoredROMExists(dest, got.CRC) || !StoredROMExists(dest, strings.ToLower(got.CRC)) {
+		t.Fatal("StoredROMExists = false after store")
+	}
+
+	if got = extractAndStore(t, src, dest, false); got.Status != "exists" {
+		t.Errorf("matching store status = %q, want exists", got.Status)
+	}
+
+	// A truncated earlier write is detected and replaced.
+	stored := filepath.Join(dest, got.CRC+".bin")
+	if err := os.WriteFile(stored, rom[:1], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got = extractAndStore(t, src, dest, false); got.Status != "replaced" {
+		t.Errorf("corrupted store status = %q, want replaced", got.Status)
+	}
+	if data, _ := os.ReadFile(stored); !bytes.Equal(data, rom) {
+		t.Errorf("stored ROM = % x, want % x", data, rom)
+	}
+
+	if got = extractAndStore(t, src, dest, true); got.Status != "replaced" {
+		t.Errorf("overwrite status = %q, want replaced", got.Status)
+	}
+
+	if !RemoveStoredROM(dest, got.CRC) || StoredROMExists(dest, got.CRC) {
+		t.Error("RemoveStoredROM did not remove the ROM")
+	}
+	if RemoveStoredROM(dest, got.CRC) {
+		t.Error("RemoveStoredROM succeeded for a missing ROM")
+	}
+	if StoredROMExists(dest, "../x") || RemoveStoredROM(dest, "../x") {
+		t.Error("invalid CRC accepted")
+	}
+}
+
+func TestStateAndSRAMBytes(t *testing.T) {
+	useStateEmulator(t, []byte{0x01})
+	def.stateData = []byte{1, 2, 3, 4, 5}
+	def.sramData = []byte{9, 8, 7}
+
+	tests := []struct {
+		offset, length int
+		want           []byte
+	}{
+		{0, 5, []byte{1, 2, 3, 4, 5}},
+		{1, 2, []byte{2, 3}},
+		{3, 100, []byte{4, 5}},
+		{5, 1, nil},
+		{-1, 1, nil},
+		{0, 0, nil},
+	}
+	for _, tt := range tests {
+		if got := StateBytes(tt.offset, tt.length); !bytes.Equal(got, tt.want) {
+			t.Errorf("StateBytes(%d, %d) = %v, want %v", tt.offset, tt.length, got, tt.want)
+		}
+	}
+
+	got := GetStateData()
+	got[0] = 0xFF
+	if def.stateData[0] != 1 {
+		t.Error("GetStateData returned the internal buffer")
+	}
+	if !bytes.Equal(SRAMBytes(1, 5), []byte{8, 7}) || !bytes.Equal(GetSRAMData(), def.sramData) {
+		t.Errorf("SRAM bytes = %v, %v", SRAMBytes(1, 5), GetSRAMData())
+	}
+}
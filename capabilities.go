@@ -0,0 +1,80 @@
+package ios
+
+import "encoding/json"
+
+// capabilities is the JSON returned by CapabilitiesJSON.
+type capabilities struct {
+	Loaded       bool `json:"loaded"`
+	SaveStates   bool `json:"saveStates"`
+	SRAM         bool `json:"sram"`
+	MemoryAccess bool `json:"memoryAccess"`
+	// Rewind and RunAhead need state serialization; Rewind is also
+	// refused in hardcore mode.
+	Rewind     bool `json:"rewind"`
+	RunAhead   bool `json:"runAhead"`
+	Cheats     bool `json:"cheats"`
+	CoreCheats bool `json:"coreCheats"`
+	SoftReset  bool `json:"softReset"`
+	Rumble     bool `json:"rumble"`
+	MultiDisc  bool `json:"multiDisc"`
+	DiscCount  int  `json:"discCount"`
+	Analog     bool `json:"analog"`
+	Pointer    bool `json:"pointer"`
+	// InputDevices reports whether SetInputDevice reaches the core.
+	InputDevices bool `json:"inputDevices"`
+	Multitap     bool `json:"multitap"`
+	MaxPlayers   int  `json:"maxPlayers"`
+	Overscan     bool `json:"overscan"`
+	Events       bool `json:"events"`
+	IdleSkip     bool `json:"idleSkip"`
+	Link         bool `json:"link"`
+}
+
+// CapabilitiesJSON reports which optional features the loaded emulator
+// supports, so the UI can hide what it cannot do: {"loaded",
+// "saveStates", "sram", "memoryAccess", "rewind", "runAhead", "cheats",
+// "coreCheats", "softReset", "rumble", "multiDisc", "discCount",
+// "analog", "pointer", "inputDevices", "multitap", "maxPlayers",
+// "overscan", "events", "idleSkip", "link"}. Everything is false when no
+// ROM is loaded. Hard reset is always available once loaded.
+func CapabilitiesJSON() string {
+	return def.capabilitiesJSON()
+}
+
+func (in *instance) capabilitiesJSON() string {
+	in.mu.Lock()
+	c := capabilities{}
+	if in.emu != nil {
+		_, link := in.emu.(LinkPort)
+		c = capabilities{
+			Loaded:       true,
+			SaveStates:   in.saveStater != nil,
+			SRAM:         in.hasSRAMLocked(),
+			MemoryAccess: in.hasMemoryAccess(),
+			Rewind:       in.saveStater != nil && !in.hardcore,
+			RunAhead:     in.saveStater != nil,
+			Cheats:       in.cheater != nil || in.systemRAMSize() > 0,
+			CoreCheats:   in.cheater != nil,
+			SoftReset:    in.resetter != nil,
+			Rumble:       in.rumble != nil,
+			MultiDisc:    in.discControl != nil,
+			DiscCount:    len(in.discs),
+			Analog:       in.analog != nil,
+			Pointer:      in.pointer != nil,
+			InputDevices: in.deviceSelector != nil,
+			Multitap:     in.multitap != nil,
+			MaxPlayers:   in.maxPlayers(),
+			Overscan:     in.overscan != nil,
+			Events:       in.eventSource != nil,
+			IdleSkip:     in.idleSkipper != nil,
+			Link:         link,
+		}
+	}
+	in.mu.Unlock()
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
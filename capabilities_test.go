@@ -0,0 +1,47 @@
+package ios
+
+import (
+	"encoding/json"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func parseCapabilities(t *testing.T, s string) capabilities {
+	t.Helper()
+	var c capabilities
+	if err := json.Unmarshal([]byte(s), &c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCapabilitiesJSON(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			return &mockSRAMEmulator{mockEmulator: newMockEmulator(rom, region)}, nil
+		},
+	})
+	if c := parseCapabilities(t, CapabilitiesJSON()); c != (capabilities{}) {
+		t.Errorf("capabilities before Init = %+v", c)
+	}
+
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	want := capabilities{Loaded: true, SRAM: true, MaxPlayers: 1}
+	if c := parseCapabilities(t, CapabilitiesJSON()); c != want {
+		t.Errorf("capabilities = %+v, want %+v", c, want)
+	}
+	if CapabilitiesJSONFor(-1) != "{}" {
+		t.Error("capabilities reported for an unknown instance")
+	}
+}
+
+func TestCapabilitiesJSONSaveStates(t *testing.T) {
+	useStateEmulator(t, []byte{0x01})
+	c := parseCapabilities(t, CapabilitiesJSON())
+	if !c.SaveStates || !c.Rewind || !c.RunAhead {
+		t.Errorf("capabilities = %+v, want state-based features", c)
+	}
+}
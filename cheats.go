@@ -0,0 +1,302 @@
+package ios
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// Cheater is an optional emulator interface for cores that apply cheat codes.
+type Cheater interface {
+	// ApplyCheat activates a cheat code. Returns an error if the core
+	// does not understand the code.
+	ApplyCheat(code string) error
+	// ResetCheats deactivates all cheat codes.
+	ResetCheats()
+}
+
+// Cheat formats reported by ListCheatsJSON.
+const (
+	// cheatCore is a code handed to a core implementing Cheater, which
+	// parses it itself.
+	cheatCore = "core"
+	// cheatRaw is a bridge "AAAA:VV" code: a hex offset into system RAM
+	// and a byte value.
+	cheatRaw = "raw"
+	// cheatActionReplay is an Action Replay "AAAAAAVV" code: a 24-bit
+	// hex bus address and a byte value.
+	cheatActionReplay = "actionReplay"
+)
+
+// cheat is a code tracked by the bridge so it can be re-applied after
+// the core loses its patches.
+type cheat struct {
+	Code    string `json:"code"`
+	Format  string `json:"format"`
+	Enabled bool   `json:"enabled"`
+
+	// patch is the parsed code of a cheat the bridge applies itself.
+	patch *memoryPatch
+}
+
+// memoryPatch is a byte forced at an offset into system RAM.
+type memoryPatch struct {
+	offset int
+	value  byte
+}
+
+// gameGenieLetters holds the union of the NES, SNES, Genesis and Game Boy
+// Game Genie alphabets, used to recognize codes the bridge cannot
+// translate.
+const gameGenieLetters = "ABCDEFGHIJKLMNOPRSTUVWXYZ0123456789"
+
+// parseMemoryPatch translates a code into a system RAM patch for a core
+// with ramSize bytes of system RAM, returning the code's format.
+//
+// Raw "AAAA:VV" codes give the offset into system RAM directly. Action
+// Replay "AAAAAAVV" codes give a bus address, which is folded onto
+// system RAM through its mirror mask, so for example SNES 7E0DBE and
+// Genesis FF0DBE both address their RAM's offset 0DBE. Game Genie codes
+// patch ROM reads rather than RAM, which a RAM patch cannot express, so
+// they are rejected; cores that support them implement Cheater.
+func parseMemoryPatch(code string, ramSize int) (*memoryPatch, string, error) {
+	if addr, value, ok := strings.Cut(code, ":"); ok {
+		offset, err := strconv.ParseUint(addr, 16, 32)
+		if err != nil {
+			return nil, "", fmt.Errorf("bad address %q", addr)
+		}
+		v, err := strconv.ParseUint(value, 16, 8)
+		if err != nil {
+			return nil, "", fmt.Errorf("bad value %q", value)
+		}
+		if int(offset) >= ramSize {
+			return nil, "", fmt.Errorf("address beyond system RAM")
+		}
+		return &memoryPatch{offset: int(offset), value: byte(v)}, cheatRaw, nil
+	}
+
+	if len(code) == 8 {
+		if n, err := strconv.ParseUint(code, 16, 32); err == nil {
+			if ramSize&(ramSize-1) != 0 {
+				return nil, "", fmt.Errorf("system RAM size %d has no mirror mask for Action Replay addresses", ramSize)
+			}
+			addr := int(n >> 8)
+			return &memoryPatch{offset: addr & (ramSize - 1), value: byte(n)}, cheatActionReplay, nil
+		}
+	}
+
+	letters := strings.ReplaceAll(code, "-", "")
+	if (len(letters) == 6 || len(letters) == 8) && strings.Trim(letters, gameGenieLetters) == "" {
+		return nil, "", fmt.Errorf("Game Genie codes patch ROM and need a core that applies cheats")
+	}
+	return nil, "", fmt.Errorf("want AAAA:VV or an Action Replay AAAAAAVV code")
+}
+
+// normalizeCheat returns the key a cheat code is stored under.
+func normalizeCheat(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// HasCheats returns whether the emulator supports cheat codes, either
+// through the core or as system RAM patches.
+func HasCheats() bool {
+	return def.hasCheats()
+}
+
+func (in *instance) hasCheats() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.cheater != nil || in.systemRAMSize() > 0
+}
+
+// systemRAMSize returns the size of the core's system RAM region, or 0
+// if it exposes none.
+func (in *instance) systemRAMSize() int {
+	for _, r := range in.memoryRegions() {
+		if r.regionType == emucore.MemorySystemRAM {
+			return r.Size
+		}
+	}
+	return 0
+}
+
+// AddCheat adds and enables a cheat code. Codes are keyed by their text,
+// ignoring case and surrounding space; adding a code already in the list
+// enables it. Cores implementing Cheater handle their own formats. For
+// other cores that expose system RAM, the bridge translates raw "AAAA:VV"
+// and Action Replay "AAAAAAVV" codes into patches written every frame,
+// and rejects Game Genie codes, which patch ROM.
+// Returns false if the code is rejected (see LastError).
+func AddCheat(code string) bool {
+	return def.addCheat(code)
+}
+
+func (in *instance) addCheat(code string) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	code = normalizeCheat(code)
+	if in.hardcore {
+		setLastError("cheats are disabled in hardcore mode")
+		return false
+	}
+	if i := in.findCheat(code); i >= 0 {
+		in.enableCheat(i, true)
+		return true
+	}
+
+	c := cheat{Code: code, Format: cheatCore, Enabled: true}
+	switch {
+	case in.cheater != nil:
+		if err := in.cheater.ApplyCheat(code); err != nil {
+			setLastError("invalid cheat %q: %v", code, err)
+			return false
+		}
+	case in.systemRAMSize() > 0:
+		patch, format, err := parseMemoryPatch(code, in.systemRAMSize())
+		if err != nil {
+			setLastError("invalid cheat %q: %v", code, err)
+			return false
+		}
+		c.patch, c.Format = patch, format
+	default:
+		setLastError("cheats not supported")
+		return false
+	}
+	in.cheats = append(in.cheats, c)
+	return true
+}
+
+// findCheat returns the index of code in the cheat list, or -1.
+// in.mu must be held.
+func (in *instance) findCheat(code string) int {
+	for i, c := range in.cheats {
+		if c.Code == code {
+			return i
+		}
+	}
+	return -1
+}
+
+// enableCheat enables or disables cheat i. in.mu must be held.
+func (in *instance) enableCheat(i int, enabled bool) {
+	if in.cheats[i].Enabled != enabled {
+		in.cheats[i].Enabled = enabled
+		in.reapplyCheats()
+	}
+}
+
+// SetCheatEnabled enables or disables a cheat. Does nothing if code is
+// not in the list.
+func SetCheatEnabled(code string, enabled bool) {
+	def.setCheatEnabled(code, enabled)
+}
+
+func (in *instance) setCheatEnabled(code string, enabled bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if i := in.findCheat(normalizeCheat(code)); i >= 0 {
+		in.enableCheat(i, enabled)
+	}
+}
+
+// RemoveCheat removes a cheat. Does nothing if code is not in the list.
+func RemoveCheat(code string) {
+	def.removeCheat(code)
+}
+
+func (in *instance) removeCheat(code string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if i := in.findCheat(normalizeCheat(code)); i >= 0 {
+		in.cheats = append(in.cheats[:i], in.cheats[i+1:]...)
+		in.reapplyCheats()
+	}
+}
+
+// ClearCheats removes all cheats.
+func ClearCheats() {
+	def.clearCheats()
+}
+
+func (in *instance) clearCheats() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.cheats = nil
+	in.reapplyCheats()
+}
+
+// ListCheatsJSON returns the cheat list as a JSON array of
+// {"code", "format", "enabled"} objects in the order they were added.
+// format is "core" for codes the core applies, "raw" or "actionReplay"
+// for codes the bridge patches into system RAM.
+func ListCheatsJSON() string {
+	return def.listCheatsJSON()
+}
+
+func (in *instance) listCheatsJSON() string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(in.cheats) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(in.cheats)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// reapplyCheats resets the core's cheats and applies every enabled cheat
+// again. Called whenever the list changes and after the core state is
+// replaced, since cores typically lose patched memory on deserialize.
+// in.mu must be held.
+func (in *instance) reapplyCheats() {
+	if in.cheater == nil {
+		return
+	}
+	in.cheater.ResetCheats()
+	if in.hardcore {
+		return
+	}
+	for _, c := range in.cheats {
+		if c.Enabled {
+			in.cheater.ApplyCheat(c.Code)
+		}
+	}
+}
+
+// hasMemoryPatches reports whether any enabled cheat is a raw patch.
+func (in *instance) hasMemoryPatches() bool {
+	if in.hardcore {
+		return false
+	}
+	for _, c := range in.cheats {
+		if c.Enabled && c.patch != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// applyMemoryPatches writes the enabled raw cheats into system RAM, each
+// byte in place if the core implements MemoryRangeWriter.
+func (in *instance) applyMemoryPatches() {
+	if _, ok := in.emu.(MemoryRangeWriter); ok {
+		for _, c := range in.cheats {
+			if c.Enabled && c.patch != nil {
+				in.writeRegionAt(emucore.MemorySystemRAM, c.patch.offset, []byte{c.patch.value})
+			}
+		}
+		return
+	}
+	ram := in.memMapper.ReadRegion(emucore.MemorySystemRAM)
+	for _, c := range in.cheats {
+		if c.Enabled && c.patch != nil && c.patch.offset < len(ram) {
+			ram[c.patch.offset] = c.patch.value
+		}
+	}
+	in.memMapper.WriteRegion(emucore.MemorySystemRAM, ram)
+}
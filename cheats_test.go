@@ -0,0 +1,167 @@
+package ios
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockCheatEmulator accepts codes starting with "OK" and loses its
+// applied cheats on Deserialize like a real core.
+type mockCheatEmulator struct {
+	*mockEmulator
+	applied []string
+}
+
+func (m *mockCheatEmulator) ApplyCheat(code string) error {
+	if !strings.HasPrefix(code, "OK") {
+		return errors.New("bad format")
+	}
+	m.applied = append(m.applied, code)
+	return nil
+}
+
+func (m *mockCheatEmulator) ResetCheats() { m.applied = nil }
+
+func (m *mockCheatEmulator) Serialize() ([]byte, error) { return []byte{1}, nil }
+
+func (m *mockCheatEmulator) Deserialize(data []byte) error {
+	m.applied = nil
+	return nil
+}
+
+func useCheatEmulator(t *testing.T) *mockCheatEmulator {
+	t.Helper()
+	var e *mockCheatEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockCheatEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func TestAddCheatRejectsInvalidCode(t *testing.T) {
+	useCheatEmulator(t)
+
+	if AddCheat("BAD") {
+		t.Error("AddCheat accepted BAD")
+	}
+	if !strings.Contains(LastError(), "BAD") {
+		t.Errorf("LastError = %q, want mention of code", LastError())
+	}
+	if ListCheatsJSON() != "[]" {
+		t.Errorf("ListCheatsJSON = %s, want []", ListCheatsJSON())
+	}
+}
+
+func TestCheatsReappliedAfterLoadState(t *testing.T) {
+	e := useCheatEmulator(t)
+
+	if !AddCheat("OK-A") || !AddCheat(" ok-b ") {
+		t.Fatalf("AddCheat failed: %s", LastError())
+	}
+	SetCheatEnabled("OK-B", false)
+
+	if !LoadState([]byte{1}) {
+		t.Fatal("LoadState failed")
+	}
+	if len(e.applied) != 1 || e.applied[0] != "OK-A" {
+		t.Errorf("applied after load = %v, want [OK-A]", e.applied)
+	}
+
+	var cheats []cheat
+	if err := json.Unmarshal([]byte(ListCheatsJSON()), &cheats); err != nil {
+		t.Fatal(err)
+	}
+	if len(cheats) != 2 || !cheats[0].Enabled || cheats[1].Enabled || cheats[1].Code != "OK-B" || cheats[0].Format != "core" {
+		t.Errorf("ListCheatsJSON = %+v", cheats)
+	}
+
+	// Adding a listed code enables it rather than duplicating it.
+	if !AddCheat("ok-b") || len(e.applied) != 2 {
+		t.Errorf("re-adding OK-B: applied %v", e.applied)
+	}
+	if err := json.Unmarshal([]byte(ListCheatsJSON()), &cheats); err != nil || len(cheats) != 2 {
+		t.Errorf("ListCheatsJSON after re-add = %s", ListCheatsJSON())
+	}
+
+	RemoveCheat("OK-A")
+	RemoveCheat("OK-A")
+	if len(e.applied) != 1 || e.applied[0] != "OK-B" {
+		t.Errorf("applied after remove = %v, want [OK-B]", e.applied)
+	}
+
+	ClearCheats()
+	if len(e.applied) != 0 || ListCheatsJSON() != "[]" {
+		t.Error("ClearCheats left cheats active")
+	}
+}
+
+func TestCheatsUnsupported(t *testing.T) {
+	useMockEmulator(t)
+	if HasCheats() {
+		t.Error("HasCheats = true")
+	}
+	if AddCheat("OK") {
+		t.Error("AddCheat succeeded without support")
+	}
+}
+
+func TestRawMemoryCheats(t *testing.T) {
+	e := useMemoryEmulator(t)
+	if !HasCheats() {
+		t.Fatal("HasCheats = false with system RAM")
+	}
+
+	if !AddCheat("0003:FF") {
+		t.Fatalf("AddCheat failed: %s", LastError())
+	}
+	for _, bad := range []string{"0003", "zz:01", "0003:100", "0008:01"} {
+		if AddCheat(bad) {
+			t.Errorf("AddCheat(%q) accepted", bad)
+		}
+	}
+
+	e.ram[3] = 0
+	RunFrame()
+	if e.ram[3] != 0xFF {
+		t.Errorf("ram[3] = %#x after frame, want 0xff", e.ram[3])
+	}
+
+	SetCheatEnabled("0003:ff", false)
+	e.ram[3] = 0
+	RunFrame()
+	if e.ram[3] != 0 {
+		t.Errorf("disabled cheat still applied, ram[3] = %#x", e.ram[3])
+	}
+}
+
+func TestActionReplayCheats(t *testing.T) {
+	e := useMemoryEmulator(t)
+
+	// 7E0005 folds onto offset 5 of the mock's 8 bytes of RAM.
+	if !AddCheat("7E00052A") {
+		t.Fatalf("AddCheat failed: %s", LastError())
+	}
+	RunFrame()
+	if e.ram[5] != 0x2A {
+		t.Errorf("ram[5] = %#x after frame, want 0x2a", e.ram[5])
+	}
+	if !strings.Contains(ListCheatsJSON(), `"format":"actionReplay"`) {
+		t.Errorf("ListCheatsJSON = %s", ListCheatsJSON())
+	}
+
+	for _, gg := range []string{"SXIOPO", "AAEAULPA", "ABCD-EFGH"} {
+		if AddCheat(gg) || !strings.Contains(LastError(), "Game Genie") {
+			t.Errorf("AddCheat(%q) not rejected as Game Genie, LastError = %q", gg, LastError())
+		}
+	}
+}
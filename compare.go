@@ -0,0 +1,160 @@
+package ios
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+)
+
+// compareOptions is the toleranceJSON accepted by CompareScreenshots.
+type compareOptions struct {
+	// Tolerance is the largest per-channel difference treated as equal.
+	Tolerance int `json:"tolerance"`
+	// Ignore lists regions excluded from comparison.
+	Ignore []compareRect `json:"ignore"`
+	// Heatmap, when set, is a path to write a PNG of the differences.
+	Heatmap string `json:"heatmap"`
+}
+
+type compareRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// compareResult is the JSON returned by CompareScreenshots.
+type compareResult struct {
+	Width        int          `json:"width"`
+	Height       int          `json:"height"`
+	SizeMismatch bool         `json:"sizeMismatch"`
+	Identical    bool         `json:"identical"`
+	DiffPixels   int          `json:"diffPixels"`
+	MaxDelta     int          `json:"maxDelta"`
+	Bounds       *compareRect `json:"bounds,omitempty"`
+}
+
+// CompareScreenshots compares two PNG screenshots pixel by pixel.
+// toleranceJSON is an optional object:
+//
+//	{"tolerance": 2, "ignore": [{"x":0,"y":0,"w":40,"h":8}], "heatmap": "/path/diff.png"}
+//
+// Returns JSON with the differing pixel count, the bounding box of the
+// differences, and the largest channel delta. Returns "{}" if either
+// image cannot be read or the options are invalid.
+func CompareScreenshots(pathA, pathB string, toleranceJSON string) string {
+	var opts compareOptions
+	if toleranceJSON != "" {
+		if err := json.Unmarshal([]byte(toleranceJSON), &opts); err != nil {
+			return "{}"
+		}
+	}
+
+	a, err := decodePNGFile(pathA)
+	if err != nil {
+		return "{}"
+	}
+	b, err := decodePNGFile(pathB)
+	if err != nil {
+		return "{}"
+	}
+
+	result, heatmap := compareImages(a, b, opts)
+	if opts.Heatmap != "" && heatmap != nil {
+		f, err := os.Create(opts.Heatmap)
+		if err != nil {
+			return "{}"
+		}
+		err = png.Encode(f, heatmap)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return "{}"
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+func decodePNGFile(path string) (*image.NRGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := img.(*image.NRGBA); ok && n.Rect.Min == (image.Point{}) {
+		return n, nil
+	}
+	n := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(n, n.Rect, img, img.Bounds().Min, draw.Src)
+	return n, nil
+}
+
+// compareImages diffs a and b. The heatmap is nil on a size mismatch;
+// otherwise it marks differing pixels in red scaled by their delta.
+func compareImages(a, b *image.NRGBA, opts compareOptions) (compareResult, *image.NRGBA) {
+	w, h := a.Rect.Dx(), a.Rect.Dy()
+	result := compareResult{Width: w, Height: h}
+	if w != b.Rect.Dx() || h != b.Rect.Dy() {
+		result.SizeMismatch = true
+		return result, nil
+	}
+
+	heatmap := image.NewNRGBA(a.Rect)
+	minX, minY, maxX, maxY := w, h, -1, -1
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if ignored(opts.Ignore, x, y) {
+				continue
+			}
+			pa := a.Pix[y*a.Stride+x*4:]
+			pb := b.Pix[y*b.Stride+x*4:]
+			delta := 0
+			for c := 0; c < 4; c++ {
+				d := int(pa[c]) - int(pb[c])
+				if d < 0 {
+					d = -d
+				}
+				delta = max(delta, d)
+			}
+			result.MaxDelta = max(result.MaxDelta, delta)
+			if delta <= opts.Tolerance {
+				heatmap.SetNRGBA(x, y, color.NRGBA{A: 0xFF})
+				continue
+			}
+
+			result.DiffPixels++
+			minX, minY = min(minX, x), min(minY, y)
+			maxX, maxY = max(maxX, x), max(maxY, y)
+			heatmap.SetNRGBA(x, y, color.NRGBA{R: byte(delta), A: 0xFF})
+		}
+	}
+
+	result.Identical = result.DiffPixels == 0
+	if !result.Identical {
+		result.Bounds = &compareRect{X: minX, Y: minY, W: maxX - minX + 1, H: maxY - minY + 1}
+	}
+	return result, heatmap
+}
+
+func ignored(regions []compareRect, x, y int) bool {
+	for _, r := range regions {
+		if x >= r.X && x < r.X+r.W && y >= r.Y && y < r.Y+r.H {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package ios
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePNG(t *testing.T, name string, w, h int, fill func(x, y int) color.NRGBA) string {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, fill(x, y))
+		}
+	}
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func gray(v byte) func(x, y int) color.NRGBA {
+	return func(x, y int) color.NRGBA { return color.NRGBA{v, v, v, 0xFF} }
+}
+
+func parseCompare(t *testing.T, s string) compareResult {
+	t.Helper()
+	var r compareResult
+	if err := json.Unmarshal([]byte(s), &r); err != nil {
+		t.Fatalf("failed to parse %q: %v", s, err)
+	}
+	return r
+}
+
+func TestCompareScreenshotsIdentical(t *testing.T) {
+	a := writePNG(t, "a.png", 4, 4, gray(50))
+	b := writePNG(t, "b.png", 4, 4, gray(50))
+
+	r := parseCompare(t, CompareScreenshots(a, b, ""))
+	if !r.Identical || r.DiffPixels != 0 || r.Bounds != nil {
+		t.Errorf("result = %+v, want identical", r)
+	}
+}
+
+func TestCompareScreenshotsSubtleDifference(t *testing.T) {
+	a := writePNG(t, "a.png", 4, 4, gray(50))
+	b := writePNG(t, "b.png", 4, 4, func(x, y int) color.NRGBA {
+		switch {
+		case x == 1 && y == 2:
+			return color.NRGBA{60, 50, 50, 0xFF}
+		case x == 3 && y == 3:
+			return color.NRGBA{52, 50, 50, 0xFF}
+		case x == 0 && y == 0:
+			return color.NRGBA{90, 50, 50, 0xFF}
+		}
+		return color.NRGBA{50, 50, 50, 0xFF}
+	})
+	heatmap := filepath.Join(t.TempDir(), "heat.png")
+
+	opts := `{"tolerance": 2, "ignore": [{"x":0,"y":0,"w":1,"h":1}], "heatmap": "` + heatmap + `"}`
+	r := parseCompare(t, CompareScreenshots(a, b, opts))
+	if r.Identical || r.DiffPixels != 1 {
+		t.Fatalf("result = %+v, want 1 differing pixel", r)
+	}
+	if r.MaxDelta != 10 {
+		t.Errorf("MaxDelta = %d, want 10", r.MaxDelta)
+	}
+	if want := (compareRect{X: 1, Y: 2, W: 1, H: 1}); *r.Bounds != want {
+		t.Errorf("Bounds = %+v, want %+v", *r.Bounds, want)
+	}
+
+	img, err := decodePNGFile(heatmap)
+	if err != nil {
+		t.Fatalf("heatmap not written: %v", err)
+	}
+	if got := img.NRGBAAt(1, 2).R; got != 10 {
+		t.Errorf("heatmap red at diff = %d, want 10", got)
+	}
+	if got := img.NRGBAAt(3, 3).R; got != 0 {
+		t.Errorf("heatmap red within tolerance = %d, want 0", got)
+	}
+}
+
+func TestCompareScreenshotsSizeMismatch(t *testing.T) {
+	a := writePNG(t, "a.png", 4, 4, gray(50))
+	b := writePNG(t, "b.png", 4, 3, gray(50))
+
+	r := parseCompare(t, CompareScreenshots(a, b, ""))
+	if !r.SizeMismatch || r.Identical {
+		t.Errorf("result = %+v, want size mismatch", r)
+	}
+}
+
+func TestCompareScreenshotsInvalidInput(t *testing.T) {
+	a := writePNG(t, "a.png", 4, 4, gray(50))
+	if got := CompareScreenshots(a, filepath.Join(t.TempDir(), "missing.png"), ""); got != "{}" {
+		t.Errorf("missing file: got %q, want {}", got)
+	}
+	if got := CompareScreenshots(a, a, "{bad"); got != "{}" {
+		t.Errorf("bad options: got %q, want {}", got)
+	}
+}
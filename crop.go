@@ -0,0 +1,154 @@
+package ios
+
+import "encoding/json"
+
+// OverscanReporter is an optional emulator interface for cores whose
+// frames carry overscan or border rows and columns that a TV would hide.
+type OverscanReporter interface {
+	// DefaultCrop returns the pixels to trim from each edge of the active
+	// display area when the app has not called SetCrop.
+	DefaultCrop() (top, bottom, left, right int)
+}
+
+// cropRect is the number of pixels trimmed from each edge of the frame.
+type cropRect struct {
+	Top    int `json:"top"`
+	Bottom int `json:"bottom"`
+	Left   int `json:"left"`
+	Right  int `json:"right"`
+}
+
+// newCrop returns a crop with negative edges counted as 0.
+func newCrop(top, bottom, left, right int) *cropRect {
+	return &cropRect{max(top, 0), max(bottom, 0), max(left, 0), max(right, 0)}
+}
+
+func (c cropRect) empty() bool {
+	return c == cropRect{}
+}
+
+// SetCrop trims pixels from each edge of the frame before it is
+// published. FrameWidth, FrameHeight, FrameStride and every frame getter
+// describe the cropped frame, and the cropped rows are packed without
+// stride padding. Negative values count as 0, and the crop is reduced as
+// needed to keep at least one pixel. The crop stays in effect across Init
+// until ResetCrop.
+func SetCrop(top, bottom, left, right int) {
+	def.setCrop(newCrop(top, bottom, left, right))
+}
+
+// ResetCrop goes back to the core's default crop, or none if the core has
+// no OverscanReporter.
+func ResetCrop() {
+	def.setCrop(nil)
+}
+
+func (in *instance) setCrop(c *cropRect) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.crop = c
+}
+
+// GetCropJSON returns the crop in effect for the current frame geometry:
+// {"top", "bottom", "left", "right", "custom"}. custom is true if the
+// crop was set with SetCrop rather than taken from the core's default.
+func GetCropJSON() string {
+	def.mu.Lock()
+	c := def.activeCrop()
+	custom := def.crop != nil
+	def.mu.Unlock()
+
+	data, err := json.Marshal(struct {
+		cropRect
+		Custom bool `json:"custom"`
+	}{c, custom})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// activeCrop returns the crop for the current frame geometry, clamped so
+// at least one pixel remains in each dimension.
+func (in *instance) activeCrop() cropRect {
+	if in.emu == nil {
+		return cropRect{}
+	}
+	var c cropRect
+	switch {
+	case in.crop != nil:
+		c = *in.crop
+	case in.overscan != nil:
+		c = *newCrop(in.overscan.DefaultCrop())
+	default:
+		return cropRect{}
+	}
+	c.Left, c.Right = clampCrop(c.Left, c.Right, in.uncroppedWidth())
+	c.Top, c.Bottom = clampCrop(c.Top, c.Bottom, in.emu.GetActiveHeight())
+	return c
+}
+
+// clampCrop reduces a and b, b first, so they leave at least one of size.
+func clampCrop(a, b, size int) (int, int) {
+	if size <= 0 {
+		return 0, 0
+	}
+	a = min(a, size-1)
+	b = min(b, size-1-a)
+	return a, b
+}
+
+// croppedWidth and croppedHeight return the frame size after any crop.
+func (in *instance) croppedWidth() int {
+	c := in.activeCrop()
+	return in.uncroppedWidth() - c.Left - c.Right
+}
+
+func (in *instance) croppedHeight() int {
+	c := in.activeCrop()
+	return in.emu.GetActiveHeight() - c.Top - c.Bottom
+}
+
+// croppedStride returns the bytes per row of the cropped frame: the
+// core's stride, or the packed cropped width.
+func (in *instance) croppedStride() int {
+	if c := in.activeCrop(); !c.empty() {
+		return in.croppedWidth() * 4
+	}
+	return in.emu.GetFramebufferStride()
+}
+
+// dataStride returns the bytes per row of frameData after any crop,
+// rotation and scaling filter.
+func (in *instance) dataStride() int {
+	if in.filterScale() > 1 {
+		return in.visibleWidth() * 4
+	}
+	return in.rotatedStride()
+}
+
+// cropFrame copies the cropped area of frameData, which holds the active
+// display area at the core's stride, into the crop buffer.
+func (in *instance) cropFrame() {
+	c := in.activeCrop()
+	if c.empty() || len(in.frameData) == 0 {
+		return
+	}
+	stride := in.emu.GetFramebufferStride()
+	rowBytes := in.croppedWidth() * 4
+	rows := len(in.frameData)/stride - c.Top - c.Bottom
+	if rows <= 0 {
+		return
+	}
+
+	n := rows * rowBytes
+	if cap(in.cropped) < n {
+		in.cropped = make([]byte, n)
+	}
+	in.cropped = in.cropped[:n]
+	for y := 0; y < rows; y++ {
+		src := in.frameData[(y+c.Top)*stride+c.Left*4:]
+		copy(in.cropped[y*rowBytes:(y+1)*rowBytes], src[:rowBytes])
+	}
+	in.frameData = in.cropped
+}
@@ -0,0 +1,112 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockOverscanEmulator reports a default crop.
+type mockOverscanEmulator struct {
+	*mockEmulator
+}
+
+func (m *mockOverscanEmulator) DefaultCrop() (top, bottom, left, right int) { return 1, 1, 0, 0 }
+
+// fillPixels numbers each pixel of a mock's 4x4 frame by its index.
+func fillPixels(e *mockEmulator) {
+	for i := 0; i < 16; i++ {
+		e.framebuffer[i*4] = byte(i)
+	}
+}
+
+func TestSetCrop(t *testing.T) {
+	e := useMockEmulator(t)
+	fillPixels(e)
+
+	SetCrop(1, 0, 1, 1)
+	RunFrame()
+	if FrameWidth() != 2 || FrameHeight() != 3 || FrameStride() != 8 {
+		t.Fatalf("geometry = %dx%d stride %d, want 2x3 stride 8", FrameWidth(), FrameHeight(), FrameStride())
+	}
+	var got []byte
+	frame := GetFrameData()
+	for i := 0; i < len(frame); i += 4 {
+		got = append(got, frame[i])
+	}
+	if want := []byte{5, 6, 9, 10, 13, 14}; !bytes.Equal(got, want) {
+		t.Errorf("cropped pixels = %v, want %v", got, want)
+	}
+
+	SetCrop(10, 10, -1, 10)
+	RunFrame()
+	if FrameWidth() != 1 || FrameHeight() != 1 {
+		t.Errorf("oversized crop = %dx%d, want 1x1", FrameWidth(), FrameHeight())
+	}
+	var crop struct {
+		Top, Bottom, Left, Right int
+		Custom                   bool
+	}
+	if err := json.Unmarshal([]byte(GetCropJSON()), &crop); err != nil {
+		t.Fatal(err)
+	}
+	if crop.Top != 3 || crop.Bottom != 0 || crop.Left != 0 || crop.Right != 3 || !crop.Custom {
+		t.Errorf("GetCropJSON = %+v", crop)
+	}
+
+	ResetCrop()
+	RunFrame()
+	if FrameWidth() != 4 || FrameHeight() != 4 || len(GetFrameData()) != 64 {
+		t.Error("ResetCrop did not restore the full frame")
+	}
+}
+
+func TestDefaultCropFromCore(t *testing.T) {
+	var e *mockOverscanEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockOverscanEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	fillPixels(e.mockEmulator)
+	RunFrame()
+	if FrameHeight() != 2 || GetFrameData()[0] != 4 {
+		t.Errorf("default crop: height %d, first pixel %d, want 2, 4", FrameHeight(), GetFrameData()[0])
+	}
+	SetCrop(0, 0, 0, 0)
+	RunFrame()
+	if FrameHeight() != 4 {
+		t.Errorf("SetCrop(0, 0, 0, 0) height = %d, want 4", FrameHeight())
+	}
+}
+
+func TestCropOffsetsPointer(t *testing.T) {
+	e := useDeviceEmulator(t)
+	SetCrop(1, 0, 2, 0)
+	SetPointerInput(0, 0, 9, true)
+	if e.pointer != [3]int{0, 2, 3} {
+		t.Errorf("pointer = %v, want [0 2 3]", e.pointer)
+	}
+}
+
+func TestCropAdjustsAspect(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			return newMockEmulator(rom, region), nil
+		},
+		modify: func(info *emucore.SystemInfo) { info.AspectRatio = 2 },
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	SetCrop(0, 0, 1, 1)
+	if PixelAspectRatio() != 2 || DisplayAspectRatio() != 1 {
+		t.Errorf("cropped PAR %v, DAR %v, want 2, 1", PixelAspectRatio(), DisplayAspectRatio())
+	}
+}
@@ -0,0 +1,97 @@
+package ios
+
+import (
+	"encoding/json"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// InputDescriptorProvider is an optional emulator interface for cores
+// whose buttons differ per player or follow the connected device, such as
+// a light gun on port 2.
+type InputDescriptorProvider interface {
+	// InputButtons returns the buttons beyond the d-pad a player's
+	// current device has, or nil to use SystemInfo.Buttons.
+	InputButtons(player int) []emucore.Button
+}
+
+// dpadButtons are the standard d-pad bits every device shares.
+var dpadButtons = []emucore.Button{
+	{Name: "Up", ID: emucore.ButtonUp},
+	{Name: "Down", ID: emucore.ButtonDown},
+	{Name: "Left", ID: emucore.ButtonLeft},
+	{Name: "Right", ID: emucore.ButtonRight},
+}
+
+// inputDescriptor is a button's entry in InputDescriptorsJSON.
+type inputDescriptor struct {
+	Name       string `json:"name"`
+	ID         int    `json:"id"`
+	Mask       int    `json:"mask"`
+	DPad       bool   `json:"dpad"`
+	DefaultPad string `json:"defaultPad,omitempty"`
+	DefaultKey string `json:"defaultKey,omitempty"`
+}
+
+// InputDescriptorsJSON lists, per player, the connected device and the
+// buttons the core reads, for building touch and remapping UI:
+//
+//	[{"player": 0, "device": "gamepad", "buttons": [
+//	    {"name": "Up", "id": 0, "mask": 1, "dpad": true}, ...,
+//	    {"name": "A", "id": 4, "mask": 16, "dpad": false, "defaultPad": "A"}]}]
+//
+// There is one entry per MaxPlayers. id is the SetInput bit and mask its
+// value. Before a game is loaded the system's buttons are listed.
+// Returns "[]" if no core is registered.
+func InputDescriptorsJSON() string {
+	return def.inputDescriptorsJSON()
+}
+
+func (in *instance) inputDescriptorsJSON() string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	f := in.coreFactory()
+	if f == nil {
+		return "[]"
+	}
+	info := f.SystemInfo()
+	provider, _ := in.emu.(InputDescriptorProvider)
+
+	type playerDescriptors struct {
+		Player  int               `json:"player"`
+		Device  string            `json:"device"`
+		Buttons []inputDescriptor `json:"buttons"`
+	}
+	players := make([]playerDescriptors, in.maxPlayers())
+	for i := range players {
+		buttons := info.Buttons
+		if provider != nil {
+			if b := provider.InputButtons(i); b != nil {
+				buttons = b
+			}
+		}
+		device := defaultInputDevice
+		if d, ok := in.inputDevices[i]; ok {
+			device = d
+		}
+
+		p := playerDescriptors{Player: i, Device: device}
+		for _, b := range append(append([]emucore.Button(nil), dpadButtons...), buttons...) {
+			p.Buttons = append(p.Buttons, inputDescriptor{
+				Name:       b.Name,
+				ID:         b.ID,
+				Mask:       1 << b.ID,
+				DPad:       b.ID <= emucore.ButtonRight,
+				DefaultPad: b.DefaultPad,
+				DefaultKey: b.DefaultKey,
+			})
+		}
+		players[i] = p
+	}
+
+	data, err := json.Marshal(players)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
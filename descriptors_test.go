@@ -0,0 +1,79 @@
+package ios
+
+import (
+	"encoding/json"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+type descriptorsResult []struct {
+	Player  int
+	Device  string
+	Buttons []struct {
+		Name       string
+		ID         int
+		Mask       int
+		DPad       bool
+		DefaultPad string
+	}
+}
+
+func parseDescriptors(t *testing.T) descriptorsResult {
+	t.Helper()
+	var got descriptorsResult
+	if err := json.Unmarshal([]byte(InputDescriptorsJSON()), &got); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+// mockGunEmulator has a light gun with a single trigger on player 1.
+type mockGunEmulator struct {
+	*mockEmulator
+}
+
+func (m *mockGunEmulator) InputButtons(player int) []emucore.Button {
+	if player == 1 {
+		return []emucore.Button{{Name: "Trigger", ID: 4}}
+	}
+	return nil
+}
+
+func TestInputDescriptorsJSON(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			return &mockGunEmulator{newMockEmulator(rom, region)}, nil
+		},
+		modify: func(info *emucore.SystemInfo) {
+			info.Players = 2
+			info.Buttons = []emucore.Button{
+				{Name: "A", ID: 4, DefaultPad: "A"},
+				{Name: "Start", ID: 7, DefaultPad: "Start"},
+			}
+		},
+	})
+
+	got := parseDescriptors(t)
+	if len(got) != 2 || len(got[1].Buttons) != 6 {
+		t.Fatalf("descriptors before load = %+v", got)
+	}
+	start := got[0].Buttons[5]
+	if start.Name != "Start" || start.ID != 7 || start.Mask != 128 || start.DPad || start.DefaultPad != "Start" {
+		t.Errorf("Start = %+v", start)
+	}
+	if up := got[0].Buttons[0]; up.Name != "Up" || up.Mask != 1 || !up.DPad {
+		t.Errorf("Up = %+v", up)
+	}
+
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	got = parseDescriptors(t)
+	if len(got[0].Buttons) != 6 || len(got[1].Buttons) != 5 || got[1].Buttons[4].Name != "Trigger" {
+		t.Errorf("per-player descriptors = %+v", got)
+	}
+	if got[1].Device != "gamepad" {
+		t.Errorf("device = %q", got[1].Device)
+	}
+}
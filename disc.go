@@ -0,0 +1,202 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/user-none/eblitui/romloader"
+)
+
+// DiscControl is an optional emulator interface for cores whose games
+// span several discs or disks.
+type DiscControl interface {
+	// LoadDiscs gives the core every image of the game, in order. The
+	// first is the image the emulator was created with and is inserted.
+	LoadDiscs(images [][]byte) error
+	// InsertDisc swaps in the image at index.
+	InsertDisc(index int) error
+}
+
+// DiscTray is an optional emulator interface for DiscControl cores that
+// model the disc tray.
+type DiscTray interface {
+	SetTrayOpen(open bool)
+}
+
+// discTrailerMagic ends save states that carry the inserted disc index.
+// The layout is the core's state, a little-endian uint32 index, then the
+// magic.
+var discTrailerMagic = []byte("EBDS")
+
+const discTrailerSize = 4 + 4
+
+// LoadWithDiscs loads a multi-disc game from a JSON array of image paths,
+// inserting the first. A single path behaves like Init; more than one
+// requires a core with DiscControl.
+// regionCode: 0=NTSC, 1=PAL
+// Returns true on success (see LastError on failure).
+func LoadWithDiscs(pathsJSON string, regionCode int) bool {
+	return def.loadWithDiscs(pathsJSON, regionCode)
+}
+
+func (in *instance) loadWithDiscs(pathsJSON string, regionCode int) bool {
+	var paths []string
+	if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+		setLastError("invalid disc list: %v", err)
+		return false
+	}
+	if len(paths) == 0 {
+		setLastError("no discs listed")
+		return false
+	}
+	// The first disc picks the core; the others must be for it too.
+	f, first, err := loadSystemROM(paths[0])
+	if err != nil {
+		setLastError("failed to load disc 1: %v", err)
+		return false
+	}
+	images := [][]byte{first}
+	for i, path := range paths[1:] {
+		rom, _, err := romloader.Load(path, f.SystemInfo().Extensions)
+		if err != nil {
+			setLastError("failed to load disc %d: %v", i+2, err)
+			return false
+		}
+		images = append(images, rom)
+	}
+
+	if !in.initROM(f, images[0], regionCode) {
+		return false
+	}
+	if len(images) == 1 && in.discControl == nil {
+		return true
+	}
+	if in.discControl == nil {
+		setLastError("core does not support multiple discs")
+		in.close()
+		return false
+	}
+	in.discs = images
+	in.disc = 0
+	if err := in.discControl.LoadDiscs(images); err != nil {
+		setLastError("core rejected discs: %v", err)
+		in.close()
+		return false
+	}
+	return true
+}
+
+// HasDiscControl returns whether the loaded core can swap discs.
+func HasDiscControl() bool {
+	return def.discControl != nil
+}
+
+// DiscCount returns the number of discs loaded with LoadWithDiscs, or 0.
+func DiscCount() int {
+	return def.discCount()
+}
+
+func (in *instance) discCount() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.discs)
+}
+
+// CurrentDisc returns the index of the inserted disc, or -1 if no
+// multi-disc game is loaded.
+func CurrentDisc() int {
+	return def.currentDisc()
+}
+
+func (in *instance) currentDisc() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(in.discs) == 0 {
+		return -1
+	}
+	return in.disc
+}
+
+// SetDisc inserts the disc at index. Returns false, leaving the current
+// disc inserted, if index is out of range or the core refuses the swap.
+func SetDisc(index int) bool {
+	return def.setDisc(index)
+}
+
+func (in *instance) setDisc(index int) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.setDiscLocked(index)
+}
+
+// setDiscLocked is setDisc with in.mu held.
+func (in *instance) setDiscLocked(index int) bool {
+	if in.discControl == nil || index < 0 || index >= len(in.discs) {
+		return false
+	}
+	if err := in.discControl.InsertDisc(index); err != nil {
+		setLastError("failed to insert disc %d: %v", index+1, err)
+		return false
+	}
+	in.disc = index
+	in.wakeIdle()
+	return true
+}
+
+// OpenDiscTray opens the disc tray. Returns false if the core does not
+// model one.
+func OpenDiscTray() bool {
+	return def.setTrayOpen(true)
+}
+
+// CloseDiscTray closes the disc tray. Returns false if the core does not
+// model one.
+func CloseDiscTray() bool {
+	return def.setTrayOpen(false)
+}
+
+func (in *instance) setTrayOpen(open bool) bool {
+	tray, ok := in.discControl.(DiscTray)
+	if !ok {
+		return false
+	}
+	tray.SetTrayOpen(open)
+	in.wakeIdle()
+	return true
+}
+
+// restoreDiscs hands a re-created core the game's discs and re-inserts
+// the current one.
+func (in *instance) restoreDiscs() error {
+	if in.discControl == nil || len(in.discs) == 0 {
+		return nil
+	}
+	if err := in.discControl.LoadDiscs(in.discs); err != nil {
+		return err
+	}
+	if in.disc == 0 {
+		return nil
+	}
+	return in.discControl.InsertDisc(in.disc)
+}
+
+// appendDiscTrailer records the inserted disc after a save state so it
+// survives a round trip even if the core does not serialize it.
+func (in *instance) appendDiscTrailer(state []byte) []byte {
+	if len(in.discs) == 0 {
+		return state
+	}
+	state = binary.LittleEndian.AppendUint32(state, uint32(in.disc))
+	return append(state, discTrailerMagic...)
+}
+
+// splitDiscTrailer separates a state from its disc trailer. ok is false
+// if the state has none.
+func splitDiscTrailer(state []byte) (core []byte, disc int, ok bool) {
+	if len(state) < discTrailerSize || !bytes.HasSuffix(state, discTrailerMagic) {
+		return state, 0, false
+	}
+	n := len(state) - discTrailerSize
+	return state[:n], int(binary.LittleEndian.Uint32(state[n:])), true
+}
@@ -0,0 +1,129 @@
+package ios
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockDiscEmulator swaps discs but, like many cores, does not serialize
+// the inserted disc in its save states.
+type mockDiscEmulator struct {
+	*mockEmulator
+	images   [][]byte
+	inserted []int
+	tray     []bool
+}
+
+func (m *mockDiscEmulator) LoadDiscs(images [][]byte) error {
+	m.images = images
+	return nil
+}
+
+func (m *mockDiscEmulator) InsertDisc(index int) error {
+	if index == 2 && len(m.images) > 3 {
+		return errors.New("disc unreadable")
+	}
+	m.inserted = append(m.inserted, index)
+	return nil
+}
+
+func (m *mockDiscEmulator) SetTrayOpen(open bool) { m.tray = append(m.tray, open) }
+
+func (m *mockDiscEmulator) Serialize() ([]byte, error)    { return []byte{0xAB}, nil }
+func (m *mockDiscEmulator) Deserialize(data []byte) error { return nil }
+
+func loadDiscs(t *testing.T, discs ...[]byte) *mockDiscEmulator {
+	t.Helper()
+	var e *mockDiscEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockDiscEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+	})
+	paths := make([]string, len(discs))
+	for i, d := range discs {
+		paths[i] = writeROM(t, "disc"+string(rune('1'+i))+".bin", d)
+	}
+	pathsJSON, _ := json.Marshal(paths)
+	if !LoadWithDiscs(string(pathsJSON), 0) {
+		t.Fatalf("LoadWithDiscs failed: %s", LastError())
+	}
+	return e
+}
+
+func TestDiscSwapOrdering(t *testing.T) {
+	e := loadDiscs(t, []byte{1}, []byte{2}, []byte{3})
+	if !HasDiscControl() || DiscCount() != 3 || CurrentDisc() != 0 {
+		t.Fatalf("discs = %d, current = %d", DiscCount(), CurrentDisc())
+	}
+	if len(e.images) != 3 || e.images[2][0] != 3 || e.rom[0] != 1 {
+		t.Error("core did not receive discs in order")
+	}
+
+	if !OpenDiscTray() || !SetDisc(2) || !SetDisc(1) || !CloseDiscTray() {
+		t.Fatal("swap failed")
+	}
+	if SetDisc(3) || SetDisc(-1) {
+		t.Error("out-of-range disc accepted")
+	}
+	if CurrentDisc() != 1 {
+		t.Errorf("current = %d, want 1", CurrentDisc())
+	}
+	if len(e.inserted) != 2 || e.inserted[0] != 2 || e.inserted[1] != 1 {
+		t.Errorf("inserted = %v, want [2 1]", e.inserted)
+	}
+	if len(e.tray) != 2 || !e.tray[0] || e.tray[1] {
+		t.Errorf("tray = %v, want [true false]", e.tray)
+	}
+}
+
+func TestDiscSurvivesStateRoundTrip(t *testing.T) {
+	e := loadDiscs(t, []byte{1}, []byte{2})
+	SetDisc(1)
+	if !SaveState() {
+		t.Fatal("SaveState failed")
+	}
+	state := make([]byte, StateLen())
+	for i := range state {
+		state[i] = byte(StateByte(i))
+	}
+
+	SetDisc(0)
+	e.inserted = nil
+	if !LoadState(state) {
+		t.Fatal("LoadState failed")
+	}
+	if CurrentDisc() != 1 || len(e.inserted) != 1 || e.inserted[0] != 1 {
+		t.Errorf("after load current = %d, inserted = %v", CurrentDisc(), e.inserted)
+	}
+}
+
+func TestSetDiscFailureKeepsState(t *testing.T) {
+	e := loadDiscs(t, []byte{1}, []byte{2}, []byte{3}, []byte{4})
+	if SetDisc(2) {
+		t.Fatal("unreadable disc accepted")
+	}
+	if CurrentDisc() != 0 || len(e.inserted) != 0 {
+		t.Errorf("current = %d after failed swap", CurrentDisc())
+	}
+}
+
+func TestSingleDiscWithoutControl(t *testing.T) {
+	useMockEmulator(t)
+	path := writeROM(t, "one.bin", []byte{7})
+	pathsJSON, _ := json.Marshal([]string{path})
+	if !LoadWithDiscs(string(pathsJSON), 0) {
+		t.Fatal("single disc load failed")
+	}
+	if HasDiscControl() || DiscCount() != 0 || CurrentDisc() != -1 {
+		t.Error("single plain ROM reported disc control")
+	}
+	pathsJSON, _ = json.Marshal([]string{path, path})
+	if LoadWithDiscs(string(pathsJSON), 0) {
+		t.Error("multiple discs accepted without DiscControl")
+	}
+}
@@ -0,0 +1,304 @@
+package ios
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Display preference values.
+var (
+	displayAspectModes = []string{"core", "4:3", "16:9", "stretch"}
+	displayCropPresets = []string{"none", "overscan"}
+	displayRotations   = []int{0, 90, 180, 270}
+)
+
+// displayPrefs is a layer of display preferences. Nil fields defer to
+// the layer beneath.
+type displayPrefs struct {
+	Aspect       *string `json:"aspect,omitempty"`
+	IntegerScale *bool   `json:"integerScale,omitempty"`
+	Crop         *string `json:"crop,omitempty"`
+	Filter       *string `json:"filter,omitempty"`
+	Rotation     *int    `json:"rotation,omitempty"`
+}
+
+// coreDisplayDefaults is the bottom layer: the core's own aspect ratio,
+// unfiltered and unrotated.
+func coreDisplayDefaults() displayPrefs {
+	return displayPrefs{
+		Aspect:       ptr("core"),
+		IntegerScale: ptr(false),
+		Crop:         ptr("none"),
+		Filter:       ptr(""),
+		Rotation:     ptr(0),
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// overlay returns p with each field set in top replaced.
+func (p displayPrefs) overlay(top displayPrefs) displayPrefs {
+	if top.Aspect != nil {
+		p.Aspect = top.Aspect
+	}
+	if top.IntegerScale != nil {
+		p.IntegerScale = top.IntegerScale
+	}
+	if top.Crop != nil {
+		p.Crop = top.Crop
+	}
+	if top.Filter != nil {
+		p.Filter = top.Filter
+	}
+	if top.Rotation != nil {
+		p.Rotation = top.Rotation
+	}
+	return p
+}
+
+func (p displayPrefs) validate() error {
+	if p.Aspect != nil && !slices.Contains(displayAspectModes, *p.Aspect) {
+		return fmt.Errorf("unknown aspect mode %q", *p.Aspect)
+	}
+	if p.Crop != nil && !slices.Contains(displayCropPresets, *p.Crop) {
+		return fmt.Errorf("unknown crop preset %q", *p.Crop)
+	}
+	if p.Rotation != nil && !slices.Contains(displayRotations, *p.Rotation) {
+		return fmt.Errorf("unsupported rotation %d", *p.Rotation)
+	}
+	if p.Filter != nil && *p.Filter != "" {
+		if _, ok := lookupFrameFilter(*p.Filter); !ok {
+			return fmt.Errorf("unknown video filter %q", *p.Filter)
+		}
+	}
+	return nil
+}
+
+func parseDisplayPrefs(prefsJSON string) (displayPrefs, error) {
+	var p displayPrefs
+	if err := json.Unmarshal([]byte(prefsJSON), &p); err != nil {
+		return displayPrefs{}, err
+	}
+	return p, p.validate()
+}
+
+var (
+	// globalDisplay is the app-wide layer set by SetGlobalDisplayDefaults.
+	globalDisplay displayPrefs
+
+	// perGameDir holds the per-game options files, one per ROM CRC32.
+	perGameDir string
+)
+
+// SetGlobalDisplayDefaults sets the app-wide display preferences, which
+// override the core defaults and are overridden per game. prefsJSON is an
+// object with any of "aspect" ("core", "4:3", "16:9", "stretch"),
+// "integerScale", "crop" ("none", "overscan"), "filter" (a SetVideoFilter
+// name, or "" for none) and "rotation" (0, 90, 180, 270).
+// Returns false if the JSON is invalid (see LastError).
+func SetGlobalDisplayDefaults(prefsJSON string) bool {
+	p, err := parseDisplayPrefs(prefsJSON)
+	if err != nil {
+		setLastError("invalid display defaults: %v", err)
+		return false
+	}
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	globalDisplay = p
+	def.applyDisplayFilter()
+	return true
+}
+
+// SetPerGameConfigDir sets the directory holding per-game options files,
+// named by the ROM's CRC32 like ExtractAndStoreROM. Display preferences
+// are read from the loaded game's file at Init.
+func SetPerGameConfigDir(dir string) {
+	perGameDir = dir
+}
+
+// perGameConfigPath returns the options file for the loaded ROM, or ""
+// if no directory is set or no ROM is loaded.
+func (in *instance) perGameConfigPath() string {
+	if perGameDir == "" || in.rom == nil {
+		return ""
+	}
+	return filepath.Join(perGameDir, fmt.Sprintf("%08X.json", crc32.ChecksumIEEE(in.rom)))
+}
+
+// readPerGameConfig returns the sections of the loaded game's options
+// file, or an empty map if it does not exist.
+func (in *instance) readPerGameConfig() (map[string]json.RawMessage, error) {
+	return readConfigFile(in.perGameConfigPath())
+}
+
+// readConfigFile returns the sections of the per-game options file at
+// path, or an empty map if path is empty or does not exist.
+func readConfigFile(path string) (map[string]json.RawMessage, error) {
+	sections := map[string]json.RawMessage{}
+	if path == "" {
+		return sections, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return sections, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// loadDisplayPreferences reads the per-game layer from the loaded game's
+// options file and applies the result.
+func (in *instance) loadDisplayPreferences() {
+	in.display = displayPrefs{}
+	sections, err := in.readPerGameConfig()
+	if err != nil {
+		journalf("warning", "per-game config unreadable: %v", err)
+	} else if raw, ok := sections["display"]; ok {
+		var p displayPrefs
+		if err := json.Unmarshal(raw, &p); err == nil && p.validate() == nil {
+			in.display = p
+		} else {
+			journalf("warning", "per-game display preferences ignored")
+		}
+	}
+	in.applyDisplayFilter()
+}
+
+// ApplyDisplayPreferencesJSON overrides display preferences for the loaded
+// game, taking effect from the next frame. Fields not present are left as
+// they are. Use SaveDisplayPreferences to persist them.
+// Returns false if the JSON is invalid (see LastError).
+func ApplyDisplayPreferencesJSON(prefsJSON string) bool {
+	p, err := parseDisplayPrefs(prefsJSON)
+	if err != nil {
+		setLastError("invalid display preferences: %v", err)
+		return false
+	}
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	def.display = def.display.overlay(p)
+	def.applyDisplayFilter()
+	return true
+}
+
+// ClearDisplayPreferences drops the loaded game's display overrides so
+// the global defaults apply.
+func ClearDisplayPreferences() {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	def.display = displayPrefs{}
+	def.applyDisplayFilter()
+}
+
+// DisplayPreferencesJSON returns the effective display preferences for the
+// loaded game, plus "overrides" listing the keys set for this game.
+func DisplayPreferencesJSON() string {
+	eff := def.effectiveDisplay()
+	overrides := []string{}
+	for key, set := range map[string]bool{
+		"aspect":       def.display.Aspect != nil,
+		"integerScale": def.display.IntegerScale != nil,
+		"crop":         def.display.Crop != nil,
+		"filter":       def.display.Filter != nil,
+		"rotation":     def.display.Rotation != nil,
+	} {
+		if set {
+			overrides = append(overrides, key)
+		}
+	}
+	slices.Sort(overrides)
+
+	data, err := json.Marshal(struct {
+		Aspect       string   `json:"aspect"`
+		IntegerScale bool     `json:"integerScale"`
+		Crop         string   `json:"crop"`
+		Filter       string   `json:"filter"`
+		Rotation     int      `json:"rotation"`
+		Overrides    []string `json:"overrides"`
+	}{*eff.Aspect, *eff.IntegerScale, *eff.Crop, *eff.Filter, *eff.Rotation, overrides})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// SaveDisplayPreferences writes the loaded game's display overrides to the
+// "display" section of its per-game options file, keeping any other
+// sections. Returns false if no per-game directory is set, no ROM is
+// loaded, or the write fails (see LastError).
+func SaveDisplayPreferences() bool {
+	return def.saveDisplayPreferences()
+}
+
+func (in *instance) saveDisplayPreferences() bool {
+	return in.writePerGameSection("display", in.display)
+}
+
+// writePerGameSection stores value as section name of the loaded game's
+// options file, keeping the other sections. Returns false on failure
+// (see LastError).
+func (in *instance) writePerGameSection(name string, value any) bool {
+	path := in.perGameConfigPath()
+	if path == "" {
+		setLastError("no per-game config directory or ROM")
+		return false
+	}
+	return writeConfigSection(path, name, value)
+}
+
+// writeConfigSection replaces one section of the per-game options file
+// at path, keeping the others.
+func writeConfigSection(path, name string, value any) bool {
+	sections, err := readConfigFile(path)
+	if err != nil {
+		setLastError("failed to read %s: %v", path, err)
+		return false
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		setLastError("failed to encode %s section: %v", name, err)
+		return false
+	}
+	sections[name] = raw
+	data, err := json.MarshalIndent(sections, "", "  ")
+	if err != nil {
+		setLastError("failed to encode %s: %v", path, err)
+		return false
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		setLastError("failed to write %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+// effectiveDisplay layers core defaults, global defaults and the game's
+// overrides.
+func (in *instance) effectiveDisplay() displayPrefs {
+	return coreDisplayDefaults().overlay(globalDisplay).overlay(in.display)
+}
+
+// applyDisplayFilter enables the preferred video filter in place of the
+// one previously chosen by display preferences. in.mu must be held.
+func (in *instance) applyDisplayFilter() {
+	want := *in.effectiveDisplay().Filter
+	if want == in.displayFilter {
+		return
+	}
+	if in.displayFilter != "" {
+		in.setVideoFilterLocked(in.displayFilter, false)
+	}
+	if want != "" {
+		in.setVideoFilterLocked(want, true)
+	}
+	in.displayFilter = want
+}
@@ -0,0 +1,113 @@
+package ios
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type displayState struct {
+	Aspect       string   `json:"aspect"`
+	IntegerScale bool     `json:"integerScale"`
+	Crop         string   `json:"crop"`
+	Filter       string   `json:"filter"`
+	Rotation     int      `json:"rotation"`
+	Overrides    []string `json:"overrides"`
+}
+
+func displayPreferences(t *testing.T) displayState {
+	t.Helper()
+	var s displayState
+	if err := json.Unmarshal([]byte(DisplayPreferencesJSON()), &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// useDisplayConfig isolates the global display layer and per-game dir.
+func useDisplayConfig(t *testing.T) string {
+	t.Helper()
+	oldGlobal, oldDir := globalDisplay, perGameDir
+	dir := t.TempDir()
+	SetPerGameConfigDir(dir)
+	t.Cleanup(func() { globalDisplay, perGameDir = oldGlobal, oldDir })
+	return dir
+}
+
+func TestDisplayPreferencePrecedence(t *testing.T) {
+	useDisplayConfig(t)
+	useMockEmulator(t)
+
+	if got := displayPreferences(t); got.Aspect != "core" || got.Rotation != 0 || got.IntegerScale {
+		t.Errorf("core defaults = %+v", got)
+	}
+
+	if !SetGlobalDisplayDefaults(`{"aspect":"4:3","integerScale":true}`) {
+		t.Fatal(LastError())
+	}
+	if got := displayPreferences(t); got.Aspect != "4:3" || !got.IntegerScale || got.Crop != "none" {
+		t.Errorf("with global = %+v", got)
+	}
+
+	if !ApplyDisplayPreferencesJSON(`{"aspect":"16:9","rotation":90}`) {
+		t.Fatal(LastError())
+	}
+	got := displayPreferences(t)
+	if got.Aspect != "16:9" || !got.IntegerScale || got.Rotation != 90 {
+		t.Errorf("with per-game = %+v", got)
+	}
+	if len(got.Overrides) != 2 || got.Overrides[0] != "aspect" || got.Overrides[1] != "rotation" {
+		t.Errorf("overrides = %v", got.Overrides)
+	}
+
+	ClearDisplayPreferences()
+	if got := displayPreferences(t); got.Aspect != "4:3" {
+		t.Errorf("after clear aspect = %q, want global 4:3", got.Aspect)
+	}
+
+	for _, bad := range []string{`{"aspect":"21:9"}`, `{"rotation":45}`, `{"filter":"missing"}`, `nope`} {
+		if ApplyDisplayPreferencesJSON(bad) {
+			t.Errorf("accepted %s", bad)
+		}
+	}
+}
+
+func TestDisplayPreferencesPersist(t *testing.T) {
+	dir := useDisplayConfig(t)
+	useMockEmulator(t)
+	path := def.perGameConfigPath()
+	if err := os.WriteFile(path, []byte(`{"options":{"opt_core":"x"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ApplyDisplayPreferencesJSON(`{"aspect":"stretch","filter":"deuteranopia"}`)
+	if !VideoFilterEnabled("deuteranopia") {
+		t.Error("filter preference not applied")
+	}
+	if !SaveDisplayPreferences() {
+		t.Fatal(LastError())
+	}
+
+	var sections map[string]json.RawMessage
+	data, _ := os.ReadFile(filepath.Join(dir, filepath.Base(path)))
+	if err := json.Unmarshal(data, &sections); err != nil || sections["options"] == nil {
+		t.Errorf("other sections not kept: %s", data)
+	}
+
+	// Reload the same ROM into a fresh instance.
+	rom := def.rom
+	ClearDisplayPreferences()
+	if VideoFilterEnabled("deuteranopia") {
+		t.Error("filter still enabled after clear")
+	}
+	if !def.initROM(factory, rom, 0) {
+		t.Fatal("re-init failed")
+	}
+	if got := displayPreferences(t); got.Aspect != "stretch" || got.Filter != "deuteranopia" {
+		t.Errorf("after reload = %+v", got)
+	}
+	if !VideoFilterEnabled("deuteranopia") {
+		t.Error("filter preference not applied at Init")
+	}
+}
@@ -0,0 +1,85 @@
+package ios
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/user-none/eblitui/romloader"
+)
+
+// lastError holds the message of the most recent bridge failure. Any
+// instance, frame thread or background job may fail, so it has its own
+// lock.
+var (
+	lastErrorMu sync.Mutex
+	lastError   string
+)
+
+// setLastError records a failure for LastError.
+func setLastError(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	lastErrorMu.Lock()
+	defer lastErrorMu.Unlock()
+	lastError = msg
+}
+
+// LastError returns the message of the most recent bridge failure,
+// or an empty string if nothing has failed.
+func LastError() string {
+	lastErrorMu.Lock()
+	defer lastErrorMu.Unlock()
+	return lastError
+}
+
+// Error codes reported by InitWithError.
+const (
+	InitErrorNone        = "ok"
+	InitErrorNoFactory   = "no_factory"
+	InitErrorNotFound    = "not_found"
+	InitErrorUnsupported = "unsupported_format"
+	InitErrorNoROM       = "no_rom_in_archive"
+	InitErrorTooLarge    = "too_large"
+	InitErrorUnreadable  = "unreadable"
+	InitErrorFirmware    = "firmware"
+	InitErrorCore        = "core_failed"
+)
+
+// initError is a load failure with a machine-readable code.
+type initError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Warnings lists dump problems found in a ROM that loaded anyway.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (e *initError) Error() string { return e.Message }
+
+var errNoFactory = &initError{Code: InitErrorNoFactory, Message: "no core factory registered"}
+
+// loadError classifies a romloader failure.
+func loadError(err error) *initError {
+	code := InitErrorUnreadable
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		code = InitErrorNotFound
+	case errors.Is(err, romloader.ErrUnsupportedFormat):
+		code = InitErrorUnsupported
+	case errors.Is(err, romloader.ErrNoROMFile):
+		code = InitErrorNoROM
+	case errors.Is(err, romloader.ErrFileTooLarge):
+		code = InitErrorTooLarge
+	}
+	return &initError{Code: code, Message: fmt.Sprintf("failed to load ROM: %v", err)}
+}
+
+// asInitError returns err as an *initError, wrapping other errors as
+// core failures.
+func asInitError(err error) *initError {
+	var ie *initError
+	if errors.As(err, &ie) {
+		return ie
+	}
+	return &initError{Code: InitErrorCore, Message: err.Error()}
+}
@@ -0,0 +1,73 @@
+package ios
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func TestInitWithError(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			if rom[0] == 0xEE {
+				return nil, errors.New("bad header")
+			}
+			return newMockEmulator(rom, region), nil
+		},
+	})
+
+	tests := []struct {
+		name string
+		path string
+		code string
+	}{
+		{"ok", writeROM(t, "game.bin", []byte{0x00}), InitErrorNone},
+		{"missing", filepath.Join(t.TempDir(), "missing.bin"), InitErrorNotFound},
+		{"extension", writeROM(t, "notes.txt", []byte{0x00}), InitErrorUnsupported},
+		{"archive", writeZip(t, zipFile{"readme.txt", []byte("hi")}), InitErrorNoROM},
+		{"core", writeROM(t, "bad.bin", []byte{0xEE}), InitErrorCore},
+	}
+	for _, tt := range tests {
+		var got initError
+		if err := json.Unmarshal([]byte(InitWithError(tt.path, 0)), &got); err != nil {
+			t.Fatalf("%s: failed to parse: %v", tt.name, err)
+		}
+		if got.Code != tt.code {
+			t.Errorf("%s: code = %q (%s), want %q", tt.name, got.Code, got.Message, tt.code)
+		}
+		if tt.code != InitErrorNone && (got.Message == "" || LastError() != got.Message) {
+			t.Errorf("%s: message = %q, LastError = %q", tt.name, got.Message, LastError())
+		}
+	}
+}
+
+func TestInitWithErrorNoFactory(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	factory = nil
+	if got := InitWithError("game.bin", 0); got != `{"code":"no_factory","message":"no core factory registered"}` {
+		t.Errorf("InitWithError = %s", got)
+	}
+}
+
+func TestLastErrorConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				setLastError("failure %d", j)
+				if !strings.HasPrefix(LastError(), "failure ") {
+					t.Errorf("LastError = %q", LastError())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+package ios
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// CoreEvent is a runtime notification from the core, such as a lag frame
+// or a request to insert another disk.
+type CoreEvent struct {
+	Type    string
+	Message string
+}
+
+// EventSource is an optional emulator interface for cores that report
+// runtime events.
+type EventSource interface {
+	// TakeEvents returns the events emitted since the last call and
+	// forgets them.
+	TakeEvents() []CoreEvent
+}
+
+// defaultEventQueueLimit is the queue size used until SetEventQueueLimit.
+const defaultEventQueueLimit = 64
+
+// queuedEvent is an entry in PollEventsJSON.
+type queuedEvent struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Frame   int64  `json:"frame"`
+	// Dropped is set on the "dropped" event reporting how many events
+	// overflowed the queue since the last poll.
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// eventQueue holds core events until the app polls them. It has its own
+// lock so polling never waits for a frame to finish.
+type eventQueue struct {
+	mu      sync.Mutex
+	limit   int
+	events  []queuedEvent
+	dropped int
+	// droppedFrame is the frame of the newest dropped event.
+	droppedFrame int64
+}
+
+// push queues events emitted at frame, dropping the oldest past the limit.
+func (q *eventQueue) push(events []CoreEvent, frame int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, e := range events {
+		q.events = append(q.events, queuedEvent{Type: e.Type, Message: e.Message, Frame: frame})
+	}
+	q.trim()
+}
+
+// trim drops the oldest events past the limit. q.mu must be held.
+func (q *eventQueue) trim() {
+	limit := q.limit
+	if limit == 0 {
+		limit = defaultEventQueueLimit
+	}
+	if over := len(q.events) - limit; over > 0 {
+		q.droppedFrame = q.events[over-1].Frame
+		q.events = append(q.events[:0], q.events[over:]...)
+		q.dropped += over
+	}
+}
+
+// drain returns and removes the queued events, led by a "dropped" event
+// if any overflowed.
+func (q *eventQueue) drain() []queuedEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	events := q.events
+	if q.dropped > 0 {
+		events = append([]queuedEvent{{
+			Type:    "dropped",
+			Message: fmt.Sprintf("%d events dropped", q.dropped),
+			Frame:   q.droppedFrame,
+			Dropped: q.dropped,
+		}}, events...)
+	}
+	q.events = nil
+	q.dropped = 0
+	return events
+}
+
+// reset discards all queued events.
+func (q *eventQueue) reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.events = nil
+	q.dropped = 0
+}
+
+// collectEvents queues the events the core emitted this frame.
+func (in *instance) collectEvents() {
+	if events := in.eventSource.TakeEvents(); len(events) > 0 {
+		in.events.push(events, in.frameCount)
+	}
+}
+
+// PollEventsJSON drains the core's events, oldest first, as a JSON array
+// of {"type", "message", "frame"} objects. If the queue overflowed since
+// the last poll, the array starts with a "dropped" event whose "dropped"
+// field counts the lost events and whose "frame" is the newest lost
+// event's.
+func PollEventsJSON() string {
+	return def.pollEventsJSON()
+}
+
+func (in *instance) pollEventsJSON() string {
+	events := in.events.drain()
+	if len(events) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// SetEventQueueLimit sets how many events are kept between polls before
+// the oldest are dropped. Values below 1 are treated as 1.
+func SetEventQueueLimit(n int) {
+	q := &def.events
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limit = max(n, 1)
+	q.trim()
+}
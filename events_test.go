@@ -0,0 +1,104 @@
+package ios
+
+import (
+	"encoding/json"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockEventEmulator emits the events queued in emit on its next frame.
+type mockEventEmulator struct {
+	*mockEmulator
+	emit    []CoreEvent
+	pending []CoreEvent
+}
+
+func (m *mockEventEmulator) RunFrame() {
+	m.mockEmulator.RunFrame()
+	m.pending = append(m.pending, m.emit...)
+	m.emit = nil
+}
+
+func (m *mockEventEmulator) TakeEvents() []CoreEvent {
+	events := m.pending
+	m.pending = nil
+	return events
+}
+
+func useEventEmulator(t *testing.T) *mockEventEmulator {
+	t.Helper()
+	var e *mockEventEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockEventEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func pollEvents(t *testing.T) []queuedEvent {
+	t.Helper()
+	var events []queuedEvent
+	if err := json.Unmarshal([]byte(PollEventsJSON()), &events); err != nil {
+		t.Fatalf("failed to parse events: %v", err)
+	}
+	return events
+}
+
+func TestPollEvents(t *testing.T) {
+	e := useEventEmulator(t)
+	e.emit = []CoreEvent{{Type: "lag", Message: "lag frame"}}
+	RunFrame()
+	e.emit = []CoreEvent{{Type: "disk", Message: "FDS disk B required"}}
+	RunFrame()
+
+	events := pollEvents(t)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].Type != "lag" || events[0].Frame != 1 || events[1].Message != "FDS disk B required" || events[1].Frame != 2 {
+		t.Errorf("events = %+v", events)
+	}
+	if got := PollEventsJSON(); got != "[]" {
+		t.Errorf("second poll = %s, want []", got)
+	}
+}
+
+func TestEventQueueOverflow(t *testing.T) {
+	e := useEventEmulator(t)
+	SetEventQueueLimit(2)
+	for _, msg := range []string{"a", "b", "c", "d", "e"} {
+		e.emit = []CoreEvent{{Type: "msg", Message: msg}}
+		RunFrame()
+	}
+
+	events := pollEvents(t)
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want dropped + 2", len(events))
+	}
+	if events[0].Type != "dropped" || events[0].Dropped != 3 || events[0].Frame != 3 {
+		t.Errorf("dropped event = %+v", events[0])
+	}
+	if events[1].Message != "d" || events[2].Message != "e" {
+		t.Errorf("kept %+v, want the newest", events[1:])
+	}
+
+	e.emit = []CoreEvent{{Type: "msg", Message: "f"}}
+	RunFrame()
+	if events = pollEvents(t); len(events) != 1 || events[0].Message != "f" {
+		t.Errorf("after drain events = %+v, want only f", events)
+	}
+}
+
+func TestPollEventsWithoutSource(t *testing.T) {
+	useMockEmulator(t)
+	RunFrame()
+	if got := PollEventsJSON(); got != "[]" {
+		t.Errorf("PollEventsJSON = %s, want []", got)
+	}
+}
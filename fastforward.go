@@ -0,0 +1,128 @@
+package ios
+
+import "encoding/binary"
+
+// maxRunFrames caps the frames a single RunFrames call executes.
+const maxRunFrames = 16
+
+// Fast-forward audio modes for SetFastForwardAudioMode.
+const (
+	// FastForwardAudioDrop discards audio from fast-forwarded frames.
+	FastForwardAudioDrop = 0
+	// FastForwardAudioKeep concatenates audio from every frame run.
+	FastForwardAudioKeep = 1
+	// FastForwardAudioCompress averages the frames' audio down to one
+	// frame's worth of samples, keeping the audio queue from growing
+	// at the cost of raised pitch.
+	FastForwardAudioCompress = 2
+)
+
+// RunFrames executes count frames back to back for fast-forward, capped
+// at 16 per call. The framebuffer is only cached after the final frame,
+// and only when renderLast is true. Audio from all frames is
+// handled as set by SetFastForwardAudioMode; by default it is dropped.
+// A count of 0 or less does nothing.
+func RunFrames(count int, renderLast bool) {
+	def.runFrames(count, renderLast)
+}
+
+func (in *instance) runFrames(count int, renderLast bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil || in.paused || count <= 0 {
+		return
+	}
+	in.runFramesLocked(count, renderLast)
+}
+
+// runFramesLocked is runFrames with in.mu held.
+func (in *instance) runFramesLocked(count int, renderLast bool) {
+	count = min(count, maxRunFrames)
+
+	in.latchInputs()
+	in.wakeIdle()
+	in.audioData = in.audioData[:0]
+	for i := 0; i < count; i++ {
+		last := i == count-1
+		in.runPipeline(framePass{fastForward: true, render: last && renderLast, last: last})
+	}
+	if in.fastForwardAudio == FastForwardAudioCompress {
+		in.compressAudio(count)
+	}
+	in.finishAudio()
+	if renderLast {
+		in.publishFrame()
+	}
+	in.publishAudio()
+}
+
+// SetAudioDuringFastForward sets whether RunFrames keeps audio from every
+// frame it runs (true) or drops it (false, the default).
+func SetAudioDuringFastForward(enabled bool) {
+	if enabled {
+		def.setFastForwardAudioMode(FastForwardAudioKeep)
+	} else {
+		def.setFastForwardAudioMode(FastForwardAudioDrop)
+	}
+}
+
+// SetFastForwardAudioMode sets how RunFrames and the SetFastForward
+// multiplier handle audio. Returns false, leaving the mode unchanged, if
+// mode is unknown.
+func SetFastForwardAudioMode(mode int) bool {
+	return def.setFastForwardAudioMode(mode)
+}
+
+func (in *instance) setFastForwardAudioMode(mode int) bool {
+	if mode < FastForwardAudioDrop || mode > FastForwardAudioCompress {
+		setLastError("unknown fast-forward audio mode %d", mode)
+		return false
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.fastForwardAudio = mode
+	return true
+}
+
+// SetFastForward makes every RunFrame run multiplier frames, like
+// RunFrames with renderLast set, until set back to 1. The multiplier is
+// clamped to 1-16.
+func SetFastForward(multiplier int) {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	def.fastForward = min(max(multiplier, 1), maxRunFrames)
+}
+
+// GetFastForward returns the multiplier set with SetFastForward.
+func GetFastForward() int {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return max(def.fastForward, 1)
+}
+
+// compressAudio averages groups of count sample frames in audioData so
+// the audio of count frames plays in the time of one.
+func (in *instance) compressAudio(count int) {
+	if count <= 1 || len(in.audioData) == 0 {
+		return
+	}
+	channels := in.audioOut.channels
+	if channels == 0 {
+		_, channels = in.sourceAudioFormat()
+	}
+	frameBytes := 2 * channels
+	frames := len(in.audioData) / frameBytes
+	out := 0
+	for start := 0; start < frames; start += count {
+		n := min(count, frames-start)
+		for ch := 0; ch < channels; ch++ {
+			sum := 0
+			for f := start; f < start+n; f++ {
+				sum += int(int16(binary.LittleEndian.Uint16(in.audioData[f*frameBytes+ch*2:])))
+			}
+			binary.LittleEndian.PutUint16(in.audioData[out:], uint16(int16(sum/n)))
+			out += 2
+		}
+	}
+	in.audioData = in.audioData[:out]
+}
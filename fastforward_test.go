@@ -0,0 +1,133 @@
+package ios
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRunFramesAudio(t *testing.T) {
+	e := useMockEmulator(t)
+	e.samples = []int16{0x0102, 0x0304}
+
+	RunFrames(3, true)
+	if e.frames != 3 {
+		t.Errorf("frames = %d, want 3", e.frames)
+	}
+	if GetAudioData() != nil {
+		t.Errorf("audio = %v, want dropped", GetAudioData())
+	}
+
+	SetAudioDuringFastForward(true)
+	t.Cleanup(func() { SetAudioDuringFastForward(false) })
+	RunFrames(3, true)
+	frame := []byte{0x02, 0x01, 0x04, 0x03}
+	want := bytes.Repeat(frame, 3)
+	if got := GetAudioData(); !bytes.Equal(got, want) {
+		t.Errorf("audio = %v, want %v", got, want)
+	}
+
+	// A following single frame returns to one frame of audio.
+	RunFrame()
+	if got := GetAudioData(); !bytes.Equal(got, frame) {
+		t.Errorf("audio after RunFrame = %v, want %v", got, frame)
+	}
+}
+
+func TestRunFramesRenderLast(t *testing.T) {
+	e := useMockEmulator(t)
+
+	RunFrames(2, false)
+	if GetFrameData() != nil {
+		t.Error("frame cached with renderLast false")
+	}
+	RunFrames(2, true)
+	if len(GetFrameData()) != e.stride*e.activeHeight {
+		t.Errorf("frame len = %d, want %d", len(GetFrameData()), e.stride*e.activeHeight)
+	}
+}
+
+func TestRunFramesBounds(t *testing.T) {
+	e := useMockEmulator(t)
+
+	RunFrames(0, true)
+	RunFrames(-5, true)
+	if e.frames != 0 {
+		t.Errorf("frames = %d after non-positive counts, want 0", e.frames)
+	}
+	RunFrames(1000, true)
+	if e.frames != maxRunFrames {
+		t.Errorf("frames = %d, want cap %d", e.frames, maxRunFrames)
+	}
+}
+
+func TestSetFastForward(t *testing.T) {
+	e := useMockEmulator(t)
+	SetFastForward(4)
+	RunFrame()
+	if e.frames != 4 || GetFastForward() != 4 {
+		t.Errorf("frames = %d, multiplier %d; want 4", e.frames, GetFastForward())
+	}
+	SetFastForward(100)
+	if GetFastForward() != maxRunFrames {
+		t.Errorf("multiplier = %d, want clamp to %d", GetFastForward(), maxRunFrames)
+	}
+	SetFastForward(0)
+	RunFrame()
+	if e.frames != 5 || GetFastForward() != 1 {
+		t.Errorf("frames = %d after disabling, want 5", e.frames)
+	}
+}
+
+func TestFastForwardAudioCompress(t *testing.T) {
+	e := useMockEmulator(t)
+	// Two stereo sample frames per video frame.
+	e.samples = []int16{100, -100, 300, -300}
+	if !SetFastForwardAudioMode(FastForwardAudioCompress) {
+		t.Fatal("SetFastForwardAudioMode failed")
+	}
+	RunFrames(2, true)
+
+	// Four sample frames average in pairs down to two.
+	want := []byte{}
+	for _, s := range []int16{200, -200, 200, -200} {
+		want = append(want, byte(s), byte(uint16(s)>>8))
+	}
+	if got := GetAudioData(); !bytes.Equal(got, want) {
+		t.Errorf("audio = %v, want %v", got, want)
+	}
+	if SetFastForwardAudioMode(3) {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func benchmarkEmulator(b *testing.B) *mockEmulator {
+	e := newMockEmulator(nil, 0)
+	e.framebuffer = make([]byte, 256*4*240)
+	e.stride = 256 * 4
+	e.activeHeight = 240
+	e.samples = make([]int16, 1600)
+
+	old := def
+	def = &instance{fastForwardAudio: FastForwardAudioKeep}
+	def.attach(e)
+	b.Cleanup(func() { def = old })
+	return e
+}
+
+func BenchmarkRunFramesFastForward(b *testing.B) {
+	benchmarkEmulator(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		RunFrames(8, true)
+	}
+}
+
+func BenchmarkRunFrameLoop(b *testing.B) {
+	benchmarkEmulator(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 8; j++ {
+			RunFrame()
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package ios
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// FrameFilter is a post-processing pass run over the cached frame.
+type FrameFilter interface {
+	// Apply modifies pixels in place. pixels holds height rows of stride
+	// bytes, each with width RGBA pixels.
+	Apply(pixels []byte, width, height, stride int)
+}
+
+// FrameScaler is an optional FrameFilter interface for filters that
+// enlarge the frame, such as scanline effects drawn at twice the size.
+// The bridge calls ApplyScaled instead of Apply, after the in-place
+// filters, and the frame getters and geometry describe the enlarged
+// frame. Only one FrameScaler is enabled at a time.
+type FrameScaler interface {
+	// Scale returns the factor, at least 1, both dimensions grow by.
+	Scale() int
+	// ApplyScaled writes src, height rows of stride bytes each holding
+	// width RGBA pixels, into dst enlarged by Scale, with packed rows of
+	// width*Scale pixels.
+	ApplyScaled(dst, src []byte, width, height, stride int)
+}
+
+// Filters taking longer than filterBudget on filterStrikeLimit frames in a
+// row are disabled.
+var (
+	filterBudget      = 4 * time.Millisecond
+	filterStrikeLimit = 10
+)
+
+// filterRegistry holds named filters in registration order, which is the
+// order enabled filters run in. Built-in filters register from this
+// package's init and so run before app-provided ones.
+var filterRegistry struct {
+	sync.Mutex
+	names   []string
+	filters map[string]FrameFilter
+}
+
+// RegisterFrameFilter makes a filter available to SetVideoFilter under
+// name. Registering an existing name replaces the filter but keeps its
+// place in the run order.
+func RegisterFrameFilter(name string, filter FrameFilter) {
+	filterRegistry.Lock()
+	defer filterRegistry.Unlock()
+	if filterRegistry.filters == nil {
+		filterRegistry.filters = map[string]FrameFilter{}
+	}
+	if _, ok := filterRegistry.filters[name]; !ok {
+		filterRegistry.names = append(filterRegistry.names, name)
+	}
+	filterRegistry.filters[name] = filter
+}
+
+// lookupFrameFilter returns the filter registered under name.
+func lookupFrameFilter(name string) (FrameFilter, bool) {
+	filterRegistry.Lock()
+	defer filterRegistry.Unlock()
+	f, ok := filterRegistry.filters[name]
+	return f, ok
+}
+
+// registeredFilterNames returns the registered names in run order.
+func registeredFilterNames() []string {
+	filterRegistry.Lock()
+	defer filterRegistry.Unlock()
+	return append([]string(nil), filterRegistry.names...)
+}
+
+// filterState tracks an enabled filter's timing.
+type filterState struct {
+	// scaler is set if the filter is a FrameScaler.
+	scaler FrameScaler
+
+	last    time.Duration
+	total   time.Duration
+	runs    int64
+	strikes int
+}
+
+// SetVideoFilter enables or disables the filter registered under name.
+// Several filters can be enabled at once; they run in registration order.
+// Built-in filters are "deuteranopia", "protanopia", "tritanopia",
+// "high-contrast" and the composite video look "ntsc", and the 2x scalers
+// "scanlines", "crt" and "lcd-grid".
+// Enabling a FrameScaler disables any other enabled one.
+// Enabling a filter that was auto-disabled resets its timing.
+// Returns false if no filter is registered under name.
+func SetVideoFilter(name string, enabled bool) bool {
+	return def.setVideoFilter(name, enabled)
+}
+
+func (in *instance) setVideoFilter(name string, enabled bool) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.setVideoFilterLocked(name, enabled)
+}
+
+// setVideoFilterLocked is setVideoFilter with in.mu held.
+func (in *instance) setVideoFilterLocked(name string, enabled bool) bool {
+	f, ok := lookupFrameFilter(name)
+	if !ok {
+		return false
+	}
+	if !enabled {
+		delete(in.videoFilters, name)
+		return true
+	}
+	if in.videoFilters == nil {
+		in.videoFilters = map[string]*filterState{}
+	}
+	scaler, _ := f.(FrameScaler)
+	if scaler != nil {
+		for other, st := range in.videoFilters {
+			if st.scaler != nil {
+				delete(in.videoFilters, other)
+			}
+		}
+	}
+	in.videoFilters[name] = &filterState{scaler: scaler}
+	return true
+}
+
+// filterScale returns the factor the enabled FrameScaler enlarges frames
+// by, or 1.
+func (in *instance) filterScale() int {
+	for _, st := range in.videoFilters {
+		if st.scaler != nil {
+			return max(st.scaler.Scale(), 1)
+		}
+	}
+	return 1
+}
+
+// VideoFilterEnabled returns whether the filter registered under name is
+// enabled.
+func VideoFilterEnabled(name string) bool {
+	return def.videoFilterEnabled(name)
+}
+
+func (in *instance) videoFilterEnabled(name string) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	_, ok := in.videoFilters[name]
+	return ok
+}
+
+// applyFrameFilters runs the enabled filters over a bridge-owned copy of
+// the frame, leaving the core's framebuffer untouched.
+func (in *instance) applyFrameFilters() {
+	if len(in.videoFilters) == 0 || len(in.frameData) == 0 {
+		return
+	}
+
+	if cap(in.filtered) < len(in.frameData) {
+		in.filtered = make([]byte, len(in.frameData))
+	}
+	in.filtered = in.filtered[:len(in.frameData)]
+	copy(in.filtered, in.frameData)
+	in.frameData = in.filtered
+
+	stride := in.rotatedStride()
+	width := in.rotatedWidth()
+	height := len(in.frameData) / stride
+	scalerName := ""
+	for _, name := range registeredFilterNames() {
+		st, ok := in.videoFilters[name]
+		if !ok {
+			continue
+		}
+		if st.scaler != nil {
+			scalerName = name
+			continue
+		}
+		f, _ := lookupFrameFilter(name)
+		in.timeFilter(name, st, func() { f.Apply(in.frameData, width, height, stride) })
+	}
+	if scalerName == "" {
+		return
+	}
+
+	st := in.videoFilters[scalerName]
+	scale := max(st.scaler.Scale(), 1)
+	n := width * scale * height * scale * 4
+	if cap(in.scaled) < n {
+		in.scaled = make([]byte, n)
+	}
+	in.scaled = in.scaled[:n]
+	in.timeFilter(scalerName, st, func() { st.scaler.ApplyScaled(in.scaled, in.frameData, width, height, stride) })
+	// A scaler disabled for running over budget no longer counts in the
+	// frame geometry, so its output is dropped.
+	if _, ok := in.videoFilters[scalerName]; ok {
+		in.frameData = in.scaled
+	}
+}
+
+// timeFilter runs apply for the enabled filter name, recording its time
+// and disabling it after filterStrikeLimit frames over budget.
+func (in *instance) timeFilter(name string, st *filterState, apply func()) {
+	start := time.Now()
+	apply()
+	d := time.Since(start)
+
+	st.last = d
+	st.total += d
+	st.runs++
+	if d <= filterBudget {
+		st.strikes = 0
+		return
+	}
+	st.strikes++
+	if st.strikes >= filterStrikeLimit {
+		delete(in.videoFilters, name)
+		journalf("warning", "video filter %q disabled: over %v budget for %d frames", name, filterBudget, st.strikes)
+	}
+}
+
+// performance copies out the filter and stage timings RunFrame records.
+func (in *instance) performance() ([]filterPerformance, []stageTiming) {
+	names := registeredFilterNames()
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	filters := []filterPerformance{}
+	for _, name := range names {
+		st, ok := in.videoFilters[name]
+		if !ok {
+			continue
+		}
+		p := filterPerformance{Name: name, LastMs: durationMs(st.last), Runs: st.runs}
+		if st.runs > 0 {
+			p.AvgMs = durationMs(st.total / time.Duration(st.runs))
+		}
+		filters = append(filters, p)
+	}
+	return filters, in.activeStages()
+}
+
+// filterPerformance is a filter's entry in PerformanceJSON.
+type filterPerformance struct {
+	Name   string  `json:"name"`
+	LastMs float64 `json:"lastMs"`
+	AvgMs  float64 `json:"avgMs"`
+	Runs   int64   `json:"runs"`
+}
+
+// PerformanceJSON returns timings for the active frame pipeline stages
+// and the enabled video filters, each in run order, as
+// {"stages": [{"name", "lastMs"}], "filters": [{"name", "lastMs", "avgMs", "runs"}]}.
+func PerformanceJSON() string {
+	filters, stages := def.performance()
+	data, err := json.Marshal(struct {
+		Stages  []stageTiming       `json:"stages"`
+		Filters []filterPerformance `json:"filters"`
+	}{stages, filters})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
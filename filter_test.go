@@ -0,0 +1,151 @@
+package ios
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingFilter records its calls and writes its tag into pixel 0.
+type recordingFilter struct {
+	tag   byte
+	calls *[]string
+	name  string
+	geom  [3]int
+	delay time.Duration
+}
+
+func (f *recordingFilter) Apply(pixels []byte, width, height, stride int) {
+	*f.calls = append(*f.calls, f.name)
+	f.geom = [3]int{width, height, stride}
+	pixels[0] = f.tag
+	time.Sleep(f.delay)
+}
+
+// withFilterRegistry isolates the filter registry for a test.
+func withFilterRegistry(t *testing.T) {
+	t.Helper()
+	filterRegistry.Lock()
+	oldNames, oldFilters := filterRegistry.names, filterRegistry.filters
+	filterRegistry.names, filterRegistry.filters = nil, nil
+	filterRegistry.Unlock()
+	t.Cleanup(func() {
+		filterRegistry.Lock()
+		filterRegistry.names, filterRegistry.filters = oldNames, oldFilters
+		filterRegistry.Unlock()
+	})
+}
+
+func TestFrameFilterGeometryAndOrdering(t *testing.T) {
+	withFilterRegistry(t)
+	e := useMockEmulator(t)
+	e.framebuffer[0] = 0xEE
+
+	var calls []string
+	first := &recordingFilter{tag: 1, calls: &calls, name: "first"}
+	second := &recordingFilter{tag: 2, calls: &calls, name: "second"}
+	RegisterFrameFilter("first", first)
+	RegisterFrameFilter("second", second)
+
+	if SetVideoFilter("missing", true) {
+		t.Error("enabled unregistered filter")
+	}
+	// Enable out of registration order; run order is still registration order.
+	SetVideoFilter("second", true)
+	SetVideoFilter("first", true)
+	RunFrame()
+
+	if strings.Join(calls, ",") != "first,second" {
+		t.Errorf("calls = %v, want [first second]", calls)
+	}
+	if first.geom != [3]int{4, 4, 16} {
+		t.Errorf("geometry = %v, want [4 4 16]", first.geom)
+	}
+	if GetFrameData()[0] != 2 {
+		t.Errorf("frame pixel = %d, want last filter's tag", GetFrameData()[0])
+	}
+	if e.framebuffer[0] != 0xEE {
+		t.Error("filter modified the core framebuffer")
+	}
+
+	SetVideoFilter("second", false)
+	calls = nil
+	RunFrame()
+	if strings.Join(calls, ",") != "first" {
+		t.Errorf("calls after disable = %v", calls)
+	}
+
+	var perf struct {
+		Filters []filterPerformance `json:"filters"`
+	}
+	if err := json.Unmarshal([]byte(PerformanceJSON()), &perf); err != nil {
+		t.Fatal(err)
+	}
+	if len(perf.Filters) != 1 || perf.Filters[0].Name != "first" || perf.Filters[0].Runs != 2 {
+		t.Errorf("performance = %+v", perf.Filters)
+	}
+}
+
+func TestFrameFilterAutoDisable(t *testing.T) {
+	withFilterRegistry(t)
+	useMockEmulator(t)
+	ClearJournal()
+	t.Cleanup(ClearJournal)
+
+	oldBudget, oldLimit := filterBudget, filterStrikeLimit
+	filterBudget, filterStrikeLimit = time.Microsecond, 3
+	t.Cleanup(func() { filterBudget, filterStrikeLimit = oldBudget, oldLimit })
+
+	var calls []string
+	RegisterFrameFilter("slow", &recordingFilter{calls: &calls, name: "slow", delay: time.Millisecond})
+	SetVideoFilter("slow", true)
+
+	for i := 0; i < 5; i++ {
+		RunFrame()
+	}
+	if len(calls) != 3 {
+		t.Errorf("slow filter ran %d times, want 3", len(calls))
+	}
+	if VideoFilterEnabled("slow") {
+		t.Error("slow filter still enabled")
+	}
+	if !strings.Contains(JournalJSON(), `slow`) {
+		t.Errorf("journal = %s, want disable event", JournalJSON())
+	}
+}
+
+func TestNoFiltersNoCopy(t *testing.T) {
+	withFilterRegistry(t)
+	e := useMockEmulator(t)
+	RunFrame()
+	if &def.frameData[0] != &e.framebuffer[0] {
+		t.Error("frame copied with no filters enabled")
+	}
+}
+
+// TestVideoFilterToggleRace toggles a filter and reads the timings while
+// frames run; run with -race.
+func TestVideoFilterToggleRace(t *testing.T) {
+	useMockEmulator(t)
+	t.Cleanup(func() { SetVideoFilter("high-contrast", false) })
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			RunFrame()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			SetVideoFilter("high-contrast", i%2 == 0)
+			VideoFilterEnabled("high-contrast")
+			PerformanceJSON()
+		}
+	}()
+	wg.Wait()
+}
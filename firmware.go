@@ -0,0 +1,148 @@
+package ios
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// Firmware describes a BIOS or firmware file a core can use.
+type Firmware struct {
+	// Name is the file name looked up in the BIOS directory.
+	Name string
+	// CRC32 and MD5 (hex) are the expected hashes; zero or empty skips
+	// that check.
+	CRC32 uint32
+	MD5   string
+	// Optional firmware improves accuracy but is not needed to boot.
+	Optional bool
+}
+
+// FirmwareProvider is an optional factory interface for cores that need
+// BIOS or firmware images.
+type FirmwareProvider interface {
+	// RequiredFirmware lists the files the core uses.
+	RequiredFirmware() []Firmware
+	// SetFirmware hands the core the valid files found, by Name, before
+	// each CreateEmulator.
+	SetFirmware(files map[string][]byte)
+}
+
+// Firmware statuses reported by ValidateFirmware.
+const (
+	firmwareOK      = "ok"
+	firmwareMissing = "missing"
+	firmwareBadHash = "bad hash"
+)
+
+// biosDir is the directory firmware files are read from.
+var biosDir string
+
+// SetBIOSDirectory sets the directory firmware files are read from.
+func SetBIOSDirectory(path string) {
+	biosDir = path
+}
+
+// requiredFirmware returns the active factory's firmware list, or nil.
+func requiredFirmware() []Firmware {
+	if p, ok := activeFactory().(FirmwareProvider); ok {
+		return p.RequiredFirmware()
+	}
+	return nil
+}
+
+// RequiredFirmwareJSON returns the firmware the core uses as
+// [{"name", "crc32", "md5", "optional"}], or "[]" if it needs none.
+func RequiredFirmwareJSON() string {
+	type entry struct {
+		Name     string `json:"name"`
+		CRC32    string `json:"crc32,omitempty"`
+		MD5      string `json:"md5,omitempty"`
+		Optional bool   `json:"optional"`
+	}
+	entries := []entry{}
+	for _, fw := range requiredFirmware() {
+		e := entry{Name: fw.Name, MD5: strings.ToLower(fw.MD5), Optional: fw.Optional}
+		if fw.CRC32 != 0 {
+			e.CRC32 = fmt.Sprintf("%08X", fw.CRC32)
+		}
+		entries = append(entries, e)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// checkFirmware reads fw from the BIOS directory and verifies its hashes.
+// The data is returned only when the status is ok.
+func checkFirmware(fw Firmware) (string, []byte) {
+	if biosDir == "" {
+		return firmwareMissing, nil
+	}
+	data, err := os.ReadFile(filepath.Join(biosDir, fw.Name))
+	if err != nil {
+		return firmwareMissing, nil
+	}
+	if fw.CRC32 != 0 && crc32.ChecksumIEEE(data) != fw.CRC32 {
+		return firmwareBadHash, nil
+	}
+	if fw.MD5 != "" {
+		sum := md5.Sum(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), fw.MD5) {
+			return firmwareBadHash, nil
+		}
+	}
+	return firmwareOK, data
+}
+
+// ValidateFirmware checks each firmware file the core uses, returning
+// [{"name", "status", "optional"}] with status "ok", "missing" or
+// "bad hash".
+func ValidateFirmware() string {
+	type entry struct {
+		Name     string `json:"name"`
+		Status   string `json:"status"`
+		Optional bool   `json:"optional"`
+	}
+	entries := []entry{}
+	for _, fw := range requiredFirmware() {
+		status, _ := checkFirmware(fw)
+		entries = append(entries, entry{fw.Name, status, fw.Optional})
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// loadFirmware hands f its valid firmware files. It fails if a required
+// file is missing or does not match its hashes.
+func loadFirmware(f emucore.CoreFactory) error {
+	p, ok := f.(FirmwareProvider)
+	if !ok {
+		return nil
+	}
+	files := map[string][]byte{}
+	for _, fw := range p.RequiredFirmware() {
+		status, data := checkFirmware(fw)
+		if status == firmwareOK {
+			files[fw.Name] = data
+			continue
+		}
+		if !fw.Optional {
+			return fmt.Errorf("required firmware %s: %s", fw.Name, status)
+		}
+	}
+	p.SetFirmware(files)
+	return nil
+}
@@ -0,0 +1,128 @@
+package ios
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+var (
+	testBIOS    = []byte("bios image")
+	testBIOSMD5 = func() string { s := md5.Sum([]byte("boot rom")); return hex.EncodeToString(s[:]) }()
+)
+
+// mockFirmwareFactory needs bios.bin and optionally boot.rom.
+type mockFirmwareFactory struct {
+	mockFactory
+	files map[string][]byte
+}
+
+func (f *mockFirmwareFactory) RequiredFirmware() []Firmware {
+	return []Firmware{
+		{Name: "bios.bin", CRC32: crc32.ChecksumIEEE(testBIOS)},
+		{Name: "boot.rom", MD5: strings.ToUpper(testBIOSMD5), Optional: true},
+	}
+}
+
+func (f *mockFirmwareFactory) SetFirmware(files map[string][]byte) { f.files = files }
+
+func useFirmwareFactory(t *testing.T) (*mockFirmwareFactory, string) {
+	t.Helper()
+	f := &mockFirmwareFactory{mockFactory: mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			return newMockEmulator(rom, region), nil
+		},
+	}}
+	oldFactory, oldDef, oldDir := factory, def, biosDir
+	factory, def = f, &instance{}
+	dir := t.TempDir()
+	SetBIOSDirectory(dir)
+	t.Cleanup(func() {
+		def.close()
+		factory, def, biosDir = oldFactory, oldDef, oldDir
+	})
+	return f, dir
+}
+
+func firmwareStatus(t *testing.T) map[string]string {
+	t.Helper()
+	var entries []struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(ValidateFirmware()), &entries); err != nil {
+		t.Fatal(err)
+	}
+	status := map[string]string{}
+	for _, e := range entries {
+		status[e.Name] = e.Status
+	}
+	return status
+}
+
+func TestRequiredFirmwareJSON(t *testing.T) {
+	useFirmwareFactory(t)
+	var entries []struct {
+		Name     string `json:"name"`
+		CRC32    string `json:"crc32"`
+		MD5      string `json:"md5"`
+		Optional bool   `json:"optional"`
+	}
+	if err := json.Unmarshal([]byte(RequiredFirmwareJSON()), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].CRC32 == "" || entries[0].Optional || entries[1].MD5 != testBIOSMD5 || !entries[1].Optional {
+		t.Errorf("firmware = %+v", entries)
+	}
+}
+
+func TestFirmwareMissing(t *testing.T) {
+	f, _ := useFirmwareFactory(t)
+	if s := firmwareStatus(t); s["bios.bin"] != "missing" || s["boot.rom"] != "missing" {
+		t.Errorf("status = %v", s)
+	}
+	if Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Error("Init succeeded without required firmware")
+	}
+	if !strings.Contains(LastError(), "bios.bin") || f.files != nil {
+		t.Errorf("LastError = %q", LastError())
+	}
+}
+
+func TestFirmwareBadHash(t *testing.T) {
+	_, dir := useFirmwareFactory(t)
+	os.WriteFile(filepath.Join(dir, "bios.bin"), []byte("wrong"), 0644)
+	os.WriteFile(filepath.Join(dir, "boot.rom"), []byte("wrong"), 0644)
+	if s := firmwareStatus(t); s["bios.bin"] != "bad hash" || s["boot.rom"] != "bad hash" {
+		t.Errorf("status = %v", s)
+	}
+	if Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Error("Init succeeded with mismatched firmware")
+	}
+}
+
+func TestFirmwareGood(t *testing.T) {
+	f, dir := useFirmwareFactory(t)
+	os.WriteFile(filepath.Join(dir, "bios.bin"), testBIOS, 0644)
+	if s := firmwareStatus(t); s["bios.bin"] != "ok" || s["boot.rom"] != "missing" {
+		t.Errorf("status = %v", s)
+	}
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatalf("Init failed: %s", LastError())
+	}
+	if string(f.files["bios.bin"]) != string(testBIOS) || len(f.files) != 1 {
+		t.Errorf("core got firmware %v", f.files)
+	}
+
+	os.WriteFile(filepath.Join(dir, "boot.rom"), []byte("boot rom"), 0644)
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) || len(f.files) != 2 {
+		t.Errorf("optional firmware not passed: %v", f.files)
+	}
+}
@@ -0,0 +1,86 @@
+package ios
+
+// GetFrameDataLen returns the size in bytes of the frame GetFrameData and
+// CopyFrameInto return, or 0 if no frame has been rendered.
+func GetFrameDataLen() int {
+	return def.frameDataLen()
+}
+
+func (in *instance) frameDataLen() int {
+	n := 0
+	in.withLatestFrame(func(s *snapshot) {
+		if s != nil {
+			n = len(s.data)
+		}
+	})
+	return n
+}
+
+// CopyFrameInto copies the active display area frame buffer, stride
+// padding included, into dst. It lets the app reuse one buffer instead of
+// receiving a new copy from GetFrameData each frame.
+// Returns the bytes written, or 0 if dst is smaller than GetFrameDataLen
+// or no frame has been rendered.
+func CopyFrameInto(dst []byte) int {
+	return def.copyFrameInto(dst)
+}
+
+func (in *instance) copyFrameInto(dst []byte) int {
+	if !in.gate.enter() {
+		return 0
+	}
+	defer in.gate.leave()
+	n := 0
+	in.withLatestFrame(func(s *snapshot) {
+		if s != nil && len(dst) >= len(s.data) {
+			n = copy(dst, s.data)
+		}
+	})
+	return n
+}
+
+// CopyFrameIntoTight copies the visible pixels of the frame into dst with
+// rows bytesPerRow apart, dropping the core's stride padding. A
+// bytesPerRow of 0 packs rows tightly. dst must hold
+// (height-1)*bytesPerRow plus one row of pixels.
+// Returns the bytes spanned in dst, or 0 if bytesPerRow is narrower than
+// a row, dst is too small, or no frame has been rendered.
+func CopyFrameIntoTight(dst []byte, bytesPerRow int) int {
+	return def.copyFrameIntoTight(dst, bytesPerRow)
+}
+
+func (in *instance) copyFrameIntoTight(dst []byte, bytesPerRow int) int {
+	if !in.gate.enter() {
+		return 0
+	}
+	defer in.gate.leave()
+	n := 0
+	in.withLatestFrame(func(s *snapshot) {
+		if s != nil {
+			n = copyRows(dst, bytesPerRow, s)
+		}
+	})
+	return n
+}
+
+// copyRows copies the visible part of each row of s into dst, rows
+// bytesPerRow apart.
+func copyRows(dst []byte, bytesPerRow int, s *snapshot) int {
+	rowBytes := s.width * s.bpp
+	if bytesPerRow == 0 {
+		bytesPerRow = rowBytes
+	}
+	rows := len(s.data) / s.stride
+	if rows == 0 || bytesPerRow < rowBytes {
+		return 0
+	}
+	needed := (rows-1)*bytesPerRow + rowBytes
+	if len(dst) < needed {
+		return 0
+	}
+
+	for y := 0; y < rows; y++ {
+		copy(dst[y*bytesPerRow:y*bytesPerRow+rowBytes], s.data[y*s.stride:])
+	}
+	return needed
+}
@@ -0,0 +1,93 @@
+package ios
+
+import (
+	"bytes"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func TestCopyFrameInto(t *testing.T) {
+	e := useMockEmulator(t)
+	if CopyFrameInto(make([]byte, 1024)) != 0 || GetFrameDataLen() != 0 {
+		t.Error("copied before any frame was rendered")
+	}
+
+	for i := range e.framebuffer {
+		e.framebuffer[i] = byte(i)
+	}
+	RunFrame()
+	if GetFrameDataLen() != 64 {
+		t.Fatalf("GetFrameDataLen = %d, want 64", GetFrameDataLen())
+	}
+	if CopyFrameInto(make([]byte, 63)) != 0 {
+		t.Error("copied into a short buffer")
+	}
+	dst := make([]byte, 100)
+	if n := CopyFrameInto(dst); n != 64 || !bytes.Equal(dst[:64], e.framebuffer) {
+		t.Errorf("CopyFrameInto = %d", n)
+	}
+}
+
+func TestCopyFrameIntoTight(t *testing.T) {
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			return e, nil
+		},
+		modify: func(info *emucore.SystemInfo) { info.ScreenWidth = 3 },
+	})
+	if CopyFrameIntoTight(make([]byte, 64), 0) != 0 {
+		t.Error("copied with no emulator")
+	}
+	Init(writeROM(t, "rom.bin", []byte{0}), 0)
+	for i := range e.framebuffer {
+		e.framebuffer[i] = byte(i)
+	}
+	RunFrame()
+
+	// 3 visible pixels of a 4-pixel stride, packed tightly.
+	tight := make([]byte, 48)
+	if n := CopyFrameIntoTight(tight, 0); n != 48 {
+		t.Fatalf("tight copy = %d, want 48", n)
+	}
+	for y := 0; y < 4; y++ {
+		if !bytes.Equal(tight[y*12:y*12+12], e.framebuffer[y*16:y*16+12]) {
+			t.Errorf("row %d mismatch", y)
+		}
+	}
+
+	// A 20-byte pitch needs 3*20+12 bytes.
+	padded := make([]byte, 72)
+	if n := CopyFrameIntoTight(padded, 20); n != 72 {
+		t.Fatalf("padded copy = %d, want 72", n)
+	}
+	if !bytes.Equal(padded[60:72], e.framebuffer[48:60]) {
+		t.Error("last padded row mismatch")
+	}
+	if CopyFrameIntoTight(padded[:71], 20) != 0 || CopyFrameIntoTight(padded, 8) != 0 {
+		t.Error("accepted short buffer or narrow pitch")
+	}
+}
+
+// BenchmarkGetFrameDataCopy mirrors what the gomobile binding does with
+// GetFrameData's result: a fresh copy per frame.
+func BenchmarkGetFrameDataCopy(b *testing.B) {
+	benchmarkEmulator(b)
+	RunFrame()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = append([]byte(nil), GetFrameData()...)
+	}
+}
+
+func BenchmarkCopyFrameInto(b *testing.B) {
+	benchmarkEmulator(b)
+	RunFrame()
+	dst := make([]byte, GetFrameDataLen())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		CopyFrameInto(dst)
+	}
+}
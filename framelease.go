@@ -0,0 +1,136 @@
+package ios
+
+import "encoding/json"
+
+// frameLease holds an acquired frame: the published snapshot itself,
+// pinned so the publisher does not recycle it while the lease is
+// outstanding.
+type frameLease struct {
+	token  int64
+	snap   *snapshot
+	height int
+}
+
+// frameLeases tracks the outstanding acquisition. Guarded by in.mu.
+type frameLeases struct {
+	held      *frameLease
+	nextToken int64
+}
+
+// AcquireFrame pins the last rendered frame and returns a token for it.
+// The token's buffer is the published frame itself, not a copy, and is
+// not modified or reused by RunFrame or any other call until
+// ReleaseFrame. While it is held the bridge renders into one extra
+// buffer. Only one frame can be acquired at a time.
+// Returns 0 if a frame is already acquired or none has been rendered
+// (see LastError).
+func AcquireFrame() int64 {
+	return def.acquireFrame()
+}
+
+func (in *instance) acquireFrame() int64 {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	l := &in.frameLeases
+	if l.held != nil {
+		setLastError("frame %d is still acquired", l.held.token)
+		return 0
+	}
+	var held *frameLease
+	in.withLatestFrame(func(s *snapshot) {
+		if s == nil {
+			return
+		}
+		s.pinned = true
+		held = &frameLease{snap: s, height: len(s.data) / s.stride}
+	})
+	if held == nil {
+		setLastError("no frame rendered")
+		return 0
+	}
+
+	l.nextToken++
+	held.token = l.nextToken
+	l.held = held
+	return l.nextToken
+}
+
+// ReleaseFrame returns an acquired frame's buffer to the bridge.
+// Returns false if token is not the outstanding acquisition.
+func ReleaseFrame(token int64) bool {
+	return def.releaseFrame(token)
+}
+
+func (in *instance) releaseFrame(token int64) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	l := &in.frameLeases
+	if l.held == nil || l.held.token != token {
+		return false
+	}
+	in.unpinFrame(l.held.snap)
+	l.held = nil
+	return true
+}
+
+// unpinFrame ends s's lease. A snapshot replaced while it was pinned
+// goes back to the publisher as its spare, unless a caller fetched it,
+// displacing the extra buffer rendered into meanwhile.
+// in.mu must be held.
+func (in *instance) unpinFrame(s *snapshot) {
+	s.pinned = false
+	p := &in.published
+	if s == p.frame || s.fetched.Load() {
+		return
+	}
+	p.spareFrame = s
+}
+
+// lease returns the outstanding acquisition for token, or nil.
+func (in *instance) lease(token int64) *frameLease {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if h := in.frameLeases.held; h != nil && h.token == token {
+		return h
+	}
+	return nil
+}
+
+// AcquiredFrameData returns the buffer pinned by token, or nil if token is
+// not outstanding.
+func AcquiredFrameData(token int64) []byte {
+	if h := def.lease(token); h != nil {
+		return h.snap.data
+	}
+	return nil
+}
+
+// FrameBufferPointerInfoJSON describes the buffer pinned by token as
+// {"length", "stride", "width", "height"}. Returns "{}" if token is not
+// outstanding.
+func FrameBufferPointerInfoJSON(token int64) string {
+	h := def.lease(token)
+	if h == nil {
+		return "{}"
+	}
+	data, err := json.Marshal(struct {
+		Length int `json:"length"`
+		Stride int `json:"stride"`
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	}{len(h.snap.data), h.snap.stride, h.snap.width, h.height})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// reclaimFrameLeases drops any outstanding acquisition on Close.
+// in.mu must be held.
+func (in *instance) reclaimFrameLeases() {
+	if h := in.frameLeases.held; h != nil {
+		journalf("warning", "frame %d was never released; reclaimed on close", h.token)
+		h.snap.pinned = false
+	}
+	in.frameLeases = frameLeases{nextToken: in.frameLeases.nextToken}
+}
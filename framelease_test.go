@@ -0,0 +1,112 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAcquireFrameStableWhileHeld(t *testing.T) {
+	e := useMockEmulator(t)
+	e.framebuffer[0] = 1
+	RunFrame()
+
+	token := AcquireFrame()
+	if token == 0 {
+		t.Fatalf("AcquireFrame failed: %s", LastError())
+	}
+	held := AcquiredFrameData(token)
+	want := append([]byte(nil), held...)
+
+	for i := 0; i < 3; i++ {
+		e.framebuffer[0] = byte(10 + i)
+		RunFrame()
+	}
+	if !bytes.Equal(held, want) || !bytes.Equal(AcquiredFrameData(token), want) {
+		t.Error("acquired buffer changed while held")
+	}
+	if GetFrameData()[0] != 12 {
+		t.Error("current frame not updated while a frame is held")
+	}
+
+	var info struct {
+		Length, Stride, Width, Height int
+	}
+	if err := json.Unmarshal([]byte(FrameBufferPointerInfoJSON(token)), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Length != 64 || info.Stride != 16 || info.Width != 4 || info.Height != 4 {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestAcquireFrameOneAtATime(t *testing.T) {
+	useMockEmulator(t)
+	RunFrame()
+
+	first := AcquireFrame()
+	if AcquireFrame() != 0 {
+		t.Fatal("second acquisition succeeded")
+	}
+	if ReleaseFrame(first + 1) {
+		t.Error("released unknown token")
+	}
+	if !ReleaseFrame(first) || ReleaseFrame(first) {
+		t.Error("release did not succeed exactly once")
+	}
+	if AcquiredFrameData(first) != nil || FrameBufferPointerInfoJSON(first) != "{}" {
+		t.Error("released token still readable")
+	}
+}
+
+func TestAcquireFrameRecyclesBuffer(t *testing.T) {
+	e := useMockEmulator(t)
+	RunFrame()
+
+	first := AcquireFrame()
+	buf := AcquiredFrameData(first)
+	if &buf[0] != &def.published.frame.data[0] {
+		t.Fatal("acquired frame is a copy of the published frame")
+	}
+
+	// The pinned buffer is not reused while held, then returns to the
+	// pool once released.
+	for i := 0; i < 3; i++ {
+		e.framebuffer[0] = byte(1 + i)
+		RunFrame()
+		if &def.published.frame.data[0] == &buf[0] {
+			t.Fatal("pinned buffer reused while held")
+		}
+	}
+	ReleaseFrame(first)
+	e.framebuffer[0] = 9
+	RunFrame()
+
+	second := AcquireFrame()
+	if second == first {
+		t.Error("token reused")
+	}
+	if got := AcquiredFrameData(second); &got[0] != &buf[0] || got[0] != 9 {
+		t.Error("released buffer not recycled")
+	}
+}
+
+func TestAcquireFrameReclaimedOnClose(t *testing.T) {
+	useMockEmulator(t)
+	ClearJournal()
+	t.Cleanup(ClearJournal)
+
+	if AcquireFrame() != 0 {
+		t.Fatal("acquired before any frame")
+	}
+	RunFrame()
+	token := AcquireFrame()
+	Close()
+	if AcquiredFrameData(token) != nil {
+		t.Error("leaked token survived Close")
+	}
+	if !strings.Contains(JournalJSON(), "never released") {
+		t.Errorf("journal = %s, want leak warning", JournalJSON())
+	}
+}
@@ -3,6 +3,8 @@ module github.com/user-none/eblitui-ios
 go 1.25.7
 
 require (
+	github.com/bodgit/sevenzip v1.6.1
+	github.com/nwaples/rardecode/v2 v2.2.2
 	github.com/user-none/eblitui/api v0.2.0
 	github.com/user-none/eblitui/romloader v0.1.0
 )
@@ -10,11 +12,9 @@ require (
 require (
 	github.com/andybalholm/brotli v1.1.1 // indirect
 	github.com/bodgit/plumbing v1.3.0 // indirect
-	github.com/bodgit/sevenzip v1.6.1 // indirect
 	github.com/bodgit/windows v1.0.1 // indirect
 	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
 	github.com/klauspost/compress v1.17.11 // indirect
-	github.com/nwaples/rardecode/v2 v2.2.2 // indirect
 	github.com/pierrec/lz4/v4 v4.1.22 // indirect
 	github.com/spf13/afero v1.11.0 // indirect
 	github.com/ulikunitz/xz v0.5.12 // indirect
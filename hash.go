@@ -0,0 +1,116 @@
+package ios
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// ROMHeaderDetector is an optional CoreFactory interface for systems
+// whose ROM dumps may carry a copier or container header that DAT files
+// leave out of their hashes.
+type ROMHeaderDetector interface {
+	// ROMHeaderSize returns the length of rom's header, or 0 if it has
+	// none.
+	ROMHeaderSize(rom []byte) int
+}
+
+// romHashes is the JSON returned by the ROM hash functions.
+type romHashes struct {
+	CRC32 string `json:"crc32"`
+	MD5   string `json:"md5"`
+	SHA1  string `json:"sha1"`
+	Size  int    `json:"size"`
+	// HeaderSize is the header HashROMJSON skipped.
+	HeaderSize int `json:"headerSize,omitempty"`
+}
+
+// hashROM computes all hashes of rom in a single pass.
+func hashROM(rom []byte) romHashes {
+	c := crc32.NewIEEE()
+	m := md5.New()
+	s := sha1.New()
+	io.MultiWriter(c, m, s).Write(rom)
+	return romHashes{
+		CRC32: fmt.Sprintf("%08X", c.Sum32()),
+		MD5:   hex.EncodeToString(m.Sum(nil)),
+		SHA1:  hex.EncodeToString(s.Sum(nil)),
+		Size:  len(rom),
+	}
+}
+
+func romHashesJSON(h romHashes) string {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// GetROMHashesJSON returns {"crc32", "md5", "sha1", "size"} for a ROM file.
+// CRC32 is uppercase hex, MD5 and SHA1 lowercase hex.
+// Returns "{}" on error (see LastError).
+func GetROMHashesJSON(path string) string {
+	return GetROMHashesSkippingHeader(path, 0)
+}
+
+// GetROMHashesSkippingHeader is GetROMHashesJSON computed over the ROM
+// with its first skip bytes removed, for systems whose databases hash
+// the ROM without its copier header.
+func GetROMHashesSkippingHeader(path string, skip int) string {
+	_, rom, err := loadSystemROM(path)
+	if err != nil {
+		setLastError("failed to load ROM: %v", err)
+		return "{}"
+	}
+	if skip < 0 || skip > len(rom) {
+		setLastError("header skip %d out of range for %d byte ROM", skip, len(rom))
+		return "{}"
+	}
+	return romHashesJSON(hashROM(rom[skip:]))
+}
+
+// GetLoadedROMHashesJSON returns GetROMHashesJSON for the ROM loaded by
+// Init without reading the file again. Returns "{}" if no ROM is loaded.
+func GetLoadedROMHashesJSON() string {
+	def.mu.Lock()
+	rom := def.rom
+	def.mu.Unlock()
+	if rom == nil {
+		setLastError("no ROM loaded")
+		return "{}"
+	}
+	return romHashesJSON(hashROM(rom))
+}
+
+// HashROMJSON returns {"crc32", "md5", "sha1", "size", "headerSize"} for
+// a ROM file, computed over the headerless ROM when the core reports a
+// header (see ROMHeaderDetector), as No-Intro and Redump DATs expect.
+// headerSize is omitted when nothing was skipped.
+// Returns "{}" on error (see LastError).
+func HashROMJSON(path string) string {
+	f, rom, err := loadSystemROM(path)
+	if err != nil {
+		setLastError("failed to load ROM: %v", err)
+		return "{}"
+	}
+	skip := romHeaderSize(f, rom)
+	h := hashROM(rom[skip:])
+	h.HeaderSize = skip
+	return romHashesJSON(h)
+}
+
+// romHeaderSize returns the header f reports for rom, clamped to the ROM.
+func romHeaderSize(f emucore.CoreFactory, rom []byte) int {
+	d, ok := f.(ROMHeaderDetector)
+	if !ok {
+		return 0
+	}
+	return min(max(d.ROMHeaderSize(rom), 0), len(rom))
+}
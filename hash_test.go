@@ -0,0 +1,97 @@
+package ios
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Reference hashes of "abc".
+var abcHashes = romHashes{
+	CRC32: "352441C2",
+	MD5:   "900150983cd24fb0d6963f7d28e17f72",
+	SHA1:  "a9993e364706816aba3e25717850c26c9cd0d89d",
+	Size:  3,
+}
+
+func parseHashes(t *testing.T, s string) romHashes {
+	t.Helper()
+	var h romHashes
+	if err := json.Unmarshal([]byte(s), &h); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestGetROMHashesJSON(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+
+	got := parseHashes(t, GetROMHashesJSON(writeROM(t, "abc.bin", []byte("abc"))))
+	if got != abcHashes {
+		t.Errorf("hashes = %+v, want %+v", got, abcHashes)
+	}
+}
+
+func TestGetROMHashesSkippingHeader(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	path := writeROM(t, "hdr.bin", []byte("HEADERabc"))
+
+	got := parseHashes(t, GetROMHashesSkippingHeader(path, 6))
+	if got != abcHashes {
+		t.Errorf("hashes = %+v, want %+v", got, abcHashes)
+	}
+	if GetROMHashesSkippingHeader(path, 100) != "{}" || LastError() == "" {
+		t.Error("out of range skip not rejected")
+	}
+}
+
+func TestGetLoadedROMHashesJSON(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	if GetLoadedROMHashesJSON() != "{}" {
+		t.Error("hashes returned with no ROM loaded")
+	}
+
+	useMockEmulator(t)
+	if !Init(writeROM(t, "abc.bin", []byte("abc")), 0) {
+		t.Fatal("Init failed")
+	}
+	if got := parseHashes(t, GetLoadedROMHashesJSON()); got != abcHashes {
+		t.Errorf("hashes = %+v, want %+v", got, abcHashes)
+	}
+}
+
+func TestGetROMHashesJSONMissingFile(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	if GetROMHashesJSON("/nonexistent/rom.bin") != "{}" {
+		t.Error("expected {} for missing file")
+	}
+}
+
+// mockHeaderFactory reports a header on ROMs starting with "HEADER".
+type mockHeaderFactory struct {
+	*mockFactory
+}
+
+func (f *mockHeaderFactory) ROMHeaderSize(rom []byte) int {
+	if string(rom[:min(6, len(rom))]) == "HEADER" {
+		return 6
+	}
+	return 0
+}
+
+func TestHashROMJSON(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	path := writeROM(t, "hdr.bin", []byte("HEADERabc"))
+	if got := parseHashes(t, HashROMJSON(path)); got.Size != 9 || got.HeaderSize != 0 {
+		t.Errorf("hashes without detector = %+v", got)
+	}
+
+	factory = &mockHeaderFactory{&mockFactory{}}
+	want := abcHashes
+	want.HeaderSize = 6
+	if got := parseHashes(t, HashROMJSON(path)); got != want {
+		t.Errorf("hashes = %+v, want %+v", got, want)
+	}
+	if got := parseHashes(t, HashROMJSON(writeROM(t, "abc.bin", []byte("abc")))); got != abcHashes {
+		t.Errorf("headerless hashes = %+v, want %+v", got, abcHashes)
+	}
+}
@@ -0,0 +1,56 @@
+package ios
+
+// IdleSkipper is an optional emulator interface for cores that can tell
+// when a frame did no visible or audible work.
+type IdleSkipper interface {
+	// FrameIdle reports whether the last frame left video and audio
+	// output unchanged, e.g. a static menu or pause screen.
+	FrameIdle() bool
+}
+
+// idleSkipInterval is the number of RunFrame calls per real frame while
+// the core reports idle.
+const idleSkipInterval = 4
+
+// SetIdleSkip enables running only one real frame out of every
+// idleSkipInterval while the core reports it is idle. Skipped frames
+// repeat the last video frame and produce silence. Any input change or
+// non-idle frame returns to full rate immediately.
+// Has no effect on cores that do not implement IdleSkipper.
+func SetIdleSkip(enabled bool) {
+	def.setIdleSkip(enabled)
+}
+
+func (in *instance) setIdleSkip(enabled bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.idleSkip = enabled
+	if !enabled {
+		in.wakeIdle()
+	}
+}
+
+// skipIdleFrame reports whether this frame should be skipped, and if so
+// fills the audio buffer with a frame of silence. Frames are never skipped
+// while an input movie is active so recordings stay frame-exact.
+func (in *instance) skipIdleFrame() bool {
+	if !in.idle || in.movie != nil || in.idleCount >= idleSkipInterval-1 {
+		in.idleCount = 0
+		return false
+	}
+	in.idleCount++
+	in.stats.recordIdleSkip()
+	clear(in.audioData)
+	return true
+}
+
+// updateIdle records whether the core reported the last frame as idle.
+func (in *instance) updateIdle() {
+	in.idle = in.idleSkip && in.idleSkipper != nil && in.idleSkipper.FrameIdle()
+}
+
+// wakeIdle returns to full rate emulation.
+func (in *instance) wakeIdle() {
+	in.idle = false
+	in.idleCount = 0
+}
@@ -0,0 +1,120 @@
+package ios
+
+import (
+	"encoding/json"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+type mockIdleEmulator struct {
+	*mockEmulator
+	idle bool
+}
+
+func (m *mockIdleEmulator) FrameIdle() bool { return m.idle }
+
+func useIdleEmulator(t *testing.T) *mockIdleEmulator {
+	t.Helper()
+	var e *mockIdleEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockIdleEmulator{mockEmulator: newMockEmulator(rom, region)}
+			e.samples = []int16{100, -100, 100, -100}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	t.Cleanup(func() { SetIdleSkip(false) })
+	return e
+}
+
+func TestIdleSkipDutyCycle(t *testing.T) {
+	e := useIdleEmulator(t)
+	SetIdleSkip(true)
+	e.idle = true
+
+	RunFrame() // real frame reports idle
+	for i := 0; i < idleSkipInterval*3; i++ {
+		RunFrame()
+	}
+	if want := 1 + 3; e.frames != want {
+		t.Errorf("core frames = %d, want %d", e.frames, want)
+	}
+
+	// Skipped frames still deliver a full frame of silence.
+	if got := GetAudioData(); len(got) != 8 {
+		t.Fatalf("audio len = %d, want 8", len(got))
+	}
+
+	var stats struct {
+		Frames            int64 `json:"frames"`
+		IdleSkippedFrames int64 `json:"idleSkippedFrames"`
+	}
+	if err := json.Unmarshal([]byte(SessionStatsJSON()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Frames != 4 || stats.IdleSkippedFrames != 9 {
+		t.Errorf("stats = %+v, want 4 frames, 9 skipped", stats)
+	}
+}
+
+func TestIdleSkipSilence(t *testing.T) {
+	e := useIdleEmulator(t)
+	SetIdleSkip(true)
+	e.idle = true
+
+	RunFrame()
+	RunFrame()
+	for i, b := range GetAudioData() {
+		if b != 0 {
+			t.Fatalf("audio byte %d = %d, want silence", i, b)
+		}
+	}
+}
+
+func TestIdleSkipWakesOnInput(t *testing.T) {
+	e := useIdleEmulator(t)
+	SetIdleSkip(true)
+	e.idle = true
+
+	RunFrame()
+	RunFrame() // skipped
+	SetInput(0, 0x10)
+	RunFrame()
+	if e.frames != 2 {
+		t.Errorf("core frames = %d, want 2 after input change", e.frames)
+	}
+
+	// Repeating the same input does not wake.
+	SetInput(0, 0x10)
+	RunFrame()
+	if e.frames != 2 {
+		t.Errorf("core frames = %d, want 2 with unchanged input", e.frames)
+	}
+
+	// Core leaving idle returns to full rate after the next real frame.
+	e.idle = false
+	for i := 0; i < idleSkipInterval; i++ {
+		RunFrame()
+	}
+	before := e.frames
+	RunFrame()
+	RunFrame()
+	if e.frames != before+2 {
+		t.Errorf("core frames = %d, want %d at full rate", e.frames, before+2)
+	}
+}
+
+func TestIdleSkipDisabled(t *testing.T) {
+	e := useIdleEmulator(t)
+	e.idle = true
+	for i := 0; i < 5; i++ {
+		RunFrame()
+	}
+	if e.frames != 5 {
+		t.Errorf("core frames = %d, want 5 with idle skip disabled", e.frames)
+	}
+}
@@ -0,0 +1,237 @@
+package ios
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// asyncCancelled is the status of an import stopped by CancelImportJob.
+const asyncCancelled = "cancelled"
+
+var errImportCancelled = errors.New("import cancelled")
+
+// importJob is a background import started by StartImportJob.
+type importJob struct {
+	id int
+	// factory and candidates are the active core and the cores to try,
+	// captured when the job was started so later registry changes do
+	// not affect it.
+	factory    emucore.CoreFactory
+	candidates []emucore.CoreFactory
+	total      int64
+	read       atomic.Int64
+	cancelled  atomic.Bool
+
+	// mu guards the fields below.
+	mu       sync.Mutex
+	status   string
+	imported []json.RawMessage
+	failures []importFailure
+}
+
+// importFailure is a file an import job could not store.
+type importFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// importJobs tracks import jobs until their results are read.
+var importJobs struct {
+	mu   sync.Mutex
+	next int
+	jobs map[int]*importJob
+}
+
+func lookupImportJob(id int) *importJob {
+	importJobs.mu.Lock()
+	defer importJobs.mu.Unlock()
+	return importJobs.jobs[id]
+}
+
+// StartImportJob imports srcPath into destDir like ExtractAndStoreROM
+// without blocking the caller. srcPath may be a ROM, an archive, or a
+// directory, in which case every ROM and archive under it is imported.
+// Returns a job ID for ImportJobProgress, CancelImportJob and
+// ImportJobResultJSON, or 0 if srcPath cannot be read (see LastError).
+func StartImportJob(srcPath, destDir string, overwrite bool) int {
+	f := activeFactory()
+	if f == nil {
+		setLastError("%v", errNoFactory)
+		return 0
+	}
+	fi, err := os.Stat(srcPath)
+	if err != nil {
+		setLastError("failed to read %s: %v", srcPath, err)
+		return 0
+	}
+	paths := []string{srcPath}
+	if fi.IsDir() {
+		paths = romCandidates(srcPath, true, f.SystemInfo().Extensions)
+	}
+
+	j := &importJob{
+		factory:    f,
+		candidates: candidateFactories(),
+		status:     asyncPending,
+		imported:   []json.RawMessage{},
+		failures:   []importFailure{},
+	}
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil {
+			j.total += fi.Size()
+		}
+	}
+
+	importJobs.mu.Lock()
+	if importJobs.jobs == nil {
+		importJobs.jobs = map[int]*importJob{}
+	}
+	importJobs.next++
+	j.id = importJobs.next
+	importJobs.jobs[j.id] = j
+	importJobs.mu.Unlock()
+
+	go j.run(paths, destDir, overwrite)
+	return j.id
+}
+
+func (j *importJob) run(paths []string, destDir string, overwrite bool) {
+	for _, path := range paths {
+		if j.cancelled.Load() {
+			break
+		}
+		result, err := j.importFile(path, destDir, overwrite)
+		if errors.Is(err, errImportCancelled) {
+			break
+		}
+
+		j.mu.Lock()
+		if err != nil {
+			j.failures = append(j.failures, importFailure{Path: path, Error: err.Error()})
+		} else {
+			j.imported = append(j.imported, json.RawMessage(result))
+		}
+		j.mu.Unlock()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case j.cancelled.Load():
+		j.status = asyncCancelled
+	case len(j.imported) == 0 && len(j.failures) > 0:
+		j.status = asyncFailed
+	default:
+		j.status = asyncDone
+	}
+}
+
+// importFile reads path, counting progress, and stores it as
+// ExtractAndStoreROMFromData does.
+func (j *importJob) importFile(path, destDir string, overwrite bool) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(&importReader{r: f, job: j})
+	if err != nil {
+		return "", err
+	}
+	return extractAndStoreROMData(j.factory, j.candidates, data, filepath.Base(path), destDir, overwrite)
+}
+
+// importReader counts bytes read for a job and stops once it is
+// cancelled.
+type importReader struct {
+	r   io.Reader
+	job *importJob
+}
+
+func (r *importReader) Read(p []byte) (int, error) {
+	if r.job.cancelled.Load() {
+		return 0, errImportCancelled
+	}
+	n, err := r.r.Read(p)
+	r.job.read.Add(int64(n))
+	return n, err
+}
+
+// ImportJobProgress returns a job's progress from 0 to 1, or -1 for an
+// unknown job. Progress counts the bytes read from the source files.
+func ImportJobProgress(id int) float64 {
+	j := lookupImportJob(id)
+	if j == nil {
+		return -1
+	}
+	j.mu.Lock()
+	finished := j.status != asyncPending
+	j.mu.Unlock()
+	if finished || j.total == 0 {
+		return 1
+	}
+	return min(float64(j.read.Load())/float64(j.total), 1)
+}
+
+// CancelImportJob stops a running import. ROMs already stored are kept.
+// Returns false for an unknown or finished job.
+func CancelImportJob(id int) bool {
+	j := lookupImportJob(id)
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != asyncPending {
+		return false
+	}
+	j.cancelled.Store(true)
+	return true
+}
+
+// ImportJobResultJSON reports a job: {"id", "status", "progress",
+// "imported", "errors"}, where status is "pending", "done", "failed" (no
+// file could be imported), "cancelled" or "unknown". imported holds the
+// ExtractAndStoreROM result for each stored ROM and errors lists
+// [{"path", "error"}]. A finished job is reported once and then
+// forgotten.
+func ImportJobResultJSON(id int) string {
+	progress := ImportJobProgress(id)
+	j := lookupImportJob(id)
+	if j == nil {
+		return fmt.Sprintf(`{"id":%d,"status":%q}`, id, asyncUnknown)
+	}
+
+	j.mu.Lock()
+	result := struct {
+		ID       int               `json:"id"`
+		Status   string            `json:"status"`
+		Progress float64           `json:"progress"`
+		Imported []json.RawMessage `json:"imported"`
+		Errors   []importFailure   `json:"errors"`
+	}{j.id, j.status, progress, j.imported, j.failures}
+	finished := j.status != asyncPending
+	if finished {
+		result.Progress = 1
+	}
+	data, err := json.Marshal(result)
+	j.mu.Unlock()
+
+	if finished {
+		importJobs.mu.Lock()
+		delete(importJobs.jobs, id)
+		importJobs.mu.Unlock()
+	}
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
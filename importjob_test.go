@@ -0,0 +1,105 @@
+package ios
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// importJobResult is the parsed ImportJobResultJSON result.
+type importJobResult struct {
+	Status   string          `json:"status"`
+	Progress float64         `json:"progress"`
+	Imported []storeResult   `json:"imported"`
+	Errors   []importFailure `json:"errors"`
+}
+
+// waitImportJob polls a job until it finishes and returns its result.
+func waitImportJob(t *testing.T, id int) importJobResult {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var r importJobResult
+		if err := json.Unmarshal([]byte(ImportJobResultJSON(id)), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Status != asyncPending {
+			return r
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("import job did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestImportJobDirectory(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	src := t.TempDir()
+	for name, data := range map[string][]byte{"a.bin": {1, 2}, "b.bin": {3}, "c.zip": []byte("not a zip")} {
+		if err := os.WriteFile(filepath.Join(src, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dest := t.TempDir()
+
+	id := StartImportJob(src, dest, false)
+	if id == 0 {
+		t.Fatalf("StartImportJob failed: %s", LastError())
+	}
+	r := waitImportJob(t, id)
+	if r.Status != asyncDone || r.Progress != 1 || len(r.Imported) != 2 || len(r.Errors) != 1 {
+		t.Fatalf("result = %+v", r)
+	}
+	for _, got := range r.Imported {
+		if got.Status != storeWritten || !StoredROMExists(dest, got.CRC) {
+			t.Errorf("imported = %+v", got)
+		}
+	}
+	if filepath.Base(r.Errors[0].Path) != "c.zip" {
+		t.Errorf("errors = %+v", r.Errors)
+	}
+
+	// The finished result is reported once.
+	if ImportJobProgress(id) != -1 || CancelImportJob(id) {
+		t.Error("finished job still tracked")
+	}
+	if StartImportJob(filepath.Join(src, "missing"), dest, false) != 0 {
+		t.Error("StartImportJob accepted a missing path")
+	}
+}
+
+func TestImportJobCancelled(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	dest := t.TempDir()
+	j := &importJob{status: asyncPending, imported: []json.RawMessage{}, failures: []importFailure{}}
+	j.cancelled.Store(true)
+	j.run([]string{writeROM(t, "a.bin", []byte{1})}, dest, false)
+
+	if j.status != asyncCancelled || len(j.imported) != 0 || len(j.failures) != 0 {
+		t.Errorf("status = %q, imported %d, failures %d", j.status, len(j.imported), len(j.failures))
+	}
+}
+
+func TestImportJobUsesCapturedFactory(t *testing.T) {
+	f := &mockFactory{}
+	useMockFactory(t, f)
+	dest := t.TempDir()
+	j := &importJob{
+		factory:    f,
+		candidates: candidateFactories(),
+		status:     asyncPending,
+		imported:   []json.RawMessage{},
+		failures:   []importFailure{},
+	}
+
+	// Swapping the registry after submission does not affect the job.
+	factory = nil
+	j.run([]string{writeROM(t, "a.bin", []byte{1})}, dest, false)
+
+	if j.status != asyncDone || len(j.imported) != 1 || len(j.failures) != 0 {
+		t.Errorf("status = %q, imported %d, failures %+v", j.status, len(j.imported), j.failures)
+	}
+}
@@ -0,0 +1,176 @@
+package ios
+
+import (
+	"math"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// AnalogInput is an optional emulator interface for cores with analog
+// sticks or triggers.
+type AnalogInput interface {
+	SetAnalog(player, axis int, value int16)
+}
+
+// PointerInput is an optional emulator interface for cores with light
+// guns, mice or touch screens. x and y are framebuffer pixels.
+type PointerInput interface {
+	SetPointer(player, x, y int, pressed bool)
+}
+
+// InputDeviceSelector is an optional emulator interface for cores that
+// can plug something other than a standard gamepad into a port.
+type InputDeviceSelector interface {
+	// SetInputDevice connects deviceType, such as "gamepad", "mouse",
+	// "lightgun" or "multitap", to a player's port. Returns false if the
+	// core does not support the device on that port.
+	SetInputDevice(player int, deviceType string) bool
+}
+
+// InputDeviceReporter is an optional CoreFactory interface listing the
+// device types the system's cores accept in SetInputDevice.
+type InputDeviceReporter interface {
+	InputDevices() []string
+}
+
+// defaultInputDevice is the device every port starts with.
+const defaultInputDevice = "gamepad"
+
+// RumbleProvider is an optional emulator interface for cores that drive
+// force feedback.
+type RumbleProvider interface {
+	// RumbleStrength returns the player's current motor strength.
+	RumbleStrength(player int) uint16
+}
+
+// HasAnalogInput returns whether the core accepts SetAnalogInput.
+func HasAnalogInput() bool {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.analog != nil
+}
+
+// HasPointerInput returns whether the core accepts SetPointerInput.
+func HasPointerInput() bool {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.pointer != nil
+}
+
+// HasRumble returns whether GetRumbleState reports the core's rumble.
+func HasRumble() bool {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.rumble != nil
+}
+
+// SetAnalogInput sets an analog axis for a player. value ranges from
+// -32768 to 32767 and is clamped to it. Does nothing if the core has no
+// analog input.
+func SetAnalogInput(player, axis int, value int) {
+	def.setAnalogInput(player, axis, value)
+}
+
+func (in *instance) setAnalogInput(player, axis int, value int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.analog == nil {
+		return
+	}
+	in.wakeIdle()
+	in.analog.SetAnalog(player, axis, int16(max(-32768, min(32767, value))))
+}
+
+// SetInputAxis sets an analog axis for a player from a normalized value,
+// -1 to 1, as game controllers report it. Out of range values are
+// clamped. Does nothing if the core has no analog input.
+func SetInputAxis(player, axis int, value float32) {
+	v := max(-1, min(1, float64(value)))
+	def.setAnalogInput(player, axis, int(math.Round(v*32767)))
+}
+
+// SetInputDevice connects a device type to a player's port: "gamepad",
+// or one the core supports, such as "mouse", "lightgun" or "multitap"
+// (see InputDevices in SystemInfoJSON). Devices reset to "gamepad" at
+// Init.
+// Returns false if no ROM is loaded or the core does not support the
+// device (see LastError).
+func SetInputDevice(player int, deviceType string) bool {
+	return def.setInputDevice(player, deviceType)
+}
+
+func (in *instance) setInputDevice(player int, deviceType string) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil {
+		setLastError("no ROM loaded")
+		return false
+	}
+	switch {
+	case in.deviceSelector != nil:
+		if !in.deviceSelector.SetInputDevice(player, deviceType) {
+			setLastError("core does not support %q on player %d", deviceType, player)
+			return false
+		}
+	case deviceType != defaultInputDevice:
+		setLastError("core only supports %q", defaultInputDevice)
+		return false
+	}
+	if in.inputDevices == nil {
+		in.inputDevices = map[int]string{}
+	}
+	in.inputDevices[player] = deviceType
+	return true
+}
+
+// GetInputDevice returns the device type connected to a player's port.
+func GetInputDevice(player int) string {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	if d, ok := def.inputDevices[player]; ok {
+		return d
+	}
+	return defaultInputDevice
+}
+
+// systemInputDevices returns the device types f's system supports, for
+// SystemInfoJSON.
+func systemInputDevices(f emucore.CoreFactory) []string {
+	if r, ok := f.(InputDeviceReporter); ok {
+		if devices := r.InputDevices(); len(devices) > 0 {
+			return devices
+		}
+	}
+	return []string{defaultInputDevice}
+}
+
+// SetPointerInput sets a player's pointer position in framebuffer pixels
+// and whether it is pressed. Positions outside the active display area
+// are clamped to its edge. Does nothing if the core has no pointer input.
+func SetPointerInput(player int, x, y int, pressed bool) {
+	def.setPointerInput(player, x, y, pressed)
+}
+
+func (in *instance) setPointerInput(player int, x, y int, pressed bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.pointer == nil {
+		return
+	}
+	w, h := in.visibleWidth(), in.frameHeightLocked()
+	if w <= 0 || h <= 0 {
+		return
+	}
+	in.wakeIdle()
+	scale := in.filterScale()
+	x, y = max(0, min(w-1, x))/scale, max(0, min(h-1, y))/scale
+	x, y = unrotatePoint(x, y, in.croppedWidth(), in.croppedHeight(), in.bridgeRotation())
+	c := in.activeCrop()
+	in.pointer.SetPointer(player, c.Left+x, c.Top+y, pressed)
+}
+
+// GetRumbleState returns a player's rumble strength from 0 to 65535 as of
+// the last frame, or 0 if the core has no rumble.
+func GetRumbleState(player int) int {
+	return int(def.rumbleLevels.level(player))
+}
@@ -0,0 +1,166 @@
+package ios
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockDeviceEmulator records analog and pointer input and reports a
+// rumble strength that follows the frame count.
+type mockDeviceEmulator struct {
+	*mockEmulator
+	analog  map[[2]int]int16
+	pointer [3]int
+	pressed bool
+}
+
+func (m *mockDeviceEmulator) SetAnalog(player, axis int, value int16) {
+	m.analog[[2]int{player, axis}] = value
+}
+
+func (m *mockDeviceEmulator) SetPointer(player, x, y int, pressed bool) {
+	m.pointer = [3]int{player, x, y}
+	m.pressed = pressed
+}
+
+func (m *mockDeviceEmulator) SetInputDevice(player int, deviceType string) bool {
+	return deviceType == "gamepad" || player == 1 && deviceType == "lightgun"
+}
+
+func (m *mockDeviceEmulator) RumbleStrength(player int) uint16 {
+	if player != 0 {
+		return 0
+	}
+	return uint16(m.frames * 1000)
+}
+
+func useDeviceEmulator(t *testing.T) *mockDeviceEmulator {
+	t.Helper()
+	var e *mockDeviceEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockDeviceEmulator{mockEmulator: newMockEmulator(rom, region), analog: map[[2]int]int16{}}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func TestPointerClamping(t *testing.T) {
+	e := useDeviceEmulator(t)
+	if !HasPointerInput() {
+		t.Fatal("pointer not detected")
+	}
+	tests := []struct{ x, y, wantX, wantY int }{
+		{2, 1, 2, 1},
+		{-5, 2, 0, 2},
+		{10, 10, 3, 3},
+		{1, -1, 1, 0},
+	}
+	for _, tt := range tests {
+		SetPointerInput(1, tt.x, tt.y, true)
+		if e.pointer != [3]int{1, tt.wantX, tt.wantY} || !e.pressed {
+			t.Errorf("pointer(%d, %d) = %v, want (%d, %d)", tt.x, tt.y, e.pointer, tt.wantX, tt.wantY)
+		}
+	}
+}
+
+func TestAnalogClamping(t *testing.T) {
+	e := useDeviceEmulator(t)
+	SetAnalogInput(0, 1, 100000)
+	SetAnalogInput(0, 2, -100000)
+	SetAnalogInput(1, 0, -1234)
+	if e.analog[[2]int{0, 1}] != 32767 || e.analog[[2]int{0, 2}] != -32768 || e.analog[[2]int{1, 0}] != -1234 {
+		t.Errorf("analog = %v", e.analog)
+	}
+}
+
+func TestRumblePolling(t *testing.T) {
+	useDeviceEmulator(t)
+	if !HasRumble() {
+		t.Fatal("rumble not detected")
+	}
+	for i := 1; i <= 3; i++ {
+		RunFrame()
+		if got := GetRumbleState(0); got != i*1000 {
+			t.Errorf("frame %d rumble = %d", i, got)
+		}
+	}
+	if GetRumbleState(1) != 0 {
+		t.Error("player 1 rumble nonzero")
+	}
+}
+
+func TestSetInputAxis(t *testing.T) {
+	e := useDeviceEmulator(t)
+	SetInputAxis(0, 0, 1)
+	SetInputAxis(0, 1, -2)
+	SetInputAxis(0, 2, 0.5)
+	if e.analog[[2]int{0, 0}] != 32767 || e.analog[[2]int{0, 1}] != -32767 || e.analog[[2]int{0, 2}] != 16384 {
+		t.Errorf("analog = %v", e.analog)
+	}
+}
+
+func TestSetInputDevice(t *testing.T) {
+	useDeviceEmulator(t)
+	if GetInputDevice(1) != "gamepad" {
+		t.Errorf("default device = %q", GetInputDevice(1))
+	}
+	if !SetInputDevice(1, "lightgun") || GetInputDevice(1) != "lightgun" {
+		t.Error("lightgun not connected to player 1")
+	}
+	if SetInputDevice(0, "lightgun") || GetInputDevice(0) != "gamepad" {
+		t.Error("unsupported device accepted")
+	}
+
+	useMockEmulator(t)
+	if !SetInputDevice(0, "gamepad") || SetInputDevice(0, "mouse") {
+		t.Error("plain core accepted a device other than gamepad")
+	}
+}
+
+// mockDeviceFactory reports extra input device types.
+type mockDeviceFactory struct {
+	*mockFactory
+}
+
+func (f *mockDeviceFactory) InputDevices() []string { return []string{"gamepad", "lightgun"} }
+
+func TestSystemInfoInputDevices(t *testing.T) {
+	old := factory
+	t.Cleanup(func() { factory = old })
+
+	devices := func() []string {
+		var info struct{ InputDevices []string }
+		if err := json.Unmarshal([]byte(SystemInfoJSON()), &info); err != nil {
+			t.Fatal(err)
+		}
+		return info.InputDevices
+	}
+	factory = &mockFactory{}
+	if got := devices(); !slices.Equal(got, []string{"gamepad"}) {
+		t.Errorf("default InputDevices = %v", got)
+	}
+	factory = &mockDeviceFactory{&mockFactory{}}
+	if got := devices(); !slices.Equal(got, []string{"gamepad", "lightgun"}) {
+		t.Errorf("InputDevices = %v", got)
+	}
+}
+
+func TestInputDevicesUnsupported(t *testing.T) {
+	useMockEmulator(t)
+	if HasAnalogInput() || HasPointerInput() || HasRumble() {
+		t.Error("plain core reported input devices")
+	}
+	SetAnalogInput(0, 0, 1)
+	SetPointerInput(0, 1, 1, true)
+	if GetRumbleState(0) != 0 {
+		t.Error("rumble from plain core")
+	}
+}
@@ -0,0 +1,780 @@
+package ios
+
+import "sync"
+
+// Instance handles are positive integers. 0 is never a valid handle and
+// is returned by CreateInstance on failure.
+var (
+	instancesMu  sync.Mutex
+	instances    = map[int]*instance{}
+	nextInstance = 1
+)
+
+// CreateInstance creates an emulator instance from a ROM file path,
+// independent of the package-level default instance.
+// regionCode: 0=NTSC, 1=PAL
+// Returns the instance handle, or 0 on failure.
+func CreateInstance(path string, regionCode int) int {
+	in := &instance{}
+	if !in.init(path, regionCode) {
+		return 0
+	}
+	return registerInstance(in)
+}
+
+// CreateInstanceFromData is CreateInstance for ROM data in memory, named
+// filename, like InitFromData.
+// Returns the instance handle, or 0 on failure.
+func CreateInstanceFromData(data []byte, filename string, regionCode int) int {
+	f, rom, _, err := loadSystemROMData(data, filename)
+	if err != nil {
+		setLastError("failed to load ROM: %v", err)
+		return 0
+	}
+	in := &instance{}
+	if !in.initROM(f, rom, regionCode) {
+		return 0
+	}
+	return registerInstance(in)
+}
+
+// CreateInstanceWithDiscs is CreateInstance for a multi-disc game, like
+// LoadWithDiscs.
+// Returns the instance handle, or 0 on failure.
+func CreateInstanceWithDiscs(pathsJSON string, regionCode int) int {
+	in := &instance{}
+	if !in.loadWithDiscs(pathsJSON, regionCode) {
+		return 0
+	}
+	return registerInstance(in)
+}
+
+// registerInstance assigns in a new handle.
+func registerInstance(in *instance) int {
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+	h := nextInstance
+	nextInstance++
+	instances[h] = in
+	return h
+}
+
+// CloseInstance releases the instance. The handle is invalid afterwards
+// and all calls using it return zero values.
+func CloseInstance(h int) {
+	instancesMu.Lock()
+	in := instances[h]
+	delete(instances, h)
+	instancesMu.Unlock()
+
+	if in != nil {
+		in.close()
+	}
+}
+
+// lookupInstance returns the instance for h, or nil if h is not a live handle.
+func lookupInstance(h int) *instance {
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+	return instances[h]
+}
+
+// RunFrameFor executes one frame of emulation on instance h.
+func RunFrameFor(h int) {
+	if in := lookupInstance(h); in != nil {
+		in.runFrame()
+	}
+}
+
+// GetFrameDataFor returns instance h's frame buffer for the active display area.
+func GetFrameDataFor(h int) []byte {
+	in := lookupInstance(h)
+	if in == nil {
+		return nil
+	}
+	return in.fetchFrame()
+}
+
+// GetAudioDataFor returns instance h's audio as int16 stereo PCM
+// little-endian bytes.
+func GetAudioDataFor(h int) []byte {
+	in := lookupInstance(h)
+	if in == nil {
+		return nil
+	}
+	return in.fetchAudio()
+}
+
+// SetInputFor sets controller state for the given player on instance h.
+func SetInputFor(h int, player int, buttons int) {
+	if in := lookupInstance(h); in != nil {
+		in.setInput(player, buttons)
+	}
+}
+
+// FrameWidthFor returns instance h's display width in pixels.
+func FrameWidthFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.frameWidth()
+}
+
+// FrameStrideFor returns instance h's framebuffer stride in bytes per row.
+func FrameStrideFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.frameStride()
+}
+
+// FrameHeightFor returns instance h's active display height.
+func FrameHeightFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.frameHeight()
+}
+
+// RegionFor returns instance h's current region (0=NTSC, 1=PAL).
+func RegionFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.region()
+}
+
+// GetFPSFor returns the frames per second for instance h.
+func GetFPSFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.fps()
+}
+
+// HasSaveStatesFor returns whether instance h supports save states.
+func HasSaveStatesFor(h int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.hasSaveStates()
+}
+
+// SaveStateFor creates a save state for instance h. Returns true on success.
+func SaveStateFor(h int) bool {
+	in := lookupInstance(h)
+	if in == nil {
+		return false
+	}
+	_, ok := in.saveStateContainer()
+	return ok
+}
+
+// StateLenFor returns the length of instance h's last saved state.
+func StateLenFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.stateLen()
+}
+
+// StateByteFor returns a single byte from instance h's saved state.
+func StateByteFor(h int, i int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.stateByte(i)
+}
+
+// StateBytesFor returns up to length bytes of instance h's saved state
+// starting at offset.
+func StateBytesFor(h int, offset, length int) []byte {
+	in := lookupInstance(h)
+	if in == nil {
+		return nil
+	}
+	return in.stateBytes(offset, length)
+}
+
+// LoadStateFor loads a save state into instance h. Returns true on success.
+func LoadStateFor(h int, data []byte) bool {
+	in := lookupInstance(h)
+	return in != nil && in.loadStateContainer(data)
+}
+
+// HasSRAMFor returns whether instance h's ROM uses battery-backed save.
+func HasSRAMFor(h int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.hasSRAM()
+}
+
+// PrepareSRAMFor copies instance h's SRAM to its internal buffer.
+func PrepareSRAMFor(h int) {
+	if in := lookupInstance(h); in != nil {
+		in.prepareSRAM()
+	}
+}
+
+// SRAMLenFor returns the length of instance h's prepared SRAM.
+func SRAMLenFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.sramLen()
+}
+
+// SRAMByteFor returns a single byte from instance h's prepared SRAM.
+func SRAMByteFor(h int, i int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.sramByte(i)
+}
+
+// SRAMBytesFor returns up to length bytes of instance h's prepared SRAM
+// starting at offset.
+func SRAMBytesFor(h int, offset, length int) []byte {
+	in := lookupInstance(h)
+	if in == nil {
+		return nil
+	}
+	return in.sramBytes(offset, length)
+}
+
+// LoadSRAMFor loads SRAM data into instance h.
+func LoadSRAMFor(h int, data []byte) {
+	if in := lookupInstance(h); in != nil {
+		in.loadSRAM(data)
+	}
+}
+
+// SetOptionFor applies a core option change to instance h.
+func SetOptionFor(h int, key string, value string) {
+	if in := lookupInstance(h); in != nil {
+		in.setOption(key, value)
+	}
+}
+
+// RunFramesFor runs count frames on instance h, like RunFrames.
+func RunFramesFor(h int, count int, renderLast bool) {
+	if in := lookupInstance(h); in != nil {
+		in.runFrames(count, renderLast)
+	}
+}
+
+// GetFrameDataLenFor returns the size in bytes of instance h's frame.
+func GetFrameDataLenFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.frameDataLen()
+}
+
+// CopyFrameIntoFor copies instance h's frame into dst, like CopyFrameInto.
+func CopyFrameIntoFor(h int, dst []byte) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.copyFrameInto(dst)
+}
+
+// ResetFor resets instance h, like Reset. Returns false if the reset is
+// not possible.
+func ResetFor(h int, hard bool) bool {
+	in := lookupInstance(h)
+	return in != nil && in.reset(hard)
+}
+
+// PauseFor pauses instance h, like Pause.
+func PauseFor(h int) {
+	if in := lookupInstance(h); in != nil {
+		in.pause()
+	}
+}
+
+// ResumeFor resumes instance h, like Resume.
+func ResumeFor(h int) {
+	if in := lookupInstance(h); in != nil {
+		in.resume()
+	}
+}
+
+// IsPausedFor returns whether instance h is paused.
+func IsPausedFor(h int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.isPaused()
+}
+
+// FrameCountFor returns the number of frames instance h has emulated.
+func FrameCountFor(h int) int64 {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.frames()
+}
+
+// SetOutputSampleRateFor sets the GetAudioDataFor output rate of instance
+// h, like SetOutputSampleRate.
+func SetOutputSampleRateFor(h int, rate int) {
+	if in := lookupInstance(h); in != nil {
+		in.setOutputSampleRate(rate)
+	}
+}
+
+// SetOutputChannelsFor sets the GetAudioDataFor output channels of
+// instance h, like SetOutputChannels.
+func SetOutputChannelsFor(h int, channels int) {
+	if in := lookupInstance(h); in != nil {
+		in.setOutputChannels(channels)
+	}
+}
+
+// ReportAudioBufferFillFor reports the audio buffer fill for instance h,
+// like ReportAudioBufferFill.
+func ReportAudioBufferFillFor(h int, pct float64) {
+	if in := lookupInstance(h); in != nil {
+		in.reportAudioBufferFill(pct)
+	}
+}
+
+// CopyFrameIntoTightFor copies instance h's frame into dst with rows
+// bytesPerRow apart, like CopyFrameIntoTight.
+func CopyFrameIntoTightFor(h int, dst []byte, bytesPerRow int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.copyFrameIntoTight(dst, bytesPerRow)
+}
+
+// FrameSequenceFor returns instance h's frame sequence, like
+// FrameSequence.
+func FrameSequenceFor(h int) int64 {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.published.frameSeq.Load()
+}
+
+// FrameChangedFor returns whether instance h published a different frame
+// since the last call, like FrameChanged.
+func FrameChangedFor(h int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.frameChanged()
+}
+
+// CaptureScreenshotPNGFor encodes instance h's last rendered frame as PNG,
+// like CaptureScreenshotPNG.
+func CaptureScreenshotPNGFor(h int) []byte {
+	in := lookupInstance(h)
+	if in == nil {
+		return nil
+	}
+	return in.screenshotPNG(0)
+}
+
+// CaptureScreenshotToFileFor writes instance h's last rendered frame to
+// path as PNG, like CaptureScreenshotToFile.
+func CaptureScreenshotToFileFor(h int, path string) bool {
+	in := lookupInstance(h)
+	return in != nil && in.captureScreenshotToFile(path)
+}
+
+// DisplayAspectRatioFor returns instance h's display aspect ratio, like
+// DisplayAspectRatio.
+func DisplayAspectRatioFor(h int) float64 {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.displayAspectRatio()
+}
+
+// PixelAspectRatioFor returns instance h's pixel aspect ratio, like
+// PixelAspectRatio.
+func PixelAspectRatioFor(h int) float64 {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.pixelAspectRatio()
+}
+
+// SetCropFor sets the crop of instance h, like SetCrop.
+func SetCropFor(h int, top, bottom, left, right int) {
+	if in := lookupInstance(h); in != nil {
+		in.setCrop(newCrop(top, bottom, left, right))
+	}
+}
+
+// ResetCropFor returns instance h to the core's default crop, like
+// ResetCrop.
+func ResetCropFor(h int) {
+	if in := lookupInstance(h); in != nil {
+		in.setCrop(nil)
+	}
+}
+
+// FrameRotationFor returns the rotation the app should apply to instance
+// h's frame, like FrameRotation.
+func FrameRotationFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.frameRotation()
+}
+
+// SetRotateFramesFor sets whether instance h rotates its frames, like
+// SetRotateFrames.
+func SetRotateFramesFor(h int, enabled bool) {
+	if in := lookupInstance(h); in != nil {
+		in.setRotateFrames(enabled)
+	}
+}
+
+// SetPreferredPixelFormatFor sets instance h's frame format by name, like
+// SetPreferredPixelFormat.
+func SetPreferredPixelFormatFor(h int, name string) bool {
+	in := lookupInstance(h)
+	return in != nil && in.setPreferredPixelFormat(name)
+}
+
+// FramePixelFormatFor returns the name of instance h's frame format, like
+// FramePixelFormat.
+func FramePixelFormatFor(h int) string {
+	in := lookupInstance(h)
+	if in == nil {
+		return ""
+	}
+	return in.framePixelFormat()
+}
+
+// StepFrameFor advances paused instance h by one frame, like StepFrame.
+func StepFrameFor(h int) string {
+	in := lookupInstance(h)
+	if in == nil {
+		return "{}"
+	}
+	return in.stepFrame()
+}
+
+// SaveStateAsyncFor saves instance h's state to path in the background,
+// like SaveStateAsync.
+func SaveStateAsyncFor(h int, path string) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.saveStateAsync(path)
+}
+
+// CapabilitiesJSONFor reports instance h's optional features, like
+// CapabilitiesJSON.
+func CapabilitiesJSONFor(h int) string {
+	in := lookupInstance(h)
+	if in == nil {
+		return "{}"
+	}
+	return in.capabilitiesJSON()
+}
+
+// WriteStateWithMetadataFor saves instance h's state to path, like
+// WriteStateWithMetadata.
+func WriteStateWithMetadataFor(h int, path string) bool {
+	in := lookupInstance(h)
+	return in != nil && in.writeStateWithMetadata(path)
+}
+
+// LoadStateWithMetadataFor loads a state file into instance h, like
+// LoadStateWithMetadata.
+func LoadStateWithMetadataFor(h int, path string) bool {
+	in := lookupInstance(h)
+	return in != nil && in.loadStateWithMetadata(path)
+}
+
+// StateHashFor returns the hash of instance h's state, like StateHash.
+func StateHashFor(h int) int64 {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.stateHash()
+}
+
+// SRAMDirtyFor returns whether instance h's SRAM changed since it was
+// last flushed or loaded, like SRAMDirty.
+func SRAMDirtyFor(h int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.sramDirty()
+}
+
+// FlushSRAMToFileFor writes instance h's SRAM to path if it changed, like
+// FlushSRAMToFile.
+func FlushSRAMToFileFor(h int, path string) bool {
+	in := lookupInstance(h)
+	return in != nil && in.flushSRAM(path)
+}
+
+// DiscCountFor returns the number of discs instance h was loaded with,
+// like DiscCount.
+func DiscCountFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.discCount()
+}
+
+// CurrentDiscFor returns the index of instance h's inserted disc, like
+// CurrentDisc.
+func CurrentDiscFor(h int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return -1
+	}
+	return in.currentDisc()
+}
+
+// SetDiscFor inserts the disc at index into instance h, like SetDisc.
+func SetDiscFor(h int, index int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.setDisc(index)
+}
+
+// HasCheatsFor returns whether instance h supports cheat codes, like
+// HasCheats.
+func HasCheatsFor(h int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.hasCheats()
+}
+
+// AddCheatFor adds and enables a cheat code on instance h, like AddCheat.
+func AddCheatFor(h int, code string) bool {
+	in := lookupInstance(h)
+	return in != nil && in.addCheat(code)
+}
+
+// SetCheatEnabledFor enables or disables a cheat on instance h, like
+// SetCheatEnabled.
+func SetCheatEnabledFor(h int, code string, enabled bool) {
+	if in := lookupInstance(h); in != nil {
+		in.setCheatEnabled(code, enabled)
+	}
+}
+
+// RemoveCheatFor removes a cheat from instance h, like RemoveCheat.
+func RemoveCheatFor(h int, code string) {
+	if in := lookupInstance(h); in != nil {
+		in.removeCheat(code)
+	}
+}
+
+// ClearCheatsFor removes all of instance h's cheats, like ClearCheats.
+func ClearCheatsFor(h int) {
+	if in := lookupInstance(h); in != nil {
+		in.clearCheats()
+	}
+}
+
+// ListCheatsJSONFor returns instance h's cheat list, like ListCheatsJSON.
+func ListCheatsJSONFor(h int) string {
+	in := lookupInstance(h)
+	if in == nil {
+		return "[]"
+	}
+	return in.listCheatsJSON()
+}
+
+// ReadMemoryFor reads length bytes at address from instance h, like
+// ReadMemory.
+func ReadMemoryFor(h int, address int, length int) []byte {
+	in := lookupInstance(h)
+	if in == nil {
+		return nil
+	}
+	return in.readMemory(address, length)
+}
+
+// WriteMemoryByteFor writes a byte at address in instance h, like
+// WriteMemoryByte.
+func WriteMemoryByteFor(h int, address int, value int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.writeMemory(address, []byte{byte(value)})
+}
+
+// WriteMemoryFor writes data at address in instance h, like WriteMemory.
+func WriteMemoryFor(h int, address int, data []byte) bool {
+	in := lookupInstance(h)
+	return in != nil && in.writeMemory(address, data)
+}
+
+// SetAnalogInputFor sets an analog axis for a player on instance h, like
+// SetAnalogInput.
+func SetAnalogInputFor(h int, player, axis int, value int) {
+	if in := lookupInstance(h); in != nil {
+		in.setAnalogInput(player, axis, value)
+	}
+}
+
+// SetPointerInputFor sets a player's pointer on instance h, like
+// SetPointerInput.
+func SetPointerInputFor(h int, player int, x, y int, pressed bool) {
+	if in := lookupInstance(h); in != nil {
+		in.setPointerInput(player, x, y, pressed)
+	}
+}
+
+// GetRumbleStateFor returns a player's rumble strength on instance h,
+// like GetRumbleState.
+func GetRumbleStateFor(h int, player int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return int(in.rumbleLevels.level(player))
+}
+
+// GetTimingJSONFor returns instance h's frame timing, like GetTimingJSON.
+func GetTimingJSONFor(h int) string {
+	in := lookupInstance(h)
+	if in == nil {
+		return "{}"
+	}
+	return in.timingJSON()
+}
+
+// PollEventsJSONFor drains instance h's core events, like PollEventsJSON.
+func PollEventsJSONFor(h int) string {
+	in := lookupInstance(h)
+	if in == nil {
+		return "[]"
+	}
+	return in.pollEventsJSON()
+}
+
+// SetFrameBlendFor sets instance h's frame blending mode, like
+// SetFrameBlend.
+func SetFrameBlendFor(h int, mode int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.setFrameBlend(mode)
+}
+
+// SetPixelFormatFor sets instance h's frame format, like SetPixelFormat.
+func SetPixelFormatFor(h int, format int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.setPixelFormat(format)
+}
+
+// SetVideoFilterFor enables or disables a video filter on instance h,
+// like SetVideoFilter.
+func SetVideoFilterFor(h int, name string, enabled bool) bool {
+	in := lookupInstance(h)
+	return in != nil && in.setVideoFilter(name, enabled)
+}
+
+// SetIdleSkipFor sets whether instance h skips idle frames, like
+// SetIdleSkip.
+func SetIdleSkipFor(h int, enabled bool) {
+	if in := lookupInstance(h); in != nil {
+		in.setIdleSkip(enabled)
+	}
+}
+
+// StartInputRecordingFor starts recording instance h's input, like
+// StartInputRecording.
+func StartInputRecordingFor(h int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.startInputRecording("")
+}
+
+// StartInputRecordingToFileFor starts recording instance h's input to
+// path, like StartInputRecordingToFile.
+func StartInputRecordingToFileFor(h int, path string) bool {
+	in := lookupInstance(h)
+	return in != nil && in.startInputRecording(path)
+}
+
+// StopInputRecordingFor ends instance h's recording and returns the
+// movie, like StopInputRecording.
+func StopInputRecordingFor(h int) []byte {
+	in := lookupInstance(h)
+	if in == nil {
+		return nil
+	}
+	return in.stopInputRecording()
+}
+
+// IsRecordingInputFor returns whether instance h is recording input, like
+// IsRecordingInput.
+func IsRecordingInputFor(h int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.isRecordingInput()
+}
+
+// StartInputPlaybackFor plays back a movie on instance h, like
+// StartInputPlayback.
+func StartInputPlaybackFor(h int, data []byte) bool {
+	in := lookupInstance(h)
+	return in != nil && in.startInputPlayback(data)
+}
+
+// PlayMovieFor plays back a movie file on instance h, like PlayMovie.
+func PlayMovieFor(h int, path string) bool {
+	in := lookupInstance(h)
+	return in != nil && in.playMovie(path)
+}
+
+// IsPlaybackActiveFor returns whether input playback is feeding instance
+// h's frames, like IsPlaybackActive.
+func IsPlaybackActiveFor(h int) bool {
+	in := lookupInstance(h)
+	return in != nil && in.isPlaybackActive()
+}
+
+// StopInputPlaybackFor ends playback on instance h, like
+// StopInputPlayback.
+func StopInputPlaybackFor(h int) {
+	if in := lookupInstance(h); in != nil {
+		in.stopInputPlayback()
+	}
+}
+
+// SetMovieRecordingVerifyFor sets instance h's recording verification,
+// like SetMovieRecordingVerify.
+func SetMovieRecordingVerifyFor(h int, enabled bool) {
+	if in := lookupInstance(h); in != nil {
+		in.setMovieRecordingVerify(enabled)
+	}
+}
+
+// MovieVerifyJSONFor reports instance h's recording verification, like
+// MovieVerifyJSON.
+func MovieVerifyJSONFor(h int) string {
+	in := lookupInstance(h)
+	if in == nil {
+		return "{}"
+	}
+	return in.movieVerifyJSON()
+}
+
+// GetLatchedInputFor returns the buttons last presented to instance h's
+// core for a player, like GetLatchedInput.
+func GetLatchedInputFor(h int, player int) int {
+	in := lookupInstance(h)
+	if in == nil {
+		return 0
+	}
+	return in.latchedInput(player)
+}
@@ -0,0 +1,221 @@
+package ios
+
+import (
+	"bytes"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func TestInstancesAdvanceIndependently(t *testing.T) {
+	var created []*mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e := newMockEmulator(rom, region)
+			e.framebuffer[0] = rom[0]
+			e.samples = []int16{int16(rom[0]), 0}
+			created = append(created, e)
+			return e, nil
+		},
+	})
+
+	h1 := CreateInstance(writeROM(t, "a.bin", []byte{0x11}), 0)
+	h2 := CreateInstance(writeROM(t, "b.bin", []byte{0x22}), 1)
+	if h1 == 0 || h2 == 0 || h1 == h2 {
+		t.Fatalf("handles = %d, %d", h1, h2)
+	}
+	t.Cleanup(func() {
+		CloseInstance(h1)
+		CloseInstance(h2)
+	})
+
+	SetInputFor(h2, 0, 0x5)
+	RunFrameFor(h1)
+	RunFrameFor(h1)
+	RunFrameFor(h2)
+
+	if created[0].frames != 2 || created[1].frames != 1 {
+		t.Errorf("frames = %d/%d, want 2/1", created[0].frames, created[1].frames)
+	}
+	if created[1].inputs[0] != 0x5 || len(created[0].inputs) != 0 {
+		t.Error("input applied to wrong instance")
+	}
+	if GetFrameDataFor(h1)[0] != 0x11 || GetFrameDataFor(h2)[0] != 0x22 {
+		t.Error("frame data shared between instances")
+	}
+	if a, b := GetAudioDataFor(h1), GetAudioDataFor(h2); bytes.Equal(a, b) || &a[0] == &b[0] {
+		t.Error("audio buffers shared between instances")
+	}
+	if RegionFor(h1) != 0 || RegionFor(h2) != 1 {
+		t.Errorf("regions = %d/%d, want 0/1", RegionFor(h1), RegionFor(h2))
+	}
+	if GetFrameData() != nil {
+		t.Error("default instance affected by handle instances")
+	}
+
+	PauseFor(h1)
+	RunFramesFor(h1, 3, true)
+	RunFramesFor(h2, 3, true)
+	if !IsPausedFor(h1) || IsPausedFor(h2) {
+		t.Error("pause applied to wrong instance")
+	}
+	if FrameCountFor(h1) != 2 || FrameCountFor(h2) != 4 {
+		t.Errorf("frame counts = %d/%d, want 2/4", FrameCountFor(h1), FrameCountFor(h2))
+	}
+	dst := make([]byte, GetFrameDataLenFor(h2))
+	if CopyFrameIntoFor(h2, dst) != len(dst) || dst[0] != 0x22 {
+		t.Error("CopyFrameIntoFor copied the wrong frame")
+	}
+	if CopyFrameIntoTightFor(h2, dst, 0) != len(dst) || dst[0] != 0x22 {
+		t.Error("CopyFrameIntoTightFor copied the wrong frame")
+	}
+	if CaptureScreenshotPNGFor(h2) == nil || CaptureScreenshotPNG() != nil {
+		t.Error("screenshot taken from the wrong instance")
+	}
+
+	SetOutputChannelsFor(h2, 1)
+	RunFrameFor(h2)
+	if n := len(GetAudioDataFor(h2)); n != 2 || def.audioOut.channels != 0 {
+		t.Errorf("mono audio = %d bytes, want 2 on h2 only", n)
+	}
+}
+
+func TestInstanceUseAfterClose(t *testing.T) {
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			return e, nil
+		},
+	})
+
+	h := CreateInstance(writeROM(t, "a.bin", []byte{0x11}), 0)
+	RunFrameFor(h)
+	CloseInstance(h)
+	if !e.closed {
+		t.Error("emulator not closed")
+	}
+
+	RunFrameFor(h)
+	SetInputFor(h, 0, 1)
+	LoadSRAMFor(h, []byte{1})
+	CloseInstance(h)
+	if GetFrameDataFor(h) != nil || GetAudioDataFor(h) != nil {
+		t.Error("closed handle returned data")
+	}
+	if FrameWidthFor(h) != 0 || FrameHeightFor(h) != 0 || GetFPSFor(h) != 0 {
+		t.Error("closed handle returned geometry")
+	}
+	if SaveStateFor(h) || LoadStateFor(h, nil) || HasSRAMFor(h) || ResetFor(h, true) {
+		t.Error("closed handle reported success")
+	}
+	if e.frames != 1 {
+		t.Errorf("frames = %d, want 1", e.frames)
+	}
+}
+
+func TestCreateInstanceFailure(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	if h := CreateInstance("/nonexistent/rom.bin", 0); h != 0 {
+		t.Errorf("CreateInstance = %d, want 0", h)
+	}
+}
+
+func TestCreateInstanceFromData(t *testing.T) {
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			return e, nil
+		},
+	})
+	data := []byte{0x42, 0x43}
+	h := CreateInstanceFromData(data, "Game.bin", 1)
+	if h == 0 {
+		t.Fatalf("CreateInstanceFromData failed: %s", LastError())
+	}
+	t.Cleanup(func() { CloseInstance(h) })
+	data[0] = 0
+	if e.rom[0] != 0x42 || e.region != emucore.RegionPAL {
+		t.Errorf("rom = %v, region = %v", e.rom, e.region)
+	}
+	if CreateInstanceFromData(data, "notes.txt", 0) != 0 {
+		t.Error("created an instance from an unsupported file")
+	}
+}
+
+func TestHandleVariantsStayOnTheirInstance(t *testing.T) {
+	var created []*mockMemoryEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e := &mockMemoryEmulator{
+				mockEmulator: newMockEmulator(rom, region),
+				ram:          make([]byte, 8),
+				sram:         make([]byte, 4),
+			}
+			created = append(created, e)
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "a.bin", []byte{0x11}), 0) {
+		t.Fatal("Init failed")
+	}
+	h := CreateInstance(writeROM(t, "b.bin", []byte{0x22}), 0)
+	if h == 0 {
+		t.Fatalf("CreateInstance failed: %s", LastError())
+	}
+	t.Cleanup(func() { CloseInstance(h) })
+	own, other := created[1], created[0]
+
+	if !AddCheatFor(h, "0002:7F") || ListCheatsJSON() != "[]" {
+		t.Fatalf("AddCheatFor: list %s, default %s", ListCheatsJSONFor(h), ListCheatsJSON())
+	}
+	RunFrameFor(h)
+	RunFrame()
+	if own.ram[2] != 0x7F || other.ram[2] != 0 {
+		t.Errorf("cheat applied to ram %v / %v", own.ram, other.ram)
+	}
+	RemoveCheatFor(h, "0002:7f")
+	if ListCheatsJSONFor(h) != "[]" {
+		t.Errorf("RemoveCheatFor left %s", ListCheatsJSONFor(h))
+	}
+
+	if !WriteMemoryByteFor(h, 5, 0x33) || other.ram[5] != 0 {
+		t.Error("WriteMemoryByteFor wrote to the wrong instance")
+	}
+	if got := ReadMemoryFor(h, 5, 1); !bytes.Equal(got, []byte{0x33}) {
+		t.Errorf("ReadMemoryFor = %v", got)
+	}
+
+	if !StartInputRecordingFor(h) || !IsRecordingInputFor(h) || IsRecordingInput() {
+		t.Fatal("recording started on the wrong instance")
+	}
+	SetInputFor(h, 0, 0x1)
+	RunFrameFor(h)
+	movie := StopInputRecordingFor(h)
+	if movie == nil || IsRecordingInputFor(h) {
+		t.Fatal("StopInputRecordingFor returned no movie")
+	}
+	if StartInputPlayback(movie) {
+		t.Error("movie for instance h's ROM played on the default instance")
+	}
+	if !StartInputPlaybackFor(h, movie) || !IsPlaybackActiveFor(h) {
+		t.Fatalf("StartInputPlaybackFor failed: %s", LastError())
+	}
+	StopInputPlaybackFor(h)
+	if IsPlaybackActiveFor(h) {
+		t.Error("StopInputPlaybackFor left playback active")
+	}
+
+	if DiscCountFor(h) != 0 || CurrentDiscFor(h) != -1 || SetDiscFor(h, 1) {
+		t.Error("disc calls on a single-image instance")
+	}
+	if StateHashFor(h) != 0 || GetTimingJSONFor(h) == "{}" || PollEventsJSONFor(h) != "[]" {
+		t.Error("unexpected state, timing or event results")
+	}
+
+	CloseInstance(h)
+	if AddCheatFor(h, "0002:7F") || ReadMemoryFor(h, 0, 1) != nil || StartInputRecordingFor(h) {
+		t.Error("closed handle reported success")
+	}
+}
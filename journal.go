@@ -0,0 +1,59 @@
+package ios
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// journalLimit is the number of entries kept before the oldest are dropped.
+const journalLimit = 256
+
+// journalEntry is a diagnostic message recorded by the bridge.
+type journalEntry struct {
+	Time    int64  `json:"time"` // unix milliseconds
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+var (
+	journalMu sync.Mutex
+	journal   []journalEntry
+)
+
+// journalf records a diagnostic message.
+func journalf(level string, format string, args ...any) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	if len(journal) == journalLimit {
+		journal = append(journal[:0], journal[1:]...)
+	}
+	journal = append(journal, journalEntry{
+		Time:    time.Now().UnixMilli(),
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// JournalJSON returns the bridge's diagnostic messages, oldest first, as a
+// JSON array of {"time", "level", "message"} objects.
+func JournalJSON() string {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	if len(journal) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// ClearJournal discards all journal entries.
+func ClearJournal() {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	journal = nil
+}
@@ -0,0 +1,366 @@
+package ios
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Pause stops emulation: RunFrame and RunFrames do nothing until Resume.
+// The last frame of audio is faded out so a buffer still playing ends
+// without a click. Safe to call from any thread.
+func Pause() {
+	def.pause()
+}
+
+func (in *instance) pause() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.pauseLocked()
+}
+
+func (in *instance) pauseLocked() {
+	if in.paused {
+		return
+	}
+	in.paused = true
+	fadeAudio(in.audioData, false)
+	in.publishAudio()
+}
+
+// Resume restarts emulation after Pause, fading in the first frame's
+// audio. Safe to call from any thread.
+func Resume() {
+	def.resume()
+}
+
+func (in *instance) resume() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if !in.paused {
+		return
+	}
+	in.paused = false
+	in.fadeIn = true
+}
+
+// IsPaused returns whether emulation is paused.
+func IsPaused() bool {
+	return def.isPaused()
+}
+
+func (in *instance) isPaused() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.paused
+}
+
+// StepFrame advances exactly one frame while paused, with the input
+// latched from SetInput, and returns its timing:
+// {"frame", "totalMs", "stages": [{"name", "lastMs"}]}.
+// Idle skipping, fast-forward and run-ahead do not apply, and the frame's
+// audio is dropped. Emulation stays paused. Returns "{}" if no ROM is
+// loaded or emulation is not paused (see LastError).
+func StepFrame() string {
+	return def.stepFrame()
+}
+
+func (in *instance) stepFrame() string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil {
+		setLastError("no ROM loaded")
+		return "{}"
+	}
+	if !in.paused {
+		setLastError("StepFrame requires emulation to be paused")
+		return "{}"
+	}
+
+	start := time.Now()
+	in.latchInputs()
+	in.audioData = in.audioData[:0]
+	in.runPipeline(framePass{render: true, last: true})
+	in.publishFrame()
+	in.audioData = nil
+	in.publishAudio()
+	total := time.Since(start)
+
+	data, err := json.Marshal(struct {
+		Frame   int64         `json:"frame"`
+		TotalMs float64       `json:"totalMs"`
+		Stages  []stageTiming `json:"stages"`
+	}{in.frameCount, durationMs(total), in.activeStages()})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// fadeInAudio ramps up the frame's audio after a resume.
+func (in *instance) fadeInAudio() {
+	fadeAudio(in.audioData, true)
+	in.fadeIn = false
+}
+
+// fadeAudio applies a linear ramp to little-endian int16 PCM in place,
+// rising from silence if in is set and falling to silence otherwise.
+func fadeAudio(pcm []byte, in bool) {
+	n := len(pcm) / 2
+	for i := 0; i < n; i++ {
+		gain := float64(i) / float64(n)
+		if !in {
+			gain = 1 - gain
+		}
+		s := int16(uint16(pcm[i*2]) | uint16(pcm[i*2+1])<<8)
+		s = int16(float64(s) * gain)
+		pcm[i*2] = byte(s)
+		pcm[i*2+1] = byte(s >> 8)
+	}
+}
+
+// snapshotPaths returns the autosave state and SRAM files for the loaded
+// ROM in dir, named by its CRC32.
+func (in *instance) snapshotPaths(dir string) (state, sram string) {
+	base := filepath.Join(dir, fmt.Sprintf("%08X", crc32.ChecksumIEEE(in.rom)))
+	return base + ".autosave", base + ".srm"
+}
+
+// PauseAndSnapshot pauses and writes an autosave state and the SRAM to
+// stateDir, for when iOS may end the process in the background.
+// Returns true if everything the core supports was written.
+func PauseAndSnapshot(stateDir string) bool {
+	return def.pauseAndSnapshot(stateDir)
+}
+
+func (in *instance) pauseAndSnapshot(stateDir string) bool {
+	in.mu.Lock()
+	if in.emu == nil {
+		in.mu.Unlock()
+		return false
+	}
+	in.pauseLocked()
+	in.mu.Unlock()
+
+	// Paused, so no frame runs while the snapshot is written.
+	statePath, sramPath := in.snapshotPaths(stateDir)
+	ok := true
+	if in.saveStater != nil && !in.writeStateWithMetadata(statePath) {
+		ok = false
+	}
+	if in.hasSRAM() {
+		data := in.batterySaver.GetSRAM()
+		if err := writeFileAtomic(sramPath, data); err != nil {
+			setLastError("failed to write SRAM: %v", err)
+			ok = false
+		} else {
+			in.markSRAMClean(crc32.ChecksumIEEE(data))
+		}
+	}
+	return ok
+}
+
+// ResumeFromSnapshot restores the SRAM and autosave state PauseAndSnapshot
+// wrote to stateDir for the loaded ROM, then resumes. Call after Init.
+// Returns true if a snapshot was restored.
+func ResumeFromSnapshot(stateDir string) bool {
+	return def.resumeFromSnapshot(stateDir)
+}
+
+func (in *instance) resumeFromSnapshot(stateDir string) bool {
+	if in.emu == nil {
+		return false
+	}
+
+	statePath, sramPath := in.snapshotPaths(stateDir)
+	restored := false
+	if in.hasSRAM() {
+		if data, err := os.ReadFile(sramPath); err == nil {
+			in.loadSRAM(data)
+			restored = true
+		}
+	}
+	if in.saveStater != nil {
+		if _, err := os.Stat(statePath); err == nil {
+			if !in.loadStateWithMetadata(statePath) {
+				return false
+			}
+			restored = true
+		}
+	}
+	in.resume()
+	return restored
+}
+
+// sessionFile is the session metadata file PersistAll writes.
+const sessionFile = "session.json"
+
+// sessionMetadata describes the session PersistAll saved.
+type sessionMetadata struct {
+	Core    string `json:"core"`
+	ROMCRC  string `json:"romCRC"`
+	ROMPath string `json:"romPath,omitempty"`
+	// PatchPath is the patch InitWithPatch applied, if any.
+	PatchPath string `json:"patchPath,omitempty"`
+	Region    int    `json:"region"`
+	Disc      int    `json:"disc"`
+	Frames    int64  `json:"frames"`
+	SavedAt   int64  `json:"savedAt"`
+	// Options holds the core options set since load.
+	Options map[string]string `json:"options,omitempty"`
+	// State and SRAM name the files written next to the metadata, or
+	// are empty if the core has none.
+	State string `json:"state,omitempty"`
+	SRAM  string `json:"sram,omitempty"`
+}
+
+// PersistAll saves everything needed to survive termination after the
+// app is backgrounded: it pauses, writes the autosave state and SRAM like
+// PauseAndSnapshot, flushes the SetSRAMAutoSave file, and records the
+// session in saveDir/session.json (see SessionMetadataJSON). Emulation
+// stays paused until Resume or ResumeFromSnapshot.
+// Returns true if everything was written.
+func PersistAll(saveDir string) bool {
+	return def.persistAll(saveDir)
+}
+
+func (in *instance) persistAll(saveDir string) bool {
+	in.mu.Lock()
+	if in.emu == nil {
+		in.mu.Unlock()
+		setLastError("no ROM loaded")
+		return false
+	}
+	in.pauseLocked()
+	// Flushed before the snapshot, which marks SRAM clean.
+	ok := true
+	if path := in.sramTracker.autoPath; path != "" && !in.flushSRAMLocked(path) {
+		ok = false
+	}
+	in.mu.Unlock()
+
+	if !in.pauseAndSnapshot(saveDir) {
+		ok = false
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil {
+		return false
+	}
+
+	statePath, sramPath := in.snapshotPaths(saveDir)
+	m := sessionMetadata{
+		ROMCRC:    fmt.Sprintf("%08X", crc32.ChecksumIEEE(in.rom)),
+		ROMPath:   in.romPath,
+		PatchPath: in.patchPath,
+		Region:    in.regionLocked(),
+		Disc:      in.disc,
+		Frames:    in.frameCount,
+		SavedAt:   time.Now().Unix(),
+	}
+	if f := in.coreFactory(); f != nil {
+		m.Core = f.SystemInfo().CoreName
+	}
+	for _, opt := range in.options {
+		if m.Options == nil {
+			m.Options = map[string]string{}
+		}
+		m.Options[opt.key] = opt.value
+	}
+	if in.saveStater != nil {
+		m.State = filepath.Base(statePath)
+	}
+	if in.hasSRAMLocked() {
+		m.SRAM = filepath.Base(sramPath)
+	}
+	data, err := json.Marshal(m)
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(saveDir, sessionFile), data)
+	}
+	if err != nil {
+		setLastError("failed to write session metadata: %v", err)
+		return false
+	}
+	return ok
+}
+
+// SessionMetadataJSON returns the metadata PersistAll last wrote to
+// saveDir: {"core", "romCRC", "romPath", "patchPath", "region", "disc",
+// "frames", "savedAt", "options", "state", "sram"}, or "{}" if there is
+// none. The app can use it at launch to offer resuming the session.
+func SessionMetadataJSON(saveDir string) string {
+	data, err := os.ReadFile(filepath.Join(saveDir, sessionFile))
+	if err != nil || !json.Valid(data) {
+		return "{}"
+	}
+	return string(data)
+}
+
+// SaveSession saves the session to dir for ResumeSession after a
+// relaunch: the ROM path and CRC, region, core options, autosave state
+// and SRAM. It is PersistAll, so emulation is left paused.
+// Returns true if everything was written.
+func SaveSession(dir string) bool {
+	return def.persistAll(dir)
+}
+
+// ResumeSession continues the session SaveSession wrote to dir. If the
+// session's ROM is not already loaded it is loaded from the saved path,
+// with its patch, in the saved region. The saved core options are applied, then the SRAM and
+// autosave state are restored and emulation resumes.
+// Returns true if the session was resumed (see LastError on failure).
+func ResumeSession(dir string) bool {
+	return def.resumeSession(dir)
+}
+
+func (in *instance) resumeSession(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, sessionFile))
+	if err != nil {
+		setLastError("no saved session: %v", err)
+		return false
+	}
+	var m sessionMetadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		setLastError("corrupt session metadata: %v", err)
+		return false
+	}
+
+	if in.emu == nil || fmt.Sprintf("%08X", crc32.ChecksumIEEE(in.rom)) != m.ROMCRC {
+		if m.ROMPath == "" {
+			setLastError("session ROM %s is not loaded and has no path", m.ROMCRC)
+			return false
+		}
+		if m.PatchPath != "" {
+			if !in.initWithPatch(m.ROMPath, m.PatchPath, m.Region) {
+				return false
+			}
+		} else if !in.init(m.ROMPath, m.Region) {
+			return false
+		}
+		if crc := fmt.Sprintf("%08X", crc32.ChecksumIEEE(in.rom)); crc != m.ROMCRC {
+			setLastError("session ROM changed: %s is %s, want %s", m.ROMPath, crc, m.ROMCRC)
+			in.close()
+			return false
+		}
+	}
+
+	keys := make([]string, 0, len(m.Options))
+	for key := range m.Options {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		in.setOption(key, m.Options[key])
+	}
+
+	if !in.resumeFromSnapshot(dir) {
+		setLastError("session snapshot in %s could not be restored", dir)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,246 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func TestPauseGatesRunFrame(t *testing.T) {
+	e := useMockEmulator(t)
+	RunFrame()
+	Pause()
+	if !IsPaused() {
+		t.Fatal("not paused")
+	}
+	RunFrame()
+	RunFrames(4, true)
+	if e.frames != 1 {
+		t.Errorf("frames = %d while paused, want 1", e.frames)
+	}
+	Resume()
+	RunFrame()
+	if e.frames != 2 || IsPaused() {
+		t.Errorf("frames = %d after resume, want 2", e.frames)
+	}
+}
+
+func TestStepFrame(t *testing.T) {
+	e := useMockEmulator(t)
+	e.samples = []int16{100, 100}
+	if StepFrame() != "{}" || LastError() == "" {
+		t.Error("StepFrame ran while not paused")
+	}
+
+	Pause()
+	SetInput(0, 3)
+	var got struct {
+		Frame  int64
+		Stages []stageTiming
+	}
+	if err := json.Unmarshal([]byte(StepFrame()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if e.frames != 1 || got.Frame != 1 || e.inputs[0] != 3 {
+		t.Errorf("step ran %d frames, reported %d, input %#x", e.frames, got.Frame, e.inputs[0])
+	}
+	if len(got.Stages) == 0 || !IsPaused() {
+		t.Errorf("stages = %v, paused = %v", got.Stages, IsPaused())
+	}
+	if GetAudioData() != nil {
+		t.Error("stepped frame published audio")
+	}
+	StepFrame()
+	if e.frames != 2 {
+		t.Errorf("frames = %d after second step, want 2", e.frames)
+	}
+}
+
+func TestPauseResumeFades(t *testing.T) {
+	e := useMockEmulator(t)
+	e.samples = make([]int16, 8)
+	for i := range e.samples {
+		e.samples[i] = 1000
+	}
+	RunFrame()
+	Pause()
+	out := decodePCM(GetAudioData())
+	if out[0] != 1000 || out[len(out)-1] >= 1000/4 {
+		t.Errorf("fade out = %v", out)
+	}
+
+	Resume()
+	RunFrame()
+	in := decodePCM(GetAudioData())
+	if in[0] != 0 || in[len(in)-1] < 1000*3/4 {
+		t.Errorf("fade in = %v", in)
+	}
+	RunFrame()
+	if got := decodePCM(GetAudioData()); got[0] != 1000 {
+		t.Errorf("second frame after resume still faded: %v", got)
+	}
+}
+
+func TestPauseAndSnapshotRoundTrip(t *testing.T) {
+	var e *mockSRAMStateEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockSRAMStateEmulator{mockSRAMEmulator: &mockSRAMEmulator{mockEmulator: newMockEmulator(rom, region), sram: []byte{1, 2}}}
+			return e, nil
+		},
+	})
+	romPath := writeROM(t, "rom.bin", []byte("game"))
+	dir := t.TempDir()
+	if !Init(romPath, 0) {
+		t.Fatal("Init failed")
+	}
+	e.value = 77
+	e.sram = []byte{9, 9}
+	if !PauseAndSnapshot(dir) || !IsPaused() {
+		t.Fatalf("snapshot failed: %s", LastError())
+	}
+	statePath, sramPath := def.snapshotPaths(dir)
+	for _, p := range []string{statePath, sramPath} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("snapshot file missing: %v", err)
+		}
+	}
+
+	if !Init(romPath, 0) {
+		t.Fatal("re-Init failed")
+	}
+	if !ResumeFromSnapshot(dir) || IsPaused() {
+		t.Fatalf("resume failed: %s", LastError())
+	}
+	if e.value != 77 || e.sram[0] != 9 {
+		t.Errorf("restored value = %d, sram = %v", e.value, e.sram)
+	}
+	if ResumeFromSnapshot(t.TempDir()) {
+		t.Error("resumed from an empty directory")
+	}
+}
+
+func TestPersistAll(t *testing.T) {
+	var e *mockSRAMStateEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockSRAMStateEmulator{mockSRAMEmulator: &mockSRAMEmulator{mockEmulator: newMockEmulator(rom, region), sram: []byte{1, 2}}}
+			return e, nil
+		},
+	})
+	dir := t.TempDir()
+	if PersistAll(dir) || SessionMetadataJSON(dir) != "{}" {
+		t.Fatal("PersistAll succeeded without a ROM")
+	}
+	if !Init(writeROM(t, "rom.bin", []byte("game")), 0) {
+		t.Fatal("Init failed")
+	}
+	autoSave := filepath.Join(t.TempDir(), "game.srm")
+	SetSRAMAutoSave(autoSave, 3600)
+	RunFrame()
+	e.sram = []byte{5, 5}
+	if !PersistAll(dir) || !IsPaused() {
+		t.Fatalf("PersistAll failed: %s", LastError())
+	}
+	if data, _ := os.ReadFile(autoSave); !bytes.Equal(data, e.sram) {
+		t.Errorf("auto-save SRAM = %v", data)
+	}
+
+	var m sessionMetadata
+	if err := json.Unmarshal([]byte(SessionMetadataJSON(dir)), &m); err != nil {
+		t.Fatal(err)
+	}
+	statePath, sramPath := def.snapshotPaths(dir)
+	if m.Frames != 1 || m.State != filepath.Base(statePath) || m.SRAM != filepath.Base(sramPath) || m.SavedAt == 0 {
+		t.Errorf("session = %+v", m)
+	}
+	if _, err := os.Stat(statePath); err != nil {
+		t.Errorf("state not written: %v", err)
+	}
+}
+
+// mockSRAMStateEmulator has both SRAM and save states.
+type mockSRAMStateEmulator struct {
+	*mockSRAMEmulator
+	value byte
+}
+
+func (m *mockSRAMStateEmulator) Serialize() ([]byte, error) { return []byte{m.value}, nil }
+func (m *mockSRAMStateEmulator) Deserialize(data []byte) error {
+	m.value = data[0]
+	return nil
+}
+
+func TestPauseRunFrameRace(t *testing.T) {
+	e := useMockEmulator(t)
+	e.samples = make([]int16, 64)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			RunFrame()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			Pause()
+			Resume()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			IsPaused()
+			RunFrames(2, false)
+		}
+	}()
+	wg.Wait()
+}
+
+func TestSaveAndResumeSession(t *testing.T) {
+	var e *mockSRAMStateEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockSRAMStateEmulator{mockSRAMEmulator: &mockSRAMEmulator{mockEmulator: newMockEmulator(rom, region), sram: []byte{1, 2}}}
+			return e, nil
+		},
+	})
+	dir := t.TempDir()
+	if ResumeSession(dir) {
+		t.Fatal("resumed without a saved session")
+	}
+	romPath := writeROM(t, "rom.bin", []byte("game"))
+	if !Init(romPath, 1) {
+		t.Fatal("Init failed")
+	}
+	SetOption("opt_video", "true")
+	e.value = 42
+	if !SaveSession(dir) {
+		t.Fatalf("SaveSession failed: %s", LastError())
+	}
+
+	// A relaunch starts with nothing loaded.
+	Close()
+	if !ResumeSession(dir) {
+		t.Fatalf("ResumeSession failed: %s", LastError())
+	}
+	if IsPaused() || e.value != 42 || e.region != emucore.RegionPAL || e.options["opt_video"] != "true" {
+		t.Errorf("resumed value = %d, region = %v, options = %v", e.value, e.region, e.options)
+	}
+
+	if err := os.WriteFile(romPath, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	Close()
+	if ResumeSession(dir) {
+		t.Error("resumed with a different ROM at the saved path")
+	}
+}
@@ -0,0 +1,257 @@
+package ios
+
+import "sync"
+
+// LinkPort is an optional emulator interface for cores that can talk to a
+// second instance over an emulated link cable.
+type LinkPort interface {
+	// LinkSend returns the bytes the core transmitted during the last frame.
+	LinkSend() []byte
+	// LinkReceive delivers bytes transmitted by the peer instance.
+	LinkReceive(data []byte)
+}
+
+// Link session sides.
+const (
+	LinkSideA = 0
+	LinkSideB = 1
+)
+
+// linkRoute is the side and side-local player that receives input for
+// a frontend player.
+type linkRoute struct {
+	side   int
+	player int
+}
+
+// linkSession holds two instances stepped in lockstep with their
+// LinkPorts wired together.
+type linkSession struct {
+	sides [2]*instance
+	ports [2]LinkPort
+
+	// pending[i] holds bytes queued for delivery to side i at the
+	// next frame boundary.
+	pending [2][]byte
+
+	routes map[int]linkRoute
+}
+
+// linkedMu guards linked and the session's pending bytes and routes. It
+// is held for a whole RunLinkedFrame so sessions are not closed or
+// replaced mid-frame.
+var (
+	linkedMu sync.Mutex
+	linked   *linkSession
+)
+
+// CreateLinkedSession loads two ROMs into separate instances and connects
+// their link ports. Both cores must implement LinkPort. Any existing
+// linked session is closed first.
+// regionCode: 0=NTSC, 1=PAL
+// Returns true on success.
+func CreateLinkedSession(romPathA, romPathB string, regionCode int) bool {
+	CloseLinkedSession()
+
+	s := &linkSession{
+		routes: map[int]linkRoute{
+			0: {side: LinkSideA, player: 0},
+			1: {side: LinkSideB, player: 0},
+		},
+	}
+	for i, path := range []string{romPathA, romPathB} {
+		in := &instance{}
+		if !in.init(path, regionCode) {
+			s.close()
+			return false
+		}
+		s.sides[i] = in
+
+		port, ok := in.emu.(LinkPort)
+		if !ok {
+			s.close()
+			return false
+		}
+		s.ports[i] = port
+	}
+
+	linkedMu.Lock()
+	old := linked
+	linked = s
+	linkedMu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	return true
+}
+
+// CloseLinkedSession releases both instances of the linked session.
+func CloseLinkedSession() {
+	linkedMu.Lock()
+	s := linked
+	linked = nil
+	linkedMu.Unlock()
+	if s != nil {
+		s.close()
+	}
+}
+
+func (s *linkSession) close() {
+	for _, in := range s.sides {
+		if in != nil {
+			in.close()
+		}
+	}
+}
+
+// HasLinkedSession returns whether a linked session is active.
+func HasLinkedSession() bool {
+	linkedMu.Lock()
+	defer linkedMu.Unlock()
+	return linked != nil
+}
+
+// RunLinkedFrame executes exactly one emulated frame on each side. Bytes
+// sent by either side during a frame are delivered to the other side
+// before the next frame, so both sides always see the same frame
+// boundaries. Pause, idle skip, fast-forward and run-ahead do not apply
+// to linked sides, since any of them would step one side differently
+// from the other.
+func RunLinkedFrame() {
+	linkedMu.Lock()
+	defer linkedMu.Unlock()
+	if linked == nil {
+		return
+	}
+	linked.runFrame()
+}
+
+// runFrame steps both sides once. linkedMu must be held.
+func (s *linkSession) runFrame() {
+	var sent [2][]byte
+	for i, in := range s.sides {
+		data, ok := in.runLinkedFrame(s.ports[i], s.pending[i])
+		if !ok {
+			// The side did not run, so it neither consumed its
+			// queue nor has anything new to send.
+			continue
+		}
+		s.pending[i] = nil
+		sent[i] = data
+	}
+	// Queue after both sides ran so neither sees the other's bytes
+	// from the frame it is on.
+	for i, data := range sent {
+		if len(data) > 0 {
+			peer := 1 - i
+			s.pending[peer] = append(s.pending[peer], data...)
+		}
+	}
+}
+
+// runLinkedFrame delivers received to port, runs one frame through the
+// pipeline and returns what the core sent during it. ok is false if no
+// core is loaded, in which case nothing was delivered or sent.
+func (in *instance) runLinkedFrame(port LinkPort, received []byte) (sent []byte, ok bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil {
+		return nil, false
+	}
+	if len(received) > 0 {
+		port.LinkReceive(received)
+	}
+	in.latchInputs()
+	in.audioData = in.audioData[:0]
+	in.runPipeline(framePass{render: true, last: true})
+	in.finishAudio()
+	in.publishFrame()
+	in.publishAudio()
+	return port.LinkSend(), true
+}
+
+// SetLinkInputRoute routes a frontend player's input to the given side
+// and side-local player. Returns false if side is invalid.
+// By default player 0 drives side A and player 1 drives side B.
+func SetLinkInputRoute(player, side, sidePlayer int) bool {
+	linkedMu.Lock()
+	defer linkedMu.Unlock()
+	if linked == nil || side < LinkSideA || side > LinkSideB || sidePlayer < 0 {
+		return false
+	}
+	linked.routes[player] = linkRoute{side: side, player: sidePlayer}
+	return true
+}
+
+// SetLinkedInput sets controller state for a frontend player, delivering
+// it to the side configured by SetLinkInputRoute.
+func SetLinkedInput(player int, buttons int) {
+	linkedMu.Lock()
+	if linked == nil {
+		linkedMu.Unlock()
+		return
+	}
+	route, ok := linked.routes[player]
+	in := linked.sides[route.side]
+	linkedMu.Unlock()
+	if ok {
+		in.setInput(route.player, buttons)
+	}
+}
+
+// linkedSide returns the instance for side, or nil if there is no
+// linked session or side is invalid.
+func linkedSide(side int) *instance {
+	linkedMu.Lock()
+	defer linkedMu.Unlock()
+	if linked == nil || side < LinkSideA || side > LinkSideB {
+		return nil
+	}
+	return linked.sides[side]
+}
+
+// GetLinkedFrameData returns the active display area frame buffer for a side.
+func GetLinkedFrameData(side int) []byte {
+	in := linkedSide(side)
+	if in == nil {
+		return nil
+	}
+	return in.fetchFrame()
+}
+
+// GetLinkedAudioData returns a side's audio as int16 stereo PCM
+// little-endian bytes.
+func GetLinkedAudioData(side int) []byte {
+	in := linkedSide(side)
+	if in == nil {
+		return nil
+	}
+	return in.fetchAudio()
+}
+
+// LinkedFrameWidth returns a side's display width in pixels.
+func LinkedFrameWidth(side int) int {
+	in := linkedSide(side)
+	if in == nil {
+		return 0
+	}
+	return in.frameWidth()
+}
+
+// LinkedFrameStride returns a side's framebuffer stride in bytes per row.
+func LinkedFrameStride(side int) int {
+	in := linkedSide(side)
+	if in == nil {
+		return 0
+	}
+	return in.frameStride()
+}
+
+// LinkedFrameHeight returns a side's active display height.
+func LinkedFrameHeight(side int) int {
+	in := linkedSide(side)
+	if in == nil {
+		return 0
+	}
+	return in.frameHeight()
+}
@@ -0,0 +1,162 @@
+package ios
+
+import (
+	"bytes"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockLinkEmulator sends its ROM's first byte plus the frame number each
+// frame and records everything it receives.
+type mockLinkEmulator struct {
+	*mockEmulator
+	received [][]byte
+}
+
+func (m *mockLinkEmulator) LinkSend() []byte {
+	return []byte{m.rom[0], byte(m.frames)}
+}
+
+func (m *mockLinkEmulator) LinkReceive(data []byte) {
+	m.received = append(m.received, append([]byte(nil), data...))
+}
+
+func useLinkFactory(t *testing.T) *[]*mockLinkEmulator {
+	t.Helper()
+	var created []*mockLinkEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e := &mockLinkEmulator{mockEmulator: newMockEmulator(rom, region)}
+			created = append(created, e)
+			return e, nil
+		},
+	})
+	t.Cleanup(CloseLinkedSession)
+	return &created
+}
+
+func TestLinkedSessionDeliveryOrdering(t *testing.T) {
+	created := useLinkFactory(t)
+
+	pathA := writeROM(t, "a.bin", []byte{0xA0})
+	pathB := writeROM(t, "b.bin", []byte{0xB0})
+	if !CreateLinkedSession(pathA, pathB, 0) {
+		t.Fatal("CreateLinkedSession failed")
+	}
+	a, b := (*created)[0], (*created)[1]
+
+	for i := 0; i < 3; i++ {
+		RunLinkedFrame()
+	}
+
+	if a.frames != 3 || b.frames != 3 {
+		t.Fatalf("frames = %d/%d, want 3/3", a.frames, b.frames)
+	}
+
+	// Bytes sent during frame N arrive before frame N+1.
+	wantA := [][]byte{{0xB0, 1}, {0xB0, 2}}
+	wantB := [][]byte{{0xA0, 1}, {0xA0, 2}}
+	for _, tc := range []struct {
+		name string
+		got  [][]byte
+		want [][]byte
+	}{
+		{"A", a.received, wantA},
+		{"B", b.received, wantB},
+	} {
+		if len(tc.got) != len(tc.want) {
+			t.Fatalf("side %s received %d messages, want %d", tc.name, len(tc.got), len(tc.want))
+		}
+		for i := range tc.want {
+			if !bytes.Equal(tc.got[i], tc.want[i]) {
+				t.Errorf("side %s message %d = %v, want %v", tc.name, i, tc.got[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestLinkedSessionInputRouting(t *testing.T) {
+	created := useLinkFactory(t)
+
+	pathA := writeROM(t, "a.bin", []byte{0xA0})
+	pathB := writeROM(t, "b.bin", []byte{0xB0})
+	if !CreateLinkedSession(pathA, pathB, 0) {
+		t.Fatal("CreateLinkedSession failed")
+	}
+	a, b := (*created)[0], (*created)[1]
+
+	SetLinkedInput(0, 0x1)
+	SetLinkedInput(1, 0x2)
+	RunLinkedFrame()
+	if a.inputs[0] != 0x1 || b.inputs[0] != 0x2 {
+		t.Errorf("default routing: A=%#x B=%#x, want 0x1 0x2", a.inputs[0], b.inputs[0])
+	}
+
+	if !SetLinkInputRoute(0, LinkSideB, 1) {
+		t.Fatal("SetLinkInputRoute failed")
+	}
+	SetLinkedInput(0, 0x4)
+	RunLinkedFrame()
+	if b.inputs[1] != 0x4 {
+		t.Errorf("rerouted input: B player 1 = %#x, want 0x4", b.inputs[1])
+	}
+	if SetLinkInputRoute(0, 2, 0) {
+		t.Error("SetLinkInputRoute accepted invalid side")
+	}
+}
+
+func TestLinkedSessionRequiresLinkPort(t *testing.T) {
+	var created []*mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e := newMockEmulator(rom, region)
+			created = append(created, e)
+			return e, nil
+		},
+	})
+
+	pathA := writeROM(t, "a.bin", []byte{0xA0})
+	pathB := writeROM(t, "b.bin", []byte{0xB0})
+	if CreateLinkedSession(pathA, pathB, 0) {
+		t.Fatal("CreateLinkedSession succeeded without LinkPort")
+	}
+	if HasLinkedSession() {
+		t.Error("HasLinkedSession = true after failure")
+	}
+	for i, e := range created {
+		if !e.closed {
+			t.Errorf("emulator %d not closed after failure", i)
+		}
+	}
+	if GetLinkedFrameData(LinkSideA) != nil {
+		t.Error("GetLinkedFrameData returned data without a session")
+	}
+}
+
+func TestLinkedSessionStepsSymmetrically(t *testing.T) {
+	created := useLinkFactory(t)
+
+	pathA := writeROM(t, "a.bin", []byte{0xA0})
+	pathB := writeROM(t, "b.bin", []byte{0xB0})
+	if !CreateLinkedSession(pathA, pathB, 0) {
+		t.Fatal("CreateLinkedSession failed")
+	}
+	a, b := (*created)[0], (*created)[1]
+
+	// Per-instance pacing must not make one side step differently.
+	linked.sides[LinkSideA].paused = true
+	linked.sides[LinkSideB].fastForward = 4
+	RunLinkedFrame()
+	RunLinkedFrame()
+	if a.frames != 2 || b.frames != 2 {
+		t.Fatalf("frames = %d/%d, want 2/2", a.frames, b.frames)
+	}
+
+	// A side that did not run sends nothing.
+	linked.sides[LinkSideA].close()
+	RunLinkedFrame()
+	if b.frames != 3 || len(b.received) != 2 {
+		t.Errorf("after closing A: B frames = %d, received %v", b.frames, b.received)
+	}
+}
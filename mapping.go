@@ -0,0 +1,130 @@
+package ios
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// buttonMapping maps frontend button bits to core button bits; -1 drops
+// the button. Bits without an entry pass through.
+type buttonMapping map[int]int
+
+func parseButtonMapping(mappingJSON string) (buttonMapping, error) {
+	var m buttonMapping
+	if err := json.Unmarshal([]byte(mappingJSON), &m); err != nil {
+		return nil, err
+	}
+	for from, to := range m {
+		if from < 0 || from > 31 {
+			return nil, fmt.Errorf("button bit %d out of range", from)
+		}
+		if to < -1 || to > 31 {
+			return nil, fmt.Errorf("button %d mapped to bit %d, out of range", from, to)
+		}
+	}
+	return m, nil
+}
+
+// apply translates a frontend button mask to the core's.
+func (m buttonMapping) apply(buttons uint32) uint32 {
+	var out uint32
+	for bit := 0; bit < 32; bit++ {
+		if buttons&(1<<bit) == 0 {
+			continue
+		}
+		to, ok := m[bit]
+		if !ok {
+			to = bit
+		}
+		if to >= 0 {
+			out |= 1 << to
+		}
+	}
+	return out
+}
+
+// SetButtonMapping sets how a player's SetInput buttons translate to the
+// core's. mappingJSON maps frontend bit numbers to core bit numbers, as
+// in SystemInfo Buttons IDs, with -1 dropping a button:
+//
+//	{"4": 5, "5": 4, "7": -1}
+//
+// Buttons not listed pass through, and several may map to one core
+// button. An empty object removes the mapping. Mappings are replaced by
+// the game's saved ones (see SaveButtonMappings) at Init.
+// Returns false, leaving the mapping unchanged, if the JSON is invalid
+// (see LastError).
+func SetButtonMapping(player int, mappingJSON string) bool {
+	return def.setButtonMapping(player, mappingJSON)
+}
+
+func (in *instance) setButtonMapping(player int, mappingJSON string) bool {
+	m, err := parseButtonMapping(mappingJSON)
+	if err != nil {
+		setLastError("invalid button mapping: %v", err)
+		return false
+	}
+	in.inputMu.Lock()
+	defer in.inputMu.Unlock()
+	if len(m) == 0 {
+		delete(in.buttonMaps, player)
+		return true
+	}
+	if in.buttonMaps == nil {
+		in.buttonMaps = map[int]buttonMapping{}
+	}
+	in.buttonMaps[player] = m
+	return true
+}
+
+// GetButtonMappingJSON returns a player's mapping as set with
+// SetButtonMapping, or "{}" if there is none.
+func GetButtonMappingJSON(player int) string {
+	def.inputMu.Lock()
+	m := def.buttonMaps[player]
+	def.inputMu.Unlock()
+	if m == nil {
+		return "{}"
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// SaveButtonMappings writes every player's mapping to the "buttonMappings"
+// section of the loaded game's per-game options file, to be applied at
+// its next Init. Returns false if no per-game directory is set, no ROM is
+// loaded, or the write fails (see LastError).
+func SaveButtonMappings() bool {
+	return def.saveButtonMappings()
+}
+
+func (in *instance) saveButtonMappings() bool {
+	in.inputMu.Lock()
+	maps := make(map[int]buttonMapping, len(in.buttonMaps))
+	for player, m := range in.buttonMaps {
+		maps[player] = m
+	}
+	in.inputMu.Unlock()
+	return in.writePerGameSection("buttonMappings", maps)
+}
+
+// loadButtonMappings replaces the mappings with the loaded game's saved
+// ones, if any.
+func (in *instance) loadButtonMappings() {
+	var maps map[int]buttonMapping
+	sections, err := in.readPerGameConfig()
+	if err != nil {
+		journalf("warning", "per-game config unreadable: %v", err)
+	} else if raw, ok := sections["buttonMappings"]; ok {
+		if err := json.Unmarshal(raw, &maps); err != nil {
+			journalf("warning", "per-game button mappings ignored")
+			maps = nil
+		}
+	}
+	in.inputMu.Lock()
+	in.buttonMaps = maps
+	in.inputMu.Unlock()
+}
@@ -0,0 +1,61 @@
+package ios
+
+import "testing"
+
+func TestButtonMappingTranslatesInput(t *testing.T) {
+	e := useMockEmulator(t)
+	if !SetButtonMapping(0, `{"4": 5, "5": 4, "7": -1, "8": 4}`) {
+		t.Fatal(LastError())
+	}
+	SetInput(0, 1<<4|1<<7|1<<1)
+	SetInput(1, 1<<4)
+	RunFrame()
+	if e.inputs[0] != 1<<5|1<<1 {
+		t.Errorf("player 0 input = %#x, want %#x", e.inputs[0], 1<<5|1<<1)
+	}
+	if e.inputs[1] != 1<<4 {
+		t.Errorf("unmapped player input = %#x, want %#x", e.inputs[1], 1<<4)
+	}
+
+	SetInput(0, 1<<8|1<<5)
+	RunFrame()
+	if e.inputs[0] != 1<<4 {
+		t.Errorf("merged input = %#x, want %#x", e.inputs[0], 1<<4)
+	}
+
+	for _, bad := range []string{`{"32": 1}`, `{"4": 40}`, `[1]`} {
+		if SetButtonMapping(0, bad) {
+			t.Errorf("SetButtonMapping accepted %s", bad)
+		}
+	}
+	if GetButtonMappingJSON(0) != `{"4":5,"5":4,"7":-1,"8":4}` {
+		t.Errorf("mapping = %s", GetButtonMappingJSON(0))
+	}
+	SetButtonMapping(0, `{}`)
+	if GetButtonMappingJSON(0) != "{}" {
+		t.Error("empty mapping not removed")
+	}
+}
+
+func TestButtonMappingsPersistPerGame(t *testing.T) {
+	useDisplayConfig(t)
+	useMockEmulator(t)
+	SetButtonMapping(1, `{"4": 6}`)
+	if !SaveDisplayPreferences() || !SaveButtonMappings() {
+		t.Fatal(LastError())
+	}
+
+	path := writeROM(t, "rom.bin", []byte{0x00})
+	if !Init(path, 0) {
+		t.Fatal("Init failed")
+	}
+	if GetButtonMappingJSON(1) != `{"4":6}` {
+		t.Errorf("reloaded mapping = %s", GetButtonMappingJSON(1))
+	}
+	if !Init(writeROM(t, "other.bin", []byte{0x01}), 0) {
+		t.Fatal("Init failed")
+	}
+	if GetButtonMappingJSON(1) != "{}" {
+		t.Errorf("other game mapping = %s", GetButtonMappingJSON(1))
+	}
+}
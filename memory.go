@@ -0,0 +1,241 @@
+package ios
+
+import (
+	"encoding/json"
+	"math"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// MemoryRangeWriter is an optional emulator interface for cores that can
+// write part of a MemoryMapper region in place. Without it the bridge
+// writes a range by reading the whole region and writing it back.
+type MemoryRangeWriter interface {
+	// WriteRegionAt writes data at offset within the region.
+	WriteRegionAt(regionType int, offset int, data []byte)
+}
+
+// memoryRegion is a MemoryMapper region placed in the bridge's flat
+// address space. Regions are laid out back to back in MemoryMap order.
+type memoryRegion struct {
+	Name string `json:"name"`
+	Base int    `json:"base"`
+	Size int    `json:"size"`
+
+	regionType int
+}
+
+// memoryRegionName returns the display name for a MemoryMapper region type.
+func memoryRegionName(t int) string {
+	switch t {
+	case emucore.MemorySaveRAM:
+		return "SaveRAM"
+	case emucore.MemorySystemRAM:
+		return "SystemRAM"
+	default:
+		return "Unknown"
+	}
+}
+
+// memoryRegions returns the flat layout of the core's memory map.
+// in.mu must be held.
+func (in *instance) memoryRegions() []memoryRegion {
+	if in.memMapper == nil {
+		return nil
+	}
+	var regions []memoryRegion
+	base := 0
+	for _, r := range in.memMapper.MemoryMap() {
+		regions = append(regions, memoryRegion{
+			Name:       memoryRegionName(r.Type),
+			Base:       base,
+			Size:       r.Size,
+			regionType: r.Type,
+		})
+		base += r.Size
+	}
+	return regions
+}
+
+// findMemoryRegion returns the region containing [address, address+length).
+// The bounds are checked without adding, so huge lengths cannot overflow.
+func (in *instance) findMemoryRegion(address, length int) (memoryRegion, bool) {
+	for _, r := range in.memoryRegions() {
+		if address >= r.Base && length <= r.Size && address-r.Base <= r.Size-length {
+			return r, true
+		}
+	}
+	return memoryRegion{}, false
+}
+
+// HasMemoryAccess returns whether the emulator exposes its memory.
+func HasMemoryAccess() bool {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.hasMemoryAccess()
+}
+
+// hasMemoryAccess reports whether the core exposes memory. in.mu must be
+// held.
+func (in *instance) hasMemoryAccess() bool {
+	return in.memMapper != nil || in.memInspector != nil
+}
+
+// MemoryRegionsJSON returns the available memory regions as a JSON array
+// of {"name", "base", "size"} objects. Addresses passed to ReadMemory and
+// WriteMemoryByte are relative to this layout.
+func MemoryRegionsJSON() string {
+	def.mu.Lock()
+	regions := def.memoryRegions()
+	def.mu.Unlock()
+	if regions == nil {
+		return "[]"
+	}
+	data, err := json.Marshal(regions)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// ReadMemory reads length bytes starting at address.
+// Returns an empty slice if the range is out of bounds or memory
+// access is unsupported.
+func ReadMemory(address int, length int) []byte {
+	return def.readMemory(address, length)
+}
+
+func (in *instance) readMemory(address int, length int) []byte {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.readMemoryLocked(address, length)
+}
+
+// readMemoryLocked is readMemory with in.mu held.
+func (in *instance) readMemoryLocked(address int, length int) []byte {
+	if address < 0 || length <= 0 {
+		return nil
+	}
+
+	if in.memMapper != nil {
+		r, ok := in.findMemoryRegion(address, length)
+		if !ok {
+			return nil
+		}
+		data := in.memMapper.ReadRegion(r.regionType)
+		off := address - r.Base
+		if length > len(data) || off > len(data)-length {
+			return nil
+		}
+		return data[off : off+length]
+	}
+
+	if in.memInspector != nil {
+		// The inspector addresses 32 bits.
+		if int64(address) > math.MaxUint32 || int64(length) > math.MaxUint32+1-int64(address) {
+			return nil
+		}
+		buf := make([]byte, length)
+		n := in.memInspector.ReadMemory(uint32(address), buf)
+		return buf[:n]
+	}
+	return nil
+}
+
+// ReadMemoryByte reads a single byte at address. Returns 0 if the address
+// is out of bounds or memory access is unsupported.
+func ReadMemoryByte(address int) int {
+	data := def.readMemory(address, 1)
+	if len(data) == 0 {
+		return 0
+	}
+	return int(data[0])
+}
+
+// WriteMemoryByte writes a single byte at address.
+// Returns false if the address is out of bounds or the core does not
+// support memory writes.
+func WriteMemoryByte(address int, value int) bool {
+	return def.writeMemory(address, []byte{byte(value)})
+}
+
+func (in *instance) writeMemory(address int, data []byte) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.writeMemoryLocked(address, data)
+}
+
+// writeMemoryLocked is writeMemory with in.mu held.
+func (in *instance) writeMemoryLocked(address int, data []byte) bool {
+	if in.memMapper == nil || address < 0 || len(data) == 0 {
+		return false
+	}
+	r, ok := in.findMemoryRegion(address, len(data))
+	if !ok {
+		return false
+	}
+	return in.writeRegionAt(r.regionType, address-r.Base, data)
+}
+
+// writeRegionAt writes data at off within a region, in place if the core
+// implements MemoryRangeWriter. Returns false if the range does not fit
+// the region. in.mu must be held.
+func (in *instance) writeRegionAt(regionType, off int, data []byte) bool {
+	if w, ok := in.emu.(MemoryRangeWriter); ok {
+		w.WriteRegionAt(regionType, off, data)
+		return true
+	}
+	region := in.memMapper.ReadRegion(regionType)
+	if off < 0 || len(data) > len(region) || off > len(region)-len(data) {
+		return false
+	}
+	copy(region[off:], data)
+	in.memMapper.WriteRegion(regionType, region)
+	return true
+}
+
+// WriteMemory writes data starting at address. The write must fall
+// within one region.
+// Returns false if the range is out of bounds or the core does not
+// support memory writes.
+func WriteMemory(address int, data []byte) bool {
+	return def.writeMemory(address, data)
+}
+
+// regionBase returns the flat base address of the named region, as listed
+// by MemoryRegionsJSON, and its size. in.mu must be held.
+func (in *instance) regionBase(name string) (base, size int, ok bool) {
+	for _, r := range in.memoryRegions() {
+		if r.Name == name {
+			return r.Base, r.Size, true
+		}
+	}
+	return 0, 0, false
+}
+
+// ReadRegionMemory reads length bytes at addr within the named region
+// ("SystemRAM" or "SaveRAM", see MemoryRegionsJSON).
+// Returns an empty slice if the region is unknown or the range is out of
+// bounds.
+func ReadRegionMemory(region string, addr int, length int) []byte {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	base, size, ok := def.regionBase(region)
+	if !ok || addr < 0 || length <= 0 || addr > size || length > size-addr {
+		return nil
+	}
+	return def.readMemoryLocked(base+addr, length)
+}
+
+// WriteRegionMemory writes data at addr within the named region.
+// Returns false if the region is unknown, the range is out of bounds or
+// the core does not support memory writes.
+func WriteRegionMemory(region string, addr int, data []byte) bool {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	base, size, ok := def.regionBase(region)
+	if !ok || addr < 0 || addr > size || len(data) > size-addr {
+		return false
+	}
+	return def.writeMemoryLocked(base+addr, data)
+}
@@ -0,0 +1,226 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockMemoryEmulator exposes 8 bytes of system RAM and 4 bytes of save RAM.
+type mockMemoryEmulator struct {
+	*mockEmulator
+	ram  []byte
+	sram []byte
+}
+
+func (m *mockMemoryEmulator) MemoryMap() []emucore.MemoryRegion {
+	return []emucore.MemoryRegion{
+		{Type: emucore.MemorySystemRAM, Size: len(m.ram)},
+		{Type: emucore.MemorySaveRAM, Size: len(m.sram)},
+	}
+}
+
+func (m *mockMemoryEmulator) region(t int) *[]byte {
+	if t == emucore.MemorySaveRAM {
+		return &m.sram
+	}
+	return &m.ram
+}
+
+func (m *mockMemoryEmulator) ReadRegion(t int) []byte {
+	return append([]byte(nil), *m.region(t)...)
+}
+
+func (m *mockMemoryEmulator) WriteRegion(t int, data []byte) {
+	copy(*m.region(t), data)
+}
+
+func useMemoryEmulator(t *testing.T) *mockMemoryEmulator {
+	t.Helper()
+	var e *mockMemoryEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockMemoryEmulator{
+				mockEmulator: newMockEmulator(rom, region),
+				ram:          []byte{0, 1, 2, 3, 4, 5, 6, 7},
+				sram:         []byte{0xA0, 0xA1, 0xA2, 0xA3},
+			}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func TestMemoryRegionsJSON(t *testing.T) {
+	useMemoryEmulator(t)
+
+	var regions []memoryRegion
+	if err := json.Unmarshal([]byte(MemoryRegionsJSON()), &regions); err != nil {
+		t.Fatal(err)
+	}
+	want := []memoryRegion{
+		{Name: "SystemRAM", Base: 0, Size: 8},
+		{Name: "SaveRAM", Base: 8, Size: 4},
+	}
+	if len(regions) != len(want) {
+		t.Fatalf("got %d regions, want %d", len(regions), len(want))
+	}
+	for i := range want {
+		if regions[i] != want[i] {
+			t.Errorf("region %d = %+v, want %+v", i, regions[i], want[i])
+		}
+	}
+}
+
+func TestReadWriteMemory(t *testing.T) {
+	e := useMemoryEmulator(t)
+
+	if !HasMemoryAccess() {
+		t.Fatal("HasMemoryAccess = false")
+	}
+	if got := ReadMemory(2, 3); !bytes.Equal(got, []byte{2, 3, 4}) {
+		t.Errorf("ReadMemory(2, 3) = %v", got)
+	}
+	if got := ReadMemoryByte(9); got != 0xA1 {
+		t.Errorf("ReadMemoryByte(9) = %#x, want 0xa1", got)
+	}
+	if !WriteMemoryByte(10, 0x55) {
+		t.Fatal("WriteMemoryByte failed")
+	}
+	if e.sram[2] != 0x55 {
+		t.Errorf("sram[2] = %#x, want 0x55", e.sram[2])
+	}
+
+	// Out of range and region-spanning accesses are rejected.
+	if got := ReadMemory(6, 4); len(got) != 0 {
+		t.Errorf("spanning read = %v, want empty", got)
+	}
+	if got := ReadMemory(100, 1); len(got) != 0 {
+		t.Errorf("out of range read = %v, want empty", got)
+	}
+	if got := ReadMemoryByte(-1); got != 0 {
+		t.Errorf("negative read = %d, want 0", got)
+	}
+	if WriteMemoryByte(12, 1) {
+		t.Error("out of range write succeeded")
+	}
+}
+
+func TestMemoryRangeOverflow(t *testing.T) {
+	useMemoryEmulator(t)
+
+	// Ranges whose end overflows int are out of bounds, not a panic.
+	for _, tc := range [][2]int{{1, math.MaxInt}, {math.MaxInt, 1}, {math.MaxInt, math.MaxInt}} {
+		if got := ReadMemory(tc[0], tc[1]); len(got) != 0 {
+			t.Errorf("ReadMemory(%d, %d) = %v, want empty", tc[0], tc[1], got)
+		}
+	}
+	if got := ReadRegionMemory("SystemRAM", 1, math.MaxInt); got != nil {
+		t.Errorf("ReadRegionMemory(SystemRAM, 1, math.MaxInt) = %v", got)
+	}
+	if got := ReadRegionMemory("SaveRAM", math.MaxInt, 1); got != nil {
+		t.Errorf("ReadRegionMemory(SaveRAM, math.MaxInt, 1) = %v", got)
+	}
+	if WriteRegionMemory("SystemRAM", math.MaxInt, []byte{1}) {
+		t.Error("WriteRegionMemory at math.MaxInt succeeded")
+	}
+	if WriteMemoryByte(math.MaxInt, 1) {
+		t.Error("write at math.MaxInt succeeded")
+	}
+	def.mu.Lock()
+	ok := def.writeRegionAt(emucore.MemorySystemRAM, math.MaxInt, []byte{1})
+	def.mu.Unlock()
+	if ok {
+		t.Error("writeRegionAt past the region succeeded")
+	}
+}
+
+func TestMemoryAccessUnsupported(t *testing.T) {
+	useMockEmulator(t)
+
+	if HasMemoryAccess() {
+		t.Error("HasMemoryAccess = true")
+	}
+	if MemoryRegionsJSON() != "[]" {
+		t.Errorf("MemoryRegionsJSON = %s", MemoryRegionsJSON())
+	}
+	if len(ReadMemory(0, 1)) != 0 || ReadMemoryByte(0) != 0 || WriteMemoryByte(0, 1) {
+		t.Error("memory access succeeded without support")
+	}
+}
+
+func TestRegionMemory(t *testing.T) {
+	e := useMemoryEmulator(t)
+
+	if got := ReadRegionMemory("SaveRAM", 1, 2); !bytes.Equal(got, []byte{0xA1, 0xA2}) {
+		t.Errorf("ReadRegionMemory(SaveRAM) = % x", got)
+	}
+	if ReadRegionMemory("SaveRAM", 3, 2) != nil || ReadRegionMemory("VRAM", 0, 1) != nil {
+		t.Error("out of range or unknown region read succeeded")
+	}
+
+	if !WriteRegionMemory("SystemRAM", 6, []byte{0xEE, 0xEF}) {
+		t.Fatal("WriteRegionMemory failed")
+	}
+	if !bytes.Equal(e.ram[6:], []byte{0xEE, 0xEF}) {
+		t.Errorf("ram = % x", e.ram)
+	}
+	// A write that would spill from system RAM into save RAM is refused.
+	if WriteRegionMemory("SystemRAM", 7, []byte{1, 2}) || e.sram[0] != 0xA0 {
+		t.Error("write crossed a region boundary")
+	}
+
+	if !WriteMemory(8, []byte{0x01, 0x02}) || !bytes.Equal(e.sram[:2], []byte{0x01, 0x02}) {
+		t.Errorf("WriteMemory flat write, sram = % x", e.sram)
+	}
+}
+
+// mockRangeMemoryEmulator writes ranges in place and counts whole-region
+// writes.
+type mockRangeMemoryEmulator struct {
+	*mockMemoryEmulator
+	regionWrites int
+}
+
+func (m *mockRangeMemoryEmulator) WriteRegion(t int, data []byte) {
+	m.regionWrites++
+	m.mockMemoryEmulator.WriteRegion(t, data)
+}
+
+func (m *mockRangeMemoryEmulator) WriteRegionAt(t int, offset int, data []byte) {
+	copy((*m.region(t))[offset:], data)
+}
+
+func TestWriteMemoryInPlace(t *testing.T) {
+	var e *mockRangeMemoryEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockRangeMemoryEmulator{mockMemoryEmulator: &mockMemoryEmulator{
+				mockEmulator: newMockEmulator(rom, region),
+				ram:          make([]byte, 8),
+				sram:         make([]byte, 4),
+			}}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+
+	if !WriteMemory(1, []byte{0x11, 0x12}) || !WriteMemoryByte(9, 0x99) || !AddCheat("0005:55") {
+		t.Fatal("write failed")
+	}
+	RunFrame()
+	if !bytes.Equal(e.ram, []byte{0, 0x11, 0x12, 0, 0, 0x55, 0, 0}) || e.sram[1] != 0x99 {
+		t.Errorf("ram = % x, sram = % x", e.ram, e.sram)
+	}
+	if e.regionWrites != 0 {
+		t.Errorf("%d whole-region writes, want none", e.regionWrites)
+	}
+}
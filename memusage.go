@@ -0,0 +1,38 @@
+package ios
+
+import "encoding/json"
+
+// MemoryUsageJSON returns the bytes held by the bridge's buffers as a
+// JSON object keyed by buffer name.
+func MemoryUsageJSON() string {
+	usage := def.memoryUsage()
+	total := 0
+	for _, n := range usage {
+		total += n
+	}
+	usage["total"] = total
+
+	data, err := json.Marshal(usage)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+func (in *instance) memoryUsage() map[string]int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	usage := map[string]int{
+		"rom":   len(in.rom),
+		"audio": cap(in.audioData),
+		"state": len(in.stateData),
+		"sram":  len(in.sramData),
+	}
+	if r := in.rewind; r != nil {
+		usage["rewind"] = r.bytes()
+	}
+	if b := in.border; b != nil {
+		usage["border"] = len(b.img.Pix) + len(b.out)
+	}
+	return usage
+}
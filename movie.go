@@ -0,0 +1,363 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+)
+
+// Movie layout, little-endian:
+//
+//	magic     [4]byte "EBIM"
+//	version   uint8
+//	region    uint8
+//	romCRC    uint32
+//	frames    uint32
+//	players   uint8
+//	stateLen  uint32
+//	state     [stateLen]byte
+//	runs      repeated { uvarint count, players × uvarint buttons }
+//
+// Each run repeats one input row for count consecutive frames.
+var movieMagic = [4]byte{'E', 'B', 'I', 'M'}
+
+const (
+	movieVersion    = 1
+	movieHeaderSize = 4 + 1 + 1 + 4 + 4 + 1 + 4
+	maxMoviePlayers = 255
+	// maxMovieFrames bounds a movie, about nine hours at 60 frames per
+	// second, so a hostile frame count cannot exhaust memory on decode.
+	// Recording stops adding frames once it is reached.
+	maxMovieFrames = 1 << 21
+)
+
+var errBadMovie = errors.New("malformed input movie")
+
+// inputMovie is an input recording in progress or being played back.
+type inputMovie struct {
+	region int
+	romCRC uint32
+	state  []byte
+	// rows holds the buttons per player for each frame, indexed by player.
+	rows [][]uint32
+
+	playing bool
+	// pos is the next row to feed during playback.
+	pos int
+	// path, when set, is where StopInputRecording writes the movie.
+	path string
+	// verify checks a recording made with SetMovieRecordingVerify on.
+	verify *movieVerifier
+}
+
+// StartInputRecording starts recording the inputs presented to the core,
+// one row per emulated frame from the next RunFrame on. If the core
+// supports save states, a snapshot is embedded so playback starts from
+// the same point. Any recording or playback in progress is discarded.
+// A recording keeps at most about nine hours of frames.
+// Returns false if no ROM is loaded.
+func StartInputRecording() bool {
+	return def.startInputRecording("")
+}
+
+// startInputRecording starts a recording that stopInputRecording writes
+// to path unless path is empty.
+func (in *instance) startInputRecording(path string) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.movie = nil
+	if in.emu == nil {
+		return false
+	}
+	m := &inputMovie{
+		region: in.regionLocked(),
+		romCRC: crc32.ChecksumIEEE(in.rom),
+		path:   path,
+	}
+	if in.saveStater != nil {
+		state, err := in.saveStater.Serialize()
+		if err != nil {
+			setLastError("input recording: snapshot failed: %v", err)
+			return false
+		}
+		m.state = state
+	}
+	if in.movieVerify {
+		m.verify = newMovieVerifier()
+	}
+	in.movie = m
+	return true
+}
+
+// IsRecordingInput returns whether an input recording is in progress.
+func IsRecordingInput() bool {
+	return def.isRecordingInput()
+}
+
+func (in *instance) isRecordingInput() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.movie != nil && !in.movie.playing
+}
+
+// StopInputRecording ends the recording and returns the serialized movie,
+// or nil if no recording was in progress.
+func StopInputRecording() []byte {
+	return def.stopInputRecording()
+}
+
+func (in *instance) stopInputRecording() []byte {
+	in.mu.Lock()
+	m := in.movie
+	if m == nil || m.playing {
+		in.mu.Unlock()
+		return nil
+	}
+	in.movie = nil
+	in.mu.Unlock()
+
+	// No frame appends to m once it is detached.
+	data := m.encode()
+	if m.path != "" {
+		if err := writeFileAtomic(m.path, data); err != nil {
+			setLastError("input recording: %v", err)
+		}
+	}
+	return data
+}
+
+// StartInputRecordingToFile starts a recording like StartInputRecording
+// that StopInputRecording also writes to path, replacing any file there.
+// Returns false if no ROM is loaded.
+func StartInputRecordingToFile(path string) bool {
+	return def.startInputRecording(path)
+}
+
+// StartInputPlayback plays back a movie from StopInputRecording. The
+// movie must have been recorded on the loaded ROM and region. Its
+// embedded save state, if any, is loaded first. While playback is active
+// SetInput is ignored and each frame receives the recorded inputs until
+// the movie ends. Returns false if the movie is rejected.
+func StartInputPlayback(data []byte) bool {
+	return def.startInputPlayback(data)
+}
+
+func (in *instance) startInputPlayback(data []byte) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.movie = nil
+	if in.emu == nil {
+		return false
+	}
+	m, err := decodeMovie(data)
+	if err != nil {
+		setLastError("input playback: %v", err)
+		return false
+	}
+	if crc := crc32.ChecksumIEEE(in.rom); m.romCRC != crc {
+		setLastError("input playback: movie is for ROM %08x, loaded ROM is %08x", m.romCRC, crc)
+		return false
+	}
+	if region := in.regionLocked(); m.region != region {
+		setLastError("input playback: movie region %d does not match %d", m.region, region)
+		return false
+	}
+	if m.state != nil && !in.loadStateLocked(m.state) {
+		setLastError("input playback: embedded save state rejected")
+		return false
+	}
+
+	if len(m.rows) == 0 {
+		return true
+	}
+	m.playing = true
+	in.movie = m
+	return true
+}
+
+// PlayMovie plays back a movie file written by StopInputRecording, like
+// StartInputPlayback. Returns false if the file cannot be read or the
+// movie is rejected (see LastError).
+func PlayMovie(path string) bool {
+	return def.playMovie(path)
+}
+
+func (in *instance) playMovie(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		in.mu.Lock()
+		in.movie = nil
+		in.mu.Unlock()
+		setLastError("input playback: %v", err)
+		return false
+	}
+	return in.startInputPlayback(data)
+}
+
+// IsPlaybackActive returns whether input playback is feeding frames.
+func IsPlaybackActive() bool {
+	return def.isPlaybackActive()
+}
+
+func (in *instance) isPlaybackActive() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.movie != nil && in.movie.playing
+}
+
+// StopInputPlayback ends playback early and returns input to SetInput.
+func StopInputPlayback() {
+	def.stopInputPlayback()
+}
+
+func (in *instance) stopInputPlayback() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.movie != nil && in.movie.playing {
+		in.movie = nil
+	}
+}
+
+// movieFrame records or feeds the inputs for the frame about to run.
+func (in *instance) movieFrame() {
+	m := in.movie
+	if m == nil {
+		return
+	}
+	if !m.playing {
+		if len(m.rows) < maxMovieFrames {
+			m.rows = append(m.rows, in.inputRow())
+		}
+		return
+	}
+
+	for player, buttons := range m.rows[m.pos] {
+		in.presentInput(player, buttons)
+	}
+	m.pos++
+	if m.pos >= len(m.rows) {
+		in.movie = nil
+	}
+}
+
+// inputRow returns the buttons presented to each player, indexed by
+// player number.
+func (in *instance) inputRow() []uint32 {
+	n := 0
+	for player := range in.inputs {
+		if player >= 0 && player < maxMoviePlayers {
+			n = max(n, player+1)
+		}
+	}
+	row := make([]uint32, n)
+	for i := range row {
+		row[i] = in.inputs[i]
+	}
+	return row
+}
+
+func (m *inputMovie) encode() []byte {
+	players := 0
+	for _, row := range m.rows {
+		players = max(players, len(row))
+	}
+
+	var buf bytes.Buffer
+	buf.Write(movieMagic[:])
+	buf.WriteByte(movieVersion)
+	buf.WriteByte(byte(m.region))
+	binary.Write(&buf, binary.LittleEndian, m.romCRC)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(m.rows)))
+	buf.WriteByte(byte(players))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(m.state)))
+	buf.Write(m.state)
+
+	var tmp [binary.MaxVarintLen64]byte
+	for i := 0; i < len(m.rows); {
+		run := 1
+		for i+run < len(m.rows) && sameRow(m.rows[i], m.rows[i+run]) {
+			run++
+		}
+		buf.Write(binary.AppendUvarint(tmp[:0], uint64(run)))
+		for p := 0; p < players; p++ {
+			var buttons uint32
+			if p < len(m.rows[i]) {
+				buttons = m.rows[i][p]
+			}
+			buf.Write(binary.AppendUvarint(tmp[:0], uint64(buttons)))
+		}
+		i += run
+	}
+	return buf.Bytes()
+}
+
+// sameRow reports whether two rows present the same input, treating
+// missing players as released.
+func sameRow(a, b []uint32) bool {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y uint32
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeMovie(data []byte) (*inputMovie, error) {
+	if len(data) < movieHeaderSize || !bytes.Equal(data[:4], movieMagic[:]) {
+		return nil, errBadMovie
+	}
+	if data[4] != movieVersion {
+		return nil, errors.New("unsupported input movie version")
+	}
+	m := &inputMovie{
+		region: int(data[5]),
+		romCRC: binary.LittleEndian.Uint32(data[6:]),
+	}
+	frames := binary.LittleEndian.Uint32(data[10:])
+	if frames > maxMovieFrames {
+		return nil, errBadMovie
+	}
+	players := int(data[14])
+	stateLen := binary.LittleEndian.Uint32(data[15:])
+	rest := data[movieHeaderSize:]
+	if uint64(stateLen) > uint64(len(rest)) {
+		return nil, errBadMovie
+	}
+	if stateLen > 0 {
+		m.state = rest[:stateLen]
+	}
+	rest = rest[stateLen:]
+
+	for uint32(len(m.rows)) < frames {
+		run, n := binary.Uvarint(rest)
+		if n <= 0 || run == 0 || run > uint64(frames)-uint64(len(m.rows)) {
+			return nil, errBadMovie
+		}
+		rest = rest[n:]
+		row := make([]uint32, players)
+		for p := range row {
+			buttons, n := binary.Uvarint(rest)
+			if n <= 0 || buttons > 0xFFFFFFFF {
+				return nil, errBadMovie
+			}
+			row[p] = uint32(buttons)
+			rest = rest[n:]
+		}
+		for ; run > 0; run-- {
+			m.rows = append(m.rows, row)
+		}
+	}
+	if len(rest) != 0 {
+		return nil, errBadMovie
+	}
+	return m, nil
+}
@@ -0,0 +1,178 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockMovieEmulator logs the inputs seen by each frame and serializes its
+// frame counter as its save state.
+type mockMovieEmulator struct {
+	*mockEmulator
+	log [][2]uint32
+}
+
+func (m *mockMovieEmulator) RunFrame() {
+	m.mockEmulator.RunFrame()
+	m.log = append(m.log, [2]uint32{m.inputs[0], m.inputs[1]})
+}
+
+func (m *mockMovieEmulator) Serialize() ([]byte, error) { return []byte{byte(m.frames)}, nil }
+func (m *mockMovieEmulator) Deserialize(data []byte) error {
+	m.frames = int(data[0])
+	return nil
+}
+
+func useMovieEmulator(t *testing.T, rom []byte) *mockMovieEmulator {
+	t.Helper()
+	var e *mockMovieEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockMovieEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", rom), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func TestInputRecordingPlayback(t *testing.T) {
+	rom := []byte{1, 2, 3}
+	e := useMovieEmulator(t, rom)
+	RunFrame()
+	RunFrame()
+
+	if !StartInputRecording() {
+		t.Fatal("StartInputRecording failed")
+	}
+	sequence := [][2]int{{0, 0}, {1, 0}, {1, 0}, {1, 0}, {3, 8}, {0, 8}, {0, 0}, {0, 0}}
+	for _, in := range sequence {
+		SetInput(0, in[0])
+		SetInput(1, in[1])
+		RunFrame()
+	}
+	recorded := append([][2]uint32(nil), e.log[2:]...)
+	movie := StopInputRecording()
+	if movie == nil || IsRecordingInput() {
+		t.Fatal("recording did not stop cleanly")
+	}
+
+	Close()
+	e = useMovieEmulator(t, rom)
+	if !StartInputPlayback(movie) {
+		t.Fatalf("StartInputPlayback failed: %s", LastError())
+	}
+	if e.frames != 2 {
+		t.Errorf("frames after embedded state = %d, want 2", e.frames)
+	}
+	for i := range sequence {
+		if !IsPlaybackActive() {
+			t.Fatalf("playback ended after %d frames", i)
+		}
+		SetInput(0, 0xFF) // ignored during playback
+		RunFrame()
+	}
+	if IsPlaybackActive() {
+		t.Error("playback still active after movie end")
+	}
+	if len(e.log) != len(recorded) {
+		t.Fatalf("played %d frames, want %d", len(e.log), len(recorded))
+	}
+	for i := range recorded {
+		if e.log[i] != recorded[i] {
+			t.Errorf("frame %d input = %v, want %v", i, e.log[i], recorded[i])
+		}
+	}
+}
+
+func TestInputPlaybackRejectsMismatch(t *testing.T) {
+	useMovieEmulator(t, []byte{1, 2, 3})
+	StartInputRecording()
+	RunFrame()
+	movie := StopInputRecording()
+
+	Close()
+	useMovieEmulator(t, []byte{9, 9, 9})
+	if StartInputPlayback(movie) {
+		t.Error("accepted movie for another ROM")
+	}
+	if LastError() == "" {
+		t.Error("no error recorded for CRC mismatch")
+	}
+
+	for _, bad := range [][]byte{nil, []byte("EBIM"), append(append([]byte(nil), movie...), 0)} {
+		if StartInputPlayback(bad) {
+			t.Errorf("accepted malformed movie %v", bad)
+		}
+	}
+}
+
+func TestMovieFiles(t *testing.T) {
+	rom := []byte{1, 2, 3}
+	e := useMovieEmulator(t, rom)
+	path := filepath.Join(t.TempDir(), "run.ebim")
+	if !StartInputRecordingToFile(path) {
+		t.Fatal("StartInputRecordingToFile failed")
+	}
+	SetInput(0, 5)
+	RunFrame()
+	movie := StopInputRecording()
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, movie) {
+		t.Fatalf("movie file = %v, %v", data, err)
+	}
+
+	Close()
+	e = useMovieEmulator(t, rom)
+	if !PlayMovie(path) || !IsPlaybackActive() {
+		t.Fatalf("PlayMovie failed: %s", LastError())
+	}
+	RunFrame()
+	if e.log[0][0] != 5 {
+		t.Errorf("played input = %v, want 5", e.log[0])
+	}
+	if PlayMovie(filepath.Join(t.TempDir(), "missing.ebim")) || LastError() == "" {
+		t.Error("PlayMovie accepted a missing file")
+	}
+}
+
+func TestMovieRunLengthEncoding(t *testing.T) {
+	m := &inputMovie{rows: make([][]uint32, 1000)}
+	for i := range m.rows {
+		m.rows[i] = []uint32{5}
+	}
+	data := m.encode()
+	if len(data) > movieHeaderSize+8 {
+		t.Errorf("1000 identical frames encoded to %d bytes", len(data))
+	}
+	got, err := decodeMovie(data)
+	if err != nil || len(got.rows) != 1000 || got.rows[999][0] != 5 {
+		t.Errorf("decode = %v, %v", got, err)
+	}
+}
+
+func TestMovieRejectsHostileFrameCount(t *testing.T) {
+	// One run covering every frame of a 0xFFFFFFFF-frame movie.
+	data := make([]byte, movieHeaderSize)
+	copy(data, movieMagic[:])
+	data[4] = movieVersion
+	data[14] = 1
+	binary.LittleEndian.PutUint32(data[10:], 0xFFFFFFFF)
+	data = append(binary.AppendUvarint(data, 0xFFFFFFFF), 0)
+	if _, err := decodeMovie(data); err == nil {
+		t.Error("movie with 0xFFFFFFFF frames accepted")
+	}
+
+	// A run longer than the frames still missing is rejected too.
+	binary.LittleEndian.PutUint32(data[10:], 2)
+	data = append(binary.AppendUvarint(data[:movieHeaderSize], 3), 0)
+	if _, err := decodeMovie(data); err == nil {
+		t.Error("run past the frame count accepted")
+	}
+}
@@ -0,0 +1,157 @@
+package ios
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"slices"
+)
+
+// movieVerifyEvent is the PollEventsJSON type queued when a verified
+// recording stops matching the input the core received; its message
+// describes the first mismatch.
+const movieVerifyEvent = "movie_verify_failed"
+
+// movieVerifyInterval is the number of frames each stored verification
+// hash covers.
+const movieVerifyInterval = 60
+
+// movieVerifier checks a recording while it is made. Each frame it hashes
+// the buttons the core was given and, separately, the row the recording
+// holds for that frame after a dry run through the movie encoding, which
+// is what playback would feed. The streams must stay equal; every
+// movieVerifyInterval frames the core's hash is stored.
+type movieVerifier struct {
+	presented hash.Hash32
+	replayed  hash.Hash32
+	frames    int
+	hashes    []uint32
+	// failedFrame is the first recorded frame the streams differed at,
+	// or -1.
+	failedFrame int
+}
+
+func newMovieVerifier() *movieVerifier {
+	return &movieVerifier{presented: crc32.NewIEEE(), replayed: crc32.NewIEEE(), failedFrame: -1}
+}
+
+// SetMovieRecordingVerify turns on verification of input recordings
+// started afterwards. A verified recording checks every frame that the
+// recorded row, as playback would decode it, matches the buttons the
+// core received, and keeps a hash of the core's input every 60 frames.
+// The first mismatch is written to the journal and queued as a
+// "movie_verify_failed" PollEventsJSON event. The setting is kept across
+// Init.
+func SetMovieRecordingVerify(enabled bool) {
+	def.setMovieRecordingVerify(enabled)
+}
+
+func (in *instance) setMovieRecordingVerify(enabled bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.movieVerify = enabled
+}
+
+// MovieVerifyJSON reports the verification of the recording in progress:
+// {"frames", "hashes", "failedFrame"}, where hashes holds the stored
+// hashes, oldest first, and failedFrame is the first mismatched frame or
+// -1. Returns "{}" if no verified recording is in progress.
+func MovieVerifyJSON() string {
+	return def.movieVerifyJSON()
+}
+
+func (in *instance) movieVerifyJSON() string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.movie == nil || in.movie.verify == nil {
+		return "{}"
+	}
+	v := in.movie.verify
+	data, err := json.Marshal(struct {
+		Frames      int      `json:"frames"`
+		Hashes      []uint32 `json:"hashes"`
+		FailedFrame int      `json:"failedFrame"`
+	}{v.frames, append([]uint32{}, v.hashes...), v.failedFrame})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// verifyMovieFrame checks the row movieFrame recorded this frame against
+// the buttons presented to the core. in.mu must be held.
+func (in *instance) verifyMovieFrame() {
+	m := in.movie
+	v := m.verify
+	var row []uint32
+	if len(m.rows) > v.frames {
+		row = replayRow(m.rows[v.frames])
+	}
+
+	writeInputs(v.presented, in.inputs)
+	recorded := map[int]uint32{}
+	for player, buttons := range row {
+		recorded[player] = buttons
+	}
+	writeInputs(v.replayed, recorded)
+
+	if v.failedFrame < 0 && v.presented.Sum32() != v.replayed.Sum32() {
+		v.failedFrame = v.frames
+		msg := fmt.Sprintf("recorded input for frame %d does not match the core's", v.frames)
+		if row == nil {
+			msg = fmt.Sprintf("frame %d was not recorded", v.frames)
+		}
+		journalf("warning", "input recording: %s", msg)
+		in.events.push([]CoreEvent{{Type: movieVerifyEvent, Message: msg}}, in.frameCount)
+	}
+	v.frames++
+	if v.frames%movieVerifyInterval == 0 {
+		v.hashes = append(v.hashes, v.presented.Sum32())
+	}
+}
+
+// replayRow returns row as playback would read it back from a movie
+// file, or nil if it does not survive the encoding.
+func replayRow(row []uint32) []uint32 {
+	m, err := decodeMovie((&inputMovie{rows: [][]uint32{row}}).encode())
+	if err != nil || len(m.rows) != 1 {
+		return nil
+	}
+	return m.rows[0]
+}
+
+// writeInputs hashes the pressed buttons per player, in player order.
+// Players with nothing pressed are skipped, so a row padded with idle
+// players hashes like the sparse map it came from.
+func writeInputs(h hash.Hash32, inputs map[int]uint32) {
+	players := make([]int, 0, len(inputs))
+	for player, buttons := range inputs {
+		if buttons != 0 {
+			players = append(players, player)
+		}
+	}
+	slices.Sort(players)
+	var buf [12]byte
+	for _, player := range players {
+		binary.LittleEndian.PutUint64(buf[:], uint64(player))
+		binary.LittleEndian.PutUint32(buf[8:], inputs[player])
+		h.Write(buf[:])
+	}
+	// Mark the frame boundary so inputs cannot shift between frames.
+	h.Write([]byte{0xFF})
+}
+
+// GetLatchedInput returns the buttons last presented to the core for a
+// player: the SetInput buttons after SetButtonMapping and turbo, or the
+// movie's during playback. These are the masks input recording stores.
+func GetLatchedInput(player int) int {
+	return def.latchedInput(player)
+}
+
+func (in *instance) latchedInput(player int) int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return int(in.inputs[player])
+}
@@ -0,0 +1,108 @@
+package ios
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// movieVerifyStatus is the parsed MovieVerifyJSON result.
+type movieVerifyStatus struct {
+	Frames      int      `json:"frames"`
+	Hashes      []uint32 `json:"hashes"`
+	FailedFrame int      `json:"failedFrame"`
+}
+
+func readMovieVerify(t *testing.T) movieVerifyStatus {
+	t.Helper()
+	var s movieVerifyStatus
+	if err := json.Unmarshal([]byte(MovieVerifyJSON()), &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRecordingMatchesCoreInput(t *testing.T) {
+	e := useMovieEmulator(t, []byte{1, 2, 3})
+	SetMovieRecordingVerify(true)
+	t.Cleanup(func() { SetMovieRecordingVerify(false) })
+	// Frontend bit 0 drives core bit 1, which autofires.
+	if !SetButtonMapping(0, `{"0": 1}`) {
+		t.Fatal(LastError())
+	}
+	SetTurboButton(0, 1<<1, true)
+
+	if !StartInputRecording() {
+		t.Fatal("StartInputRecording failed")
+	}
+	const frames = 2 * movieVerifyInterval
+	for i := 0; i < frames; i++ {
+		SetInput(0, 1|1<<2)
+		SetInput(1, i%3)
+		RunFrame()
+		if got := GetLatchedInput(0); uint32(got) != e.inputs[0] {
+			t.Fatalf("frame %d: latched input %#x, core has %#x", i, got, e.inputs[0])
+		}
+	}
+	status := readMovieVerify(t)
+	if status.Frames != frames || len(status.Hashes) != 2 || status.FailedFrame != -1 {
+		t.Errorf("verify = %+v", status)
+	}
+
+	m, err := decodeMovie(StopInputRecording())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.rows) != len(e.log) {
+		t.Fatalf("recorded %d frames, core ran %d", len(m.rows), len(e.log))
+	}
+	sawTurboOff := false
+	for i, row := range m.rows {
+		got := [2]uint32{row[0], row[1]}
+		if got != e.log[i] {
+			t.Errorf("frame %d recorded %#x, core received %#x", i, got, e.log[i])
+		}
+		sawTurboOff = sawTurboOff || row[0] == 1<<2
+	}
+	if !sawTurboOff {
+		t.Error("turbo never released the remapped button")
+	}
+}
+
+func TestMovieVerifyReportsMismatch(t *testing.T) {
+	useMovieEmulator(t, []byte{1, 2, 3})
+	SetMovieRecordingVerify(true)
+	t.Cleanup(func() { SetMovieRecordingVerify(false) })
+	StartInputRecording()
+	RunFrame()
+	PollEventsJSON()
+
+	// Movies hold at most maxMoviePlayers players, so this one is lost.
+	SetInput(maxMoviePlayers+1, 1)
+	RunFrame()
+	RunFrame()
+
+	if got := readMovieVerify(t).FailedFrame; got != 1 {
+		t.Errorf("failedFrame = %d, want 1", got)
+	}
+	var events []queuedEvent
+	json.Unmarshal([]byte(PollEventsJSON()), &events)
+	if len(events) != 1 || events[0].Type != movieVerifyEvent {
+		t.Errorf("events = %+v, want one %s", events, movieVerifyEvent)
+	}
+	if !strings.Contains(JournalJSON(), "frame 1") {
+		t.Error("mismatch not journaled")
+	}
+}
+
+func TestMovieVerifyOff(t *testing.T) {
+	useMovieEmulator(t, []byte{1, 2, 3})
+	StartInputRecording()
+	RunFrame()
+	if got := MovieVerifyJSON(); got != "{}" {
+		t.Errorf("MovieVerifyJSON without verification = %s", got)
+	}
+	if GetLatchedInput(7) != 0 {
+		t.Error("unset player has latched input")
+	}
+}
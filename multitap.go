@@ -0,0 +1,91 @@
+package ios
+
+// Multitap is an optional emulator interface for cores that accept more
+// players than SystemInfo.Players through a multitap adapter.
+type Multitap interface {
+	// MaxPlayers returns how many players the core accepts with the
+	// adapter connected.
+	MaxPlayers() int
+	// SetConnectedPlayers connects controllers for players 0 to n-1,
+	// attaching the adapter when n is above SystemInfo.Players.
+	SetConnectedPlayers(n int)
+}
+
+// MaxPlayers returns how many players SetInput can drive: the multitap
+// limit if the core has one, otherwise SystemInfo.Players. Returns 0 if
+// no core is registered.
+func MaxPlayers() int {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.maxPlayers()
+}
+
+// maxPlayers returns the player limit. in.mu must be held.
+func (in *instance) maxPlayers() int {
+	if in.multitap != nil {
+		return in.multitap.MaxPlayers()
+	}
+	f := in.coreFactory()
+	if f == nil {
+		return 0
+	}
+	return max(f.SystemInfo().Players, 1)
+}
+
+// SetConnectedPlayers sets how many controllers are plugged in, from 1
+// to MaxPlayers. Input for players past n is dropped and their buttons
+// are released. The count resets to all players at Init.
+// Returns false if no ROM is loaded or n is out of range (see LastError).
+func SetConnectedPlayers(n int) bool {
+	return def.setConnectedPlayers(n)
+}
+
+func (in *instance) setConnectedPlayers(n int) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil {
+		setLastError("no ROM loaded")
+		return false
+	}
+	if limit := in.maxPlayers(); n < 1 || n > limit {
+		setLastError("connected players %d out of range 1-%d", n, limit)
+		return false
+	}
+	if in.multitap != nil {
+		in.multitap.SetConnectedPlayers(n)
+	}
+
+	in.inputMu.Lock()
+	defer in.inputMu.Unlock()
+	in.connectedPlayers = n
+	for player := range in.pendingInputs {
+		if player >= n {
+			delete(in.pendingInputs, player)
+		}
+	}
+	for player := range in.heldInputs {
+		if player >= n {
+			delete(in.heldInputs, player)
+		}
+	}
+	for player, buttons := range in.inputs {
+		if player >= n && buttons != 0 {
+			in.presentInput(player, 0)
+		}
+	}
+	return true
+}
+
+// GetConnectedPlayers returns the count set by SetConnectedPlayers, or
+// MaxPlayers if it was not set for this game.
+func GetConnectedPlayers() int {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	def.inputMu.Lock()
+	n := def.connectedPlayers
+	def.inputMu.Unlock()
+	if n == 0 {
+		return def.maxPlayers()
+	}
+	return n
+}
@@ -0,0 +1,72 @@
+package ios
+
+import (
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockMultitapEmulator accepts up to five players through a multitap.
+type mockMultitapEmulator struct {
+	*mockEmulator
+	connected int
+}
+
+func (m *mockMultitapEmulator) MaxPlayers() int           { return 5 }
+func (m *mockMultitapEmulator) SetConnectedPlayers(n int) { m.connected = n }
+
+func TestMultitapPlayers(t *testing.T) {
+	var e *mockMultitapEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockMultitapEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+		modify: func(info *emucore.SystemInfo) { info.Players = 2 },
+	})
+	if MaxPlayers() != 2 {
+		t.Errorf("MaxPlayers before load = %d, want 2", MaxPlayers())
+	}
+	if SetConnectedPlayers(2) {
+		t.Error("SetConnectedPlayers succeeded without a ROM")
+	}
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	if MaxPlayers() != 5 || GetConnectedPlayers() != 5 {
+		t.Fatalf("players = %d/%d, want 5/5", GetConnectedPlayers(), MaxPlayers())
+	}
+
+	for player := range 5 {
+		SetInput(player, 1<<player)
+	}
+	RunFrame()
+	if e.inputs[4] != 1<<4 {
+		t.Errorf("player 5 input = %#x", e.inputs[4])
+	}
+
+	if SetConnectedPlayers(6) || SetConnectedPlayers(0) {
+		t.Error("out of range count accepted")
+	}
+	if !SetConnectedPlayers(3) || e.connected != 3 || GetConnectedPlayers() != 3 {
+		t.Fatalf("connected = %d", e.connected)
+	}
+	if e.inputs[3] != 0 || e.inputs[4] != 0 || e.inputs[2] != 1<<2 {
+		t.Errorf("inputs after disconnect = %v", e.inputs)
+	}
+	SetInput(4, 1)
+	RunFrame()
+	if e.inputs[4] != 0 {
+		t.Error("input forwarded for a disconnected player")
+	}
+}
+
+func TestMaxPlayersWithoutMultitap(t *testing.T) {
+	useMockEmulator(t)
+	if MaxPlayers() != 1 {
+		t.Errorf("MaxPlayers = %d, want 1", MaxPlayers())
+	}
+	if SetConnectedPlayers(2) || !SetConnectedPlayers(1) {
+		t.Error("connected player range not enforced")
+	}
+}
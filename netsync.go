@@ -0,0 +1,146 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"hash/fnv"
+)
+
+// StateAppender is an optional emulator interface for cores that can
+// serialize into a caller-supplied buffer, letting StateHash and
+// SerializedStateSize reuse one buffer instead of allocating a state on
+// every call.
+type StateAppender interface {
+	// AppendState appends the complete emulator state to dst and
+	// returns the extended slice.
+	AppendState(dst []byte) ([]byte, error)
+}
+
+// frameTrailerMagic ends save states that carry the frame counter. The
+// layout is the state, then little-endian fields: the int64 count, the
+// uint32 length of the state and its uint32 CRC-32, then the magic. The
+// length and checksum keep a core state that happens to end in the magic
+// from being mistaken for a trailer. It follows the disc trailer when
+// both are present.
+var frameTrailerMagic = []byte("EBFC")
+
+const frameTrailerSize = 8 + 4 + 4 + 4
+
+// FrameCount returns the number of frames emulated since Init, or since
+// the frame count restored by the last LoadState.
+func FrameCount() int64 {
+	return def.frames()
+}
+
+func (in *instance) frames() int64 {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.frameCount
+}
+
+// StateHash serializes the current state and returns its 64-bit FNV-1a
+// hash, for lockstep peers to compare every few frames.
+// Returns 0 if the core doesn't support save states (see LastError).
+func StateHash() int64 {
+	return def.stateHash()
+}
+
+func (in *instance) stateHash() int64 {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	state, ok := in.serializeScratch()
+	if !ok {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write(state)
+	return int64(h.Sum64())
+}
+
+// SerializedStateSize returns the length of the state SaveState would
+// produce now, so peers can agree on buffer sizes. With
+// SetStateCompression on it is the uncompressed length, an upper bound
+// in practice.
+// Returns 0 if the core doesn't support save states.
+func SerializedStateSize() int {
+	return def.serializedStateSize()
+}
+
+func (in *instance) serializedStateSize() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	var n int
+	// Cores that declare a fixed state size are not serialized to
+	// measure it.
+	if size := in.coreFactory().SystemInfo().SerializeSize; size > 0 && in.saveStater != nil {
+		n = size
+	} else {
+		state, ok := in.serializeScratch()
+		if !ok {
+			return 0
+		}
+		n = len(state)
+	}
+	n += frameTrailerSize
+	if len(in.discs) > 0 {
+		n += discTrailerSize
+	}
+	// SaveState wraps the state in a header whose size does not depend
+	// on the state.
+	header := in.newStateFile(nil, nil)
+	header.level = 0
+	return n + len(header.encode())
+}
+
+// serializeScratch returns the core's current state. With a StateAppender
+// the state is written into the instance's scratch buffer, which the
+// next call overwrites. in.mu must be held.
+func (in *instance) serializeScratch() ([]byte, bool) {
+	if in.saveStater == nil {
+		setLastError("core does not support save states")
+		return nil, false
+	}
+	var (
+		state []byte
+		err   error
+	)
+	if a, ok := in.emu.(StateAppender); ok {
+		state, err = a.AppendState(in.stateScratch[:0])
+		in.stateScratch = state
+	} else {
+		state, err = in.saveStater.Serialize()
+	}
+	if err != nil {
+		setLastError("failed to serialize state: %v", err)
+		return nil, false
+	}
+	return state, true
+}
+
+// appendFrameTrailer records the frame counter after a save state so
+// peers agree on frame numbering after loading it.
+func (in *instance) appendFrameTrailer(state []byte) []byte {
+	n := len(state)
+	sum := crc32.ChecksumIEEE(state)
+	state = binary.LittleEndian.AppendUint64(state, uint64(in.frameCount))
+	state = binary.LittleEndian.AppendUint32(state, uint32(n))
+	state = binary.LittleEndian.AppendUint32(state, sum)
+	return append(state, frameTrailerMagic...)
+}
+
+// splitFrameTrailer separates a state from its frame trailer. ok is
+// false if the state has none, including when it ends in the magic but
+// the recorded length or checksum does not match.
+func splitFrameTrailer(state []byte) (core []byte, frames int64, ok bool) {
+	if len(state) < frameTrailerSize || !bytes.HasSuffix(state, frameTrailerMagic) {
+		return state, 0, false
+	}
+	n := len(state) - frameTrailerSize
+	trailer := state[n:]
+	if int(binary.LittleEndian.Uint32(trailer[8:])) != n ||
+		binary.LittleEndian.Uint32(trailer[12:]) != crc32.ChecksumIEEE(state[:n]) {
+		return state, 0, false
+	}
+	return state[:n], int64(binary.LittleEndian.Uint64(trailer)), true
+}
@@ -0,0 +1,133 @@
+package ios
+
+import (
+	"strings"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockAppendEmulator serializes into the caller's buffer.
+type mockAppendEmulator struct {
+	*mockStateEmulator
+}
+
+func (m *mockAppendEmulator) AppendState(dst []byte) ([]byte, error) {
+	return append(dst, m.value, 0x55), nil
+}
+
+func TestFrameCount(t *testing.T) {
+	useStateEmulator(t, []byte{0x01})
+	RunFrame()
+	RunFrames(3, true)
+	if got := FrameCount(); got != 4 {
+		t.Fatalf("FrameCount = %d, want 4", got)
+	}
+
+	if !SaveState() {
+		t.Fatal("SaveState failed")
+	}
+	state := append([]byte(nil), def.stateData...)
+	RunFrame()
+	if !LoadState(state) || FrameCount() != 4 {
+		t.Errorf("after LoadState FrameCount = %d, want 4", FrameCount())
+	}
+
+	// A state without the trailer, e.g. from an older build, restarts
+	// the count.
+	if !LoadState([]byte{0x07, 0x55}) || FrameCount() != 0 {
+		t.Errorf("legacy LoadState FrameCount = %d, want 0", FrameCount())
+	}
+
+	RunFrame()
+	if !Init(writeROM(t, "rom.bin", []byte{0x01}), 0) || FrameCount() != 0 {
+		t.Errorf("after Init FrameCount = %d, want 0", FrameCount())
+	}
+}
+
+func TestStateHash(t *testing.T) {
+	e := useStateEmulator(t, []byte{0x01})
+	e.value = 3
+	first := StateHash()
+	if first == 0 || StateHash() != first {
+		t.Fatalf("StateHash not stable: %d, %d", first, StateHash())
+	}
+	e.value = 4
+	if StateHash() == first {
+		t.Error("StateHash unchanged after state changed")
+	}
+	want := 2 + frameTrailerSize + len(def.newStateFile(nil, nil).encode())
+	if got := SerializedStateSize(); got != want {
+		t.Errorf("SerializedStateSize = %d, want %d", got, want)
+	}
+	SaveState()
+	if SerializedStateSize() != StateLen() {
+		t.Errorf("SerializedStateSize = %d, StateLen = %d", SerializedStateSize(), StateLen())
+	}
+}
+
+func TestStateHashReusesScratch(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			return &mockAppendEmulator{&mockStateEmulator{mockEmulator: newMockEmulator(rom, region)}}, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x01}), 0) {
+		t.Fatal("Init failed")
+	}
+	StateHash()
+	scratch := &def.stateScratch[0]
+	StateHash()
+	if &def.stateScratch[0] != scratch {
+		t.Error("scratch buffer reallocated between calls")
+	}
+}
+
+func TestStateHashUnsupported(t *testing.T) {
+	useMockEmulator(t)
+	if got := StateHash(); got != 0 {
+		t.Errorf("StateHash = %d, want 0", got)
+	}
+	if !strings.Contains(LastError(), "save states") {
+		t.Errorf("LastError = %q", LastError())
+	}
+	if SerializedStateSize() != 0 {
+		t.Error("SerializedStateSize nonzero without SaveStater")
+	}
+}
+
+func TestSerializedStateSizeDeclared(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			return &mockStateEmulator{mockEmulator: newMockEmulator(rom, region)}, nil
+		},
+		modify: func(info *emucore.SystemInfo) { info.SerializeSize = 40 },
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x01}), 0) {
+		t.Fatal("Init failed")
+	}
+	want := 40 + frameTrailerSize + len(def.newStateFile(nil, nil).encode())
+	if got := SerializedStateSize(); got != want {
+		t.Errorf("SerializedStateSize = %d, want %d", got, want)
+	}
+}
+
+func TestFrameTrailer(t *testing.T) {
+	in := &instance{frameCount: 7}
+	state := in.appendFrameTrailer([]byte("core"))
+	core, frames, ok := splitFrameTrailer(state)
+	if !ok || string(core) != "core" || frames != 7 {
+		t.Fatalf("splitFrameTrailer = %q, %d, %v", core, frames, ok)
+	}
+
+	// A core state that merely ends in the magic is not a trailer.
+	lookalike := append(make([]byte, frameTrailerSize), frameTrailerMagic...)
+	if core, _, ok := splitFrameTrailer(lookalike); ok || len(core) != len(lookalike) {
+		t.Errorf("lookalike state split: %d bytes, %v", len(core), ok)
+	}
+
+	state[0] ^= 0xFF
+	if _, _, ok := splitFrameTrailer(state); ok {
+		t.Error("trailer accepted with a checksum mismatch")
+	}
+}
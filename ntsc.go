@@ -0,0 +1,64 @@
+package ios
+
+import "math"
+
+func init() {
+	RegisterFrameFilter("ntsc", &ntscFilter{})
+}
+
+// ntscFilter approximates composite video by band-limiting each row in
+// YIQ: luma is softened just enough to merge two-pixel dithering and
+// chroma is blurred across several pixels, so dithered transparency and
+// color mixing blend the way they did on a TV.
+type ntscFilter struct{}
+
+// Horizontal low-pass kernels for luma and chroma.
+var (
+	ntscLumaKernel   = []float32{1, 2, 1}
+	ntscChromaKernel = []float32{1, 2, 3, 4, 3, 2, 1}
+)
+
+func (f *ntscFilter) Apply(pixels []byte, width, height, stride int) {
+	if width == 0 {
+		return
+	}
+	// Filters are shared by every instance, so scratch is per call.
+	yiq := make([][3]float32, width)
+	for y := 0; y < height; y++ {
+		row := pixels[y*stride : y*stride+width*4]
+		for x := range yiq {
+			r, g, b := float32(row[x*4]), float32(row[x*4+1]), float32(row[x*4+2])
+			yiq[x] = [3]float32{
+				0.299*r + 0.587*g + 0.114*b,
+				0.596*r - 0.274*g - 0.322*b,
+				0.211*r - 0.523*g + 0.312*b,
+			}
+		}
+		for x := range yiq {
+			yy := lowPass(yiq, x, 0, ntscLumaKernel)
+			i := lowPass(yiq, x, 1, ntscChromaKernel)
+			q := lowPass(yiq, x, 2, ntscChromaKernel)
+			p := row[x*4:]
+			p[0] = clampByte(yy + 0.956*i + 0.621*q)
+			p[1] = clampByte(yy - 0.272*i - 0.647*q)
+			p[2] = clampByte(yy - 1.106*i + 1.703*q)
+		}
+	}
+}
+
+// lowPass returns channel ch of row filtered by kernel around x, with
+// the edge pixels repeated.
+func lowPass(row [][3]float32, x, ch int, kernel []float32) float32 {
+	var sum, total float32
+	half := len(kernel) / 2
+	for k, w := range kernel {
+		i := min(max(x+k-half, 0), len(row)-1)
+		sum += row[i][ch] * w
+		total += w
+	}
+	return sum / total
+}
+
+func clampByte(v float32) byte {
+	return byte(min(max(math.Round(float64(v)), 0), 255))
+}
@@ -0,0 +1,42 @@
+package ios
+
+import "testing"
+
+func TestNTSCFilterBlendsDithering(t *testing.T) {
+	const width = 8
+	pixels := make([]byte, width*4)
+	for x := 0; x < width; x++ {
+		v := byte(0)
+		if x%2 == 1 {
+			v = 200
+		}
+		copy(pixels[x*4:], []byte{v, v, v, 0xFF})
+	}
+	(&ntscFilter{}).Apply(pixels, width, 1, width*4)
+
+	for x := 1; x < width-1; x++ {
+		p := pixels[x*4 : x*4+4]
+		for c := 0; c < 3; c++ {
+			if d := int(p[c]) - 100; d < -2 || d > 2 {
+				t.Fatalf("pixel %d = %v, want about 100 gray", x, p)
+			}
+		}
+		if p[3] != 0xFF {
+			t.Fatalf("pixel %d alpha = %d", x, p[3])
+		}
+	}
+}
+
+func TestNTSCFilterKeepsFlatColor(t *testing.T) {
+	pixels := []byte{200, 40, 90, 0xFF, 200, 40, 90, 0xFF, 200, 40, 90, 0xFF}
+	(&ntscFilter{}).Apply(pixels, 3, 1, 12)
+	for x := 0; x < 3; x++ {
+		p := pixels[x*4 : x*4+3]
+		want := []byte{200, 40, 90}
+		for c := range want {
+			if d := int(p[c]) - int(want[c]); d < -1 || d > 1 {
+				t.Errorf("pixel %d = %v, want %v", x, p, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package ios
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// OptionReporter is an optional emulator interface for cores that can
+// report the value an option actually has, such as the one a core picked
+// itself for an "auto" setting.
+type OptionReporter interface {
+	// OptionValue returns key's value in effect, or false if the core
+	// does not track it.
+	OptionValue(key string) (value string, ok bool)
+}
+
+// effectiveOptions returns each declared core option's current value:
+// its Default, overridden by any value set since load, overridden in
+// turn by what an OptionReporter core reports. Options set via SetOption
+// that the system does not declare are included as well. in.mu must be
+// held.
+func (in *instance) effectiveOptions() map[string]string {
+	values := map[string]string{}
+	if f := in.coreFactory(); f != nil {
+		for _, opt := range f.SystemInfo().CoreOptions {
+			values[opt.Key] = opt.Default
+		}
+	}
+	for _, opt := range in.options {
+		values[opt.key] = opt.value
+	}
+	if in.optionReporter != nil {
+		for key := range values {
+			if v, ok := in.optionReporter.OptionValue(key); ok {
+				values[key] = v
+			}
+		}
+	}
+	return values
+}
+
+// GetOption returns a core option's current value, as in GetOptionsJSON,
+// or an empty string if the option is unknown.
+func GetOption(key string) string {
+	return def.currentOptions()[key]
+}
+
+// currentOptions is effectiveOptions for callers not holding in.mu.
+func (in *instance) currentOptions() map[string]string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.effectiveOptions()
+}
+
+// GetOptionsJSON returns the current core option values as a JSON object
+// of key to value, starting from each option's default and reflecting
+// the values an OptionReporter core says are in effect.
+func GetOptionsJSON() string {
+	data, err := json.Marshal(def.currentOptions())
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// ApplyOptionsJSON applies a {"key": "value"} object of core options.
+// Keys the system does not declare are ignored. Options are applied in
+// declaration order. Returns the number applied, or -1 without applying
+// anything if the JSON is invalid (see LastError).
+func ApplyOptionsJSON(optionsJSON string) int {
+	return def.applyOptionsJSON(optionsJSON)
+}
+
+func (in *instance) applyOptionsJSON(optionsJSON string) int {
+	var values map[string]string
+	if err := json.Unmarshal([]byte(optionsJSON), &values); err != nil {
+		setLastError("invalid options JSON: %v", err)
+		return -1
+	}
+	f, loaded := in.loadedFactory()
+	if !loaded {
+		return 0
+	}
+
+	applied := 0
+	for _, opt := range f.SystemInfo().CoreOptions {
+		if v, ok := values[opt.Key]; ok {
+			in.setOption(opt.Key, v)
+			applied++
+		}
+	}
+	return applied
+}
+
+// rejectedOption is a SetOptionsJSON key that was not applied.
+type rejectedOption struct {
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+// SetOptionsJSON applies a {"key": "value"} object of core options in
+// one call, such as a game's saved settings at startup, validating each
+// value against its declaration first. Options are applied in
+// declaration order. Returns {"applied": [keys], "rejected": [{"key",
+// "reason"}]}, where keys the system does not declare and invalid
+// values are rejected, or "{}" if the JSON is invalid (see LastError).
+func SetOptionsJSON(optionsJSON string) string {
+	return def.setOptionsJSON(optionsJSON)
+}
+
+func (in *instance) setOptionsJSON(optionsJSON string) string {
+	var values map[string]string
+	if err := json.Unmarshal([]byte(optionsJSON), &values); err != nil {
+		setLastError("invalid options JSON: %v", err)
+		return "{}"
+	}
+
+	applied := []string{}
+	rejected := []rejectedOption{}
+	seen := map[string]bool{}
+	f, loaded := in.loadedFactory()
+	if f != nil {
+		for _, opt := range f.SystemInfo().CoreOptions {
+			v, ok := values[opt.Key]
+			if !ok {
+				continue
+			}
+			seen[opt.Key] = true
+			switch reason := optionValueProblem(opt, v); {
+			case !loaded:
+				rejected = append(rejected, rejectedOption{opt.Key, "no ROM loaded"})
+			case reason != "":
+				rejected = append(rejected, rejectedOption{opt.Key, reason})
+			default:
+				in.setOption(opt.Key, v)
+				applied = append(applied, opt.Key)
+			}
+		}
+	}
+	unknown := []string{}
+	for key := range values {
+		if !seen[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	for _, key := range unknown {
+		rejected = append(rejected, rejectedOption{key, "unknown option"})
+	}
+
+	data, err := json.Marshal(struct {
+		Applied  []string         `json:"applied"`
+		Rejected []rejectedOption `json:"rejected"`
+	}{applied, rejected})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// loadedFactory returns the instance's core, and whether a game is
+// loaded.
+func (in *instance) loadedFactory() (emucore.CoreFactory, bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.coreFactory(), in.emu != nil
+}
+
+// declaredOption returns f's declaration of key.
+func declaredOption(f emucore.CoreFactory, key string) (emucore.CoreOption, bool) {
+	if f == nil {
+		return emucore.CoreOption{}, false
+	}
+	for _, opt := range f.SystemInfo().CoreOptions {
+		if opt.Key == key {
+			return opt, true
+		}
+	}
+	return emucore.CoreOption{}, false
+}
+
+// coerceOptionValue returns value fitted to opt: range values are clamped
+// and snapped to the nearest step. Values that cannot be fitted are an
+// error.
+func coerceOptionValue(opt emucore.CoreOption, value string) (string, error) {
+	if opt.Type != emucore.CoreOptionRange {
+		if reason := optionValueProblem(opt, value); reason != "" {
+			return "", errors.New(reason)
+		}
+		return value, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("expected a number from %d to %d", opt.Min, opt.Max)
+	}
+	if opt.Step > 0 {
+		n = opt.Min + (n-opt.Min+opt.Step/2)/opt.Step*opt.Step
+	}
+	return strconv.Itoa(max(opt.Min, min(opt.Max, n))), nil
+}
+
+// ValidateOption checks value against key's declaration, for inline
+// errors in settings UIs. Returns an empty string if SetOption would
+// apply value unchanged, or a message saying what is expected.
+func ValidateOption(key, value string) string {
+	opt, ok := declaredOption(activeFactory(), key)
+	if !ok {
+		return "unknown option"
+	}
+	return optionValueProblem(opt, value)
+}
+
+// optionValueProblem describes why value is invalid for opt, or returns
+// "" if it is valid.
+func optionValueProblem(opt emucore.CoreOption, value string) string {
+	switch opt.Type {
+	case emucore.CoreOptionBool:
+		if value != "true" && value != "false" {
+			return "expected true or false"
+		}
+	case emucore.CoreOptionSelect:
+		if len(opt.Values) > 0 && !slices.Contains(opt.Values, value) {
+			return fmt.Sprintf("expected one of %s", strings.Join(opt.Values, ", "))
+		}
+	case emucore.CoreOptionRange:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < opt.Min || n > opt.Max {
+			return fmt.Sprintf("expected a number from %d to %d", opt.Min, opt.Max)
+		}
+		if opt.Step > 0 && (n-opt.Min)%opt.Step != 0 {
+			return fmt.Sprintf("expected a multiple of %d from %d", opt.Step, opt.Min)
+		}
+	}
+	return ""
+}
+
+// GetPerGameOptionKeysJSON returns a JSON array of the keys of core
+// options flagged PerGame, which the app stores per title.
+func GetPerGameOptionKeysJSON() string {
+	keys := []string{}
+	if f := activeFactory(); f != nil {
+		for _, opt := range f.SystemInfo().CoreOptions {
+			if opt.PerGame {
+				keys = append(keys, opt.Key)
+			}
+		}
+	}
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// gameOptionsPath returns the per-game options file for a CRC32 hex
+// string in dir, named like SetPerGameConfigDir's files.
+func gameOptionsPath(dir, crc string) (string, bool) {
+	v, err := strconv.ParseUint(crc, 16, 32)
+	if err != nil {
+		setLastError("invalid CRC %q", crc)
+		return "", false
+	}
+	return filepath.Join(dir, fmt.Sprintf("%08X.json", v)), true
+}
+
+// perGameOptionKeys returns the keys of f's options flagged PerGame.
+func perGameOptionKeys(f emucore.CoreFactory) map[string]bool {
+	keys := map[string]bool{}
+	if f != nil {
+		for _, opt := range f.SystemInfo().CoreOptions {
+			if opt.PerGame {
+				keys[opt.Key] = true
+			}
+		}
+	}
+	return keys
+}
+
+// SaveGameOptions writes the PerGame options set since load to the
+// "options" section of the per-game options file for the ROM with the
+// given CRC32 hex string in dir ({CRC32}.json, the layout
+// SetPerGameConfigDir uses), keeping its other sections. Options not
+// set since load are not saved, so they follow later default changes.
+// Returns false if the write fails (see LastError).
+func SaveGameOptions(dir, crc string) bool {
+	return def.saveGameOptions(dir, crc)
+}
+
+func (in *instance) saveGameOptions(dir, crc string) bool {
+	path, ok := gameOptionsPath(dir, crc)
+	if !ok {
+		return false
+	}
+	in.mu.Lock()
+	perGame := perGameOptionKeys(in.coreFactory())
+	values := map[string]string{}
+	for _, opt := range in.options {
+		if perGame[opt.key] {
+			values[opt.key] = opt.value
+		}
+	}
+	in.mu.Unlock()
+	return writeConfigSection(path, "options", values)
+}
+
+// LoadGameOptions applies the options SaveGameOptions stored for the ROM
+// with the given CRC32 hex string in dir. Only PerGame options with
+// valid values are applied, in declaration order. Returns true if there
+// is nothing saved, and false if no ROM is loaded or the file is
+// unreadable (see LastError).
+func LoadGameOptions(dir, crc string) bool {
+	return def.loadGameOptions(dir, crc)
+}
+
+func (in *instance) loadGameOptions(dir, crc string) bool {
+	path, ok := gameOptionsPath(dir, crc)
+	if !ok {
+		return false
+	}
+	f, loaded := in.loadedFactory()
+	if !loaded {
+		setLastError("no ROM loaded")
+		return false
+	}
+	sections, err := readConfigFile(path)
+	if err != nil {
+		setLastError("failed to read %s: %v", path, err)
+		return false
+	}
+	raw, ok := sections["options"]
+	if !ok {
+		return true
+	}
+	var values map[string]string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		setLastError("invalid options in %s: %v", path, err)
+		return false
+	}
+
+	for _, opt := range f.SystemInfo().CoreOptions {
+		v, ok := values[opt.Key]
+		if !ok || !opt.PerGame {
+			continue
+		}
+		if reason := optionValueProblem(opt, v); reason != "" {
+			journalf("warning", "saved option %s=%q ignored: %s", opt.Key, v, reason)
+			continue
+		}
+		in.setOption(opt.Key, v)
+	}
+	return true
+}
@@ -0,0 +1,247 @@
+package ios
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// useOptionsEmulator declares three options, "region_lock" per game.
+func useOptionsEmulator(t *testing.T) *mockEmulator {
+	t.Helper()
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			return e, nil
+		},
+		modify: func(info *emucore.SystemInfo) {
+			info.CoreOptions = []emucore.CoreOption{
+				{Key: "palette", Type: emucore.CoreOptionSelect, Default: "default", Values: []string{"default", "vivid"}},
+				{Key: "sprite_limit", Type: emucore.CoreOptionBool, Default: "true"},
+				{Key: "region_lock", Type: emucore.CoreOptionBool, Default: "false", PerGame: true},
+			}
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func parseOptions(t *testing.T) map[string]string {
+	t.Helper()
+	var values map[string]string
+	if err := json.Unmarshal([]byte(GetOptionsJSON()), &values); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestGetOptionsJSONDefaults(t *testing.T) {
+	useOptionsEmulator(t)
+
+	values := parseOptions(t)
+	want := map[string]string{"palette": "default", "sprite_limit": "true", "region_lock": "false"}
+	if len(values) != len(want) {
+		t.Fatalf("options = %v, want %v", values, want)
+	}
+	for k, v := range want {
+		if values[k] != v {
+			t.Errorf("%s = %q, want %q", k, values[k], v)
+		}
+	}
+}
+
+func TestApplyOptionsJSONRoundTrip(t *testing.T) {
+	e := useOptionsEmulator(t)
+
+	n := ApplyOptionsJSON(`{"palette": "vivid", "region_lock": "true", "unknown": "x"}`)
+	if n != 2 {
+		t.Errorf("applied = %d, want 2", n)
+	}
+	if e.options["palette"] != "vivid" || e.options["region_lock"] != "true" {
+		t.Errorf("core options = %v", e.options)
+	}
+	if _, ok := e.options["unknown"]; ok {
+		t.Error("unknown key applied")
+	}
+
+	saved := GetOptionsJSON()
+	useOptionsEmulator(t)
+	ApplyOptionsJSON(saved)
+	if values := parseOptions(t); values["palette"] != "vivid" || values["sprite_limit"] != "true" {
+		t.Errorf("round trip = %v", values)
+	}
+}
+
+func TestApplyOptionsJSONInvalid(t *testing.T) {
+	e := useOptionsEmulator(t)
+
+	if n := ApplyOptionsJSON(`{"palette": "vivid", "sprite_limit": `); n != -1 {
+		t.Errorf("applied = %d, want -1", n)
+	}
+	if n := ApplyOptionsJSON(`{"palette": "vivid", "sprite_limit": false}`); n != -1 {
+		t.Errorf("applied = %d, want -1 for non-string value", n)
+	}
+	if len(e.options) != 0 {
+		t.Errorf("partially applied %v", e.options)
+	}
+}
+
+func TestGetPerGameOptionKeysJSON(t *testing.T) {
+	useOptionsEmulator(t)
+	if got := GetPerGameOptionKeysJSON(); got != `["region_lock"]` {
+		t.Errorf("per-game keys = %s", got)
+	}
+}
+
+func TestSetOptionsJSONReportsRejected(t *testing.T) {
+	e := useOptionsEmulator(t)
+
+	var got struct {
+		Applied  []string         `json:"applied"`
+		Rejected []rejectedOption `json:"rejected"`
+	}
+	result := SetOptionsJSON(`{"region_lock": "true", "palette": "neon", "sprite_limit": "false", "zoom": "2", "aspect": "x"}`)
+	if err := json.Unmarshal([]byte(result), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Applied) != 2 || got.Applied[0] != "sprite_limit" || got.Applied[1] != "region_lock" {
+		t.Errorf("applied = %v, want declaration order", got.Applied)
+	}
+	want := []rejectedOption{
+		{"palette", "expected one of default, vivid"},
+		{"aspect", "unknown option"},
+		{"zoom", "unknown option"},
+	}
+	if len(got.Rejected) != len(want) {
+		t.Fatalf("rejected = %+v, want %+v", got.Rejected, want)
+	}
+	for i := range want {
+		if got.Rejected[i] != want[i] {
+			t.Errorf("rejected[%d] = %+v, want %+v", i, got.Rejected[i], want[i])
+		}
+	}
+	if e.options["sprite_limit"] != "false" || e.options["region_lock"] != "true" || e.options["palette"] != "" {
+		t.Errorf("core options = %v", e.options)
+	}
+	if SetOptionsJSON(`{"palette":`) != "{}" {
+		t.Error("invalid JSON accepted")
+	}
+}
+
+func TestOptionValueProblem(t *testing.T) {
+	r := emucore.CoreOption{Type: emucore.CoreOptionRange, Min: 0, Max: 10, Step: 2}
+	for value, ok := range map[string]bool{"0": true, "4": true, "10": true, "3": false, "12": false, "x": false} {
+		if got := optionValueProblem(r, value) == ""; got != ok {
+			t.Errorf("range value %q valid = %v, want %v", value, got, ok)
+		}
+	}
+	if optionValueProblem(emucore.CoreOption{Type: emucore.CoreOptionBool}, "yes") == "" {
+		t.Error("bool option accepted yes")
+	}
+}
+
+// mockOptionReporter picks "vivid" itself when palette is "default".
+type mockOptionReporter struct {
+	*mockEmulator
+}
+
+func (m *mockOptionReporter) OptionValue(key string) (string, bool) {
+	if key == "palette" && m.options["palette"] == "default" {
+		return "vivid", true
+	}
+	return "", false
+}
+
+func TestGetOption(t *testing.T) {
+	useOptionsEmulator(t)
+	SetOption("sprite_limit", "false")
+	if GetOption("sprite_limit") != "false" || GetOption("palette") != "default" || GetOption("zoom") != "" {
+		t.Errorf("GetOption = %q, %q, %q", GetOption("sprite_limit"), GetOption("palette"), GetOption("zoom"))
+	}
+
+	def.attach(&mockOptionReporter{def.emu.(*mockEmulator)})
+	SetOption("palette", "default")
+	if got := GetOption("palette"); got != "vivid" {
+		t.Errorf("reported palette = %q, want vivid", got)
+	}
+	if got := parseOptions(t)["palette"]; got != "vivid" {
+		t.Errorf("GetOptionsJSON palette = %q, want vivid", got)
+	}
+}
+
+func TestSaveAndLoadGameOptions(t *testing.T) {
+	e := useOptionsEmulator(t)
+	dir := t.TempDir()
+	SetOption("region_lock", "true")
+	SetOption("palette", "vivid") // not PerGame
+
+	if !SaveGameOptions(dir, "1a2b") {
+		t.Fatalf("SaveGameOptions failed: %s", LastError())
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "00001A2B.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sections struct{ Options map[string]string }
+	if err := json.Unmarshal(data, &sections); err != nil || len(sections.Options) != 1 || sections.Options["region_lock"] != "true" {
+		t.Fatalf("saved file = %s, %v", data, err)
+	}
+
+	clear(e.options)
+	if !LoadGameOptions(dir, "00001A2B") || e.options["region_lock"] != "true" || e.options["palette"] != "" {
+		t.Errorf("loaded options = %v (%s)", e.options, LastError())
+	}
+	if !LoadGameOptions(dir, "FFFF") {
+		t.Error("LoadGameOptions failed with nothing saved")
+	}
+	if SaveGameOptions(dir, "../x") || LoadGameOptions(dir, "zz") {
+		t.Error("invalid CRC accepted")
+	}
+}
+
+func TestSetOptionEnforcesDeclaration(t *testing.T) {
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			return e, nil
+		},
+		modify: func(info *emucore.SystemInfo) {
+			info.CoreOptions = []emucore.CoreOption{
+				{Key: "palette", Type: emucore.CoreOptionSelect, Values: []string{"default", "vivid"}},
+				{Key: "volume", Type: emucore.CoreOptionRange, Min: 0, Max: 100, Step: 10},
+			}
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+
+	SetOption("palette", "neon")
+	if _, ok := e.options["palette"]; ok || LastError() == "" {
+		t.Errorf("invalid select value applied: %v", e.options)
+	}
+	for value, want := range map[string]string{"40": "40", "44": "40", "46": "50", "250": "100", "-5": "0"} {
+		SetOption("volume", value)
+		if e.options["volume"] != want {
+			t.Errorf("SetOption(volume, %s) applied %q, want %q", value, e.options["volume"], want)
+		}
+	}
+	SetOption("hidden", "anything")
+	if e.options["hidden"] != "anything" {
+		t.Error("undeclared option not passed through")
+	}
+
+	if ValidateOption("volume", "40") != "" || ValidateOption("palette", "vivid") != "" {
+		t.Error("valid values rejected")
+	}
+	if ValidateOption("volume", "45") == "" || ValidateOption("palette", "neon") == "" || ValidateOption("hidden", "x") != "unknown option" {
+		t.Error("invalid values accepted")
+	}
+}
@@ -0,0 +1,303 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+)
+
+// Patch format magics.
+var (
+	ipsMagic = []byte("PATCH")
+	upsMagic = []byte("UPS1")
+	bpsMagic = []byte("BPS1")
+)
+
+// ipsEOF is the IPS record offset that ends the patch.
+const ipsEOF = 0x454F46
+
+// maxPatchedSize bounds the target a UPS or BPS patch may declare.
+const maxPatchedSize = 256 << 20
+
+var errPatchTruncated = errors.New("patch is truncated")
+
+// ApplyPatch applies an IPS, UPS or BPS patch to rom and returns the
+// patched ROM. The format is detected from the patch header. UPS and BPS
+// patches are rejected if their checksums do not match rom or the result.
+func ApplyPatch(romBytes, patchBytes []byte) ([]byte, error) {
+	return applyPatch(romBytes, patchBytes)
+}
+
+func applyPatch(rom, patch []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(patch, ipsMagic):
+		return applyIPS(rom, patch)
+	case bytes.HasPrefix(patch, upsMagic):
+		return applyUPS(rom, patch)
+	case bytes.HasPrefix(patch, bpsMagic):
+		return applyBPS(rom, patch)
+	}
+	return nil, fmt.Errorf("unrecognized patch format")
+}
+
+// InitWithPatch is Init for a ROM soft-patched with the IPS, UPS or BPS
+// patch at patchPath. The patch is applied in memory; neither file is
+// modified.
+// regionCode: 0=NTSC, 1=PAL
+// Returns true on success (see LastError on failure).
+func InitWithPatch(romPath, patchPath string, regionCode int) bool {
+	return def.initWithPatch(romPath, patchPath, regionCode)
+}
+
+func (in *instance) initWithPatch(romPath, patchPath string, regionCode int) bool {
+	if activeFactory() == nil {
+		setLastError("%v", errNoFactory)
+		return false
+	}
+	f, rom, err := loadSystemROM(romPath)
+	if err != nil {
+		setLastError("%v", loadError(err))
+		return false
+	}
+	patch, err := os.ReadFile(patchPath)
+	if err != nil {
+		setLastError("failed to read patch: %v", err)
+		return false
+	}
+	patched, err := applyPatch(rom, patch)
+	if err != nil {
+		setLastError("failed to apply patch: %v", err)
+		return false
+	}
+	if !in.initROM(f, patched, regionCode) {
+		return false
+	}
+	in.romPath = romPath
+	in.patchPath = patchPath
+	return true
+}
+
+// applyIPS applies an IPS patch. Records may write past the end of rom,
+// growing it, and an optional 3-byte size after EOF truncates the result.
+func applyIPS(rom, patch []byte) ([]byte, error) {
+	out := bytes.Clone(rom)
+	p := len(ipsMagic)
+	for {
+		if p+3 > len(patch) {
+			return nil, errPatchTruncated
+		}
+		offset := int(patch[p])<<16 | int(patch[p+1])<<8 | int(patch[p+2])
+		p += 3
+		if offset == ipsEOF {
+			break
+		}
+		if p+2 > len(patch) {
+			return nil, errPatchTruncated
+		}
+		size := int(binary.BigEndian.Uint16(patch[p:]))
+		p += 2
+
+		var data []byte
+		if size == 0 {
+			// RLE record: a 2-byte count and the byte to repeat.
+			if p+3 > len(patch) {
+				return nil, errPatchTruncated
+			}
+			size = int(binary.BigEndian.Uint16(patch[p:]))
+			data = bytes.Repeat(patch[p+2:p+3], size)
+			p += 3
+		} else {
+			if p+size > len(patch) {
+				return nil, errPatchTruncated
+			}
+			data = patch[p : p+size]
+			p += size
+		}
+		if end := offset + size; end > len(out) {
+			out = append(out, make([]byte, end-len(out))...)
+		}
+		copy(out[offset:], data)
+	}
+	if p+3 <= len(patch) {
+		size := int(patch[p])<<16 | int(patch[p+1])<<8 | int(patch[p+2])
+		if size < len(out) {
+			out = out[:size]
+		}
+	}
+	return out, nil
+}
+
+// patchReader reads the variable-length integers UPS and BPS use.
+type patchReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *patchReader) byte() byte {
+	if r.pos >= len(r.data) {
+		r.err = errPatchTruncated
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *patchReader) number() int {
+	n, shift := 0, 1
+	for r.err == nil {
+		b := r.byte()
+		n += int(b&0x7F) * shift
+		if b&0x80 != 0 {
+			break
+		}
+		shift <<= 7
+		n += shift
+		if shift > maxPatchedSize {
+			r.err = fmt.Errorf("patch number overflows")
+		}
+	}
+	return n
+}
+
+// checkPatchFooter verifies the trailing source, target and patch CRCs
+// shared by UPS and BPS.
+func checkPatchFooter(patch, source, target []byte) error {
+	footer := patch[len(patch)-12:]
+	if crc32.ChecksumIEEE(patch[:len(patch)-4]) != binary.LittleEndian.Uint32(footer[8:]) {
+		return fmt.Errorf("patch is corrupt")
+	}
+	if crc32.ChecksumIEEE(source) != binary.LittleEndian.Uint32(footer[0:]) {
+		return fmt.Errorf("patch does not match this ROM")
+	}
+	if crc32.ChecksumIEEE(target) != binary.LittleEndian.Uint32(footer[4:]) {
+		return fmt.Errorf("patched ROM checksum mismatch")
+	}
+	return nil
+}
+
+// applyUPS applies a UPS patch: runs of bytes XORed into the source, each
+// preceded by the distance from the end of the previous run.
+func applyUPS(rom, patch []byte) ([]byte, error) {
+	if len(patch) < len(upsMagic)+12 {
+		return nil, errPatchTruncated
+	}
+	r := &patchReader{data: patch[:len(patch)-12], pos: len(upsMagic)}
+	r.number() // source size
+	targetSize := r.number()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if targetSize > maxPatchedSize {
+		return nil, fmt.Errorf("patched ROM too large: %d bytes", targetSize)
+	}
+
+	out := make([]byte, targetSize)
+	copy(out, rom)
+	pos := 0
+	for r.pos < len(r.data) && r.err == nil {
+		pos += r.number()
+		for r.err == nil {
+			x := r.byte()
+			if pos < len(out) {
+				out[pos] ^= x
+			}
+			pos++
+			if x == 0 {
+				break
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err := checkPatchFooter(patch, rom, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BPS actions.
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+// applyBPS applies a BPS patch, which builds the target from runs read
+// from the source, the patch, or earlier in either file.
+func applyBPS(rom, patch []byte) ([]byte, error) {
+	if len(patch) < len(bpsMagic)+12 {
+		return nil, errPatchTruncated
+	}
+	r := &patchReader{data: patch[:len(patch)-12], pos: len(bpsMagic)}
+	r.number() // source size
+	targetSize := r.number()
+	r.pos += r.number() // metadata
+	if r.err != nil {
+		return nil, r.err
+	}
+	if targetSize > maxPatchedSize {
+		return nil, fmt.Errorf("patched ROM too large: %d bytes", targetSize)
+	}
+
+	out := make([]byte, targetSize)
+	pos, sourceRel, targetRel := 0, 0, 0
+	relative := func(base int) int {
+		d := r.number()
+		if d&1 != 0 {
+			return base - d>>1
+		}
+		return base + d>>1
+	}
+	for r.pos < len(r.data) && r.err == nil {
+		action := r.number()
+		length := action>>2 + 1
+		if pos+length > len(out) {
+			return nil, fmt.Errorf("patch writes past the end of the ROM")
+		}
+		switch action & 3 {
+		case bpsSourceRead:
+			if pos+length > len(rom) {
+				return nil, fmt.Errorf("patch reads past the end of the ROM")
+			}
+			copy(out[pos:], rom[pos:pos+length])
+		case bpsTargetRead:
+			if r.pos+length > len(r.data) {
+				return nil, errPatchTruncated
+			}
+			copy(out[pos:], r.data[r.pos:r.pos+length])
+			r.pos += length
+		case bpsSourceCopy:
+			sourceRel = relative(sourceRel)
+			if sourceRel < 0 || sourceRel+length > len(rom) {
+				return nil, fmt.Errorf("patch reads past the end of the ROM")
+			}
+			copy(out[pos:], rom[sourceRel:sourceRel+length])
+			sourceRel += length
+		case bpsTargetCopy:
+			targetRel = relative(targetRel)
+			if targetRel < 0 || targetRel >= pos {
+				return nil, fmt.Errorf("patch copies from unwritten output")
+			}
+			// Byte by byte: the runs may overlap to repeat a pattern.
+			for i := 0; i < length; i++ {
+				out[pos+i] = out[targetRel+i]
+			}
+			targetRel += length
+		}
+		pos += length
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err := checkPatchFooter(patch, rom, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
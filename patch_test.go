@@ -0,0 +1,137 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// patchNumber encodes n as a UPS/BPS variable-length integer.
+func patchNumber(n int) []byte {
+	var out []byte
+	for {
+		x := byte(n & 0x7F)
+		n >>= 7
+		if n == 0 {
+			return append(out, 0x80|x)
+		}
+		out = append(out, x)
+		n--
+	}
+}
+
+// withPatchFooter appends the source, target and patch CRCs.
+func withPatchFooter(patch, source, target []byte) []byte {
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(source))
+	patch = binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(target))
+	return binary.LittleEndian.AppendUint32(patch, crc32.ChecksumIEEE(patch))
+}
+
+func TestApplyIPS(t *testing.T) {
+	rom := []byte{0, 1, 2, 3}
+	patch := []byte("PATCH")
+	patch = append(patch, 0, 0, 1, 0, 2, 0xAA, 0xBB) // 2 bytes at 1
+	patch = append(patch, 0, 0, 5, 0, 0, 0, 3, 0xCC) // RLE past the end
+	patch = append(patch, "EOF"...)
+
+	got, err := ApplyPatch(rom, patch)
+	want := []byte{0, 0xAA, 0xBB, 3, 0, 0xCC, 0xCC, 0xCC}
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("ApplyPatch = % x, %v, want % x", got, err, want)
+	}
+	if rom[1] != 1 {
+		t.Error("ApplyPatch modified the ROM")
+	}
+
+	// A trailing size truncates the result.
+	got, err = ApplyPatch(rom, append([]byte("PATCHEOF"), 0, 0, 2))
+	if err != nil || !bytes.Equal(got, []byte{0, 1}) {
+		t.Errorf("truncating patch = % x, %v", got, err)
+	}
+
+	if _, err := ApplyPatch(rom, []byte("PATCH\x00\x00\x01\x00\x05\xAA")); err == nil {
+		t.Error("truncated IPS patch applied")
+	}
+}
+
+func TestApplyUPS(t *testing.T) {
+	rom := []byte{1, 2, 3, 4, 5}
+	target := []byte{1, 9, 3, 4, 5, 7}
+
+	patch := append([]byte("UPS1"), patchNumber(len(rom))...)
+	patch = append(patch, patchNumber(len(target))...)
+	patch = append(patch, patchNumber(1)...)
+	patch = append(patch, 2^9, 0)
+	patch = append(patch, patchNumber(2)...)
+	patch = append(patch, 7, 0)
+	patch = withPatchFooter(patch, rom, target)
+
+	got, err := ApplyPatch(rom, patch)
+	if err != nil || !bytes.Equal(got, target) {
+		t.Fatalf("ApplyPatch = % x, %v, want % x", got, err, target)
+	}
+
+	if _, err := ApplyPatch([]byte{1, 2, 3, 4, 6}, patch); err == nil {
+		t.Error("UPS patch applied to the wrong ROM")
+	}
+	patch[len(patch)-1] ^= 0xFF
+	if _, err := ApplyPatch(rom, patch); err == nil {
+		t.Error("corrupt UPS patch applied")
+	}
+}
+
+func TestApplyBPS(t *testing.T) {
+	rom := []byte("ABCDEFGH")
+	target := []byte("ABCxyxyxyEFG")
+
+	action := func(kind, length int) []byte { return patchNumber((length-1)<<2 | kind) }
+	patch := append([]byte("BPS1"), patchNumber(len(rom))...)
+	patch = append(patch, patchNumber(len(target))...)
+	patch = append(patch, patchNumber(0)...)
+	patch = append(patch, action(bpsSourceRead, 3)...)
+	patch = append(patch, action(bpsTargetRead, 2)...)
+	patch = append(patch, "xy"...)
+	patch = append(patch, action(bpsTargetCopy, 4)...)
+	patch = append(patch, patchNumber(3<<1)...) // from target offset 3
+	patch = append(patch, action(bpsSourceCopy, 3)...)
+	patch = append(patch, patchNumber(4<<1)...) // from source offset 4
+	patch = withPatchFooter(patch, rom, target)
+
+	got, err := ApplyPatch(rom, patch)
+	if err != nil || !bytes.Equal(got, target) {
+		t.Fatalf("ApplyPatch = %q, %v, want %q", got, err, target)
+	}
+	if _, err := ApplyPatch([]byte("ABCDEFGX"), patch); err == nil {
+		t.Error("BPS patch applied to the wrong ROM")
+	}
+	if _, err := ApplyPatch(rom, []byte("NOPE")); err == nil {
+		t.Error("unknown patch format applied")
+	}
+}
+
+func TestInitWithPatch(t *testing.T) {
+	useMockEmulator(t)
+	dir := t.TempDir()
+	romPath := writeROM(t, "game.bin", []byte{0, 1, 2})
+	patchPath := filepath.Join(dir, "game.ips")
+	if err := os.WriteFile(patchPath, []byte("PATCH\x00\x00\x01\x00\x01\xAAEOF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !InitWithPatch(romPath, patchPath, 0) {
+		t.Fatalf("InitWithPatch failed: %s", LastError())
+	}
+	if e := def.emu.(*mockEmulator); !bytes.Equal(e.rom, []byte{0, 0xAA, 2}) {
+		t.Errorf("core ROM = % x", e.rom)
+	}
+	if def.romPath != romPath || def.patchPath != patchPath {
+		t.Errorf("paths = %q, %q", def.romPath, def.patchPath)
+	}
+
+	if InitWithPatch(romPath, filepath.Join(dir, "missing.ips"), 0) {
+		t.Error("InitWithPatch succeeded without a patch file")
+	}
+}
@@ -0,0 +1,247 @@
+package ios
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// framePass describes one trip through the frame pipeline.
+type framePass struct {
+	// fastForward is set for frames run by RunFrames.
+	fastForward bool
+	// render runs the video stages for this frame.
+	render bool
+	// last is set on the final frame of a RunFrame or RunFrames call.
+	last bool
+}
+
+// frameStage is a named step of the frame pipeline. Stages run in slice
+// order; active, when set, decides whether the stage runs this pass.
+type frameStage struct {
+	name   string
+	active func(in *instance, p framePass) bool
+	run    func(in *instance)
+	// speculative stages also run on run-ahead frames, which are
+	// rolled back after rendering and must leave no other trace.
+	speculative bool
+}
+
+// framePipeline is the canonical per-frame order. Input must reach the
+// core before it runs, with turbo applied before a movie records it,
+// and the video stages run in the order they read each other's output:
+// cached frame, then blend, then filters, then border. Filters and
+// borders work in RGBA, so conversion to the app's pixel format comes
+// after them.
+var framePipeline = []frameStage{
+	{
+		name:   "turbo",
+		active: func(in *instance, _ framePass) bool { return in.hasTurbo() },
+		run:    (*instance).applyTurbo,
+	},
+	{
+		name:   "input",
+		active: func(in *instance, _ framePass) bool { return in.movie != nil },
+		run:    (*instance).movieFrame,
+	},
+	{
+		name:   "verify",
+		active: func(in *instance, _ framePass) bool { return in.movie != nil && in.movie.verify != nil },
+		run:    (*instance).verifyMovieFrame,
+	},
+	{
+		name:        "cheats",
+		active:      func(in *instance, _ framePass) bool { return in.hasMemoryPatches() },
+		run:         (*instance).applyMemoryPatches,
+		speculative: true,
+	},
+	{
+		name:        "core",
+		run:         (*instance).runCore,
+		speculative: true,
+	},
+	{
+		name:   "events",
+		active: func(in *instance, _ framePass) bool { return in.eventSource != nil },
+		run:    (*instance).collectEvents,
+	},
+	{
+		name:   "rumble",
+		active: func(in *instance, _ framePass) bool { return in.rumble != nil },
+		run:    (*instance).sampleRumble,
+	},
+	{
+		name: "achievements",
+		active: func(in *instance, _ framePass) bool {
+			return len(in.achievements.list) > 0
+		},
+		run: (*instance).evaluateAchievements,
+	},
+	{
+		name:   "idle",
+		active: func(_ *instance, p framePass) bool { return !p.fastForward },
+		run:    (*instance).updateIdle,
+	},
+	{
+		name:        "frame",
+		active:      func(_ *instance, p framePass) bool { return p.render },
+		run:         (*instance).cacheFrame,
+		speculative: true,
+	},
+	{
+		name: "blend",
+		active: func(in *instance, p framePass) bool {
+			return p.render && in.frameBlend != FrameBlendOff
+		},
+		run:         (*instance).blendFrame,
+		speculative: true,
+	},
+	{
+		name: "filters",
+		active: func(in *instance, p framePass) bool {
+			return p.render && len(in.videoFilters) > 0
+		},
+		run:         (*instance).applyFrameFilters,
+		speculative: true,
+	},
+	{
+		name:        "border",
+		active:      func(in *instance, p framePass) bool { return p.render && in.border != nil },
+		run:         (*instance).compositeBorder,
+		speculative: true,
+	},
+	{
+		name: "format",
+		active: func(in *instance, p framePass) bool {
+			return p.render && in.pixelFormat != PixelFormatRGBA8888
+		},
+		run:         (*instance).convertFrame,
+		speculative: true,
+	},
+	{
+		name: "audio",
+		active: func(in *instance, p framePass) bool {
+			return !p.fastForward || in.fastForwardAudio != FastForwardAudioDrop
+		},
+		run: func(in *instance) { in.appendAudio(in.emu.GetAudioSamples()) },
+	},
+	{
+		name:   "fade",
+		active: func(in *instance, _ framePass) bool { return in.fadeIn },
+		run:    (*instance).fadeInAudio,
+	},
+	{
+		name:   "sram",
+		active: func(_ *instance, p framePass) bool { return p.last },
+		run:    (*instance).checkSRAM,
+	},
+	{
+		name:   "rewind",
+		active: func(in *instance, _ framePass) bool { return in.rewind != nil },
+		run:    (*instance).captureRewind,
+	},
+}
+
+// pipelineOrder lists stages that must run before others.
+var pipelineOrder = [][2]string{
+	{"turbo", "input"},
+	{"input", "core"},
+	{"input", "verify"},
+	{"cheats", "core"},
+	{"core", "events"},
+	{"core", "rumble"},
+	{"core", "achievements"},
+	{"core", "frame"},
+	{"frame", "blend"},
+	{"blend", "filters"},
+	{"filters", "border"},
+	{"filters", "format"},
+	{"core", "audio"},
+	{"audio", "fade"},
+	{"core", "rewind"},
+}
+
+// checkPipelineOrder reports the first pipelineOrder rule framePipeline
+// breaks.
+func checkPipelineOrder() error {
+	index := func(name string) int {
+		return slices.IndexFunc(framePipeline, func(s frameStage) bool { return s.name == name })
+	}
+	for _, rule := range pipelineOrder {
+		before, after := index(rule[0]), index(rule[1])
+		if before < 0 || after < 0 {
+			return fmt.Errorf("pipeline missing stage %q or %q", rule[0], rule[1])
+		}
+		if before > after {
+			return fmt.Errorf("pipeline runs %q before %q", rule[1], rule[0])
+		}
+	}
+	return nil
+}
+
+// runPipeline runs each active stage once, recording its time.
+func (in *instance) runPipeline(p framePass) {
+	if len(in.stageTimes) != len(framePipeline) {
+		in.stageTimes = make([]time.Duration, len(framePipeline))
+	}
+	for i, s := range framePipeline {
+		if in.speculating && !s.speculative {
+			continue
+		}
+		if s.active != nil && !s.active(in, p) {
+			continue
+		}
+		start := time.Now()
+		s.run(in)
+		in.stageTimes[i] = time.Since(start)
+	}
+}
+
+// runCore emulates one frame.
+func (in *instance) runCore() {
+	start := time.Now()
+	in.emu.RunFrame()
+	if in.speculating {
+		return
+	}
+	in.stats.record(time.Since(start))
+	in.frameCount++
+}
+
+// stageTiming is a stage's entry in PipelineJSON and PerformanceJSON.
+type stageTiming struct {
+	Name   string  `json:"name"`
+	LastMs float64 `json:"lastMs"`
+}
+
+// activeStages returns the stages a RunFrame would run now, in order,
+// with the time each took when it last ran. in.mu must be held.
+func (in *instance) activeStages() []stageTiming {
+	stages := []stageTiming{}
+	p := framePass{render: true, last: true}
+	for i, s := range framePipeline {
+		if s.active != nil && !s.active(in, p) {
+			continue
+		}
+		t := stageTiming{Name: s.name}
+		if i < len(in.stageTimes) {
+			t.LastMs = durationMs(in.stageTimes[i])
+		}
+		stages = append(stages, t)
+	}
+	return stages
+}
+
+// PipelineJSON returns the stages RunFrame currently runs, in execution
+// order, as [{"name", "lastMs"}].
+func PipelineJSON() string {
+	def.mu.Lock()
+	stages := def.activeStages()
+	def.mu.Unlock()
+	data, err := json.Marshal(stages)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
@@ -0,0 +1,9 @@
+//go:build debug
+
+package ios
+
+func init() {
+	if err := checkPipelineOrder(); err != nil {
+		panic(err)
+	}
+}
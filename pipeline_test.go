@@ -0,0 +1,86 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func pipelineStages(t *testing.T) string {
+	t.Helper()
+	var stages []stageTiming
+	if err := json.Unmarshal([]byte(PipelineJSON()), &stages); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(stages))
+	for i, s := range stages {
+		names[i] = s.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestPipelineCanonicalOrder(t *testing.T) {
+	if err := checkPipelineOrder(); err != nil {
+		t.Fatal(err)
+	}
+
+	old := framePipeline
+	t.Cleanup(func() { framePipeline = old })
+	i := slices.IndexFunc(old, func(s frameStage) bool { return s.name == "border" })
+	framePipeline = append([]frameStage{old[i]}, slices.Delete(slices.Clone(old), i, i+1)...)
+	if checkPipelineOrder() == nil {
+		t.Error("border before filters not reported")
+	}
+}
+
+func TestPipelineActiveStages(t *testing.T) {
+	withFilterRegistry(t)
+	useMockEmulator(t)
+
+	if got := pipelineStages(t); got != "core,idle,frame,audio,sram" {
+		t.Errorf("default stages = %s", got)
+	}
+
+	var calls []string
+	RegisterFrameFilter("rec", &recordingFilter{calls: &calls, name: "rec"})
+	SetVideoFilter("rec", true)
+	StartInputRecording()
+	if got := pipelineStages(t); got != "input,core,idle,frame,filters,audio,sram" {
+		t.Errorf("stages with filter and recording = %s", got)
+	}
+	RunFrame()
+
+	var perf struct {
+		Stages []stageTiming `json:"stages"`
+	}
+	if err := json.Unmarshal([]byte(PerformanceJSON()), &perf); err != nil || len(perf.Stages) != 7 {
+		t.Errorf("performance stages = %+v, %v", perf.Stages, err)
+	}
+}
+
+func TestPipelineDisabledStageHasNoEffect(t *testing.T) {
+	withFilterRegistry(t)
+	e := useMockEmulator(t)
+	for i := range e.framebuffer {
+		e.framebuffer[i] = byte(i)
+	}
+	RunFrame()
+	plain := append([]byte(nil), GetFrameData()...)
+
+	var calls []string
+	RegisterFrameFilter("rec", &recordingFilter{tag: 0xAA, calls: &calls, name: "rec"})
+	SetVideoFilter("rec", true)
+	RunFrame()
+	filtered := GetFrameData()
+	if filtered[0] != 0xAA || !bytes.Equal(filtered[1:], plain[1:]) {
+		t.Error("filter stage changed more than its own effect")
+	}
+
+	SetVideoFilter("rec", false)
+	RunFrame()
+	if !bytes.Equal(GetFrameData(), plain) {
+		t.Error("disabled filter stage still affects the frame")
+	}
+}
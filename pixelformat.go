@@ -0,0 +1,134 @@
+package ios
+
+import "slices"
+
+// Pixel formats for SetPixelFormat.
+const (
+	// PixelFormatRGBA8888 is the core's native format, passed through.
+	PixelFormatRGBA8888 = 0
+	// PixelFormatBGRA8888 swaps red and blue for Metal's bgra8Unorm.
+	PixelFormatBGRA8888 = 1
+	// PixelFormatRGB565 packs each pixel into a little-endian uint16,
+	// halving the bytes per frame.
+	PixelFormatRGB565 = 2
+)
+
+// pixelFormatNames are the FramePixelFormat names, indexed by format.
+var pixelFormatNames = []string{"RGBA8888", "BGRA8888", "RGB565"}
+
+// SetPixelFormat sets the format of the frames returned by GetFrameData,
+// CopyFrameInto and AcquireFrame, and the stride reported by FrameStride.
+// Screenshots, borders and video filters always work in RGBA8888.
+// Returns false, leaving the format unchanged, if format is unknown.
+func SetPixelFormat(format int) bool {
+	return def.setPixelFormat(format)
+}
+
+func (in *instance) setPixelFormat(format int) bool {
+	if format < PixelFormatRGBA8888 || format > PixelFormatRGB565 {
+		setLastError("unknown pixel format %d", format)
+		return false
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.pixelFormat = format
+	in.convertFrame()
+	in.publishFrame()
+	return true
+}
+
+// GetPixelFormat returns the format set with SetPixelFormat.
+func GetPixelFormat() int {
+	return def.pixelFormat
+}
+
+// SetPreferredPixelFormat sets the frame format by name, "RGBA8888",
+// "BGRA8888" or "RGB565", like SetPixelFormat. Core frames are RGBA8888
+// and pass through without conversion in that format.
+// Returns false, leaving the format unchanged, if name is unknown.
+func SetPreferredPixelFormat(name string) bool {
+	return def.setPreferredPixelFormat(name)
+}
+
+func (in *instance) setPreferredPixelFormat(name string) bool {
+	format := slices.Index(pixelFormatNames, name)
+	if format < 0 {
+		setLastError("unknown pixel format %q", name)
+		return false
+	}
+	return in.setPixelFormat(format)
+}
+
+// FramePixelFormat returns the name of the frame format, for choosing the
+// matching texture format: "RGBA8888", "BGRA8888" or "RGB565".
+func FramePixelFormat() string {
+	return def.framePixelFormat()
+}
+
+func (in *instance) framePixelFormat() string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return pixelFormatNames[in.pixelFormat]
+}
+
+// FrameBytesPerPixel returns the size of a pixel in the frame format.
+func FrameBytesPerPixel() int {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.bytesPerPixel()
+}
+
+// bytesPerPixel returns the size of a pixel in the output format.
+// in.mu must be held.
+func (in *instance) bytesPerPixel() int {
+	if in.pixelFormat == PixelFormatRGB565 {
+		return 2
+	}
+	return 4
+}
+
+// outputStride converts a core stride to the output format.
+func (in *instance) outputStride(stride int) int {
+	return stride / 4 * in.bytesPerPixel()
+}
+
+// outputFrame returns the frame in the output format.
+func (in *instance) outputFrame() []byte {
+	if in.pixelFormat == PixelFormatRGBA8888 {
+		return in.frameData
+	}
+	return in.converted
+}
+
+// convertFrame converts frameData into the output format, reusing the
+// conversion buffer.
+func (in *instance) convertFrame() {
+	if in.pixelFormat == PixelFormatRGBA8888 || len(in.frameData) == 0 {
+		in.converted = in.converted[:0]
+		return
+	}
+
+	src := in.frameData
+	n := len(src) / 4 * in.bytesPerPixel()
+	if cap(in.converted) < n {
+		in.converted = make([]byte, n)
+	}
+	dst := in.converted[:n]
+	in.converted = dst
+
+	switch in.pixelFormat {
+	case PixelFormatBGRA8888:
+		for i := 0; i+3 < len(src); i += 4 {
+			dst[i] = src[i+2]
+			dst[i+1] = src[i+1]
+			dst[i+2] = src[i]
+			dst[i+3] = src[i+3]
+		}
+	case PixelFormatRGB565:
+		for i, j := 0, 0; i+3 < len(src); i, j = i+4, j+2 {
+			p := uint16(src[i]>>3)<<11 | uint16(src[i+1]>>2)<<5 | uint16(src[i+2]>>3)
+			dst[j] = byte(p)
+			dst[j+1] = byte(p >> 8)
+		}
+	}
+}
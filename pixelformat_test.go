@@ -0,0 +1,84 @@
+package ios
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPixelFormatConversion(t *testing.T) {
+	e := useMockEmulator(t)
+	pattern := []byte{
+		0xFF, 0x00, 0x00, 0xFF, // red
+		0x00, 0xFF, 0x00, 0xFF, // green
+		0x00, 0x00, 0xFF, 0x80, // blue, half alpha
+		0x84, 0x82, 0x08, 0xFF, // mixed
+	}
+	for i := 0; i < len(e.framebuffer); i += len(pattern) {
+		copy(e.framebuffer[i:], pattern)
+	}
+
+	tests := []struct {
+		format int
+		stride int
+		first  []byte
+	}{
+		{PixelFormatRGBA8888, 16, pattern},
+		{PixelFormatBGRA8888, 16, []byte{
+			0x00, 0x00, 0xFF, 0xFF,
+			0x00, 0xFF, 0x00, 0xFF,
+			0xFF, 0x00, 0x00, 0x80,
+			0x08, 0x82, 0x84, 0xFF,
+		}},
+		// 0xF800, 0x07E0, 0x001F, 0x8401
+		{PixelFormatRGB565, 8, []byte{0x00, 0xF8, 0xE0, 0x07, 0x1F, 0x00, 0x01, 0x84}},
+	}
+	for _, tt := range tests {
+		if !SetPixelFormat(tt.format) {
+			t.Fatalf("SetPixelFormat(%d) failed", tt.format)
+		}
+		RunFrame()
+		frame := GetFrameData()
+		if FrameStride() != tt.stride || len(frame) != tt.stride*4 || GetFrameDataLen() != len(frame) {
+			t.Errorf("format %d: stride = %d, len = %d", tt.format, FrameStride(), len(frame))
+		}
+		if !bytes.Equal(frame[:len(tt.first)], tt.first) {
+			t.Errorf("format %d: row = % x, want % x", tt.format, frame[:len(tt.first)], tt.first)
+		}
+		if !bytes.Equal(e.framebuffer[:16], pattern) {
+			t.Fatalf("format %d: core framebuffer modified", tt.format)
+		}
+	}
+
+	if SetPixelFormat(7) || GetPixelFormat() != PixelFormatRGB565 {
+		t.Error("unknown format accepted or changed the format")
+	}
+}
+
+func TestPixelFormatReusesBuffer(t *testing.T) {
+	useMockEmulator(t)
+	SetPixelFormat(PixelFormatBGRA8888)
+	RunFrame()
+	first := &def.converted[0]
+	RunFrame()
+	if &def.converted[0] != first {
+		t.Error("conversion buffer reallocated between frames")
+	}
+}
+
+func TestPreferredPixelFormatByName(t *testing.T) {
+	useMockEmulator(t)
+	t.Cleanup(func() { SetPixelFormat(PixelFormatRGBA8888) })
+
+	if FramePixelFormat() != "RGBA8888" || FrameBytesPerPixel() != 4 {
+		t.Errorf("default format = %s, %d bytes", FramePixelFormat(), FrameBytesPerPixel())
+	}
+	if !SetPreferredPixelFormat("RGB565") || GetPixelFormat() != PixelFormatRGB565 {
+		t.Fatal("SetPreferredPixelFormat(RGB565) failed")
+	}
+	if FramePixelFormat() != "RGB565" || FrameBytesPerPixel() != 2 {
+		t.Errorf("format = %s, %d bytes", FramePixelFormat(), FrameBytesPerPixel())
+	}
+	if SetPreferredPixelFormat("YUV420") || GetPixelFormat() != PixelFormatRGB565 {
+		t.Error("unknown format accepted")
+	}
+}
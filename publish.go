@@ -0,0 +1,201 @@
+package ios
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// snapshot is a published frame or audio buffer. Its data is never
+// written once published, so getters on other threads can hand it out
+// while the next frame runs.
+type snapshot struct {
+	data []byte
+	// stride, width and bpp describe frame snapshots: bytes per row,
+	// visible pixels per row and bytes per pixel.
+	stride int
+	width  int
+	bpp    int
+	// fetched is set once data has been returned to a caller, after
+	// which the buffer cannot be recycled.
+	fetched atomic.Bool
+	// pinned is set while AcquireFrame holds the snapshot, which keeps
+	// it out of recycling until ReleaseFrame. Guarded by in.mu.
+	pinned bool
+}
+
+// publisher double-buffers the output of the last completed frame.
+// RunFrame fills a back buffer without holding mu and only takes it to
+// swap, so the audio and video getters never wait on emulation.
+type publisher struct {
+	mu     sync.RWMutex
+	frame  *snapshot
+	audio  *snapshot
+	border *snapshot
+
+	// spareFrame, spareAudio and spareBorder are previous snapshots no
+	// caller saw, reused for the next publish. Only the frame thread
+	// touches them.
+	spareFrame  *snapshot
+	spareAudio  *snapshot
+	spareBorder *snapshot
+
+	// frameSeq counts published frames that differed from the previous
+	// one; seenSeq is the count FrameChanged last reported.
+	frameSeq atomic.Int64
+	seenSeq  atomic.Int64
+}
+
+// fill copies src into spare, or a new snapshot if spare is nil,
+// growing its buffer if needed.
+func fill(spare *snapshot, src []byte) *snapshot {
+	s := spare
+	if s == nil {
+		s = &snapshot{}
+	}
+	if cap(s.data) < len(src) {
+		s.data = make([]byte, len(src))
+	}
+	s.data = s.data[:len(src)]
+	copy(s.data, src)
+	return s
+}
+
+// swap publishes next in *slot and returns the snapshot it replaced if
+// no caller fetched or pinned it. in.mu must be held.
+func (p *publisher) swap(slot **snapshot, next *snapshot) *snapshot {
+	p.mu.Lock()
+	old := *slot
+	*slot = next
+	p.mu.Unlock()
+	if old == nil || old.pinned || old.fetched.Load() {
+		return nil
+	}
+	return old
+}
+
+// publishFrame publishes the output frame. A frame identical to the one
+// already published is dropped, leaving the sequence unchanged.
+// in.mu must be held.
+func (in *instance) publishFrame() {
+	in.publishBorder()
+	p := &in.published
+	frame := in.outputFrame()
+	if len(frame) == 0 {
+		return
+	}
+	stride, width, bpp := in.frameStrideLocked(), in.visibleWidth(), in.bytesPerPixel()
+	// Only this thread writes p.frame, so it can be read without mu.
+	if cur := p.frame; cur != nil && cur.stride == stride && cur.width == width &&
+		cur.bpp == bpp && bytes.Equal(cur.data, frame) {
+		return
+	}
+	s := fill(p.spareFrame, frame)
+	s.stride = stride
+	s.width = width
+	s.bpp = bpp
+	p.spareFrame = p.swap(&p.frame, s)
+	p.frameSeq.Add(1)
+}
+
+// publishAudio publishes the audio of the last RunFrame or RunFrames.
+// in.mu must be held.
+func (in *instance) publishAudio() {
+	p := &in.published
+	var s *snapshot
+	if len(in.audioData) > 0 {
+		s = fill(p.spareAudio, in.audioData)
+		p.spareAudio = nil
+	}
+	if old := p.swap(&p.audio, s); old != nil {
+		p.spareAudio = old
+	}
+}
+
+// publishBorder publishes the frame composited into the border image,
+// or clears it when no border is set. in.mu must be held.
+func (in *instance) publishBorder() {
+	p := &in.published
+	var s *snapshot
+	if b := in.border; b != nil && len(b.out) > 0 {
+		s = fill(p.spareBorder, b.out)
+		s.stride, s.width, s.bpp = b.img.Stride, b.img.Rect.Dx(), 4
+		p.spareBorder = nil
+	}
+	if old := p.swap(&p.border, s); old != nil {
+		p.spareBorder = old
+	}
+}
+
+// FrameSequence returns a counter that advances each time RunFrame
+// publishes a frame different from the previous one. The app can skip the
+// texture upload while it is unchanged.
+func FrameSequence() int64 {
+	return def.published.frameSeq.Load()
+}
+
+// FrameChanged returns whether a different frame has been published since
+// the last call.
+func FrameChanged() bool {
+	return def.frameChanged()
+}
+
+func (in *instance) frameChanged() bool {
+	p := &in.published
+	seq := p.frameSeq.Load()
+	return p.seenSeq.Swap(seq) != seq
+}
+
+// clearPublished drops the published buffers on close.
+func (in *instance) clearPublished() {
+	p := &in.published
+	p.mu.Lock()
+	p.frame, p.audio, p.border = nil, nil, nil
+	p.mu.Unlock()
+	p.spareFrame, p.spareAudio, p.spareBorder = nil, nil, nil
+}
+
+// latestFrame returns the published frame data, marking it fetched.
+func (in *instance) latestFrame() []byte {
+	p := &in.published
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.frame == nil {
+		return nil
+	}
+	p.frame.fetched.Store(true)
+	return p.frame.data
+}
+
+// withLatestFrame calls f with the published frame while holding the
+// read lock, so f may copy from it without marking it fetched.
+func (in *instance) withLatestFrame(f func(s *snapshot)) {
+	p := &in.published
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	f(p.frame)
+}
+
+// latestAudio returns the published audio, marking it fetched.
+func (in *instance) latestAudio() []byte {
+	p := &in.published
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.audio == nil {
+		return nil
+	}
+	p.audio.fetched.Store(true)
+	return p.audio.data
+}
+
+// latestBorder returns the published bordered frame, marking it fetched.
+func (in *instance) latestBorder() []byte {
+	p := &in.published
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.border == nil {
+		return nil
+	}
+	p.border.fetched.Store(true)
+	return p.border.data
+}
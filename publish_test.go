@@ -0,0 +1,205 @@
+package ios
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func TestPublishedFrameIsStable(t *testing.T) {
+	e := useMockEmulator(t)
+	e.framebuffer[0] = 1
+	RunFrame()
+	first := GetFrameData()
+
+	e.framebuffer[0] = 2
+	RunFrame()
+	if first[0] != 1 {
+		t.Error("fetched frame modified by a later RunFrame")
+	}
+	if GetFrameData()[0] != 2 {
+		t.Error("latest frame not published")
+	}
+}
+
+func TestPublishRecyclesUnfetchedBuffers(t *testing.T) {
+	e := useMockEmulator(t)
+	RunFrame()
+	RunFrame()
+	dst := make([]byte, GetFrameDataLen())
+	CopyFrameInto(dst)
+
+	// Two buffers alternate between front and spare.
+	seen := map[*byte]bool{}
+	for i := 0; i < 10; i++ {
+		e.framebuffer[0]++
+		RunFrame()
+		CopyFrameInto(dst)
+		if dst[0] != e.framebuffer[0] {
+			t.Fatal("CopyFrameInto returned a stale frame")
+		}
+		seen[&def.published.frame.data[0]] = true
+	}
+	if len(seen) > 2 {
+		t.Errorf("%d frame buffers used, want 2", len(seen))
+	}
+}
+
+func TestFrameChangedSkipsIdenticalFrames(t *testing.T) {
+	e := useMockEmulator(t)
+	RunFrame()
+	seq := FrameSequence()
+	if !FrameChanged() || FrameChanged() {
+		t.Fatal("FrameChanged did not report the first frame once")
+	}
+
+	RunFrame()
+	if FrameChanged() || FrameSequence() != seq {
+		t.Error("identical frame reported as changed")
+	}
+
+	e.framebuffer[0] = 9
+	RunFrame()
+	if !FrameChanged() || FrameSequence() != seq+1 {
+		t.Error("new frame not reported as changed")
+	}
+	if GetFrameData()[0] != 9 {
+		t.Error("new frame not published")
+	}
+}
+
+func TestInputLatchedUntilRunFrame(t *testing.T) {
+	e := useMockEmulator(t)
+	SetInput(0, 0x3)
+	if e.inputs[0] != 0 {
+		t.Error("input reached the core before RunFrame")
+	}
+	RunFrame()
+	if e.inputs[0] != 0x3 {
+		t.Errorf("input = %#x after RunFrame, want 0x3", e.inputs[0])
+	}
+}
+
+// TestThreadSplitRace drives the display link, touch, audio render and UI
+// call patterns concurrently; run with -race.
+func TestThreadSplitRace(t *testing.T) {
+	e := useMockEmulator(t)
+	e.samples = make([]int16, 256)
+	const iterations = 2000
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() { // display link
+		defer wg.Done()
+		dst := make([]byte, 64)
+		for i := 0; i < iterations; i++ {
+			RunFrame()
+			CopyFrameInto(dst)
+			GetFrameData()
+		}
+	}()
+	go func() { // touch handlers
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			SetInput(i%2, i)
+		}
+	}()
+	go func() { // audio render
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			if pcm := GetAudioData(); len(pcm) != 0 && len(pcm) != 512 {
+				t.Errorf("torn audio buffer of %d bytes", len(pcm))
+				return
+			}
+		}
+	}()
+	go func() { // UI actions
+		defer wg.Done()
+		for i := 0; i < iterations/10; i++ {
+			SaveState()
+			Pause()
+			Resume()
+		}
+	}()
+	wg.Wait()
+
+	Close()
+	if GetFrameData() != nil || !bytes.Equal(GetAudioData(), nil) {
+		t.Error("buffers still published after Close")
+	}
+}
+
+// TestCloseDuringFrameRace closes and reloads the game while frames run,
+// states and SRAM are saved and loaded and the frame, state and SRAM
+// getters read from other threads; run with -race.
+func TestCloseDuringFrameRace(t *testing.T) {
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			return &mockSRAMEmulator{mockEmulator: newMockEmulator(rom, region), sram: []byte{1, 2}}, nil
+		},
+	})
+	rom := writeROM(t, "reload.bin", []byte{0x01})
+	if !Init(rom, 0) {
+		t.Fatal("Init failed")
+	}
+	const iterations = 500
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() { // display link
+		defer wg.Done()
+		dst := make([]byte, 64)
+		for i := 0; i < iterations; i++ {
+			RunFrame()
+			CopyFrameInto(dst)
+		}
+	}()
+	go func() { // UI save button
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			SaveState()
+			LoadSRAM([]byte{1, 2})
+			SetSRAMCheckInterval(1 + i%2)
+		}
+	}()
+	go func() { // readers
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			FrameWidth()
+			FrameStride()
+			FrameHeight()
+			Region()
+			HasSaveStates()
+			if n := StateLen(); n > 0 {
+				StateByte(n - 1)
+			}
+			GetStateData()
+			GetFrameData()
+			FramePixelFormat()
+			HasAnalogInput()
+			HasPointerInput()
+			HasRumble()
+			if HasSRAM() {
+				PrepareSRAM()
+			}
+			if n := SRAMLen(); n > 0 {
+				SRAMByte(n - 1)
+			}
+			SRAMBytes(0, 4)
+			GetSRAMData()
+		}
+	}()
+	go func() { // app lifecycle
+		defer wg.Done()
+		for i := 0; i < iterations/10; i++ {
+			Close()
+			if !Init(rom, 0) {
+				t.Error("Init failed")
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
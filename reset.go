@@ -0,0 +1,131 @@
+package ios
+
+// Resetter is an optional emulator interface for cores that can reset
+// without being re-created.
+type Resetter interface {
+	// Reset resets the console. A hard reset is a power cycle; a soft
+	// reset presses the console's reset button.
+	Reset(hard bool)
+}
+
+// OptionRestarter is an optional emulator interface for cores with
+// options that only take effect once the emulator is re-created.
+type OptionRestarter interface {
+	// OptionRequiresRestart reports whether a change to key needs
+	// ReinitializeWithCurrentROM to take effect.
+	OptionRequiresRestart(key string) bool
+}
+
+// restartEvent is the PollEventsJSON type queued when an option change
+// needs ReinitializeWithCurrentROM; its message is the option key.
+const restartEvent = "restart_required"
+
+// Reset resets the emulator without tearing down the instance.
+// Cores implementing Resetter handle both kinds of reset. Otherwise a
+// hard reset re-creates the emulator from the ROM loaded by Init,
+// keeping the region, options set since load, and SRAM.
+// Returns false if the reset is not possible.
+func Reset(hard bool) bool {
+	return def.reset(hard)
+}
+
+func (in *instance) reset(hard bool) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil {
+		return false
+	}
+
+	if in.resetter != nil {
+		in.resetter.Reset(hard)
+	} else if !hard || !in.recreate() {
+		return false
+	}
+
+	in.wakeIdle()
+	in.resetBlend()
+	in.reapplyCheats()
+	return true
+}
+
+// recreate replaces the emulator with a fresh one built from the loaded
+// ROM with the core it was loaded with, carrying over region, options
+// and SRAM.
+func (in *instance) recreate() bool {
+	if in.factory == nil || in.rom == nil {
+		return false
+	}
+
+	region := in.emu.GetRegion()
+	var sram []byte
+	if in.hasSRAMLocked() {
+		sram = in.batterySaver.GetSRAM()
+	}
+
+	e, err := in.factory.CreateEmulator(in.rom, region)
+	if err != nil {
+		setLastError("failed to re-create emulator: %v", err)
+		return false
+	}
+
+	in.emu.Close()
+	in.attach(e)
+
+	if err := in.restoreDiscs(); err != nil {
+		setLastError("failed to restore discs: %v", err)
+	}
+	for _, opt := range in.options {
+		e.SetOption(opt.key, opt.value)
+	}
+	if sram != nil && in.batterySaver != nil {
+		in.batterySaver.SetSRAM(sram)
+	}
+	for player, buttons := range in.inputs {
+		in.presentInput(player, buttons)
+	}
+	in.restartRequired = false
+	return true
+}
+
+// noteOptionRestart queues a restart_required event if changing key to
+// value needs the emulator re-created.
+func (in *instance) noteOptionRestart(key, old, value string) {
+	r, ok := in.emu.(OptionRestarter)
+	if !ok || old == value || !r.OptionRequiresRestart(key) {
+		return
+	}
+	in.restartRequired = true
+	in.events.push([]CoreEvent{{Type: restartEvent, Message: key}}, in.frameCount)
+}
+
+// RestartRequired reports whether an option changed since load needs
+// ReinitializeWithCurrentROM to take effect. PollEventsJSON also reports
+// each such change as a "restart_required" event naming the option.
+func RestartRequired() bool {
+	return def.restartRequired
+}
+
+// ReinitializeWithCurrentROM re-creates the emulator from the loaded ROM
+// so options that need a restart take effect, keeping the region,
+// options set since load, SRAM and inserted disc. This is a hard reset
+// even for cores implementing Resetter.
+// Returns false if no ROM is loaded or the core fails (see LastError).
+func ReinitializeWithCurrentROM() bool {
+	return def.reinitialize()
+}
+
+func (in *instance) reinitialize() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.emu == nil {
+		setLastError("no ROM loaded")
+		return false
+	}
+	if !in.recreate() {
+		return false
+	}
+	in.wakeIdle()
+	in.resetBlend()
+	in.reapplyCheats()
+	return true
+}
@@ -0,0 +1,138 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+type mockResetEmulator struct {
+	*mockEmulator
+	resets []bool
+}
+
+func (m *mockResetEmulator) Reset(hard bool) { m.resets = append(m.resets, hard) }
+
+func TestResetUsesResetter(t *testing.T) {
+	var e *mockResetEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockResetEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+
+	if !Reset(false) || !Reset(true) {
+		t.Fatal("Reset failed")
+	}
+	if len(e.resets) != 2 || e.resets[0] || !e.resets[1] {
+		t.Errorf("resets = %v, want [false true]", e.resets)
+	}
+}
+
+func TestHardResetFallbackKeepsOptionsAndSRAM(t *testing.T) {
+	var created []*mockSRAMEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e := &mockSRAMEmulator{mockEmulator: newMockEmulator(rom, region)}
+			created = append(created, e)
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x42}), 1) {
+		t.Fatal("Init failed")
+	}
+	SetOption("opt_video", "true")
+	SetOption("opt_audio", "true")
+	SetOption("opt_video", "false")
+	created[0].sram = []byte{1, 2, 3}
+
+	if Reset(false) {
+		t.Error("soft reset succeeded without Resetter")
+	}
+	if !Reset(true) {
+		t.Fatal("hard reset failed")
+	}
+	if len(created) != 2 {
+		t.Fatalf("created %d emulators, want 2", len(created))
+	}
+	old, e := created[0], created[1]
+	if !old.closed {
+		t.Error("old emulator not closed")
+	}
+	if !bytes.Equal(e.rom, []byte{0x42}) || e.region != emucore.RegionPAL {
+		t.Errorf("re-created with rom %v region %v", e.rom, e.region)
+	}
+	if e.options["opt_video"] != "false" || e.options["opt_audio"] != "true" {
+		t.Errorf("options = %v", e.options)
+	}
+	if !bytes.Equal(e.sram, []byte{1, 2, 3}) {
+		t.Errorf("sram = %v, want [1 2 3]", e.sram)
+	}
+
+	RunFrame()
+	if e.frames != 1 || old.frames != 0 {
+		t.Error("RunFrame not routed to re-created emulator")
+	}
+}
+
+func TestResetWithoutEmulator(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	if Reset(true) {
+		t.Error("Reset succeeded without an emulator")
+	}
+}
+
+// mockRestartEmulator needs a restart for changes to opt_core.
+type mockRestartEmulator struct {
+	*mockEmulator
+}
+
+func (m *mockRestartEmulator) OptionRequiresRestart(key string) bool { return key == "opt_core" }
+
+func TestOptionRestartAndReinitialize(t *testing.T) {
+	var created []*mockRestartEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e := &mockRestartEmulator{newMockEmulator(rom, region)}
+			created = append(created, e)
+			return e, nil
+		},
+	})
+	if ReinitializeWithCurrentROM() {
+		t.Error("reinitialized with no ROM loaded")
+	}
+	if !Init(writeROM(t, "rom.bin", []byte{0x42}), 1) {
+		t.Fatal("Init failed")
+	}
+
+	SetOption("opt_video", "true")
+	if RestartRequired() {
+		t.Error("restart required without a restart option change")
+	}
+	SetOption("opt_core", "true")
+	SetOption("opt_core", "true") // unchanged: no second event
+	if !RestartRequired() {
+		t.Fatal("restart not required after opt_core changed")
+	}
+	var events []queuedEvent
+	if err := json.Unmarshal([]byte(PollEventsJSON()), &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != restartEvent || events[0].Message != "opt_core" {
+		t.Errorf("events = %+v", events)
+	}
+
+	if !ReinitializeWithCurrentROM() || len(created) != 2 || RestartRequired() {
+		t.Fatalf("reinitialize: created %d, restart required %v", len(created), RestartRequired())
+	}
+	e := created[1]
+	if e.options["opt_core"] != "true" || e.options["opt_video"] != "true" || e.region != emucore.RegionPAL {
+		t.Errorf("re-created with options %v region %v", e.options, e.region)
+	}
+}
@@ -0,0 +1,215 @@
+package ios
+
+// defaultRewindInterval is the number of frames between rewind
+// snapshots until SetRewindInterval is called.
+const defaultRewindInterval = 4
+
+// rewindSnapshot is one captured state and the frame it was taken at.
+type rewindSnapshot struct {
+	state []byte
+	frame int64
+	// compressed is set when state was deflated at SetStateCompression.
+	compressed bool
+	// delta is set when state holds only the bytes that differ from
+	// the nearest earlier keyframe.
+	delta bool
+}
+
+// rewindBuffer holds the most recent snapshots within a byte budget.
+// Snapshots are kept oldest first; buffers of dropped and popped
+// snapshots are reused by later captures.
+type rewindBuffer struct {
+	capacity  int
+	interval  int
+	snapshots []rewindSnapshot
+	size      int
+	spare     [][]byte
+
+	// keyInterval is how many snapshots share a keyframe. key is the
+	// newest keyframe's full state, taken at keyFrame, and sinceKey
+	// counts the deltas stored against it.
+	keyInterval int
+	key         []byte
+	keyFrame    int64
+	sinceKey    int
+}
+
+// EnableRewind keeps up to capacityMB megabytes of save states, one every
+// SetRewindInterval frames, for RewindStep. A capacity of 0 disables
+// rewind and frees the history. Rewind applies to the loaded game; Init
+// and Close turn it off. Returns false if the core does not support save
+// states or hardcore mode is on.
+func EnableRewind(capacityMB int) bool {
+	return def.enableRewind(capacityMB)
+}
+
+func (in *instance) enableRewind(capacityMB int) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if capacityMB <= 0 {
+		in.rewind = nil
+		return true
+	}
+	if in.saveStater == nil {
+		setLastError("rewind: core does not support save states")
+		return false
+	}
+	if in.hardcore {
+		setLastError("rewind is disabled in hardcore mode")
+		return false
+	}
+	if in.rewind == nil {
+		in.rewind = &rewindBuffer{
+			interval:    defaultRewindInterval,
+			keyInterval: defaultRewindKeyframeInterval,
+		}
+	}
+	in.rewind.capacity = capacityMB * 1024 * 1024
+	in.rewind.trim()
+	return true
+}
+
+// SetRewindInterval sets how many frames pass between rewind snapshots.
+// Values below 1 are treated as 1.
+func SetRewindInterval(frames int) {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	if def.rewind != nil {
+		def.rewind.interval = max(frames, 1)
+	}
+}
+
+// RewindStep restores the newest rewind snapshot and removes it, moving
+// back one interval. Returns false if there is nothing to rewind to.
+func RewindStep() bool {
+	return def.rewindStep()
+}
+
+func (in *instance) rewindStep() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	r := in.rewind
+	if r == nil || len(r.snapshots) == 0 {
+		return false
+	}
+	if in.movie != nil {
+		setLastError("rewind: not available during input recording or playback")
+		return false
+	}
+
+	last := len(r.snapshots) - 1
+	s := r.snapshots[last]
+	state, err := r.unpack(last)
+	r.snapshots = r.snapshots[:last]
+	r.size -= len(s.state)
+	defer r.recycle(s.state)
+	if s.delta {
+		r.sinceKey = max(r.sinceKey-1, 0)
+	} else if s.frame == r.keyFrame {
+		// The next capture starts a new keyframe.
+		r.key = nil
+	}
+	if err != nil {
+		setLastError("rewind: failed to unpack state: %v", err)
+		return false
+	}
+
+	if err := in.saveStater.Deserialize(state); err != nil {
+		setLastError("rewind: failed to restore state: %v", err)
+		return false
+	}
+	in.frameCount = s.frame
+	in.resetBlend()
+	in.reapplyCheats()
+	return true
+}
+
+// RewindDepth returns the number of snapshots RewindStep can restore.
+func RewindDepth() int {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	if def.rewind == nil {
+		return 0
+	}
+	return len(def.rewind.snapshots)
+}
+
+// captureRewind snapshots the state every interval frames.
+func (in *instance) captureRewind() {
+	r := in.rewind
+	if in.frameCount%int64(r.interval) != 0 {
+		return
+	}
+
+	var buf []byte
+	if n := len(r.spare); n > 0 {
+		buf, r.spare = r.spare[n-1], r.spare[:n-1]
+	}
+	var (
+		state []byte
+		err   error
+	)
+	if a, ok := in.emu.(StateAppender); ok {
+		state, err = a.AppendState(buf[:0])
+	} else {
+		state, err = in.saveStater.Serialize()
+		r.recycle(buf)
+	}
+	if err != nil {
+		journalf("warning", "rewind: snapshot failed: %v", err)
+		return
+	}
+
+	snap := r.pack(state, in.frameCount, in.stateCompression)
+	r.snapshots = append(r.snapshots, snap)
+	r.size += len(snap.state)
+	r.trim()
+}
+
+// trim drops the oldest snapshots until the buffer fits its capacity.
+// A keyframe is dropped together with the deltas that depend on it.
+func (r *rewindBuffer) trim() {
+	drop := 0
+	for r.size > r.capacity && drop < len(r.snapshots) {
+		r.size -= len(r.snapshots[drop].state)
+		r.recycle(r.snapshots[drop].state)
+		drop++
+		for drop < len(r.snapshots) && r.snapshots[drop].delta {
+			r.size -= len(r.snapshots[drop].state)
+			r.recycle(r.snapshots[drop].state)
+			drop++
+		}
+	}
+	if drop > 0 {
+		r.snapshots = append(r.snapshots[:0], r.snapshots[drop:]...)
+	}
+	if len(r.snapshots) == 0 {
+		r.key = nil
+	}
+}
+
+// recycle keeps buf for a later capture. Only a couple of buffers are
+// kept; captures replace dropped snapshots one for one.
+func (r *rewindBuffer) recycle(buf []byte) {
+	if buf != nil && len(r.spare) < 2 {
+		r.spare = append(r.spare, buf)
+	}
+}
+
+// clear drops every snapshot, keeping the configuration.
+func (r *rewindBuffer) clear() {
+	r.snapshots = nil
+	r.size = 0
+	r.spare = nil
+	r.key = nil
+	r.sinceKey = 0
+}
+
+// bytes returns the memory held by snapshots and spare buffers.
+func (r *rewindBuffer) bytes() int {
+	n := r.size + cap(r.key)
+	for _, b := range r.spare {
+		n += cap(b)
+	}
+	return n
+}
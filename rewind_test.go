@@ -0,0 +1,64 @@
+package ios
+
+import "testing"
+
+func TestRewindStep(t *testing.T) {
+	e := useStateEmulator(t, []byte{0x01})
+	if !EnableRewind(1) {
+		t.Fatalf("EnableRewind failed: %s", LastError())
+	}
+	SetRewindInterval(2)
+	for i := 1; i <= 6; i++ {
+		e.value = byte(i)
+		RunFrame()
+	}
+	if got := RewindDepth(); got != 3 {
+		t.Fatalf("RewindDepth = %d, want 3", got)
+	}
+
+	for _, want := range []byte{6, 4, 2} {
+		e.value = 0
+		if !RewindStep() {
+			t.Fatal("RewindStep failed")
+		}
+		if e.value != want || FrameCount() != int64(want) {
+			t.Errorf("rewound to value %d frame %d, want %d", e.value, FrameCount(), want)
+		}
+	}
+	if RewindStep() {
+		t.Error("RewindStep succeeded with empty history")
+	}
+}
+
+func TestRewindCapacity(t *testing.T) {
+	e := useStateEmulator(t, []byte{0x01})
+	EnableRewind(1)
+	SetRewindInterval(1)
+	// Each snapshot is 2 bytes; shrink the budget to hold three.
+	def.rewind.capacity = 6
+	for i := 1; i <= 10; i++ {
+		e.value = byte(i)
+		RunFrame()
+	}
+	if got := RewindDepth(); got != 3 {
+		t.Fatalf("RewindDepth = %d, want 3", got)
+	}
+	RewindStep()
+	RewindStep()
+	RewindStep()
+	if e.value != 8 {
+		t.Errorf("oldest kept snapshot = %d, want 8", e.value)
+	}
+
+	EnableRewind(0)
+	if RewindDepth() != 0 || RewindStep() {
+		t.Error("disabled rewind kept history")
+	}
+}
+
+func TestRewindUnsupported(t *testing.T) {
+	useMockEmulator(t)
+	if EnableRewind(1) {
+		t.Error("EnableRewind succeeded without save states")
+	}
+}
@@ -0,0 +1,133 @@
+package ios
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// defaultRewindKeyframeInterval is how many rewind snapshots share one
+// full keyframe until SetRewindKeyframeInterval is called.
+const defaultRewindKeyframeInterval = 30
+
+// deltaMergeGap is the longest run of unchanged bytes kept inside a
+// delta run; shorter gaps cost less than starting a new run.
+const deltaMergeGap = 8
+
+var errBadDelta = errors.New("malformed rewind delta")
+
+// encodeDelta appends to dst the runs where state differs from key, which
+// must be the same length. Each run is a uvarint gap since the previous
+// run, a uvarint length and the run's bytes from state.
+func encodeDelta(dst, key, state []byte) []byte {
+	pos := 0
+	for i := 0; i < len(state); i++ {
+		if state[i] == key[i] {
+			continue
+		}
+		end := i + 1
+		for j := end; j < len(state) && j-end < deltaMergeGap; j++ {
+			if state[j] != key[j] {
+				end = j + 1
+			}
+		}
+		dst = binary.AppendUvarint(dst, uint64(i-pos))
+		dst = binary.AppendUvarint(dst, uint64(end-i))
+		dst = append(dst, state[i:end]...)
+		pos = end
+		i = end - 1
+	}
+	return dst
+}
+
+// applyDelta rebuilds the state encodeDelta encoded against key into
+// dst's capacity.
+func applyDelta(dst, key, delta []byte) ([]byte, error) {
+	state := append(dst[:0], key...)
+	pos := 0
+	for len(delta) > 0 {
+		gap, n := binary.Uvarint(delta)
+		if n <= 0 {
+			return nil, errBadDelta
+		}
+		delta = delta[n:]
+		length, n := binary.Uvarint(delta)
+		if n <= 0 {
+			return nil, errBadDelta
+		}
+		delta = delta[n:]
+		if gap > uint64(len(state)-pos) || length > uint64(len(state)-pos-int(gap)) || length > uint64(len(delta)) {
+			return nil, errBadDelta
+		}
+		pos += int(gap)
+		pos += copy(state[pos:pos+int(length)], delta[:length])
+		delta = delta[length:]
+	}
+	return state, nil
+}
+
+// SetRewindKeyframeInterval sets how many rewind snapshots share one full
+// keyframe. The others store only the bytes that differ from it, which
+// keeps a long rewind window small. Values below 1 are treated as 1,
+// which stores every snapshot in full.
+func SetRewindKeyframeInterval(snapshots int) {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	if def.rewind != nil {
+		def.rewind.keyInterval = max(snapshots, 1)
+	}
+}
+
+// pack turns a captured state into a snapshot: a delta against the
+// current keyframe when that is smaller, otherwise a new keyframe, then
+// compressed at level if it is positive. Buffers the snapshot does not
+// keep are recycled.
+func (r *rewindBuffer) pack(state []byte, frame int64, level int) rewindSnapshot {
+	s := rewindSnapshot{state: state, frame: frame}
+	if r.key != nil && r.sinceKey+1 < r.keyInterval && len(state) == len(r.key) {
+		if d := encodeDelta(nil, r.key, state); len(d) < len(state) {
+			r.recycle(state)
+			s.state, s.delta = d, true
+			r.sinceKey++
+		}
+	}
+	if !s.delta {
+		r.key = append(r.key[:0], state...)
+		r.keyFrame = frame
+		r.sinceKey = 0
+	}
+	if level > 0 {
+		packed := deflateState(nil, s.state, level)
+		r.recycle(s.state)
+		s.state, s.compressed = packed, true
+	}
+	return s
+}
+
+// unpack returns the full state of snapshot i.
+func (r *rewindBuffer) unpack(i int) ([]byte, error) {
+	s := r.snapshots[i]
+	data := s.state
+	if s.compressed {
+		var err error
+		if data, err = inflateState(data); err != nil {
+			return nil, err
+		}
+	}
+	if !s.delta {
+		return data, nil
+	}
+
+	// Deltas follow their keyframe, which trimming drops with them.
+	k := i - 1
+	for k > 0 && r.snapshots[k].delta {
+		k--
+	}
+	key := r.key
+	if key == nil || r.snapshots[k].frame != r.keyFrame {
+		var err error
+		if key, err = r.unpack(k); err != nil {
+			return nil, err
+		}
+	}
+	return applyDelta(nil, key, data)
+}
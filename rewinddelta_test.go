@@ -0,0 +1,103 @@
+package ios
+
+import (
+	"bytes"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{1, 2, 3, 4}, 256)
+	state := append([]byte(nil), key...)
+	state[0] = 9
+	state[5], state[9] = 7, 7 // merged into one run
+	state[500] = 8
+	state[len(state)-1] = 6
+
+	delta := encodeDelta(nil, key, state)
+	if len(delta) >= 64 {
+		t.Errorf("delta is %d bytes for 5 changed bytes", len(delta))
+	}
+	got, err := applyDelta(nil, key, delta)
+	if err != nil || !bytes.Equal(got, state) {
+		t.Fatalf("applyDelta = %v", err)
+	}
+	if d := encodeDelta(nil, key, key); len(d) != 0 {
+		t.Errorf("delta of identical states = %v", d)
+	}
+	for _, bad := range [][]byte{{0x80}, {0, 5, 1}, {200, 8, 1, 2, 3, 4, 5, 6, 7, 8}} {
+		if _, err := applyDelta(nil, key[:16], bad); err == nil {
+			t.Errorf("applied malformed delta %v", bad)
+		}
+	}
+}
+
+// mockLargeStateEmulator has a 4 KiB state of which one byte changes.
+type mockLargeStateEmulator struct {
+	*mockEmulator
+	state []byte
+}
+
+func (m *mockLargeStateEmulator) Serialize() ([]byte, error) {
+	return append([]byte(nil), m.state...), nil
+}
+
+func (m *mockLargeStateEmulator) Deserialize(data []byte) error {
+	m.state = append(m.state[:0], data...)
+	return nil
+}
+
+func TestRewindDeltas(t *testing.T) {
+	var e *mockLargeStateEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockLargeStateEmulator{mockEmulator: newMockEmulator(rom, region), state: make([]byte, 4096)}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	EnableRewind(1)
+	SetRewindInterval(1)
+	SetRewindKeyframeInterval(4)
+
+	for i := 1; i <= 10; i++ {
+		e.state[100] = byte(i)
+		RunFrame()
+	}
+	r := def.rewind
+	keys := 0
+	for _, s := range r.snapshots {
+		if !s.delta {
+			keys++
+		}
+	}
+	if keys != 3 || r.size > 3*4096+7*64 {
+		t.Errorf("%d keyframes, %d bytes", keys, r.size)
+	}
+
+	// Trimming drops a keyframe with its deltas.
+	r.capacity = r.size - 1
+	r.trim()
+	if RewindDepth() != 6 || r.snapshots[0].delta {
+		t.Errorf("depth after trim = %d", RewindDepth())
+	}
+
+	for want := 10; want >= 5; want-- {
+		if !RewindStep() || e.state[100] != byte(want) {
+			t.Fatalf("rewound to %d, want %d", e.state[100], want)
+		}
+	}
+	// Captures after rewinding past a keyframe start a new one.
+	e.state[100] = 42
+	RunFrame()
+	if r.snapshots[len(r.snapshots)-1].delta {
+		t.Error("capture after popping the keyframe stored a delta")
+	}
+	RewindStep()
+	if e.state[100] != 42 {
+		t.Errorf("restored %d, want 42", e.state[100])
+	}
+}
@@ -0,0 +1,209 @@
+package ios
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+	emucore "github.com/user-none/eblitui/api"
+	"github.com/user-none/eblitui/romloader"
+)
+
+// Archive magics romloader detects, besides zipMagic.
+var (
+	zipEndMagic = []byte{0x50, 0x4B, 0x05, 0x06} // empty zip
+	sevenZMagic = []byte{0x37, 0x7A, 0xBC, 0xAF, 0x27, 0x1C}
+	gzipMagic   = []byte{0x1F, 0x8B}
+	rarMagic    = []byte("Rar!")
+)
+
+// InitFromData is Init for ROM data already in memory, such as a file
+// opened from the Files app. filename is the file's name and is matched
+// against the system's extensions; archives are detected from data as
+// romloader does for files. data is copied, so the caller may free it
+// once the call returns.
+// regionCode: 0=NTSC, 1=PAL
+// Returns true on success (see LastError on failure).
+// When several cores are registered, filename picks one as for Init.
+func InitFromData(data []byte, filename string, regionCode int) bool {
+	f, rom, _, err := loadSystemROMData(data, filename)
+	if err != nil {
+		setLastError("failed to load ROM: %v", err)
+		return false
+	}
+	return def.initROM(f, rom, regionCode)
+}
+
+// DetectRegionFromData is DetectRegionFromPath for ROM data in memory.
+func DetectRegionFromData(data []byte, filename string) int {
+	f, rom, _, err := loadSystemROMData(data, filename)
+	if err != nil {
+		return 0
+	}
+	region, _ := f.DetectRegion(rom)
+	return int(region)
+}
+
+// ExtractAndStoreROMFromData is ExtractAndStoreROM for ROM data in memory.
+func ExtractAndStoreROMFromData(data []byte, filename, destDir string, overwrite bool) (string, error) {
+	return extractAndStoreROMData(activeFactory(), candidateFactories(), data, filename, destDir, overwrite)
+}
+
+// extractAndStoreROMData is ExtractAndStoreROMFromData with the active
+// core and the cores to try passed in rather than read from the registry.
+func extractAndStoreROMData(active emucore.CoreFactory, candidates []emucore.CoreFactory, data []byte, filename, destDir string, overwrite bool) (string, error) {
+	if active == nil {
+		return "", fmt.Errorf("no factory registered")
+	}
+	if len(active.SystemInfo().Extensions) == 0 {
+		return "", fmt.Errorf("no extensions configured")
+	}
+
+	f, rom, romFilename, err := loadWithFactories(candidates, func(exts []string) ([]byte, string, error) {
+		return loadROMData(data, filename, exts)
+	})
+	if errors.Is(err, romloader.ErrUnsupportedFormat) && len(data) <= maxEntrySize {
+		// Misnamed file: accept it if the contents identify the system.
+		if sniffROMWith(active, data).Confidence >= sniffAcceptConfidence {
+			f, rom, romFilename, err = active, bytes.Clone(data), filepath.Base(filename), nil
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load ROM: %w", err)
+	}
+
+	return storeROM(f, rom, romFilename, destDir, overwrite)
+}
+
+// loadROMData is romloader.Load for data in memory named filename. Raw
+// ROMs are copied out of data; archive entries are decompressed into new
+// buffers.
+func loadROMData(data []byte, filename string, extensions []string) ([]byte, string, error) {
+	lower := strings.ToLower(filename)
+	switch {
+	case bytes.HasPrefix(data, zipMagic), bytes.HasPrefix(data, zipEndMagic), strings.HasSuffix(lower, ".zip"):
+		return loadZipData(data, extensions)
+	case bytes.HasPrefix(data, rarMagic), strings.HasSuffix(lower, ".rar"):
+		return loadRARData(data, extensions)
+	case bytes.HasPrefix(data, sevenZMagic), strings.HasSuffix(lower, ".7z"):
+		return load7zData(data, extensions)
+	case bytes.HasPrefix(data, gzipMagic), strings.HasSuffix(lower, ".gz"), strings.HasSuffix(lower, ".tgz"):
+		return loadGzipData(data, filename, extensions)
+	case hasROMExtension(filename, extensions):
+		if len(data) > maxEntrySize {
+			return nil, "", romloader.ErrFileTooLarge
+		}
+		return bytes.Clone(data), filepath.Base(filename), nil
+	}
+	return nil, "", fmt.Errorf("%w: %s", romloader.ErrUnsupportedFormat, filename)
+}
+
+// readEntry reads an archive entry, enforcing the ROM size limit.
+func readEntry(r io.Reader, name string) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxEntrySize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxEntrySize {
+		return nil, "", romloader.ErrFileTooLarge
+	}
+	return data, filepath.Base(name), nil
+}
+
+func loadZipData(data []byte, extensions []string) ([]byte, string, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open zip: %w", err)
+	}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !hasROMExtension(f.Name, extensions) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open %s in archive: %w", f.Name, err)
+		}
+		defer rc.Close()
+		return readEntry(rc, f.Name)
+	}
+	return nil, "", romloader.ErrNoROMFile
+}
+
+func load7zData(data []byte, extensions []string) ([]byte, string, error) {
+	r, err := sevenzip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open 7z: %w", err)
+	}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !hasROMExtension(f.Name, extensions) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open %s in archive: %w", f.Name, err)
+		}
+		defer rc.Close()
+		return readEntry(rc, f.Name)
+	}
+	return nil, "", romloader.ErrNoROMFile
+}
+
+func loadRARData(data []byte, extensions []string) ([]byte, string, error) {
+	r, err := rardecode.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open rar: %w", err)
+	}
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			return nil, "", romloader.ErrNoROMFile
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read rar entry: %w", err)
+		}
+		if !header.IsDir && hasROMExtension(header.Name, extensions) {
+			return readEntry(r, header.Name)
+		}
+	}
+}
+
+// loadGzipData decompresses a .gz ROM, or the first ROM in a tar.gz.
+func loadGzipData(data []byte, filename string, extensions []string) ([]byte, string, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gr.Close()
+
+	lower := strings.ToLower(filename)
+	if !strings.HasSuffix(lower, ".tar.gz") && !strings.HasSuffix(lower, ".tgz") {
+		name := filepath.Base(filename)
+		if strings.HasSuffix(strings.ToLower(name), ".gz") {
+			name = name[:len(name)-3]
+		}
+		rom, _, err := readEntry(gr, name)
+		return rom, name, err
+	}
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, "", romloader.ErrNoROMFile
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read tar entry: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && hasROMExtension(header.Name, extensions) {
+			return readEntry(tr, header.Name)
+		}
+	}
+}
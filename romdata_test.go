@@ -0,0 +1,82 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func TestInitFromData(t *testing.T) {
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			return e, nil
+		},
+	})
+
+	raw := []byte{0x01, 0x02, 0x03}
+	data := append([]byte(nil), raw...)
+	if !InitFromData(data, "Game.BIN", 1) {
+		t.Fatalf("InitFromData raw failed: %s", LastError())
+	}
+	// The caller's buffer may be reused once the call returns.
+	data[0] = 0xFF
+	if !bytes.Equal(e.rom, raw) || e.region != emucore.RegionPAL {
+		t.Errorf("core got rom % x region %d", e.rom, e.region)
+	}
+
+	zipped, err := os.ReadFile(writeZip(t, zipFile{"readme.txt", []byte("hi")}, zipFile{"dir/game.bin", raw}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Archives are detected from their contents, whatever the name.
+	if !InitFromData(zipped, "download", 0) {
+		t.Fatalf("InitFromData zip failed: %s", LastError())
+	}
+	if !bytes.Equal(e.rom, raw) {
+		t.Errorf("zip rom = % x, want % x", e.rom, raw)
+	}
+
+	if InitFromData(raw, "notes.txt", 0) {
+		t.Error("InitFromData accepted a mismatched extension")
+	}
+	if !strings.Contains(LastError(), "unsupported") {
+		t.Errorf("LastError = %q", LastError())
+	}
+}
+
+func TestDetectRegionFromData(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	if got := DetectRegionFromData([]byte{0x00}, "game.bin"); got != 0 {
+		t.Errorf("DetectRegionFromData = %d, want 0", got)
+	}
+}
+
+func TestExtractAndStoreROMFromData(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	raw := []byte{0x0A, 0x0B}
+	zipped, err := os.ReadFile(writeZip(t, zipFile{"Game (USA).bin", raw}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	result, err := ExtractAndStoreROMFromData(zipped, "Game (USA).zip", dest, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got storeResult
+	json.Unmarshal([]byte(result), &got)
+	if got.Name != "Game (USA)" || got.Size != len(raw) || !StoredROMExists(dest, got.CRC) {
+		t.Errorf("result = %s", result)
+	}
+
+	if _, err := ExtractAndStoreROMFromData([]byte("plain"), "notes.txt", dest, false); err == nil {
+		t.Error("expected error for unrecognized data")
+	}
+}
@@ -0,0 +1,178 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// romDBEntry is one ROM of a loaded database, as LookupROMJSON reports it.
+type romDBEntry struct {
+	CRC32    string `json:"crc32"`
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	Region   string `json:"region,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	ROMName  string `json:"romName,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	SHA1     string `json:"sha1,omitempty"`
+}
+
+// romDB holds the database LoadROMDatabase loaded, keyed by CRC32. Scan
+// workers read it concurrently.
+var romDB struct {
+	mu    sync.RWMutex
+	byCRC map[uint32]*romDBEntry
+}
+
+// datFile is the Logiqx XML layout No-Intro and Redump DATs use.
+type datFile struct {
+	Games []struct {
+		Name string `xml:"name,attr"`
+		ROMs []struct {
+			Name string `xml:"name,attr"`
+			Size int64  `xml:"size,attr"`
+			CRC  string `xml:"crc,attr"`
+			SHA1 string `xml:"sha1,attr"`
+		} `xml:"rom"`
+	} `xml:"game"`
+}
+
+// LoadROMDatabase loads a No-Intro style Logiqx XML DAT file for
+// LookupROMJSON, replacing any database loaded before. ExtractAndStoreROM
+// and ScanDirectoryJSON then include a "title" for ROMs it lists.
+// Returns false if the file cannot be parsed (see LastError).
+func LoadROMDatabase(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		setLastError("failed to read ROM database: %v", err)
+		return false
+	}
+	return LoadROMDatabaseFromData(data)
+}
+
+// LoadROMDatabaseFromData is LoadROMDatabase for a DAT already in memory,
+// such as one bundled with the app.
+func LoadROMDatabaseFromData(data []byte) bool {
+	var dat datFile
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&dat); err != nil {
+		setLastError("failed to parse ROM database: %v", err)
+		return false
+	}
+
+	byCRC := map[uint32]*romDBEntry{}
+	for _, g := range dat.Games {
+		title, region, revision := parseGameName(g.Name)
+		for _, r := range g.ROMs {
+			crc, err := strconv.ParseUint(r.CRC, 16, 32)
+			if err != nil {
+				continue
+			}
+			byCRC[uint32(crc)] = &romDBEntry{
+				CRC32:    fmt.Sprintf("%08X", crc),
+				Name:     g.Name,
+				Title:    title,
+				Region:   region,
+				Revision: revision,
+				ROMName:  r.Name,
+				Size:     r.Size,
+				SHA1:     strings.ToLower(r.SHA1),
+			}
+		}
+	}
+
+	romDB.mu.Lock()
+	romDB.byCRC = byCRC
+	romDB.mu.Unlock()
+	return true
+}
+
+// ROMDatabaseSize returns the number of ROMs in the loaded database.
+func ROMDatabaseSize() int {
+	romDB.mu.RLock()
+	defer romDB.mu.RUnlock()
+	return len(romDB.byCRC)
+}
+
+// LookupROMJSON returns the database entry for a CRC32 hex string:
+// {"crc32", "name", "title", "region", "revision", "romName", "size",
+// "sha1"}. name is the full database name and title the name without
+// its tags. Returns "{}" if no database is loaded or the ROM is not in
+// it.
+func LookupROMJSON(crc string) string {
+	v, err := strconv.ParseUint(crc, 16, 32)
+	if err != nil {
+		return "{}"
+	}
+	e := lookupROM(uint32(v))
+	if e == nil {
+		return "{}"
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// lookupROM returns the database entry for crc, or nil.
+func lookupROM(crc uint32) *romDBEntry {
+	romDB.mu.RLock()
+	defer romDB.mu.RUnlock()
+	return romDB.byCRC[crc]
+}
+
+// romTitle returns the canonical title for crc, or "" if it is unknown.
+func romTitle(crc uint32) string {
+	if e := lookupROM(crc); e != nil {
+		return e.Title
+	}
+	return ""
+}
+
+// parseGameName splits a No-Intro name such as
+// "Game Title (USA, Europe) (Rev 1)" into its title, region and
+// revision. The region is the first tag; the revision is a "Rev" or
+// version tag.
+func parseGameName(name string) (title, region, revision string) {
+	i := strings.IndexAny(name, "([")
+	if i < 0 {
+		return strings.TrimSpace(name), "", ""
+	}
+	title = strings.TrimSpace(name[:i])
+
+	rest := name[i:]
+	for len(rest) > 0 {
+		open := strings.IndexByte(rest, '(')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], ')')
+		if end < 0 {
+			break
+		}
+		tag := rest[open+1 : open+end]
+		rest = rest[open+end+1:]
+
+		switch {
+		case region == "":
+			region = tag
+		case revision == "" && isRevisionTag(tag):
+			revision = tag
+		}
+	}
+	return title, region, revision
+}
+
+// isRevisionTag reports whether tag is "Rev 1", "Rev A" or "v1.1".
+func isRevisionTag(tag string) bool {
+	if strings.HasPrefix(tag, "Rev ") {
+		return true
+	}
+	return len(tag) > 1 && tag[0] == 'v' && tag[1] >= '0' && tag[1] <= '9'
+}
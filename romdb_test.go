@@ -0,0 +1,117 @@
+package ios
+
+import (
+	"encoding/json"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testDAT = `<?xml version="1.0"?>
+<datafile>
+	<header><name>Test</name></header>
+	<game name="Game Title (USA, Europe) (Rev 1)">
+		<rom name="Game Title (USA, Europe) (Rev 1).bin" size="3" crc="352441c2" sha1="A9993E364706816ABA3E25717850C26C9CD0D89D"/>
+	</game>
+	<game name="Other Game (Japan)">
+		<rom name="Other Game (Japan).bin" size="1" crc="xyz"/>
+	</game>
+</datafile>`
+
+// useROMDatabase loads testDAT and clears it after the test.
+func useROMDatabase(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		romDB.mu.Lock()
+		romDB.byCRC = nil
+		romDB.mu.Unlock()
+	})
+	if !LoadROMDatabaseFromData([]byte(testDAT)) {
+		t.Fatalf("LoadROMDatabaseFromData failed: %s", LastError())
+	}
+}
+
+func TestLookupROMJSON(t *testing.T) {
+	if LookupROMJSON("352441C2") != "{}" {
+		t.Error("lookup succeeded with no database")
+	}
+	useROMDatabase(t)
+	if ROMDatabaseSize() != 1 {
+		t.Errorf("ROMDatabaseSize = %d, want 1 (bad CRC skipped)", ROMDatabaseSize())
+	}
+
+	var got romDBEntry
+	if err := json.Unmarshal([]byte(LookupROMJSON("352441c2")), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := romDBEntry{
+		CRC32:    "352441C2",
+		Name:     "Game Title (USA, Europe) (Rev 1)",
+		Title:    "Game Title",
+		Region:   "USA, Europe",
+		Revision: "Rev 1",
+		ROMName:  "Game Title (USA, Europe) (Rev 1).bin",
+		Size:     3,
+		SHA1:     "a9993e364706816aba3e25717850c26c9cd0d89d",
+	}
+	if got != want {
+		t.Errorf("LookupROMJSON = %+v, want %+v", got, want)
+	}
+	if LookupROMJSON("00000000") != "{}" || LookupROMJSON("xyz") != "{}" {
+		t.Error("unknown CRC matched")
+	}
+	if LoadROMDatabaseFromData([]byte("<datafile>")) {
+		t.Error("truncated DAT loaded")
+	}
+}
+
+func TestParseGameName(t *testing.T) {
+	tests := []struct {
+		name, title, region, revision string
+	}{
+		{"Plain", "Plain", "", ""},
+		{"Game (Japan) (v1.1)", "Game", "Japan", "v1.1"},
+		{"Game (Europe) (En,Fr,De) (Rev A)", "Game", "Europe", "Rev A"},
+		{"game (U) [!]", "game", "U", ""},
+	}
+	for _, tt := range tests {
+		title, region, revision := parseGameName(tt.name)
+		if title != tt.title || region != tt.region || revision != tt.revision {
+			t.Errorf("parseGameName(%q) = %q, %q, %q", tt.name, title, region, revision)
+		}
+	}
+}
+
+func TestStoreAndScanReportTitle(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	useROMDatabase(t)
+	src := t.TempDir()
+	path := filepath.Join(src, "game (U) [!].bin")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ExtractAndStoreROM(path, t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored struct{ Name, Title string }
+	if err := json.Unmarshal([]byte(result), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "game (U) [!]" || stored.Title != "Game Title" {
+		t.Errorf("store result = %+v", stored)
+	}
+
+	var entries []scanEntry
+	if err := json.Unmarshal([]byte(ScanDirectoryJSON(src, false)), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Title != "Game Title" {
+		t.Errorf("scan = %+v", entries)
+	}
+	if romTitle(crc32.ChecksumIEEE([]byte("abd"))) != "" {
+		t.Error("title reported for an unknown ROM")
+	}
+}
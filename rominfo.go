@@ -0,0 +1,194 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// ROMHeader holds the fields a core parsed from a ROM's internal header.
+// Fields the system's header does not have are left zero.
+type ROMHeader struct {
+	InternalName string
+	// Region is the region the header declares, as the header spells
+	// it, e.g. "USA" or "J".
+	Region string
+	// Mapper names the mapper or board the cartridge uses.
+	Mapper   string
+	SRAMSize int
+	// DeclaredSize is the ROM size the header declares, or 0 if it
+	// declares none.
+	DeclaredSize int
+	// ChecksumChecked reports whether the header has a checksum the core
+	// verified; ChecksumValid is the result.
+	ChecksumChecked bool
+	ChecksumValid   bool
+	// Extra holds system-specific fields for display.
+	Extra map[string]string
+}
+
+// ROMInspector is an optional CoreFactory interface for cores that can
+// parse their ROMs' internal headers.
+type ROMInspector interface {
+	// InspectROM parses rom's header. ok is false if rom has no
+	// recognizable header.
+	InspectROM(rom []byte) (header ROMHeader, ok bool)
+}
+
+// Checksum statuses reported by ROMInfoJSON.
+const (
+	checksumValid   = "valid"
+	checksumInvalid = "invalid"
+	checksumUnknown = "unknown"
+)
+
+// Dump problems reported by ROMInfoJSON, InitWithError and
+// ExtractAndStoreROM.
+const (
+	dumpBadChecksum = "bad_checksum"
+	dumpOverdump    = "overdump"
+	dumpTrimmed     = "trimmed"
+)
+
+// romInfo is the JSON returned by ROMInfoJSON.
+type romInfo struct {
+	CRC32        string            `json:"crc32"`
+	Size         int               `json:"size"`
+	HasHeader    bool              `json:"hasHeader"`
+	InternalName string            `json:"internalName,omitempty"`
+	Region       string            `json:"region,omitempty"`
+	Mapper       string            `json:"mapper,omitempty"`
+	SRAMSize     int               `json:"sramSize,omitempty"`
+	DeclaredSize int               `json:"declaredSize,omitempty"`
+	Checksum     string            `json:"checksum"`
+	Extra        map[string]string `json:"extra,omitempty"`
+	Warnings     []string          `json:"warnings,omitempty"`
+}
+
+// inspectROM describes rom using f's ROMInspector, if any.
+func inspectROM(f emucore.CoreFactory, rom []byte) romInfo {
+	info := romInfo{
+		CRC32:    fmt.Sprintf("%08X", crc32.ChecksumIEEE(rom)),
+		Size:     len(rom),
+		Checksum: checksumUnknown,
+	}
+	i, ok := f.(ROMInspector)
+	if !ok {
+		return info
+	}
+	h, ok := i.InspectROM(rom)
+	if !ok {
+		return info
+	}
+	info.HasHeader = true
+	info.InternalName = h.InternalName
+	info.Region = h.Region
+	info.Mapper = h.Mapper
+	info.SRAMSize = h.SRAMSize
+	info.DeclaredSize = h.DeclaredSize
+	info.Extra = h.Extra
+	if h.ChecksumChecked {
+		info.Checksum = checksumInvalid
+		if h.ChecksumValid {
+			info.Checksum = checksumValid
+		}
+	}
+	info.Warnings = dumpWarnings(rom, h)
+	return info
+}
+
+// romDumpWarnings returns the dump problems f's ROMInspector finds in
+// rom, or nil.
+func romDumpWarnings(f emucore.CoreFactory, rom []byte) []string {
+	i, ok := f.(ROMInspector)
+	if !ok {
+		return nil
+	}
+	h, ok := i.InspectROM(rom)
+	if !ok {
+		return nil
+	}
+	return dumpWarnings(rom, h)
+}
+
+// dumpWarnings compares rom against its parsed header. A ROM larger than
+// its declared size is an overdump only if the excess is padding or a
+// mirror of the start; anything else is taken as an expansion hack.
+func dumpWarnings(rom []byte, h ROMHeader) []string {
+	var warnings []string
+	if h.ChecksumChecked && !h.ChecksumValid {
+		warnings = append(warnings, dumpBadChecksum)
+	}
+	if n := h.DeclaredSize; n > 0 {
+		switch {
+		case len(rom) < n:
+			warnings = append(warnings, dumpTrimmed)
+		case len(rom) > n && (isPadding(rom[n:]) || isMirror(rom, n)):
+			warnings = append(warnings, dumpOverdump)
+		}
+	}
+	return warnings
+}
+
+// isPadding reports whether b is a single repeated byte.
+func isPadding(b []byte) bool {
+	for _, c := range b {
+		if c != b[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// isMirror reports whether rom past n repeats its first n bytes.
+func isMirror(rom []byte, n int) bool {
+	for i := n; i < len(rom); i += n {
+		end := min(i+n, len(rom))
+		if !bytes.Equal(rom[i:end], rom[:end-i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func romInfoJSON(info romInfo) string {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// ROMInfoJSON returns what a ROM file's internal header declares:
+// {"crc32", "size", "hasHeader", "internalName", "region", "mapper",
+// "sramSize", "declaredSize", "checksum", "extra", "warnings"}. checksum
+// is "valid", "invalid" (a likely bad dump or hack) or "unknown" when the
+// header has none or the core cannot parse headers. warnings lists the
+// dump problems found: "bad_checksum", "overdump" (padding past the
+// declared size) and "trimmed" (shorter than declared). Header fields
+// are omitted when hasHeader is false. Returns "{}" on error (see
+// LastError).
+func ROMInfoJSON(path string) string {
+	f, rom, err := loadSystemROM(path)
+	if err != nil {
+		setLastError("failed to load ROM: %v", err)
+		return "{}"
+	}
+	return romInfoJSON(inspectROM(f, rom))
+}
+
+// GetLoadedROMInfoJSON returns ROMInfoJSON for the ROM loaded by Init.
+// Returns "{}" if no ROM is loaded.
+func GetLoadedROMInfoJSON() string {
+	def.mu.Lock()
+	f, rom := def.factory, def.rom
+	def.mu.Unlock()
+	if f == nil || rom == nil {
+		setLastError("no ROM loaded")
+		return "{}"
+	}
+	return romInfoJSON(inspectROM(f, rom))
+}
@@ -0,0 +1,122 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockInspectFactory parses an 8 byte "HDR" + 4 byte name + checksum
+// header declaring an 8 byte ROM. The checksum is valid if it is 8.
+type mockInspectFactory struct {
+	*mockFactory
+}
+
+func (f *mockInspectFactory) InspectROM(rom []byte) (ROMHeader, bool) {
+	if len(rom) < 8 || string(rom[:3]) != "HDR" {
+		return ROMHeader{}, false
+	}
+	return ROMHeader{
+		InternalName:    string(rom[3:7]),
+		Region:          "USA",
+		Mapper:          "ROM only",
+		SRAMSize:        8192,
+		DeclaredSize:    8,
+		ChecksumChecked: true,
+		ChecksumValid:   rom[7] == 8,
+		Extra:           map[string]string{"serial": "T-1"},
+	}, true
+}
+
+func parseROMInfo(t *testing.T, s string) romInfo {
+	t.Helper()
+	var info romInfo
+	if err := json.Unmarshal([]byte(s), &info); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestROMInfoJSON(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	good := writeROM(t, "good.bin", []byte("HDRGAME\x08"))
+	if got := parseROMInfo(t, ROMInfoJSON(good)); got.HasHeader || got.Checksum != checksumUnknown || got.Size != 8 {
+		t.Errorf("info without inspector = %+v", got)
+	}
+
+	factory = &mockInspectFactory{&mockFactory{}}
+	got := parseROMInfo(t, ROMInfoJSON(good))
+	if !got.HasHeader || got.InternalName != "GAME" || got.Region != "USA" || got.Mapper != "ROM only" ||
+		got.SRAMSize != 8192 || got.Checksum != checksumValid || got.Extra["serial"] != "T-1" {
+		t.Errorf("info = %+v", got)
+	}
+	if got := parseROMInfo(t, ROMInfoJSON(writeROM(t, "bad.bin", []byte("HDRGAME\x00")))); got.Checksum != checksumInvalid {
+		t.Errorf("bad dump checksum = %q", got.Checksum)
+	}
+	if len(got.Warnings) != 0 || got.DeclaredSize != 8 {
+		t.Errorf("good dump warnings = %v, declared %d", got.Warnings, got.DeclaredSize)
+	}
+	if got := parseROMInfo(t, ROMInfoJSON(writeROM(t, "raw.bin", []byte("raw")))); got.HasHeader || got.InternalName != "" {
+		t.Errorf("headerless info = %+v", got)
+	}
+	if ROMInfoJSON(good+".missing") != "{}" {
+		t.Error("info returned for a missing file")
+	}
+}
+
+func TestDumpWarnings(t *testing.T) {
+	good := []byte("HDRGAME\x08")
+	tests := []struct {
+		name string
+		rom  []byte
+		want []string
+	}{
+		{"good", good, nil},
+		{"bad checksum", []byte("HDRGAME\x00"), []string{dumpBadChecksum}},
+		{"padded", append(bytes.Clone(good), 0xFF, 0xFF, 0xFF), []string{dumpOverdump}},
+		{"mirrored", append(bytes.Clone(good), good[:5]...), []string{dumpOverdump}},
+		{"expanded", append(bytes.Clone(good), 1, 2, 3), nil},
+	}
+	for _, tt := range tests {
+		h, _ := (&mockInspectFactory{}).InspectROM(tt.rom)
+		if got := dumpWarnings(tt.rom, h); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: warnings = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	h, _ := (&mockInspectFactory{}).InspectROM(good)
+	h.DeclaredSize = 16
+	if got := dumpWarnings(good, h); !slices.Equal(got, []string{dumpTrimmed}) {
+		t.Errorf("trimmed: warnings = %v", got)
+	}
+}
+
+func TestInitReportsDumpWarnings(t *testing.T) {
+	useMockFactory(t, &mockFactory{create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+		return newMockEmulator(rom, region), nil
+	}})
+	factory = &mockInspectFactory{factory.(*mockFactory)}
+	ClearJournal()
+
+	var result initError
+	path := writeROM(t, "bad.bin", []byte("HDRGAME\x00\xFF\xFF"))
+	if err := json.Unmarshal([]byte(InitWithError(path, 0)), &result); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{dumpBadChecksum, dumpOverdump}
+	if result.Code != InitErrorNone || !slices.Equal(result.Warnings, want) {
+		t.Errorf("InitWithError = %+v, want warnings %v", result, want)
+	}
+	if !strings.Contains(JournalJSON(), dumpOverdump) {
+		t.Errorf("journal = %s", JournalJSON())
+	}
+
+	stored, err := ExtractAndStoreROM(path, t.TempDir(), false)
+	if err != nil || !strings.Contains(stored, `"warnings":["bad_checksum","overdump"]`) {
+		t.Errorf("ExtractAndStoreROM = %s, %v", stored, err)
+	}
+}
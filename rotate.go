@@ -0,0 +1,147 @@
+package ios
+
+// RotationReporter is an optional emulator interface for cores running
+// games made for a rotated screen, such as vertical arcade shooters.
+type RotationReporter interface {
+	// FrameRotation returns the clockwise rotation in degrees, a multiple
+	// of 90, the frame needs to appear upright.
+	FrameRotation() int
+}
+
+// FrameRotation returns the clockwise rotation in degrees (0, 90, 180 or
+// 270) the app should apply to the frame: the core's rotation for the
+// loaded game plus the "rotation" display preference. Returns 0 while
+// SetRotateFrames is on, since the bridge then rotates frames itself.
+func FrameRotation() int {
+	return def.frameRotation()
+}
+
+func (in *instance) frameRotation() int {
+	if in.rotateFrames {
+		return 0
+	}
+	return in.contentRotation()
+}
+
+// SetRotateFrames makes the bridge rotate each frame by the rotation
+// FrameRotation would report, so the frame getters return an upright
+// frame and FrameWidth, FrameHeight and FrameStride describe it. Pointer
+// input is given in rotated frame coordinates. The setting stays in
+// effect across Init.
+func SetRotateFrames(enabled bool) {
+	def.setRotateFrames(enabled)
+}
+
+func (in *instance) setRotateFrames(enabled bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.rotateFrames = enabled
+}
+
+// contentRotation returns the core's rotation plus the display preference,
+// normalized to 0, 90, 180 or 270.
+func (in *instance) contentRotation() int {
+	deg := *in.effectiveDisplay().Rotation
+	if r, ok := in.emu.(RotationReporter); ok {
+		deg += r.FrameRotation() / 90 * 90
+	}
+	return (deg%360 + 360) % 360
+}
+
+// bridgeRotation returns the rotation the bridge applies to frames.
+func (in *instance) bridgeRotation() int {
+	if !in.rotateFrames || in.emu == nil {
+		return 0
+	}
+	return in.contentRotation()
+}
+
+// quarterTurned returns whether frames are rotated by 90 or 270 degrees,
+// swapping their width and height.
+func (in *instance) quarterTurned() bool {
+	r := in.bridgeRotation()
+	return r == 90 || r == 270
+}
+
+// rotatedWidth, rotatedHeight and rotatedStride describe the frame after
+// any crop and rotation.
+func (in *instance) rotatedWidth() int {
+	if in.quarterTurned() {
+		return in.croppedHeight()
+	}
+	return in.croppedWidth()
+}
+
+func (in *instance) rotatedHeight() int {
+	if in.quarterTurned() {
+		return in.croppedWidth()
+	}
+	return in.croppedHeight()
+}
+
+func (in *instance) rotatedStride() int {
+	if in.bridgeRotation() != 0 {
+		return in.rotatedWidth() * 4
+	}
+	return in.croppedStride()
+}
+
+// rotateFrame rotates frameData, already cropped, into the rotation
+// buffer.
+func (in *instance) rotateFrame() {
+	r := in.bridgeRotation()
+	if r == 0 || len(in.frameData) == 0 {
+		return
+	}
+	w, h, stride := in.croppedWidth(), in.croppedHeight(), in.croppedStride()
+	h = min(h, len(in.frameData)/stride)
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	n := w * h * 4
+	if cap(in.rotated) < n {
+		in.rotated = make([]byte, n)
+	}
+	in.rotated = in.rotated[:n]
+	dw := w
+	if r != 180 {
+		dw = h
+	}
+	for y := 0; y < h; y++ {
+		row := in.frameData[y*stride:]
+		for x := 0; x < w; x++ {
+			dx, dy := rotatePoint(x, y, w, h, r)
+			copy(in.rotated[(dy*dw+dx)*4:(dy*dw+dx)*4+4], row[x*4:x*4+4])
+		}
+	}
+	in.frameData = in.rotated
+}
+
+// rotatePoint maps (x, y) in a w x h frame to the frame rotated
+// clockwise by deg.
+func rotatePoint(x, y, w, h, deg int) (int, int) {
+	switch deg {
+	case 90:
+		return h - 1 - y, x
+	case 180:
+		return w - 1 - x, h - 1 - y
+	case 270:
+		return y, w - 1 - x
+	}
+	return x, y
+}
+
+// unrotatePoint maps (x, y) in the rotated frame back to the w x h frame
+// before the clockwise rotation by deg.
+func unrotatePoint(x, y, w, h, deg int) (int, int) {
+	switch deg {
+	case 90:
+		return y, h - 1 - x
+	case 180:
+		return w - 1 - x, h - 1 - y
+	case 270:
+		return w - 1 - y, x
+	}
+	return x, y
+}
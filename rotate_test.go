@@ -0,0 +1,102 @@
+package ios
+
+import (
+	"bytes"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockRotatedEmulator asks for its frame to be rotated.
+type mockRotatedEmulator struct {
+	*mockDeviceEmulator
+	rotation int
+}
+
+func (m *mockRotatedEmulator) FrameRotation() int { return m.rotation }
+
+func useRotatedEmulator(t *testing.T, rotation int) *mockRotatedEmulator {
+	t.Helper()
+	var e *mockRotatedEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockRotatedEmulator{
+				mockDeviceEmulator: &mockDeviceEmulator{mockEmulator: newMockEmulator(rom, region)},
+				rotation:           rotation,
+			}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+	fillPixels(e.mockEmulator)
+	return e
+}
+
+// framePixels returns the first byte of each pixel of the published frame.
+func framePixels() []byte {
+	var px []byte
+	frame := GetFrameData()
+	for i := 0; i < len(frame); i += 4 {
+		px = append(px, frame[i])
+	}
+	return px
+}
+
+func TestFrameRotationSources(t *testing.T) {
+	useRotatedEmulator(t, 270)
+	if FrameRotation() != 270 {
+		t.Errorf("core rotation = %d, want 270", FrameRotation())
+	}
+	if !ApplyDisplayPreferencesJSON(`{"rotation": 180}`) {
+		t.Fatal(LastError())
+	}
+	if FrameRotation() != 90 {
+		t.Errorf("core plus preference rotation = %d, want 90", FrameRotation())
+	}
+	SetRotateFrames(true)
+	if FrameRotation() != 0 {
+		t.Errorf("FrameRotation = %d while the bridge rotates, want 0", FrameRotation())
+	}
+}
+
+func TestRotateFrames(t *testing.T) {
+	e := useRotatedEmulator(t, 90)
+	// A 2x4 source: pixels 0,1 / 4,5 / 8,9 / 12,13.
+	SetCrop(0, 0, 0, 2)
+	SetRotateFrames(true)
+	RunFrame()
+
+	if FrameWidth() != 4 || FrameHeight() != 2 || FrameStride() != 16 {
+		t.Fatalf("geometry = %dx%d stride %d, want 4x2 stride 16", FrameWidth(), FrameHeight(), FrameStride())
+	}
+	if got, want := framePixels(), []byte{12, 8, 4, 0, 13, 9, 5, 1}; !bytes.Equal(got, want) {
+		t.Errorf("rotated pixels = %v, want %v", got, want)
+	}
+
+	// The top-left of the rotated frame is the bottom-left source pixel.
+	SetPointerInput(0, 0, 0, true)
+	if e.pointer != [3]int{0, 0, 3} {
+		t.Errorf("pointer = %v, want [0 0 3]", e.pointer)
+	}
+
+	e.rotation = 180
+	RunFrame()
+	if got, want := framePixels(), []byte{13, 12, 9, 8, 5, 4, 1, 0}; !bytes.Equal(got, want) {
+		t.Errorf("180 degree pixels = %v, want %v", got, want)
+	}
+}
+
+func TestRotatePointRoundTrip(t *testing.T) {
+	for _, deg := range []int{0, 90, 180, 270} {
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 5; x++ {
+				rx, ry := rotatePoint(x, y, 5, 3, deg)
+				if ux, uy := unrotatePoint(rx, ry, 5, 3, deg); ux != x || uy != y {
+					t.Errorf("deg %d: (%d,%d) -> (%d,%d) -> (%d,%d)", deg, x, y, rx, ry, ux, uy)
+				}
+			}
+		}
+	}
+}
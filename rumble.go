@@ -0,0 +1,97 @@
+package ios
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// rumbleChange is an entry in PollRumbleJSON.
+type rumbleChange struct {
+	Player   int     `json:"player"`
+	Strength float64 `json:"strength"`
+	Frame    int64   `json:"frame"`
+}
+
+// rumbleState holds the motor strengths read after each frame. It has its
+// own lock so the haptics thread never waits for a frame to finish or
+// calls into the core.
+type rumbleState struct {
+	mu      sync.Mutex
+	levels  []uint16
+	changes []rumbleChange
+}
+
+// maxRumbleChanges bounds the changes kept between polls. Only the
+// newest strength matters, so older changes are dropped first.
+const maxRumbleChanges = 64
+
+// sampleRumble records each player's motor strength after the core ran.
+func (in *instance) sampleRumble() {
+	r := &in.rumbleLevels
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.levels == nil {
+		r.levels = make([]uint16, max(in.factory.SystemInfo().Players, 1))
+	}
+	for player, old := range r.levels {
+		level := in.rumble.RumbleStrength(player)
+		if level == old {
+			continue
+		}
+		r.levels[player] = level
+		r.changes = append(r.changes, rumbleChange{
+			Player:   player,
+			Strength: float64(level) / 0xFFFF,
+			Frame:    in.frameCount,
+		})
+	}
+	if over := len(r.changes) - maxRumbleChanges; over > 0 {
+		r.changes = append(r.changes[:0], r.changes[over:]...)
+	}
+}
+
+// level returns a player's strength as of the last frame.
+func (r *rumbleState) level(player int) uint16 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if player < 0 || player >= len(r.levels) {
+		return 0
+	}
+	return r.levels[player]
+}
+
+// reset forgets all strengths and changes.
+func (r *rumbleState) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.levels = nil
+	r.changes = nil
+}
+
+// GetRumbleStrength returns a player's rumble strength from 0 to 1 as of
+// the last frame, suitable for a CoreHaptics intensity. Returns 0 if the
+// core has no rumble.
+func GetRumbleStrength(player int) float64 {
+	return float64(def.rumbleLevels.level(player)) / 0xFFFF
+}
+
+// PollRumbleJSON drains the rumble changes since the last poll, oldest
+// first, as a JSON array of {"player", "strength", "frame"} objects with
+// strength from 0 to 1. A motor that keeps its strength reports nothing,
+// so the app only updates haptics when a change arrives.
+func PollRumbleJSON() string {
+	r := &def.rumbleLevels
+	r.mu.Lock()
+	changes := r.changes
+	r.changes = nil
+	r.mu.Unlock()
+
+	if len(changes) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
@@ -0,0 +1,45 @@
+package ios
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRumbleStrengthAndChanges(t *testing.T) {
+	e := useDeviceEmulator(t)
+	if GetRumbleStrength(0) != 0 || PollRumbleJSON() != "[]" {
+		t.Fatal("rumble reported before the first frame")
+	}
+
+	RunFrame()
+	RunFrame()
+	if got := GetRumbleStrength(0); got != 2000.0/0xFFFF {
+		t.Errorf("strength = %v", got)
+	}
+
+	var changes []rumbleChange
+	if err := json.Unmarshal([]byte(PollRumbleJSON()), &changes); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Frame != 1 || changes[1].Player != 0 || changes[1].Strength != 2000.0/0xFFFF {
+		t.Errorf("changes = %+v", changes)
+	}
+	if PollRumbleJSON() != "[]" {
+		t.Error("changes not drained")
+	}
+
+	// Stopping the motor reports a zero strength.
+	e.frames = -1
+	RunFrame()
+	if got := PollRumbleJSON(); got != `[{"player":0,"strength":0,"frame":3}]` {
+		t.Errorf("changes after stopping = %s", got)
+	}
+	if GetRumbleStrength(1) != 0 || GetRumbleStrength(-1) != 0 {
+		t.Error("strength for unknown player")
+	}
+
+	Close()
+	if GetRumbleStrength(0) != 0 || PollRumbleJSON() != "[]" {
+		t.Error("rumble kept after Close")
+	}
+}
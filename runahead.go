@@ -0,0 +1,68 @@
+package ios
+
+// maxRunAhead caps the frames SetRunAhead can look ahead.
+const maxRunAhead = 4
+
+// SetRunAhead makes each RunFrame show the frame the given number of
+// frames ahead of the emulated one, cutting input latency by as many
+// frames. After the real frame runs, its state is saved, the extra frames
+// are run and rendered, and the state is restored. Costs one save and load
+// plus the extra frames per RunFrame. frames is clamped to 0-4; 0 turns
+// run-ahead off.
+// Returns false if frames is positive and the core does not support save
+// states.
+func SetRunAhead(frames int) bool {
+	return def.setRunAhead(frames)
+}
+
+func (in *instance) setRunAhead(frames int) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	frames = min(max(frames, 0), maxRunAhead)
+	if frames > 0 && in.saveStater == nil {
+		setLastError("run-ahead: core does not support save states")
+		return false
+	}
+	in.runAhead = frames
+	return true
+}
+
+// GetRunAhead returns the frames set with SetRunAhead.
+func GetRunAhead() int {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.runAhead
+}
+
+// runAheadFrame runs the real frame, keeping its audio and side effects,
+// then renders runAhead speculative frames ahead of it and rolls back.
+func (in *instance) runAheadFrame() {
+	in.runPipeline(framePass{last: true})
+
+	state, ok := in.serializeScratch()
+	if !ok {
+		journalf("warning", "run-ahead disabled: %s", LastError())
+		in.runAhead = 0
+		return
+	}
+	if _, isScratch := in.emu.(StateAppender); !isScratch {
+		// Serialize may hand back a buffer it reuses; keep a copy.
+		in.stateScratch = append(in.stateScratch[:0], state...)
+		state = in.stateScratch
+	}
+
+	keep := len(in.audioData)
+	in.speculating = true
+	for i := 1; i <= in.runAhead; i++ {
+		in.runPipeline(framePass{render: i == in.runAhead, last: i == in.runAhead})
+	}
+	in.speculating = false
+	in.audioData = in.audioData[:keep]
+
+	if err := in.saveStater.Deserialize(state); err != nil {
+		journalf("warning", "run-ahead disabled: failed to restore state: %v", err)
+		in.runAhead = 0
+		return
+	}
+	in.reapplyCheats()
+}
@@ -0,0 +1,72 @@
+package ios
+
+import (
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockRunAheadEmulator saves its frame count as state and draws it into
+// the first framebuffer byte.
+type mockRunAheadEmulator struct {
+	*mockEmulator
+}
+
+func (m *mockRunAheadEmulator) RunFrame() {
+	m.frames++
+	m.framebuffer[0] = byte(m.frames)
+	m.samples = []int16{int16(m.frames), int16(m.frames)}
+}
+
+func (m *mockRunAheadEmulator) Serialize() ([]byte, error) { return []byte{byte(m.frames)}, nil }
+func (m *mockRunAheadEmulator) Deserialize(data []byte) error {
+	m.frames = int(data[0])
+	return nil
+}
+
+func TestRunAhead(t *testing.T) {
+	var e *mockRunAheadEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockRunAheadEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+
+	if !SetRunAhead(9) || GetRunAhead() != maxRunAhead {
+		t.Fatalf("GetRunAhead() = %d, want %d", GetRunAhead(), maxRunAhead)
+	}
+	SetRunAhead(2)
+
+	for i := 1; i <= 3; i++ {
+		RunFrame()
+		if e.frames != i || FrameCount() != int64(i) {
+			t.Fatalf("after frame %d: core frames %d, FrameCount %d", i, e.frames, FrameCount())
+		}
+		if got := GetFrameData()[0]; got != byte(i+2) {
+			t.Errorf("frame %d shows byte %d, want %d", i, got, i+2)
+		}
+		if audio := GetAudioData(); len(audio) != 4 || audio[0] != byte(i) {
+			t.Errorf("frame %d audio = %v, want only real frame samples", i, audio)
+		}
+	}
+
+	SetRunAhead(0)
+	RunFrame()
+	if got := GetFrameData()[0]; got != 4 {
+		t.Errorf("run-ahead off shows byte %d, want 4", got)
+	}
+}
+
+func TestRunAheadRequiresSaveStates(t *testing.T) {
+	useMockEmulator(t)
+	if SetRunAhead(1) || GetRunAhead() != 0 {
+		t.Error("SetRunAhead succeeded without save state support")
+	}
+	if !SetRunAhead(0) {
+		t.Error("SetRunAhead(0) failed")
+	}
+}
@@ -0,0 +1,75 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+)
+
+// Save formats reported by ValidateSaveFormat.
+const (
+	saveFormatState       = "state"
+	saveFormatLegacyState = "legacyState"
+	saveFormatMovie       = "movie"
+	saveFormatUnknown     = "unknown"
+)
+
+// saveFormatReport is the result of ValidateSaveFormat.
+type saveFormatReport struct {
+	Format   string   `json:"format"`
+	Version  int      `json:"version"`
+	Portable bool     `json:"portable"`
+	Issues   []string `json:"issues"`
+}
+
+// ValidateSaveFormat checks a state file or input movie at path without
+// loading it and reports {"format", "version", "portable", "issues"}.
+// format is "state" for files from SaveStateToFile or
+// WriteStateWithMetadata, "legacyState" for headerless Serialize output,
+// "movie" for StopInputRecording movies, or "unknown". Every field of the
+// state and movie formats is fixed-width little-endian or a uvarint, so a
+// file that decodes is portable across bridges; issues lists why one is
+// not, such as corruption, an unsupported version or a headerless state
+// whose layout is the core's own.
+// Returns "{}" if the file cannot be read (see LastError).
+func ValidateSaveFormat(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		setLastError("failed to read %s: %v", path, err)
+		return "{}"
+	}
+	out, err := json.Marshal(validateSaveFormat(data))
+	if err != nil {
+		return "{}"
+	}
+	return string(out)
+}
+
+func validateSaveFormat(data []byte) saveFormatReport {
+	r := saveFormatReport{Format: saveFormatUnknown, Issues: []string{}}
+	switch {
+	case bytes.HasPrefix(data, stateFileMagic):
+		r.Format = saveFormatState
+		if len(data) > len(stateFileMagic) {
+			r.Version = int(data[len(stateFileMagic)])
+		}
+		if _, err := decodeStateFile(data); err != nil {
+			r.Issues = append(r.Issues, err.Error())
+		}
+	case bytes.HasPrefix(data, movieMagic[:]):
+		r.Format = saveFormatMovie
+		if len(data) > len(movieMagic) {
+			r.Version = int(data[len(movieMagic)])
+		}
+		if _, err := decodeMovie(data); err != nil {
+			r.Issues = append(r.Issues, err.Error())
+		}
+	case len(data) > 0:
+		r.Format = saveFormatLegacyState
+		r.Issues = append(r.Issues, "headerless state: its layout and byte order are the core's own")
+	default:
+		r.Issues = append(r.Issues, "file is empty")
+	}
+	r.Portable = len(r.Issues) == 0
+	return r
+}
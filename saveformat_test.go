@@ -0,0 +1,159 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the save format fixtures in testdata")
+
+// goldenState is the state every state fixture carries.
+var goldenState = []byte("golden state")
+
+// legacyV1StateFile builds a version 1 state file, which has no core
+// version or encoding byte, as earlier bridges wrote it.
+func legacyV1StateFile() []byte {
+	var buf bytes.Buffer
+	buf.Write(stateFileMagic)
+	buf.WriteByte(1)
+	buf.WriteByte(1)
+	binary.Write(&buf, binary.LittleEndian, uint32(0x352441C2))
+	binary.Write(&buf, binary.LittleEndian, int64(1700000000))
+	binary.Write(&buf, binary.LittleEndian, uint16(len("mockcore")))
+	buf.WriteString("mockcore")
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(goldenState)))
+	binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(goldenState))
+	buf.Write(goldenState)
+	return buf.Bytes()
+}
+
+// goldenFixtures returns each fixture's file name and the bytes the
+// current encoders produce for it.
+func goldenFixtures() map[string][]byte {
+	state := stateFile{
+		version:     stateFileVersion,
+		region:      1,
+		romCRC:      0x352441C2,
+		timestamp:   1700000000,
+		core:        "mockcore",
+		coreVersion: "1.2",
+		thumbnail:   []byte{0x89, 'P', 'N', 'G'},
+		state:       goldenState,
+	}
+	v3 := state
+	v3.version = 3
+	movie := &inputMovie{
+		region: 0,
+		romCRC: 0x352441C2,
+		state:  goldenState,
+		rows:   [][]uint32{{0x1}, {0x1}, {0x1, 0x80}, {0}},
+	}
+	return map[string][]byte{
+		"state_v1.ebst": legacyV1StateFile(),
+		"state_v3.ebst": v3.encode(),
+		"state_v4.ebst": state.encode(),
+		"movie_v1.ebim": movie.encode(),
+	}
+}
+
+// TestSaveFormatGolden locks the state and movie layouts: any encoder
+// change that alters them fails here and needs a version bump. Run with
+// -update to rewrite the fixtures after one.
+func TestSaveFormatGolden(t *testing.T) {
+	for name, want := range goldenFixtures() {
+		path := filepath.Join("testdata", name)
+		if *updateGolden {
+			if err := os.WriteFile(path, want, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: encoder output differs from the fixture", name)
+		}
+	}
+}
+
+func TestSaveFormatGoldenDecodes(t *testing.T) {
+	for _, name := range []string{"state_v1.ebst", "state_v3.ebst", "state_v4.ebst"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := decodeStateFile(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if f.region != 1 || f.romCRC != 0x352441C2 || f.timestamp != 1700000000 ||
+			f.core != "mockcore" || !bytes.Equal(f.state, goldenState) {
+			t.Errorf("%s decoded as %+v", name, f)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "movie_v1.ebim"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := decodeMovie(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.rows) != 4 || m.rows[2][1] != 0x80 || !bytes.Equal(m.state, goldenState) {
+		t.Errorf("movie decoded as %+v", m)
+	}
+}
+
+func TestValidateSaveFormat(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	v4, err := os.ReadFile(filepath.Join("testdata", "state_v4.ebst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := append([]byte(nil), v4...)
+	corrupt[len(corrupt)-1] ^= 0xFF
+
+	for _, tc := range []struct {
+		path     string
+		format   string
+		version  int
+		portable bool
+	}{
+		{filepath.Join("testdata", "state_v1.ebst"), "state", 1, true},
+		{filepath.Join("testdata", "state_v3.ebst"), "state", 3, true},
+		{filepath.Join("testdata", "state_v4.ebst"), "state", 4, true},
+		{filepath.Join("testdata", "movie_v1.ebim"), "movie", 1, true},
+		{write("corrupt.ebst", corrupt), "state", 4, false},
+		{write("raw.state", []byte{1, 2, 3}), "legacyState", 0, false},
+		{write("future.ebim", append(append([]byte(nil), movieMagic[:]...), 9, 0, 0)), "movie", 9, false},
+	} {
+		var r saveFormatReport
+		if err := json.Unmarshal([]byte(ValidateSaveFormat(tc.path)), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Format != tc.format || r.Version != tc.version || r.Portable != tc.portable ||
+			r.Portable != (len(r.Issues) == 0) {
+			t.Errorf("%s: %+v", filepath.Base(tc.path), r)
+		}
+	}
+
+	if ValidateSaveFormat(filepath.Join(dir, "missing")) != "{}" {
+		t.Error("missing file not reported")
+	}
+}
@@ -0,0 +1,59 @@
+package ios
+
+// Built-in 2x scaling filters, which give the app screen effects without
+// shaders of its own.
+func init() {
+	RegisterFrameFilter("scanlines", &maskFilter{weights: [4]int{256, 256, 128, 128}})
+	RegisterFrameFilter("crt", &maskFilter{weights: [4]int{256, 256, 154, 154}, soften: true})
+	RegisterFrameFilter("lcd-grid", &maskFilter{weights: [4]int{256, 192, 192, 144}})
+}
+
+// maskFilter draws each pixel as a 2x2 block, dimming each position of
+// the block by a fixed weight.
+type maskFilter struct {
+	// weights scale the top-left, top-right, bottom-left and bottom-right
+	// pixels of each block, in 256ths.
+	weights [4]int
+	// soften blends the right column of each block with the next pixel,
+	// like the horizontal bleed of a CRT.
+	soften bool
+}
+
+func (f *maskFilter) Scale() int { return 2 }
+
+// Apply is not called for a FrameScaler.
+func (f *maskFilter) Apply(pixels []byte, width, height, stride int) {}
+
+func (f *maskFilter) ApplyScaled(dst, src []byte, width, height, stride int) {
+	outStride := width * 2 * 4
+	var right [4]byte
+	for y := 0; y < height; y++ {
+		row := src[y*stride:]
+		top := dst[2*y*outStride:]
+		bottom := dst[(2*y+1)*outStride:]
+		for x := 0; x < width; x++ {
+			p := row[x*4 : x*4+4]
+			copy(right[:], p)
+			if f.soften && x+1 < width {
+				next := row[(x+1)*4:]
+				for c := 0; c < 3; c++ {
+					right[c] = byte((int(p[c]) + int(next[c])) / 2)
+				}
+			}
+			o := x * 8
+			shade(top[o:o+4], p, f.weights[0])
+			shade(top[o+4:o+8], right[:], f.weights[1])
+			shade(bottom[o:o+4], p, f.weights[2])
+			shade(bottom[o+4:o+8], right[:], f.weights[3])
+		}
+	}
+}
+
+// shade writes p to dst with its color scaled by weight/256, keeping
+// alpha.
+func shade(dst, p []byte, weight int) {
+	for c := 0; c < 3; c++ {
+		dst[c] = byte(int(p[c]) * weight >> 8)
+	}
+	dst[3] = p[3]
+}
@@ -0,0 +1,55 @@
+package ios
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScanlinesFilter(t *testing.T) {
+	e := useMockEmulator(t)
+	for i := range e.framebuffer {
+		e.framebuffer[i] = 200
+	}
+	if !SetVideoFilter("scanlines", true) {
+		t.Fatal("scanlines not registered")
+	}
+	RunFrame()
+
+	if FrameWidth() != 8 || FrameHeight() != 8 || FrameStride() != 32 {
+		t.Fatalf("geometry = %dx%d stride %d, want 8x8 stride 32", FrameWidth(), FrameHeight(), FrameStride())
+	}
+	frame := GetFrameData()
+	if !bytes.Equal(frame[:4], []byte{200, 200, 200, 200}) || !bytes.Equal(frame[32:36], []byte{100, 100, 100, 200}) {
+		t.Errorf("rows = %v / %v, want full and half brightness", frame[:4], frame[32:36])
+	}
+
+	// Enabling another scaler replaces the first.
+	SetVideoFilter("lcd-grid", true)
+	if VideoFilterEnabled("scanlines") || !VideoFilterEnabled("lcd-grid") {
+		t.Error("scalers not mutually exclusive")
+	}
+	SetVideoFilter("lcd-grid", false)
+	RunFrame()
+	if FrameWidth() != 4 || len(GetFrameData()) != 64 {
+		t.Error("frame still scaled with no scaler enabled")
+	}
+}
+
+func TestScalerRunsAfterInPlaceFilters(t *testing.T) {
+	e := useMockEmulator(t)
+	SetVideoFilter("crt", true)
+	SetVideoFilter("high-contrast", true)
+	RunFrame()
+	if len(GetFrameData()) != 4*len(e.framebuffer) {
+		t.Errorf("frame = %d bytes, want %d", len(GetFrameData()), 4*len(e.framebuffer))
+	}
+}
+
+func TestScalerPointerInput(t *testing.T) {
+	e := useDeviceEmulator(t)
+	SetVideoFilter("crt", true)
+	SetPointerInput(0, 7, 5, true)
+	if e.pointer != [3]int{0, 3, 2} {
+		t.Errorf("pointer = %v, want [0 3 2]", e.pointer)
+	}
+}
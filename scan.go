@@ -0,0 +1,131 @@
+package ios
+
+import (
+	"encoding/json"
+	"hash/crc32"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	emucore "github.com/user-none/eblitui/api"
+	"github.com/user-none/eblitui/romloader"
+)
+
+// scanWorkers bounds concurrent loads, and so the ROMs held in memory,
+// during ScanDirectoryJSON.
+const scanWorkers = 4
+
+// archiveExtensions are the containers romloader can open.
+var archiveExtensions = []string{".zip", ".7z", ".gz", ".tgz", ".rar"}
+
+// scanEntry is one file in ScanDirectoryJSON's result.
+type scanEntry struct {
+	Path   string `json:"path"`
+	Name   string `json:"name,omitempty"`
+	Title  string `json:"title,omitempty"`
+	CRC32  string `json:"crc32,omitempty"`
+	SHA1   string `json:"sha1,omitempty"`
+	Size   int    `json:"size,omitempty"`
+	Region int    `json:"region"`
+	Error  string `json:"error,omitempty"`
+}
+
+// scanProgress tracks the running scan for ScanDirectoryProgress.
+var scanProgress struct {
+	total atomic.Int64
+	done  atomic.Int64
+}
+
+// ScanDirectoryJSON hashes every ROM in dir, and its subdirectories if
+// recursive, returning [{"path", "name", "title", "crc32", "sha1",
+// "size", "region"}]. title is the LoadROMDatabase title, if any.
+// Files are included if they have one of the system's extensions or are
+// archives. Files that fail to load are listed with an "error" field
+// instead. Returns "[]" if dir cannot be read.
+func ScanDirectoryJSON(dir string, recursive bool) string {
+	f := activeFactory()
+	if f == nil {
+		return "[]"
+	}
+	exts := f.SystemInfo().Extensions
+	paths := romCandidates(dir, recursive, exts)
+
+	scanProgress.done.Store(0)
+	scanProgress.total.Store(int64(len(paths)))
+
+	entries := make([]scanEntry, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(scanWorkers, len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entries[i] = scanFile(f, paths[i])
+				scanProgress.done.Add(1)
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// romCandidates returns the files in dir, and its subdirectories if
+// recursive, that have one of exts or are archives.
+func romCandidates(dir string, recursive bool, exts []string) []string {
+	var paths []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && !recursive {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if hasROMExtension(d.Name(), exts) || hasROMExtension(d.Name(), archiveExtensions) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths
+}
+
+// scanFile loads and describes one ROM file for f.
+func scanFile(f emucore.CoreFactory, path string) scanEntry {
+	e := scanEntry{Path: path}
+	rom, name, err := romloader.Load(path, f.SystemInfo().Extensions)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	e.Name = strings.TrimSuffix(name, filepath.Ext(name))
+	h := hashROM(rom)
+	e.CRC32, e.SHA1, e.Size = h.CRC32, h.SHA1, h.Size
+	e.Title = romTitle(crc32.ChecksumIEEE(rom))
+	region, _ := f.DetectRegion(rom)
+	e.Region = int(region)
+	return e
+}
+
+// ScanDirectoryProgress returns the running or last ScanDirectoryJSON's
+// progress from 0 to 100, for polling from another thread.
+func ScanDirectoryProgress() int {
+	total := scanProgress.total.Load()
+	if total == 0 {
+		return 100
+	}
+	return int(scanProgress.done.Load() * 100 / total)
+}
@@ -0,0 +1,74 @@
+package ios
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanDirectoryJSON(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+
+	dir := t.TempDir()
+	good := []byte{1, 2, 3, 4}
+	write := func(name string, data []byte) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.bin", good)
+	write("b.zip", []byte("not a zip"))
+	write("notes.txt", []byte("ignored"))
+	write("sub/c.bin", []byte{5, 6})
+
+	zipped, err := os.ReadFile(writeZip(t, zipFile{"d.bin", good}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("d.zip", zipped)
+
+	var entries []scanEntry
+	if err := json.Unmarshal([]byte(ScanDirectoryJSON(dir, false)), &entries); err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", entries)
+	}
+
+	want := fmt.Sprintf("%08X", crc32.ChecksumIEEE(good))
+	a, bad, d := entries[0], entries[1], entries[2]
+	if a.Name != "a" || a.CRC32 != want || a.Size != len(good) || a.Error != "" {
+		t.Errorf("a.bin = %+v", a)
+	}
+	if filepath.Base(bad.Path) != "b.zip" || bad.Error == "" || bad.CRC32 != "" {
+		t.Errorf("b.zip = %+v, want error", bad)
+	}
+	if d.Name != "d" || d.CRC32 != want || d.SHA1 != hashROM(good).SHA1 || d.SHA1 == "" {
+		t.Errorf("d.zip = %+v", d)
+	}
+	if p := ScanDirectoryProgress(); p != 100 {
+		t.Errorf("progress = %d, want 100", p)
+	}
+
+	if err := json.Unmarshal([]byte(ScanDirectoryJSON(dir, true)), &entries); err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("recursive: expected 4 entries, got %d", len(entries))
+	}
+}
+
+func TestScanDirectoryJSONMissingDir(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	if got := ScanDirectoryJSON(filepath.Join(t.TempDir(), "missing"), true); got != "[]" {
+		t.Errorf("got %s, want []", got)
+	}
+}
@@ -0,0 +1,228 @@
+package ios
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+)
+
+// CaptureScreenshotPNG encodes the last rendered frame as PNG.
+// Returns nil if no frame has been run yet.
+func CaptureScreenshotPNG() []byte {
+	return def.screenshotPNG(0)
+}
+
+// CaptureScreenshotPNGScaled encodes the last rendered frame as PNG,
+// box-filtered down so neither dimension exceeds maxDim. A maxDim of 0
+// or less disables scaling. Returns nil if no frame has been run yet.
+func CaptureScreenshotPNGScaled(maxDim int) []byte {
+	return def.screenshotPNG(maxDim)
+}
+
+// ThumbnailPNG encodes the last rendered frame as PNG, scaled to fit
+// within maxWidth x maxHeight at DisplayAspectRatio. The
+// frame is only shrunk, never enlarged. A limit of 0 or less leaves that
+// dimension unbounded. Returns nil if no frame has been run yet.
+func ThumbnailPNG(maxWidth, maxHeight int) []byte {
+	return def.thumbnailPNG(maxWidth, maxHeight)
+}
+
+func (in *instance) thumbnailPNG(maxWidth, maxHeight int) []byte {
+	in.mu.Lock()
+	img := in.frameImage()
+	dar := in.displayAspectRatio()
+	in.mu.Unlock()
+	if img == nil {
+		return nil
+	}
+	w, h := thumbnailSize(img.Rect.Dx(), img.Rect.Dy(), dar, maxWidth, maxHeight)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, areaScale(img, w, h)); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// thumbnailSize returns the size a w x h frame is shown at, corrected to
+// the display aspect ratio dar and shrunk to fit within maxWidth x
+// maxHeight. A dar of 0 keeps square pixels.
+func thumbnailSize(w, h int, dar float64, maxWidth, maxHeight int) (int, int) {
+	dispW, dispH := float64(w), float64(h)
+	if dar > 0 {
+		dispW = dispH * dar
+	}
+	scale := 1.0
+	if maxWidth > 0 {
+		scale = min(scale, float64(maxWidth)/dispW)
+	}
+	if maxHeight > 0 {
+		scale = min(scale, float64(maxHeight)/dispH)
+	}
+	return max(int(math.Round(dispW*scale)), 1), max(int(math.Round(dispH*scale)), 1)
+}
+
+// areaScale resizes img to outW x outH, averaging the source pixels each
+// output pixel covers. Used for shrinking; enlarging repeats pixels.
+func areaScale(img *image.NRGBA, outW, outH int) *image.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w == outW && h == outH {
+		return img
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, outW, outH))
+	for oy := 0; oy < outH; oy++ {
+		y0 := oy * h / outH
+		y1 := max((oy+1)*h/outH, y0+1)
+		for ox := 0; ox < outW; ox++ {
+			x0 := ox * w / outW
+			x1 := max((ox+1)*w/outW, x0+1)
+			var sum [4]int
+			for y := y0; y < y1; y++ {
+				row := img.Pix[y*img.Stride:]
+				for x := x0; x < x1; x++ {
+					for c := range sum {
+						sum[c] += int(row[x*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			d := out.Pix[oy*out.Stride+ox*4:]
+			for c := range sum {
+				d[c] = byte(sum[c] / n)
+			}
+		}
+	}
+	return out
+}
+
+// CaptureScreenshotToFile writes the last rendered frame to path as PNG.
+// The file is replaced atomically.
+// Returns true on success; on failure see LastError.
+func CaptureScreenshotToFile(path string) bool {
+	return def.captureScreenshotToFile(path)
+}
+
+func (in *instance) captureScreenshotToFile(path string) bool {
+	data := in.screenshotPNG(0)
+	if data == nil {
+		setLastError("no frame rendered")
+		return false
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		setLastError("failed to write %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+func (in *instance) screenshotPNG(maxDim int) []byte {
+	in.mu.Lock()
+	img := in.frameImage()
+	in.mu.Unlock()
+	return encodePNG(img, maxDim)
+}
+
+// encodePNG encodes img, first downscaling it to fit maxDim if maxDim is
+// positive. Returns nil if img is nil.
+func encodePNG(img *image.NRGBA, maxDim int) []byte {
+	if img == nil {
+		return nil
+	}
+	if maxDim > 0 {
+		img = boxDownscale(img, maxDim)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// frameImage converts the cached frame into an opaque NRGBA image,
+// dropping any stride padding. Returns nil if there is no frame.
+func (in *instance) frameImage() *image.NRGBA {
+	if in.emu == nil || len(in.frameData) == 0 {
+		return nil
+	}
+	stride := in.dataStride()
+	width := in.visibleWidth()
+	if stride <= 0 {
+		return nil
+	}
+	height := len(in.frameData) / stride
+	if width == 0 || height == 0 {
+		return nil
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		src := in.frameData[y*stride : y*stride+width*4]
+		dst := img.Pix[y*img.Stride : y*img.Stride+width*4]
+		copy(dst, src)
+		for x := 3; x < len(dst); x += 4 {
+			dst[x] = 0xFF
+		}
+	}
+	return img
+}
+
+// visibleWidth returns the frame width in pixels excluding stride
+// padding, after any crop, rotation and scaling filter.
+func (in *instance) visibleWidth() int {
+	if in.emu == nil {
+		return 0
+	}
+	return in.rotatedWidth() * in.filterScale()
+}
+
+// uncroppedWidth returns the frame width in pixels excluding stride
+// padding, using the system's screen width when it is narrower than
+// the stride.
+func (in *instance) uncroppedWidth() int {
+	if in.emu == nil {
+		return 0
+	}
+	width := in.emu.GetFramebufferStride() / 4
+	if f := in.coreFactory(); f != nil {
+		if w := f.SystemInfo().ScreenWidth; w > 0 && w < width {
+			width = w
+		}
+	}
+	return width
+}
+
+// boxDownscale shrinks img by the smallest integer factor that fits it
+// within maxDim, averaging each factor x factor block.
+func boxDownscale(img *image.NRGBA, maxDim int) *image.NRGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	longest := max(w, h)
+	if longest <= maxDim {
+		return img
+	}
+	factor := (longest + maxDim - 1) / maxDim
+	outW := max(w/factor, 1)
+	outH := max(h/factor, 1)
+
+	out := image.NewNRGBA(image.Rect(0, 0, outW, outH))
+	for oy := 0; oy < outH; oy++ {
+		for ox := 0; ox < outW; ox++ {
+			var sum [4]int
+			n := 0
+			for y := oy * factor; y < min((oy+1)*factor, h); y++ {
+				row := img.Pix[y*img.Stride:]
+				for x := ox * factor; x < min((ox+1)*factor, w); x++ {
+					p := row[x*4 : x*4+4]
+					for c := range sum {
+						sum[c] += int(p[c])
+					}
+					n++
+				}
+			}
+			d := out.Pix[oy*out.Stride+ox*4:]
+			for c := range sum {
+				d[c] = byte(sum[c] / n)
+			}
+		}
+	}
+	return out
+}
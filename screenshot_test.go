@@ -0,0 +1,149 @@
+package ios
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// useScreenshotEmulator sets up a 3x2 visible frame inside a 4 pixel
+// stride, with pixel (x, y) = {x*10, y*10, 7, 0}.
+func useScreenshotEmulator(t *testing.T) *mockEmulator {
+	t.Helper()
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			e.stride = 16
+			e.activeHeight = 2
+			e.framebuffer = make([]byte, 16*3)
+			for y := 0; y < 3; y++ {
+				for x := 0; x < 4; x++ {
+					copy(e.framebuffer[y*16+x*4:], []byte{byte(x * 10), byte(y * 10), 7, 0})
+				}
+			}
+			return e, nil
+		},
+		modify: func(info *emucore.SystemInfo) { info.ScreenWidth = 3 },
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func TestCaptureScreenshotPNG(t *testing.T) {
+	useScreenshotEmulator(t)
+
+	if CaptureScreenshotPNG() != nil {
+		t.Fatal("expected nil before first frame")
+	}
+	RunFrame()
+
+	img, err := png.Decode(bytes.NewReader(CaptureScreenshotPNG()))
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 3 || b.Dy() != 2 {
+		t.Fatalf("size = %dx%d, want 3x2", b.Dx(), b.Dy())
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			got := [4]uint32{r >> 8, g >> 8, b >> 8, a >> 8}
+			want := [4]uint32{uint32(x * 10), uint32(y * 10), 7, 0xFF}
+			if got != want {
+				t.Errorf("pixel (%d,%d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestCaptureScreenshotPNGScaled(t *testing.T) {
+	e := useMockEmulator(t)
+	e.stride = 4 * 4
+	e.activeHeight = 4
+	for i := range e.framebuffer {
+		e.framebuffer[i] = 0
+	}
+	// Top-left 2x2 block: red channel 0, 100, 200, 100 -> average 100.
+	for i, v := range []byte{0, 100, 100, 200} {
+		x, y := i%2, i/2
+		e.framebuffer[y*16+x*4] = v
+	}
+	RunFrame()
+
+	img, err := png.Decode(bytes.NewReader(CaptureScreenshotPNGScaled(2)))
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 2 || b.Dy() != 2 {
+		t.Fatalf("size = %dx%d, want 2x2", b.Dx(), b.Dy())
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r>>8 != 100 {
+		t.Errorf("averaged red = %d, want 100", r>>8)
+	}
+}
+
+func TestThumbnailPNG(t *testing.T) {
+	useScreenshotEmulator(t)
+	if ThumbnailPNG(10, 10) != nil {
+		t.Fatal("expected nil before first frame")
+	}
+	RunFrame()
+
+	img, err := png.Decode(bytes.NewReader(ThumbnailPNG(10, 1)))
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("size = %dx%d, want 2x1", b.Dx(), b.Dy())
+	}
+	if _, g, _, _ := img.At(0, 0).RGBA(); g>>8 != 5 {
+		t.Errorf("averaged green = %d, want 5", g>>8)
+	}
+}
+
+func TestThumbnailSizeAspect(t *testing.T) {
+	tests := []struct {
+		w, h, maxW, maxH int
+		wantW, wantH     int
+	}{
+		{256, 224, 160, 160, 160, 120},
+		{256, 224, 0, 112, 149, 112},
+		{256, 224, 0, 0, 299, 224},
+		{320, 240, 1000, 1000, 320, 240},
+	}
+	for _, tt := range tests {
+		w, h := thumbnailSize(tt.w, tt.h, 4.0/3, tt.maxW, tt.maxH)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("thumbnailSize(%d, %d, %d, %d) = %dx%d, want %dx%d",
+				tt.w, tt.h, tt.maxW, tt.maxH, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestCaptureScreenshotToFile(t *testing.T) {
+	useScreenshotEmulator(t)
+	path := filepath.Join(t.TempDir(), "shot.png")
+
+	if CaptureScreenshotToFile(path) {
+		t.Fatal("expected false before first frame")
+	}
+	RunFrame()
+	if !CaptureScreenshotToFile(path) {
+		t.Fatal("CaptureScreenshotToFile failed")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := png.Decode(f); err != nil {
+		t.Errorf("written file is not a PNG: %v", err)
+	}
+}
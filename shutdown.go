@@ -0,0 +1,98 @@
+package ios
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// shutdownDrainTimeout bounds how long BeginShutdown waits for in-flight
+// data fetches to finish.
+const shutdownDrainTimeout = 250 * time.Millisecond
+
+// shutdownGate lets data-fetch paths run without the emulator lock and
+// lets teardown wait for them to drain.
+type shutdownGate struct {
+	closing  atomic.Bool
+	inFlight atomic.Int32
+}
+
+// enter registers a fetch. Returns false, without registering, once
+// shutdown has begun.
+func (g *shutdownGate) enter() bool {
+	// Check before registering so rejected fetches never hold the
+	// counter above zero while begin is draining.
+	if g.closing.Load() {
+		return false
+	}
+	g.inFlight.Add(1)
+	if g.closing.Load() {
+		g.inFlight.Add(-1)
+		return false
+	}
+	return true
+}
+
+// leave ends a fetch registered by enter.
+func (g *shutdownGate) leave() {
+	g.inFlight.Add(-1)
+}
+
+// begin rejects new fetches and waits, up to shutdownDrainTimeout, for
+// in-flight ones to finish. Returns false if the wait timed out.
+func (g *shutdownGate) begin() bool {
+	g.closing.Store(true)
+	deadline := time.Now().Add(shutdownDrainTimeout)
+	for g.inFlight.Load() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+	return true
+}
+
+// reopen accepts fetches again for a new session.
+func (g *shutdownGate) reopen() {
+	g.closing.Store(false)
+}
+
+// BeginShutdown starts tearing down the emulator. From this call on,
+// GetFrameData and GetAudioData return nil immediately, and the call
+// waits (bounded) for fetches already in progress to return.
+//
+// Required ordering when stopping a session:
+//
+//  1. BeginShutdown, or check IsShuttingDown from each callback.
+//  2. Stop the display link and audio render callbacks.
+//  3. Close.
+//
+// Close calls BeginShutdown itself if it has not been called, so the
+// explicit call only matters for letting callbacks stop early.
+// Returns false if in-flight fetches did not drain in time.
+func BeginShutdown() bool {
+	return def.gate.begin()
+}
+
+// IsShuttingDown returns whether BeginShutdown or Close has been called
+// since the last Init. Audio and video callbacks should stop when true.
+func IsShuttingDown() bool {
+	return def.gate.closing.Load()
+}
+
+// fetchFrame returns the last published frame unless shutdown has begun.
+func (in *instance) fetchFrame() []byte {
+	if !in.gate.enter() {
+		return nil
+	}
+	defer in.gate.leave()
+	return in.latestFrame()
+}
+
+// fetchAudio returns the last published audio unless shutdown has begun.
+func (in *instance) fetchAudio() []byte {
+	if !in.gate.enter() {
+		return nil
+	}
+	defer in.gate.leave()
+	return in.latestAudio()
+}
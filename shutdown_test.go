@@ -0,0 +1,109 @@
+package ios
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownRejectsFetches(t *testing.T) {
+	e := useMockEmulator(t)
+	e.samples = []int16{1, 2}
+	RunFrame()
+
+	if IsShuttingDown() || GetFrameData() == nil {
+		t.Fatal("fetch rejected before shutdown")
+	}
+	if !BeginShutdown() {
+		t.Fatal("BeginShutdown timed out with no fetchers")
+	}
+	if !IsShuttingDown() {
+		t.Error("IsShuttingDown = false after BeginShutdown")
+	}
+	if GetFrameData() != nil || GetAudioData() != nil {
+		t.Error("fetch returned data during shutdown")
+	}
+
+	Close()
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	if IsShuttingDown() {
+		t.Error("IsShuttingDown = true after Init")
+	}
+}
+
+func TestShutdownWaitsForInFlightFetch(t *testing.T) {
+	useMockEmulator(t)
+
+	if !def.gate.enter() {
+		t.Fatal("enter failed")
+	}
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(released)
+		def.gate.leave()
+	}()
+
+	if !BeginShutdown() {
+		t.Fatal("BeginShutdown timed out")
+	}
+	select {
+	case <-released:
+	default:
+		t.Error("BeginShutdown returned before the fetch finished")
+	}
+}
+
+func TestShutdownDrainIsBounded(t *testing.T) {
+	useMockEmulator(t)
+
+	def.gate.enter()
+	defer def.gate.leave()
+
+	start := time.Now()
+	if BeginShutdown() {
+		t.Error("BeginShutdown reported drained with a stuck fetch")
+	}
+	if d := time.Since(start); d > 4*shutdownDrainTimeout {
+		t.Errorf("BeginShutdown took %v", d)
+	}
+}
+
+func TestShutdownRacesConcurrentFetchers(t *testing.T) {
+	for iter := 0; iter < 20; iter++ {
+		e := useMockEmulator(t)
+		e.samples = []int16{1, 2, 3, 4}
+		RunFrame()
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if f := GetFrameData(); f != nil {
+						_ = f[0]
+					}
+					if a := GetAudioData(); a != nil {
+						_ = a[0]
+					}
+					_ = IsShuttingDown()
+				}
+			}()
+		}
+
+		time.Sleep(time.Millisecond)
+		BeginShutdown()
+		Close()
+		close(stop)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,91 @@
+package ios
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"path/filepath"
+)
+
+// maxStateSlots is the number of save slots per game, numbered from 0.
+const maxStateSlots = 100
+
+// slotPath returns the state file for the loaded ROM's slot in dir.
+func (in *instance) slotPath(dir string, slot int) string {
+	return filepath.Join(dir, fmt.Sprintf("%08X.slot%d", crc32.ChecksumIEEE(in.rom), slot))
+}
+
+// validSlot reports whether slot is in range, recording an error if not.
+func (in *instance) validSlot(slot int) bool {
+	if in.emu == nil {
+		setLastError("no game loaded")
+		return false
+	}
+	if slot < 0 || slot >= maxStateSlots {
+		setLastError("slot %d out of range 0-%d", slot, maxStateSlots-1)
+		return false
+	}
+	return true
+}
+
+// SaveStateToSlot saves a state with metadata and a thumbnail, as
+// WriteStateWithMetadata does, to a numbered slot for the loaded ROM in
+// dir. Slots are 0-99 and are kept per ROM, so games can share a dir.
+// Returns true on success (see LastError on failure).
+func SaveStateToSlot(dir string, slot int) bool {
+	return def.saveStateToSlot(dir, slot)
+}
+
+func (in *instance) saveStateToSlot(dir string, slot int) bool {
+	return in.validSlot(slot) && in.writeStateWithMetadata(in.slotPath(dir, slot))
+}
+
+// LoadStateFromSlot loads the state saved to a slot by SaveStateToSlot.
+// Returns true on success (see LastError on failure).
+func LoadStateFromSlot(dir string, slot int) bool {
+	return def.loadStateFromSlot(dir, slot)
+}
+
+func (in *instance) loadStateFromSlot(dir string, slot int) bool {
+	return in.validSlot(slot) && in.loadStateWithMetadata(in.slotPath(dir, slot))
+}
+
+// ListStateSlotsJSON returns the loaded ROM's filled slots in dir, in slot
+// order, as a JSON array of ReadStateMetadataJSON objects with an added
+// "slot" field. Returns "[]" if no game is loaded.
+func ListStateSlotsJSON(dir string) string {
+	return def.listStateSlotsJSON(dir)
+}
+
+func (in *instance) listStateSlotsJSON(dir string) string {
+	type slotInfo struct {
+		Slot int `json:"slot"`
+		stateMetadata
+	}
+	slots := []slotInfo{}
+	if in.emu != nil {
+		for slot := range maxStateSlots {
+			f, err := readStateHeader(in.slotPath(dir, slot))
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				journalf("warning", "slot %d: %v", slot, err)
+				continue
+			}
+			meta, ok := f.metadata().(stateMetadata)
+			if !ok {
+				journalf("warning", "slot %d holds a state without metadata", slot)
+				continue
+			}
+			slots = append(slots, slotInfo{Slot: slot, stateMetadata: meta})
+		}
+	}
+	data, err := json.Marshal(slots)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
@@ -0,0 +1,79 @@
+package ios
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateSlots(t *testing.T) {
+	e := useStateEmulator(t, []byte{0x01})
+	dir := t.TempDir()
+	RunFrame()
+
+	e.value = 3
+	if !SaveStateToSlot(dir, 0) {
+		t.Fatalf("SaveStateToSlot(0) failed: %s", LastError())
+	}
+	e.value = 9
+	if !SaveStateToSlot(dir, 7) {
+		t.Fatalf("SaveStateToSlot(7) failed: %s", LastError())
+	}
+
+	var slots []struct {
+		Slot      int    `json:"slot"`
+		ROMCRC    string `json:"romCRC"`
+		Timestamp int64  `json:"timestamp"`
+		Thumbnail []byte `json:"thumbnail"`
+	}
+	if err := json.Unmarshal([]byte(ListStateSlotsJSON(dir)), &slots); err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 2 || slots[0].Slot != 0 || slots[1].Slot != 7 {
+		t.Fatalf("slots = %+v", slots)
+	}
+	if slots[0].ROMCRC == "" || slots[0].Timestamp == 0 || len(slots[0].Thumbnail) == 0 {
+		t.Errorf("slot 0 metadata = %+v", slots[0])
+	}
+
+	e.value = 0
+	if !LoadStateFromSlot(dir, 0) || e.value != 3 {
+		t.Errorf("LoadStateFromSlot(0) value = %d, want 3", e.value)
+	}
+	if LoadStateFromSlot(dir, 1) {
+		t.Error("LoadStateFromSlot succeeded for an empty slot")
+	}
+	if SaveStateToSlot(dir, maxStateSlots) || SaveStateToSlot(dir, -1) {
+		t.Error("out of range slot accepted")
+	}
+}
+
+func TestStateSlotsSkipCorrupt(t *testing.T) {
+	useStateEmulator(t, []byte{0x01})
+	dir := t.TempDir()
+	SaveStateToSlot(dir, 2)
+	path := def.slotPath(dir, 3)
+	if err := os.WriteFile(path, append([]byte("EBST"), 1, 2), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var slots []struct{ Slot int }
+	json.Unmarshal([]byte(ListStateSlotsJSON(dir)), &slots)
+	if len(slots) != 1 || slots[0].Slot != 2 {
+		t.Errorf("slots = %+v, want only 2", slots)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("slot path %s outside dir", path)
+	}
+}
+
+func TestStateSlotsNoGame(t *testing.T) {
+	useMockFactory(t, &mockFactory{})
+	if got := ListStateSlotsJSON(t.TempDir()); got != "[]" {
+		t.Errorf("ListStateSlotsJSON = %s, want []", got)
+	}
+	if SaveStateToSlot(t.TempDir(), 0) {
+		t.Error("SaveStateToSlot succeeded without a game")
+	}
+}
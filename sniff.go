@@ -0,0 +1,123 @@
+package ios
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	emucore "github.com/user-none/eblitui/api"
+	"github.com/user-none/eblitui/romloader"
+)
+
+// ROMSniffer is an optional CoreFactory interface for cores that can
+// recognize their ROMs by content, e.g. from a header signature.
+type ROMSniffer interface {
+	// SniffROM returns a confidence from 0 to 100 that rom belongs to
+	// this system.
+	SniffROM(rom []byte) int
+}
+
+// sniffAcceptConfidence is the confidence at which ExtractAndStoreROM
+// accepts a file whose extension does not match.
+const sniffAcceptConfidence = 50
+
+// sizeConfidence is the confidence given to a ROM whose size is a power
+// of two multiple of 8 KiB, which cartridge dumps usually are.
+const sizeConfidence = 20
+
+// sniffResult is the JSON returned by SniffROM.
+type sniffResult struct {
+	System     string `json:"system"`
+	Confidence int    `json:"confidence"`
+}
+
+// sniffROMWith scores rom against f.
+func sniffROMWith(f emucore.CoreFactory, rom []byte) sniffResult {
+	if f == nil || len(rom) == 0 {
+		return sniffResult{}
+	}
+	confidence := 0
+	if s, ok := f.(ROMSniffer); ok {
+		confidence = s.SniffROM(rom)
+	}
+	if confidence < sizeConfidence && alignedROMSize(len(rom)) {
+		confidence = sizeConfidence
+	}
+	if confidence <= 0 {
+		return sniffResult{}
+	}
+	return sniffResult{System: f.SystemInfo().Name, Confidence: min(confidence, 100)}
+}
+
+// alignedROMSize reports whether n is a power of two of at least 8 KiB.
+func alignedROMSize(n int) bool {
+	return n >= 8*1024 && n&(n-1) == 0
+}
+
+// loadROMAnyExtension loads a ROM like romloader.Load, but reads files
+// that are neither archives nor named with a known extension as raw ROM
+// data instead of failing.
+func loadROMAnyExtension(path string, extensions []string) ([]byte, string, error) {
+	rom, name, err := romloader.Load(path, extensions)
+	if !errors.Is(err, romloader.ErrUnsupportedFormat) {
+		return rom, name, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxEntrySize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read ROM: %w", err)
+	}
+	if len(data) > maxEntrySize {
+		return nil, "", romloader.ErrFileTooLarge
+	}
+	return data, filepath.Base(path), nil
+}
+
+// SniffROM guesses the system a ROM file belongs to from its contents,
+// ignoring its extension. Every registered core is asked and the most
+// confident wins. Returns JSON {"system", "confidence"} with confidence
+// from 0 to 100; system is empty when nothing matched.
+func SniffROM(path string) string {
+	var result sniffResult
+	for _, f := range candidateFactories() {
+		rom, _, err := loadROMAnyExtension(path, f.SystemInfo().Extensions)
+		if err != nil {
+			setLastError("failed to load ROM: %v", err)
+			continue
+		}
+		if r := sniffROMWith(f, rom); r.Confidence > result.Confidence {
+			result = r
+		}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// InitForcingSystem is Init without the extension check: the file's bytes
+// are handed to the system named systemName regardless of its name, and
+// that system's core is made active.
+// Returns false if systemName is not registered (see LastError).
+func InitForcingSystem(path, systemName string, regionCode int) bool {
+	f := systemFactory(systemName)
+	if f == nil {
+		setLastError("unknown system %q", systemName)
+		return false
+	}
+	rom, _, err := loadROMAnyExtension(path, f.SystemInfo().Extensions)
+	if err != nil {
+		setLastError("failed to load ROM: %v", err)
+		return false
+	}
+	return def.initROM(f, rom, regionCode)
+}
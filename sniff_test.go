@@ -0,0 +1,101 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockSniffFactory recognizes ROMs starting with "TEST".
+type mockSniffFactory struct {
+	mockFactory
+}
+
+func (f *mockSniffFactory) SniffROM(rom []byte) int {
+	if bytes.HasPrefix(rom, []byte("TEST")) {
+		return 90
+	}
+	return 0
+}
+
+var headeredROM = append([]byte("TEST"), make([]byte, 60)...)
+
+func parseSniff(t *testing.T, s string) sniffResult {
+	t.Helper()
+	var r sniffResult
+	if err := json.Unmarshal([]byte(s), &r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestSniffROMIgnoresExtension(t *testing.T) {
+	oldFactory := factory
+	t.Cleanup(func() { factory = oldFactory })
+	factory = &mockSniffFactory{}
+
+	r := parseSniff(t, SniffROM(writeROM(t, "game.dat", headeredROM)))
+	if r.System != "test" || r.Confidence != 90 {
+		t.Errorf("headered ROM = %+v", r)
+	}
+
+	r = parseSniff(t, SniffROM(writeROM(t, "document", make([]byte, 16*1024))))
+	if r.System != "test" || r.Confidence != sizeConfidence {
+		t.Errorf("aligned ROM = %+v", r)
+	}
+
+	r = parseSniff(t, SniffROM(writeROM(t, "notes.rom", []byte("plain text"))))
+	if r.System != "" || r.Confidence != 0 {
+		t.Errorf("unrecognized file = %+v", r)
+	}
+}
+
+func TestInitForcingSystem(t *testing.T) {
+	var e *mockEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = newMockEmulator(rom, region)
+			return e, nil
+		},
+	})
+	path := writeROM(t, "game.dat", headeredROM)
+
+	if Init(path, 0) {
+		t.Fatal("Init accepted a misnamed ROM")
+	}
+	if InitForcingSystem(path, "other", 0) {
+		t.Error("InitForcingSystem accepted an unknown system")
+	}
+	if !InitForcingSystem(path, "test", 0) {
+		t.Fatalf("InitForcingSystem failed: %s", LastError())
+	}
+	if !bytes.Equal(e.rom, headeredROM) {
+		t.Error("ROM bytes not passed through")
+	}
+}
+
+func TestExtractAndStoreROMSniffsMisnamedFile(t *testing.T) {
+	oldFactory := factory
+	t.Cleanup(func() { factory = oldFactory })
+	factory = &mockSniffFactory{}
+	dest := t.TempDir()
+
+	result, err := ExtractAndStoreROM(writeROM(t, "game.dat", headeredROM), dest, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crc := fmt.Sprintf("%08X", crc32.ChecksumIEEE(headeredROM))
+	if _, err := os.Stat(filepath.Join(dest, crc+".bin")); err != nil {
+		t.Errorf("ROM not stored with system extension: %v (%s)", err, result)
+	}
+
+	if _, err := ExtractAndStoreROM(writeROM(t, "notes.dat", []byte("plain")), dest, false); err == nil {
+		t.Error("unrecognized misnamed file accepted")
+	}
+}
@@ -0,0 +1,211 @@
+package ios
+
+import (
+	"hash/crc32"
+	"time"
+)
+
+// SRAMChangeReporter is an optional BatterySaver extension for cores that
+// track SRAM writes themselves, letting the bridge skip hashing.
+type SRAMChangeReporter interface {
+	// SRAMChanged reports whether SRAM was written since the last call.
+	SRAMChanged() bool
+}
+
+// defaultSRAMCheckInterval is how often, in frames, RunFrame hashes SRAM
+// to detect changes.
+const defaultSRAMCheckInterval = 60
+
+// sramTracker detects SRAM changes since the last flush.
+type sramTracker struct {
+	interval  int
+	countdown int
+
+	// cleanCRC is the CRC32 of the SRAM contents last flushed or loaded.
+	cleanCRC uint32
+	dirty    bool
+
+	// autoPath and autoInterval are the SetSRAMAutoSave target;
+	// autoFlushed is when RunFrame last flushed to it.
+	autoPath     string
+	autoInterval time.Duration
+	autoFlushed  time.Time
+}
+
+// resetSRAMTracking takes the current SRAM as the clean baseline.
+func (in *instance) resetSRAMTracking() {
+	t := &in.sramTracker
+	t.countdown = 0
+	t.dirty = false
+	if in.hasSRAMLocked() {
+		t.cleanCRC = crc32.ChecksumIEEE(in.batterySaver.GetSRAM())
+	}
+	if r, ok := in.batterySaver.(SRAMChangeReporter); ok {
+		r.SRAMChanged()
+	}
+}
+
+// markSRAMClean records crc as the contents now persisted.
+func (in *instance) markSRAMClean(crc uint32) {
+	in.sramTracker.cleanCRC = crc
+	in.sramTracker.dirty = false
+}
+
+// checkSRAM updates the dirty flag. Cores implementing SRAMChangeReporter
+// are polled every frame; others are hashed every check interval.
+func (in *instance) checkSRAM() {
+	if !in.hasSRAMLocked() {
+		return
+	}
+	t := &in.sramTracker
+	if r, ok := in.batterySaver.(SRAMChangeReporter); ok {
+		if r.SRAMChanged() {
+			t.dirty = true
+		}
+		in.autoSaveSRAM()
+		return
+	}
+
+	if t.countdown > 0 {
+		t.countdown--
+	} else {
+		t.countdown = t.checkInterval() - 1
+		if crc32.ChecksumIEEE(in.batterySaver.GetSRAM()) != t.cleanCRC {
+			t.dirty = true
+		}
+	}
+	in.autoSaveSRAM()
+}
+
+// autoSaveSRAM flushes dirty SRAM to the SetSRAMAutoSave path once the
+// interval has passed since the last flush.
+func (in *instance) autoSaveSRAM() {
+	t := &in.sramTracker
+	if t.autoPath == "" || !t.dirty || time.Since(t.autoFlushed) < t.autoInterval {
+		return
+	}
+	if in.flushSRAMLocked(t.autoPath) {
+		t.autoFlushed = time.Now()
+	} else {
+		journalf("warning", "SRAM auto-save failed: %s", LastError())
+		// Retry after another interval rather than every frame.
+		t.autoFlushed = time.Now()
+	}
+}
+
+func (t *sramTracker) checkInterval() int {
+	if t.interval <= 0 {
+		return defaultSRAMCheckInterval
+	}
+	return t.interval
+}
+
+// SetSRAMCheckInterval sets how often, in frames, RunFrame hashes SRAM to
+// detect changes. Values below 1 restore the default of 60.
+func SetSRAMCheckInterval(frames int) {
+	def.setSRAMCheckInterval(frames)
+}
+
+func (in *instance) setSRAMCheckInterval(frames int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.sramTracker.interval = frames
+	in.sramTracker.countdown = 0
+}
+
+// SRAMDirty returns whether SRAM has changed since it was last flushed
+// with FlushSRAMToFile or loaded with LoadSRAM. Changes are detected when
+// RunFrame checks, so the result can lag by up to the check interval.
+func SRAMDirty() bool {
+	return def.sramDirty()
+}
+
+func (in *instance) sramDirty() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.sramTracker.dirty
+}
+
+// FlushSRAMToFile writes SRAM to path if its contents changed since the
+// last flush or load. The file is replaced atomically.
+// Returns true if the file is up to date, whether or not it was written.
+func FlushSRAMToFile(path string) bool {
+	return def.flushSRAM(path)
+}
+
+func (in *instance) flushSRAM(path string) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.flushSRAMLocked(path)
+}
+
+// flushSRAMLocked is flushSRAM with in.mu held.
+func (in *instance) flushSRAMLocked(path string) bool {
+	if !in.hasSRAMLocked() {
+		return false
+	}
+	t := &in.sramTracker
+	if _, ok := in.batterySaver.(SRAMChangeReporter); ok && !t.dirty {
+		return true
+	}
+
+	data := in.batterySaver.GetSRAM()
+	crc := crc32.ChecksumIEEE(data)
+	if crc == t.cleanCRC {
+		t.dirty = false
+		return true
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		setLastError("failed to write SRAM: %v", err)
+		return false
+	}
+	in.markSRAMClean(crc)
+	return true
+}
+
+// SetSRAMAutoSave makes RunFrame write SRAM to path, atomically, whenever
+// it changed and at least intervalSeconds have passed since the last
+// write, so saves survive the app being killed. Close also flushes it.
+// An empty path turns auto-save off. The setting lasts until the next
+// Init. Returns false if no ROM is loaded or the core has no SRAM.
+func SetSRAMAutoSave(path string, intervalSeconds int) bool {
+	return def.setSRAMAutoSave(path, intervalSeconds)
+}
+
+func (in *instance) setSRAMAutoSave(path string, intervalSeconds int) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if !in.hasSRAMLocked() {
+		setLastError("core has no SRAM")
+		return false
+	}
+	t := &in.sramTracker
+	t.autoPath = path
+	t.autoInterval = time.Duration(max(intervalSeconds, 0)) * time.Second
+	t.autoFlushed = time.Now()
+	return true
+}
+
+// FlushSRAM writes SRAM to the SetSRAMAutoSave path now if it changed,
+// for the app-suspend path. It checks SRAM itself rather than waiting
+// for the next periodic check.
+// Returns true if the file is up to date, false if auto-save is off or
+// the write failed (see LastError).
+func FlushSRAM() bool {
+	return def.flushAutoSaveSRAM()
+}
+
+func (in *instance) flushAutoSaveSRAM() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	t := &in.sramTracker
+	if t.autoPath == "" {
+		setLastError("SRAM auto-save is off")
+		return false
+	}
+	if !in.flushSRAMLocked(t.autoPath) {
+		return false
+	}
+	t.autoFlushed = time.Now()
+	return true
+}
@@ -0,0 +1,182 @@
+package ios
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func useSRAMEmulator(t *testing.T) *mockSRAMEmulator {
+	t.Helper()
+	var e *mockSRAMEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockSRAMEmulator{mockEmulator: newMockEmulator(rom, region), sram: make([]byte, 8)}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func TestFlushSRAMOnlyWhenDirty(t *testing.T) {
+	e := useSRAMEmulator(t)
+	SetSRAMCheckInterval(1)
+	t.Cleanup(func() { SetSRAMCheckInterval(0) })
+	path := filepath.Join(t.TempDir(), "game.srm")
+
+	RunFrame()
+	if SRAMDirty() {
+		t.Fatal("SRAM dirty without changes")
+	}
+	if !FlushSRAMToFile(path) {
+		t.Fatal("FlushSRAMToFile failed")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("SRAM written without changes")
+	}
+
+	e.sram[3] = 0x7F
+	RunFrame()
+	if !SRAMDirty() {
+		t.Fatal("SRAM not dirty after change")
+	}
+	if !FlushSRAMToFile(path) {
+		t.Fatal("FlushSRAMToFile failed")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(data, e.sram) {
+		t.Fatalf("file = %v, %v; want %v", data, err, e.sram)
+	}
+	if SRAMDirty() {
+		t.Error("SRAM dirty after flush")
+	}
+
+	// Unchanged content is not rewritten.
+	os.Remove(path)
+	RunFrame()
+	FlushSRAMToFile(path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("SRAM rewritten without changes")
+	}
+}
+
+func TestSRAMCheckInterval(t *testing.T) {
+	e := useSRAMEmulator(t)
+	SetSRAMCheckInterval(3)
+	t.Cleanup(func() { SetSRAMCheckInterval(0) })
+
+	RunFrame() // checks
+	e.sram[0] = 1
+	RunFrame()
+	RunFrame()
+	if SRAMDirty() {
+		t.Fatal("SRAM hashed between intervals")
+	}
+	RunFrame() // checks
+	if !SRAMDirty() {
+		t.Error("SRAM change missed at interval")
+	}
+}
+
+func TestLoadSRAMMarksClean(t *testing.T) {
+	useSRAMEmulator(t)
+	SetSRAMCheckInterval(1)
+	t.Cleanup(func() { SetSRAMCheckInterval(0) })
+
+	LoadSRAM([]byte{9, 9, 9, 9, 9, 9, 9, 9})
+	RunFrame()
+	if SRAMDirty() {
+		t.Error("SRAM dirty right after LoadSRAM")
+	}
+}
+
+type mockReportingSRAMEmulator struct {
+	*mockSRAMEmulator
+	changed bool
+}
+
+func (m *mockReportingSRAMEmulator) SRAMChanged() bool {
+	c := m.changed
+	m.changed = false
+	return c
+}
+
+func TestSRAMChangeReporter(t *testing.T) {
+	var e *mockReportingSRAMEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockReportingSRAMEmulator{mockSRAMEmulator: &mockSRAMEmulator{
+				mockEmulator: newMockEmulator(rom, region),
+				sram:         make([]byte, 4),
+			}}
+			return e, nil
+		},
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0x00}), 0) {
+		t.Fatal("Init failed")
+	}
+
+	e.sram[0] = 1 // not reported, so not dirty
+	RunFrame()
+	if SRAMDirty() {
+		t.Fatal("dirty without core report")
+	}
+	e.changed = true
+	RunFrame()
+	if !SRAMDirty() {
+		t.Fatal("core report ignored")
+	}
+	path := filepath.Join(t.TempDir(), "game.srm")
+	if !FlushSRAMToFile(path) || SRAMDirty() {
+		t.Fatal("flush failed")
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, e.sram) {
+		t.Errorf("file = %v, want %v", data, e.sram)
+	}
+}
+
+func TestSRAMAutoSave(t *testing.T) {
+	e := useSRAMEmulator(t)
+	SetSRAMCheckInterval(1)
+	t.Cleanup(func() { SetSRAMCheckInterval(0) })
+	path := filepath.Join(t.TempDir(), "game.srm")
+	if FlushSRAM() {
+		t.Error("FlushSRAM succeeded with auto-save off")
+	}
+	if !SetSRAMAutoSave(path, 0) {
+		t.Fatal("SetSRAMAutoSave failed")
+	}
+
+	e.sram[0] = 1
+	RunFrame()
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, e.sram) {
+		t.Fatalf("auto-saved SRAM = %v, want %v", data, e.sram)
+	}
+
+	// Within the interval changes wait for FlushSRAM.
+	SetSRAMAutoSave(path, 3600)
+	e.sram[1] = 2
+	RunFrame()
+	if data, _ := os.ReadFile(path); data[1] != 0 {
+		t.Error("auto-save wrote before the interval passed")
+	}
+	if !FlushSRAM() {
+		t.Fatalf("FlushSRAM failed: %s", LastError())
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, e.sram) {
+		t.Errorf("flushed SRAM = %v, want %v", data, e.sram)
+	}
+
+	e.sram[2] = 3
+	want := append([]byte(nil), e.sram...)
+	Close()
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, want) {
+		t.Errorf("SRAM after Close = %v, want %v", data, want)
+	}
+}
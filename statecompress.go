@@ -0,0 +1,72 @@
+package ios
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+)
+
+// State encodings in the state container.
+const (
+	stateEncodingRaw  = 0
+	stateEncodingZlib = 1
+)
+
+// maxInflatedState bounds a decompressed state, so a corrupt or hostile
+// file cannot exhaust memory.
+const maxInflatedState = 256 << 20
+
+var errStateTooLarge = errors.New("decompressed state too large")
+
+// SetStateCompression sets the zlib level used for save states, state
+// files and rewind snapshots: 0 stores them uncompressed (the default),
+// 1 is fastest and 9 smallest. Out of range levels are clamped. Higher
+// levels cost more time per rewind snapshot. LoadState reads either
+// form. The setting is kept across Init.
+func SetStateCompression(level int) {
+	def.setStateCompression(level)
+}
+
+func (in *instance) setStateCompression(level int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.stateCompression = max(0, min(zlib.BestCompression, level))
+}
+
+// GetStateCompression returns the SetStateCompression level.
+func GetStateCompression() int {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.stateCompression
+}
+
+// deflateState compresses state at level into dst's capacity.
+func deflateState(dst, state []byte, level int) []byte {
+	buf := bytes.NewBuffer(dst[:0])
+	w, err := zlib.NewWriterLevel(buf, level)
+	if err != nil {
+		// Levels are clamped by SetStateCompression.
+		panic(err)
+	}
+	w.Write(state)
+	w.Close()
+	return buf.Bytes()
+}
+
+// inflateState decompresses a deflateState result.
+func inflateState(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	state, err := io.ReadAll(io.LimitReader(r, maxInflatedState+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(state) > maxInflatedState {
+		return nil, errStateTooLarge
+	}
+	return state, nil
+}
@@ -0,0 +1,89 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateCompressionRoundTrip(t *testing.T) {
+	e := useStateEmulator(t, []byte{0x01})
+	SetStateCompression(42)
+	t.Cleanup(func() { SetStateCompression(0) })
+	if GetStateCompression() != 9 {
+		t.Errorf("level = %d, want clamped 9", GetStateCompression())
+	}
+
+	e.value = 0x42
+	if !SaveState() {
+		t.Fatal("SaveState failed")
+	}
+	var info struct {
+		Compressed bool
+		StateSize  int
+	}
+	json.Unmarshal([]byte(StateInfoJSON(GetStateData())), &info)
+	if !info.Compressed || info.StateSize != 2+frameTrailerSize {
+		t.Errorf("info = %+v", info)
+	}
+	e.value = 0
+	if !LoadState(GetStateData()) || e.value != 0x42 {
+		t.Errorf("compressed state value = %#x, want 0x42", e.value)
+	}
+
+	// Uncompressed states still load with compression on.
+	state := GetStateData()
+	SetStateCompression(0)
+	SaveState()
+	if bytes.Equal(GetStateData(), state) {
+		t.Error("state still compressed with compression off")
+	}
+	SetStateCompression(1)
+	e.value = 7
+	raw := append([]byte(nil), GetStateData()...)
+	if !LoadState(raw) || e.value != 0x42 {
+		t.Errorf("raw state value = %#x, want 0x42", e.value)
+	}
+
+	path := filepath.Join(t.TempDir(), "game.state")
+	e.value = 9
+	if !WriteStateWithMetadata(path) {
+		t.Fatal(LastError())
+	}
+	e.value = 0
+	if !LoadStateFromFile(path) || e.value != 9 {
+		t.Errorf("compressed file value = %d, want 9", e.value)
+	}
+}
+
+func TestRewindCompression(t *testing.T) {
+	e := useStateEmulator(t, []byte{0x01})
+	SetStateCompression(6)
+	t.Cleanup(func() { SetStateCompression(0) })
+	EnableRewind(1)
+	SetRewindInterval(1)
+	for i := 1; i <= 3; i++ {
+		e.value = byte(i)
+		RunFrame()
+	}
+	if !def.rewind.snapshots[0].compressed {
+		t.Fatal("rewind snapshot not compressed")
+	}
+	for _, want := range []byte{3, 2, 1} {
+		if !RewindStep() || e.value != want {
+			t.Errorf("rewound to %d, want %d", e.value, want)
+		}
+	}
+}
+
+func TestInflateStateRejectsGarbage(t *testing.T) {
+	if _, err := inflateState([]byte{1, 2, 3}); err == nil {
+		t.Error("inflated garbage")
+	}
+	state := bytes.Repeat([]byte{0xAB}, 1000)
+	got, err := inflateState(deflateState(nil, state, 1))
+	if err != nil || !bytes.Equal(got, state) {
+		t.Errorf("round trip = %d bytes, %v", len(got), err)
+	}
+}
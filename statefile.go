@@ -0,0 +1,536 @@
+package ios
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+// State file layout, little-endian:
+//
+//	magic      [4]byte "EBST"
+//	version    uint8
+//	region     uint8
+//	romCRC     uint32
+//	timestamp  int64 unix seconds
+//	coreLen    uint16, core [coreLen]byte
+//	verLen     uint16, coreVersion [verLen]byte (version 2 and later)
+//	thumbLen   uint32, thumb [thumbLen]byte PNG
+//	encoding   uint8 (version 3 and later; 0 raw, 1 zlib)
+//	rawLen     uint32 (version 4 and later)
+//	stateLen   uint32, stateCRC uint32, state [stateLen]byte
+//
+// rawLen is the uncompressed state length, so the header alone reports
+// it. stateCRC covers the stored, possibly compressed, state.
+//
+// Files without the magic are legacy raw Serialize output.
+var stateFileMagic = []byte("EBST")
+
+const (
+	stateFileVersion = 4
+	// stateThumbnailSize bounds the embedded thumbnail's longer side.
+	stateThumbnailSize = 160
+)
+
+var errCorruptStateFile = errors.New("corrupt state file")
+
+// stateFile is a decoded state file.
+type stateFile struct {
+	legacy    bool
+	version   int
+	region    int
+	romCRC    uint32
+	timestamp int64
+	core      string
+	// coreVersion is empty in version 1 files.
+	coreVersion string
+	thumbnail   []byte
+	// level is the zlib level state is compressed at when encoded, or
+	// 0 to store it raw. compressed reports a decoded file's encoding.
+	level      int
+	compressed bool
+	// stateSize is the uncompressed state length. A header read by
+	// readStateHeader has it without state.
+	stateSize int
+	state     []byte
+}
+
+// WriteStateWithMetadata saves a state to path with a header naming the
+// ROM, region, core and time, and a thumbnail of the current frame. The
+// container is also kept for StateLen and StateByte, as SaveState keeps
+// it. Returns true on success (see LastError on failure).
+func WriteStateWithMetadata(path string) bool {
+	return def.writeStateWithMetadata(path)
+}
+
+func (in *instance) writeStateWithMetadata(path string) bool {
+	in.mu.Lock()
+	state, err := in.serializeState()
+	if err != nil {
+		in.stateData = nil
+		in.mu.Unlock()
+		setLastError("save state failed")
+		return false
+	}
+	f := in.newStateFile(state, nil)
+	img := in.frameImage()
+	in.mu.Unlock()
+
+	f.thumbnail = encodePNG(img, stateThumbnailSize)
+	data := f.encode()
+	// Keep the container in stateData, as SaveState does.
+	in.mu.Lock()
+	in.stateData = data
+	in.mu.Unlock()
+	if err := writeFileAtomic(path, data); err != nil {
+		setLastError("failed to write %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+// LoadStateWithMetadata loads a state file written by
+// WriteStateWithMetadata, refusing files made from a different ROM.
+// Legacy files holding only a raw state are loaded as is.
+// Returns true on success (see LastError on failure).
+func LoadStateWithMetadata(path string) bool {
+	return def.loadStateWithMetadata(path)
+}
+
+func (in *instance) loadStateWithMetadata(path string) bool {
+	f, err := readStateFile(path)
+	if err != nil {
+		setLastError("%v", err)
+		return false
+	}
+	if !f.legacy {
+		in.mu.Lock()
+		err = in.checkStateFile(f)
+		in.mu.Unlock()
+		if err != nil {
+			setLastError("%v", err)
+			return false
+		}
+	}
+	if !in.loadState(f.state) {
+		setLastError("core rejected state")
+		return false
+	}
+	return true
+}
+
+// SaveStateToFile saves a state, as returned by SaveState, to path.
+// The file is replaced atomically, so an interrupted save leaves any
+// previous file intact. Returns true on success (see LastError on
+// failure).
+func SaveStateToFile(path string) bool {
+	return def.saveStateToFile(path)
+}
+
+func (in *instance) saveStateToFile(path string) bool {
+	data, ok := in.saveStateContainer()
+	if !ok {
+		setLastError("save state failed")
+		return false
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		setLastError("failed to write %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+// newStateFile wraps a raw state in a container for the loaded ROM and
+// core. in.mu must be held.
+func (in *instance) newStateFile(state, thumbnail []byte) stateFile {
+	f := stateFile{
+		version:   stateFileVersion,
+		region:    in.regionLocked(),
+		romCRC:    crc32.ChecksumIEEE(in.rom),
+		timestamp: time.Now().Unix(),
+		thumbnail: thumbnail,
+		level:     in.stateCompression,
+		state:     state,
+	}
+	if cf := in.coreFactory(); cf != nil {
+		info := cf.SystemInfo()
+		f.core, f.coreVersion = info.CoreName, info.CoreVersion
+	}
+	return f
+}
+
+// checkStateFile returns why a state container cannot be loaded into
+// this session: it was made from another ROM, or by another core or core
+// version, whose state layout may differ. in.mu must be held.
+func (in *instance) checkStateFile(f stateFile) error {
+	if crc := crc32.ChecksumIEEE(in.rom); f.romCRC != crc {
+		return fmt.Errorf("state is for ROM %08X, loaded ROM is %08X", f.romCRC, crc)
+	}
+	cf := in.coreFactory()
+	if cf == nil {
+		return nil
+	}
+	info := cf.SystemInfo()
+	if f.core != "" && f.core != info.CoreName {
+		return fmt.Errorf("state is from core %q, loaded core is %q", f.core, info.CoreName)
+	}
+	if f.version >= 2 && f.coreVersion != info.CoreVersion {
+		return fmt.Errorf("state is from %s version %q, loaded version is %q", f.core, f.coreVersion, info.CoreVersion)
+	}
+	return nil
+}
+
+// saveStateContainer serializes the state and wraps it in a container
+// without a thumbnail, for SaveState. The container is kept in stateData
+// and also returned, so the caller need not read stateData back.
+func (in *instance) saveStateContainer() ([]byte, bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	state, err := in.serializeState()
+	if err != nil {
+		in.stateData = nil
+		return nil, false
+	}
+	in.stateData = in.newStateFile(state, nil).encode()
+	return in.stateData, true
+}
+
+// loadStateContainer loads a SaveState container after checking it
+// matches the session. Legacy raw states are loaded as is.
+func (in *instance) loadStateContainer(data []byte) bool {
+	if !bytes.HasPrefix(data, stateFileMagic) {
+		return in.loadState(data)
+	}
+	f, err := decodeStateFile(data)
+	if err == nil {
+		in.mu.Lock()
+		err = in.checkStateFile(f)
+		in.mu.Unlock()
+	}
+	if err != nil {
+		setLastError("%v", err)
+		return false
+	}
+	if !in.loadState(f.state) {
+		setLastError("core rejected state")
+		return false
+	}
+	return true
+}
+
+// LoadStateFromFile loads a state file written by SaveStateToFile or
+// WriteStateWithMetadata. Returns true on success (see LastError on
+// failure).
+func LoadStateFromFile(path string) bool {
+	return def.loadStateWithMetadata(path)
+}
+
+// ReadStateMetadataJSON returns a state file's header without loading it:
+// {"legacy", "version", "romCRC", "region", "core", "coreVersion",
+// "timestamp", "thumbnail" (base64 PNG), "stateSize", "compressed"}.
+// stateSize is the uncompressed size. Legacy files report only legacy
+// and stateSize. Returns "{}" if the file cannot be read.
+func ReadStateMetadataJSON(path string) string {
+	f, err := readStateHeader(path)
+	if err != nil {
+		setLastError("%v", err)
+		return "{}"
+	}
+
+	data, err := json.Marshal(f.metadata())
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// StateInfoJSON returns the header of a state from SaveState without
+// loading it, like ReadStateMetadataJSON, plus whether LoadState would
+// accept it in this session: "compatible", and "problem" describing why
+// not, such as a different game or core version. Headerless states
+// cannot be checked and are reported compatible whenever a game with save
+// states is loaded. Returns "{}" if the header is corrupt (see
+// LastError).
+func StateInfoJSON(data []byte) string {
+	if !bytes.HasPrefix(data, stateFileMagic) {
+		return def.stateInfoJSON(stateFile{legacy: true, stateSize: len(data), state: data})
+	}
+	f, err := decodeStateFile(data)
+	if err != nil {
+		setLastError("%v", err)
+		return "{}"
+	}
+	return def.stateInfoJSON(f)
+}
+
+// StateFileInfoJSON is StateInfoJSON for a state file written by
+// SaveStateToFile or WriteStateWithMetadata. Returns "{}" if the file
+// cannot be read.
+func StateFileInfoJSON(path string) string {
+	f, err := readStateHeader(path)
+	if err != nil {
+		setLastError("%v", err)
+		return "{}"
+	}
+	return def.stateInfoJSON(f)
+}
+
+func (in *instance) stateInfoJSON(f stateFile) string {
+	in.mu.Lock()
+	var problem string
+	switch {
+	case in.saveStater == nil:
+		problem = "no game with save states is loaded"
+	case !f.legacy:
+		if err := in.checkStateFile(f); err != nil {
+			problem = err.Error()
+		}
+	}
+	in.mu.Unlock()
+
+	// Merge the header fields with the check result.
+	header, err := json.Marshal(f.metadata())
+	if err != nil {
+		return "{}"
+	}
+	info := map[string]any{}
+	if err := json.Unmarshal(header, &info); err != nil {
+		return "{}"
+	}
+	info["compatible"] = problem == ""
+	if problem != "" {
+		info["problem"] = problem
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// stateMetadata is a state file header as reported by
+// ReadStateMetadataJSON.
+type stateMetadata struct {
+	Legacy  bool   `json:"legacy"`
+	Version int    `json:"version"`
+	ROMCRC  string `json:"romCRC"`
+	Region  int    `json:"region"`
+	Core    string `json:"core"`
+	// CoreVersion is empty for version 1 files.
+	CoreVersion string `json:"coreVersion"`
+	Timestamp   int64  `json:"timestamp"`
+	Thumbnail   []byte `json:"thumbnail"`
+	// StateSize is the uncompressed size.
+	StateSize  int  `json:"stateSize"`
+	Compressed bool `json:"compressed"`
+}
+
+// metadata returns the file's stateMetadata, or only legacy and
+// stateSize for legacy files.
+func (f stateFile) metadata() any {
+	if f.legacy {
+		return struct {
+			Legacy    bool `json:"legacy"`
+			StateSize int  `json:"stateSize"`
+		}{true, f.stateSize}
+	}
+	return stateMetadata{
+		Version:     f.version,
+		ROMCRC:      fmt.Sprintf("%08X", f.romCRC),
+		Region:      f.region,
+		Core:        f.core,
+		CoreVersion: f.coreVersion,
+		Timestamp:   f.timestamp,
+		Thumbnail:   f.thumbnail,
+		StateSize:   f.stateSize,
+		Compressed:  f.compressed,
+	}
+}
+
+func readStateFile(path string) (stateFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return stateFile{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !bytes.HasPrefix(data, stateFileMagic) {
+		return stateFile{legacy: true, stateSize: len(data), state: data}, nil
+	}
+	return decodeStateFile(data)
+}
+
+// readStateHeader reads a state file's header and metadata without
+// reading the state, which is left nil. Only a compressed version 3
+// state, whose header lacks rawLen, is read and inflated to size it.
+func readStateHeader(path string) (stateFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return stateFile{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return stateFile{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	r := &stateReader{r: bufio.NewReader(file), left: info.Size(), ok: true}
+	if !bytes.Equal(r.take(len(stateFileMagic)), stateFileMagic) {
+		return stateFile{legacy: true, stateSize: int(info.Size())}, nil
+	}
+	f, stored, err := decodeStateHeader(r)
+	if err != nil {
+		return stateFile{}, err
+	}
+	if r.left != int64(stored.len) {
+		return stateFile{}, errCorruptStateFile
+	}
+	if f.stateSize < 0 {
+		return decodeStateBody(f, stored, r)
+	}
+	return f, nil
+}
+
+// encode writes f in the layout of f.version. Versions before 3 have no
+// encoding byte, so their state is stored raw.
+func (f stateFile) encode() []byte {
+	var buf bytes.Buffer
+	buf.Write(stateFileMagic)
+	buf.WriteByte(byte(f.version))
+	buf.WriteByte(byte(f.region))
+	binary.Write(&buf, binary.LittleEndian, f.romCRC)
+	binary.Write(&buf, binary.LittleEndian, f.timestamp)
+	binary.Write(&buf, binary.LittleEndian, uint16(len(f.core)))
+	buf.WriteString(f.core)
+	if f.version >= 2 {
+		binary.Write(&buf, binary.LittleEndian, uint16(len(f.coreVersion)))
+		buf.WriteString(f.coreVersion)
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(len(f.thumbnail)))
+	buf.Write(f.thumbnail)
+	stored, encoding := f.state, stateEncodingRaw
+	if f.level > 0 && f.version >= 3 {
+		stored, encoding = deflateState(nil, f.state, f.level), stateEncodingZlib
+	}
+	if f.version >= 3 {
+		buf.WriteByte(byte(encoding))
+	}
+	if f.version >= 4 {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(f.state)))
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(len(stored)))
+	binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(stored))
+	buf.Write(stored)
+	return buf.Bytes()
+}
+
+// stateReader reads fields from a state file holding left more bytes.
+// Once it runs out, ok is false and reads return zeros.
+type stateReader struct {
+	r    io.Reader
+	left int64
+	ok   bool
+}
+
+func (r *stateReader) take(n int) []byte {
+	if !r.ok || int64(n) > r.left {
+		r.ok = false
+		return make([]byte, min(n, 8))
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		r.ok = false
+		return make([]byte, min(n, 8))
+	}
+	r.left -= int64(n)
+	return b
+}
+
+func (r *stateReader) u8() int     { return int(r.take(1)[0]) }
+func (r *stateReader) u16() int    { return int(binary.LittleEndian.Uint16(r.take(2))) }
+func (r *stateReader) u32() uint32 { return binary.LittleEndian.Uint32(r.take(4)) }
+
+// storedState describes the state as stored after a state file's header.
+type storedState struct {
+	encoding int
+	len      int
+	crc      uint32
+}
+
+func decodeStateFile(data []byte) (stateFile, error) {
+	r := &stateReader{r: bytes.NewReader(data), left: int64(len(data)), ok: true}
+	r.take(len(stateFileMagic))
+	f, stored, err := decodeStateHeader(r)
+	if err != nil {
+		return stateFile{}, err
+	}
+	return decodeStateBody(f, stored, r)
+}
+
+// decodeStateHeader reads a state file from after its magic up to the
+// stored state. stateSize is -1 if the header does not record it.
+func decodeStateHeader(r *stateReader) (stateFile, storedState, error) {
+	f := stateFile{version: r.u8()}
+	if r.ok && (f.version < 1 || f.version > stateFileVersion) {
+		return stateFile{}, storedState{}, fmt.Errorf("unsupported state file version %d", f.version)
+	}
+	f.region = r.u8()
+	f.romCRC = r.u32()
+	f.timestamp = int64(binary.LittleEndian.Uint64(r.take(8)))
+	f.core = string(r.take(r.u16()))
+	if f.version >= 2 {
+		f.coreVersion = string(r.take(r.u16()))
+	}
+	if n := int(r.u32()); n > 0 {
+		f.thumbnail = r.take(n)
+	}
+	stored := storedState{encoding: stateEncodingRaw}
+	if f.version >= 3 {
+		stored.encoding = r.u8()
+	}
+	f.stateSize = -1
+	if f.version >= 4 {
+		f.stateSize = int(r.u32())
+	}
+	stored.len = int(r.u32())
+	stored.crc = r.u32()
+	if !r.ok {
+		return stateFile{}, storedState{}, errCorruptStateFile
+	}
+	switch stored.encoding {
+	case stateEncodingRaw:
+		if f.stateSize >= 0 && f.stateSize != stored.len {
+			return stateFile{}, storedState{}, errCorruptStateFile
+		}
+		f.stateSize = stored.len
+	case stateEncodingZlib:
+		f.compressed = true
+	default:
+		return stateFile{}, storedState{}, fmt.Errorf("unsupported state encoding %d", stored.encoding)
+	}
+	return f, stored, nil
+}
+
+// decodeStateBody reads and checks the stored state following the
+// header decodeStateHeader returned, inflating it if compressed.
+func decodeStateBody(f stateFile, stored storedState, r *stateReader) (stateFile, error) {
+	f.state = r.take(stored.len)
+	if !r.ok || r.left != 0 || crc32.ChecksumIEEE(f.state) != stored.crc {
+		return stateFile{}, errCorruptStateFile
+	}
+	if f.compressed {
+		state, err := inflateState(f.state)
+		if err != nil {
+			return stateFile{}, fmt.Errorf("%w: %v", errCorruptStateFile, err)
+		}
+		if f.stateSize >= 0 && len(state) != f.stateSize {
+			return stateFile{}, errCorruptStateFile
+		}
+		f.state, f.stateSize = state, len(state)
+	}
+	return f, nil
+}
@@ -0,0 +1,293 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockStateEmulator serializes a single settable byte.
+type mockStateEmulator struct {
+	*mockEmulator
+	value byte
+}
+
+func (m *mockStateEmulator) Serialize() ([]byte, error) { return []byte{m.value, 0x55}, nil }
+func (m *mockStateEmulator) Deserialize(data []byte) error {
+	m.value = data[0]
+	return nil
+}
+
+func useStateEmulator(t *testing.T, rom []byte) *mockStateEmulator {
+	t.Helper()
+	var e *mockStateEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockStateEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+		modify: func(info *emucore.SystemInfo) { info.CoreName = "mockcore" },
+	})
+	if !Init(writeROM(t, "rom.bin", rom), 1) {
+		t.Fatal("Init failed")
+	}
+	return e
+}
+
+func TestStateFileRoundTrip(t *testing.T) {
+	e := useStateEmulator(t, []byte("abc"))
+	RunFrame()
+	e.value = 42
+	path := filepath.Join(t.TempDir(), "slot1.state")
+	if !WriteStateWithMetadata(path) {
+		t.Fatal(LastError())
+	}
+	// The container is kept in stateData, as SaveState keeps it.
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, def.stateData) {
+		t.Errorf("stateData = % x, want the file % x (%v)", def.stateData, data, err)
+	}
+
+	var meta struct {
+		Legacy    bool   `json:"legacy"`
+		ROMCRC    string `json:"romCRC"`
+		Region    int    `json:"region"`
+		Core      string `json:"core"`
+		Timestamp int64  `json:"timestamp"`
+		Thumbnail string `json:"thumbnail"`
+		StateSize int    `json:"stateSize"`
+	}
+	if err := json.Unmarshal([]byte(ReadStateMetadataJSON(path)), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Legacy || meta.ROMCRC != "352441C2" || meta.Region != 1 || meta.Core != "mockcore" || meta.StateSize != 2+frameTrailerSize {
+		t.Errorf("metadata = %+v", meta)
+	}
+	if d := time.Now().Unix() - meta.Timestamp; d < 0 || d > 60 {
+		t.Errorf("timestamp %d is not recent", meta.Timestamp)
+	}
+	thumb, _ := base64.StdEncoding.DecodeString(meta.Thumbnail)
+	if _, err := png.Decode(bytes.NewReader(thumb)); err != nil {
+		t.Errorf("thumbnail: %v", err)
+	}
+	if e.value != 42 {
+		t.Error("reading metadata touched the emulator")
+	}
+
+	e.value = 0
+	if !LoadStateWithMetadata(path) || e.value != 42 {
+		t.Errorf("load failed: %s, value = %d", LastError(), e.value)
+	}
+}
+
+func TestStateFileLegacy(t *testing.T) {
+	e := useStateEmulator(t, []byte("abc"))
+	path := filepath.Join(t.TempDir(), "old.state")
+	os.WriteFile(path, []byte{7, 0x55}, 0644)
+
+	if !LoadStateWithMetadata(path) || e.value != 7 {
+		t.Errorf("legacy load failed: %s", LastError())
+	}
+	if got := ReadStateMetadataJSON(path); got != `{"legacy":true,"stateSize":2}` {
+		t.Errorf("legacy metadata = %s", got)
+	}
+}
+
+func TestStateFileCorruption(t *testing.T) {
+	e := useStateEmulator(t, []byte("abc"))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "good.state")
+	if !WriteStateWithMetadata(path) {
+		t.Fatal(LastError())
+	}
+	good, _ := os.ReadFile(path)
+
+	write := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		os.WriteFile(p, data, 0644)
+		return p
+	}
+
+	truncated := write("truncated.state", good[:len(good)-1])
+	if LoadStateWithMetadata(truncated) || ReadStateMetadataJSON(truncated) != "{}" {
+		t.Error("truncated file accepted")
+	}
+
+	flipped := append([]byte(nil), good...)
+	flipped[len(flipped)-1] ^= 0xFF
+	if LoadStateWithMetadata(write("flipped.state", flipped)) {
+		t.Error("state with bad checksum accepted")
+	}
+
+	// Wrong magic reads as a legacy blob, which the core then gets as is.
+	wrongMagic := append([]byte("XBST"), good[4:]...)
+	e.value = 9
+	LoadStateWithMetadata(write("magic.state", wrongMagic))
+	if e.value != 'X' {
+		t.Errorf("wrong magic not treated as legacy, value = %d", e.value)
+	}
+
+	Close()
+	useStateEmulator(t, []byte("other"))
+	if LoadStateWithMetadata(path) {
+		t.Error("state for another ROM accepted")
+	}
+}
+
+func TestReadStateHeader(t *testing.T) {
+	dir := t.TempDir()
+	state := bytes.Repeat([]byte{0xAB}, 1000)
+	for _, version := range []int{3, stateFileVersion} {
+		for _, level := range []int{0, 6} {
+			f := stateFile{version: version, core: "mockcore", thumbnail: []byte{1}, level: level, state: state}
+			path := filepath.Join(dir, fmt.Sprintf("v%d-%d.state", version, level))
+			if err := os.WriteFile(path, f.encode(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readStateHeader(path)
+			if err != nil || got.core != "mockcore" || got.stateSize != len(state) || got.compressed != (level > 0) {
+				t.Errorf("v%d level %d: %+v, %v", version, level, got, err)
+			}
+			// Only a compressed version 3 state is read, to size it.
+			if read := got.state != nil; read != (version == 3 && level > 0) {
+				t.Errorf("v%d level %d: state read = %v", version, level, read)
+			}
+		}
+	}
+
+	short := filepath.Join(dir, "short.state")
+	os.WriteFile(short, []byte{1, 2}, 0644)
+	if got, err := readStateHeader(short); err != nil || !got.legacy || got.stateSize != 2 {
+		t.Errorf("short legacy file = %+v, %v", got, err)
+	}
+}
+
+func TestSaveStateToFile(t *testing.T) {
+	e := useStateEmulator(t, []byte{0x01})
+	path := filepath.Join(t.TempDir(), "game.state")
+
+	e.value = 0x42
+	if !SaveStateToFile(path) {
+		t.Fatalf("SaveStateToFile failed: %s", LastError())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(data, def.stateData) {
+		t.Fatalf("file = % x, %v; want state % x", data, err, def.stateData)
+	}
+
+	e.value = 0
+	if !LoadStateFromFile(path) || e.value != 0x42 {
+		t.Errorf("LoadStateFromFile value = %#x, want 0x42", e.value)
+	}
+	if LoadStateFromFile(filepath.Join(t.TempDir(), "missing.state")) {
+		t.Error("LoadStateFromFile succeeded for a missing file")
+	}
+	if SaveStateToFile(filepath.Join(t.TempDir(), "missing", "game.state")) {
+		t.Error("SaveStateToFile succeeded in a missing directory")
+	}
+}
+
+func TestLoadStateChecksHeader(t *testing.T) {
+	version := "1.0"
+	var e *mockStateEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockStateEmulator{mockEmulator: newMockEmulator(rom, region)}
+			return e, nil
+		},
+		modify: func(info *emucore.SystemInfo) {
+			info.CoreName = "mockcore"
+			info.CoreVersion = version
+		},
+	})
+	romPath := writeROM(t, "rom.bin", []byte{0x01})
+	if !Init(romPath, 0) {
+		t.Fatal("Init failed")
+	}
+	e.value = 7
+	if !SaveState() || !bytes.HasPrefix(GetStateData(), stateFileMagic) {
+		t.Fatal("SaveState did not write a container")
+	}
+	state := GetStateData()
+
+	e.value = 0
+	if !LoadState(state) || e.value != 7 {
+		t.Fatalf("LoadState value = %d, want 7 (%s)", e.value, LastError())
+	}
+	if !LoadState([]byte{9, 0x55}) || e.value != 9 {
+		t.Error("legacy raw state rejected")
+	}
+
+	version = "2.0"
+	if LoadState(state) || e.value != 9 {
+		t.Error("loaded a state from another core version")
+	}
+	version = "1.0"
+
+	if !Init(writeROM(t, "other.bin", []byte{0x02}), 0) {
+		t.Fatal("Init failed")
+	}
+	if LoadState(state) {
+		t.Error("loaded a state from another ROM")
+	}
+	corrupt := append([]byte(nil), state...)
+	corrupt[len(corrupt)-1] ^= 0xFF
+	if !Init(romPath, 0) || LoadState(corrupt) {
+		t.Error("loaded a corrupt state")
+	}
+}
+
+func TestStateInfoJSON(t *testing.T) {
+	useStateEmulator(t, []byte{0x01})
+	RunFrame()
+	SaveState()
+	state := GetStateData()
+	path := filepath.Join(t.TempDir(), "game.state")
+	if !WriteStateWithMetadata(path) {
+		t.Fatal(LastError())
+	}
+
+	type info struct {
+		Legacy     bool
+		ROMCRC     string `json:"romCRC"`
+		Core       string
+		Compatible bool
+		Problem    string
+		Thumbnail  []byte
+	}
+	parse := func(s string) info {
+		t.Helper()
+		var got info
+		if err := json.Unmarshal([]byte(s), &got); err != nil {
+			t.Fatalf("%v: %s", err, s)
+		}
+		return got
+	}
+
+	if got := parse(StateInfoJSON(state)); !got.Compatible || got.Core != "mockcore" || got.ROMCRC == "" {
+		t.Errorf("StateInfoJSON = %+v", got)
+	}
+	if got := parse(StateFileInfoJSON(path)); !got.Compatible || len(got.Thumbnail) == 0 {
+		t.Errorf("StateFileInfoJSON = %+v", got)
+	}
+	if got := parse(StateInfoJSON([]byte{1, 2})); !got.Legacy || !got.Compatible {
+		t.Errorf("legacy info = %+v", got)
+	}
+	if StateInfoJSON(append([]byte(nil), state[:8]...)) != "{}" {
+		t.Error("truncated header parsed")
+	}
+
+	if !Init(writeROM(t, "other.bin", []byte{0x02}), 0) {
+		t.Fatal("Init failed")
+	}
+	if got := parse(StateInfoJSON(state)); got.Compatible || got.Problem == "" {
+		t.Errorf("info for another ROM = %+v", got)
+	}
+}
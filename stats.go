@@ -0,0 +1,61 @@
+package ios
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// sessionStats accumulates per-session frame counters.
+type sessionStats struct {
+	frames      int64
+	frameTime   time.Duration
+	idleSkipped int64
+}
+
+// record counts a frame the core actually emulated and how long it took.
+func (s *sessionStats) record(d time.Duration) {
+	s.frames++
+	s.frameTime += d
+}
+
+// recordIdleSkip counts a frame skipped because the core was idle.
+func (s *sessionStats) recordIdleSkip() {
+	s.idleSkipped++
+}
+
+// averageFrameTime returns the mean time spent in the core per frame.
+func (s *sessionStats) averageFrameTime() time.Duration {
+	if s.frames == 0 {
+		return 0
+	}
+	return s.frameTime / time.Duration(s.frames)
+}
+
+// SessionStatsJSON returns counters for the current session:
+// {"frames", "avgFrameMs", "idleSkippedFrames", "idleTimeSavedMs"}.
+// idleTimeSavedMs estimates core time saved from the average frame time.
+func SessionStatsJSON() string {
+	def.mu.Lock()
+	s := def.stats
+	def.mu.Unlock()
+	avg := s.averageFrameTime()
+	data, err := json.Marshal(struct {
+		Frames            int64   `json:"frames"`
+		AvgFrameMs        float64 `json:"avgFrameMs"`
+		IdleSkippedFrames int64   `json:"idleSkippedFrames"`
+		IdleTimeSavedMs   float64 `json:"idleTimeSavedMs"`
+	}{
+		Frames:            s.frames,
+		AvgFrameMs:        durationMs(avg),
+		IdleSkippedFrames: s.idleSkipped,
+		IdleTimeSavedMs:   durationMs(avg * time.Duration(s.idleSkipped)),
+	})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,256 @@
+package ios
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	emucore "github.com/user-none/eblitui/api"
+	"github.com/user-none/eblitui/romloader"
+)
+
+// registeredSystem is a core registered with RegisterFactoryFor.
+type registeredSystem struct {
+	name    string
+	factory emucore.CoreFactory
+}
+
+// systems holds the registered cores in registration order. factory is
+// the active one: Init switches it to the core that accepts the ROM, and
+// package-level functions that are not about a loaded game use it. Each
+// instance keeps the factory it was created with. systemsMu guards both.
+var (
+	systemsMu sync.RWMutex
+	systems   []registeredSystem
+)
+
+// activeFactory returns the active core's factory, or nil.
+func activeFactory() emucore.CoreFactory {
+	systemsMu.RLock()
+	defer systemsMu.RUnlock()
+	return factory
+}
+
+// setActiveFactory makes f the active core.
+func setActiveFactory(f emucore.CoreFactory) {
+	systemsMu.Lock()
+	defer systemsMu.Unlock()
+	factory = f
+}
+
+// coreFactory returns the factory in's emulator was created with, or the
+// active one before a game is loaded. in.mu must be held.
+func (in *instance) coreFactory() emucore.CoreFactory {
+	if in.factory != nil {
+		return in.factory
+	}
+	return activeFactory()
+}
+
+// RegisterFactoryFor registers the CoreFactory for a system, replacing
+// any factory registered under the same name, so that one app can bundle
+// several cores. The first factory registered becomes active. Called by
+// each core's init().
+func RegisterFactoryFor(system string, f emucore.CoreFactory) {
+	systemsMu.Lock()
+	defer systemsMu.Unlock()
+	for i := range systems {
+		if systems[i].name == system {
+			if factory == systems[i].factory {
+				factory = f
+			}
+			systems[i].factory = f
+			return
+		}
+	}
+	systems = append(systems, registeredSystem{name: system, factory: f})
+	if factory == nil {
+		factory = f
+	}
+}
+
+// systemFactory returns the factory registered for system, matched by
+// its registered name or its SystemInfo name, or nil.
+func systemFactory(system string) emucore.CoreFactory {
+	systemsMu.RLock()
+	defer systemsMu.RUnlock()
+	for _, s := range systems {
+		if s.name == system || s.factory.SystemInfo().Name == system {
+			return s.factory
+		}
+	}
+	if factory != nil && factory.SystemInfo().Name == system {
+		return factory
+	}
+	return nil
+}
+
+// candidateFactories returns the active factory followed by the other
+// registered ones.
+func candidateFactories() []emucore.CoreFactory {
+	systemsMu.RLock()
+	defer systemsMu.RUnlock()
+	var fs []emucore.CoreFactory
+	if factory != nil {
+		fs = append(fs, factory)
+	}
+	for _, s := range systems {
+		if s.factory != factory {
+			fs = append(fs, s.factory)
+		}
+	}
+	return fs
+}
+
+// loadSystemROM loads the ROM at path with the first core whose
+// extensions it matches, trying the active core first, and returns that
+// core's factory. The active core's error is returned if none match.
+func loadSystemROM(path string) (emucore.CoreFactory, []byte, error) {
+	f, rom, _, err := loadWithSystems(func(exts []string) ([]byte, string, error) {
+		return romloader.Load(path, exts)
+	})
+	return f, rom, err
+}
+
+// loadSystemROMData is loadSystemROM for ROM data in memory named
+// filename, as loadROMData reads it.
+func loadSystemROMData(data []byte, filename string) (emucore.CoreFactory, []byte, string, error) {
+	return loadWithSystems(func(exts []string) ([]byte, string, error) {
+		return loadROMData(data, filename, exts)
+	})
+}
+
+// loadWithSystems calls load with each candidate core's extensions until
+// one succeeds, and returns that core's factory with load's results.
+func loadWithSystems(load func(exts []string) ([]byte, string, error)) (emucore.CoreFactory, []byte, string, error) {
+	return loadWithFactories(candidateFactories(), load)
+}
+
+// loadWithFactories is loadWithSystems over a fixed list of candidates,
+// for callers that captured the registry earlier.
+func loadWithFactories(candidates []emucore.CoreFactory, load func(exts []string) ([]byte, string, error)) (emucore.CoreFactory, []byte, string, error) {
+	firstErr := error(errNoFactory)
+	for i, f := range candidates {
+		rom, name, err := load(f.SystemInfo().Extensions)
+		if err == nil {
+			return f, rom, name, nil
+		}
+		if i == 0 {
+			firstErr = err
+		}
+		if !errors.Is(err, romloader.ErrUnsupportedFormat) && !errors.Is(err, romloader.ErrNoROMFile) {
+			// Only a mismatched extension is worth another core.
+			break
+		}
+	}
+	return nil, nil, "", firstErr
+}
+
+// SelectSystem makes the core registered for system active.
+// Returns false if no such core is registered.
+func SelectSystem(system string) bool {
+	f := systemFactory(system)
+	if f == nil {
+		setLastError("unknown system %q", system)
+		return false
+	}
+	setActiveFactory(f)
+	return true
+}
+
+// ActiveSystem returns the name of the active core's system, or an empty
+// string if none is registered.
+func ActiveSystem() string {
+	f := activeFactory()
+	if f == nil {
+		return ""
+	}
+	return f.SystemInfo().Name
+}
+
+// registeredSystems returns a copy of the registry and the active
+// factory. With no core registered through RegisterFactoryFor, a factory
+// set directly is listed under its system name.
+func registeredSystems() ([]registeredSystem, emucore.CoreFactory) {
+	systemsMu.RLock()
+	defer systemsMu.RUnlock()
+	list := slices.Clone(systems)
+	if len(list) == 0 && factory != nil {
+		list = []registeredSystem{{name: factory.SystemInfo().Name, factory: factory}}
+	}
+	return list, factory
+}
+
+// SupportedSystemsJSON lists the registered cores as [{"system", "name",
+// "consoleName", "extensions", "active"}], where system is the name given
+// to RegisterFactoryFor. Returns "[]" if none is registered.
+func SupportedSystemsJSON() string {
+	type systemEntry struct {
+		System      string   `json:"system"`
+		Name        string   `json:"name"`
+		ConsoleName string   `json:"consoleName"`
+		Extensions  []string `json:"extensions"`
+		Active      bool     `json:"active"`
+	}
+	entries := []systemEntry{}
+	list, active := registeredSystems()
+	for _, s := range list {
+		info := s.factory.SystemInfo()
+		entries = append(entries, systemEntry{
+			System:      s.name,
+			Name:        info.Name,
+			ConsoleName: info.ConsoleName,
+			Extensions:  info.Extensions,
+			Active:      s.factory == active,
+		})
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// SupportedExtensionsJSON returns the file extensions the bridge opens,
+// for the document picker and Info.plist generation: {"rom", "archive",
+// "all"}. rom merges every registered core's extensions, archive lists
+// the containers the import functions unpack, and all is both. Entries
+// are lowercase with a leading dot, without duplicates, in registration
+// order.
+func SupportedExtensionsJSON() string {
+	var rom []string
+	for _, f := range candidateFactories() {
+		rom = appendExtensions(rom, f.SystemInfo().Extensions)
+	}
+	result := struct {
+		ROM     []string `json:"rom"`
+		Archive []string `json:"archive"`
+		All     []string `json:"all"`
+	}{
+		ROM:     appendExtensions([]string{}, rom),
+		Archive: archiveExtensions,
+		All:     appendExtensions(appendExtensions([]string{}, rom), archiveExtensions),
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// appendExtensions appends the normalized exts not already in list.
+func appendExtensions(list, exts []string) []string {
+	for _, ext := range exts {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if !slices.Contains(list, ext) {
+			list = append(list, ext)
+		}
+	}
+	return list
+}
@@ -0,0 +1,159 @@
+package ios
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// useSystems registers mock cores named after their only extension and
+// restores the registry afterwards.
+func useSystems(t *testing.T, exts ...string) map[string]*mockFactory {
+	t.Helper()
+	useMockFactory(t, &mockFactory{})
+	oldSystems := systems
+	systems, factory = nil, nil
+	t.Cleanup(func() { systems = oldSystems })
+
+	fs := map[string]*mockFactory{}
+	for _, ext := range exts {
+		name := ext[1:]
+		f := &mockFactory{
+			modify: func(info *emucore.SystemInfo) {
+				info.Name = name
+				info.Extensions = []string{ext}
+			},
+			create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+				return newMockEmulator(rom, region), nil
+			},
+		}
+		RegisterFactoryFor(name, f)
+		fs[name] = f
+	}
+	return fs
+}
+
+func TestInitDispatchesBySystem(t *testing.T) {
+	fs := useSystems(t, ".aaa", ".bbb")
+	if factory != fs["aaa"] || ActiveSystem() != "aaa" {
+		t.Fatalf("active system = %q, want the first registered", ActiveSystem())
+	}
+
+	if !Init(writeROM(t, "game.bbb", []byte{1}), 0) {
+		t.Fatalf("Init failed: %s", LastError())
+	}
+	if ActiveSystem() != "bbb" {
+		t.Errorf("active system after Init = %q, want bbb", ActiveSystem())
+	}
+	if !Init(writeROM(t, "game.aaa", []byte{1}), 0) || ActiveSystem() != "aaa" {
+		t.Errorf("Init did not switch back to aaa: %s", LastError())
+	}
+	if Init(writeROM(t, "game.ccc", []byte{1}), 0) {
+		t.Error("Init accepted an extension no core supports")
+	}
+
+	if !SelectSystem("bbb") || ActiveSystem() != "bbb" || SelectSystem("ccc") {
+		t.Error("SelectSystem did not switch cores")
+	}
+}
+
+func TestSupportedSystemsJSON(t *testing.T) {
+	fs := useSystems(t, ".aaa", ".bbb")
+	replacement := &mockFactory{modify: fs["bbb"].modify}
+	RegisterFactoryFor("bbb", replacement)
+
+	var got []struct {
+		System     string   `json:"system"`
+		Extensions []string `json:"extensions"`
+		Active     bool     `json:"active"`
+	}
+	if err := json.Unmarshal([]byte(SupportedSystemsJSON()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].System != "aaa" || !got[0].Active || got[1].System != "bbb" || got[1].Active {
+		t.Fatalf("SupportedSystemsJSON = %+v", got)
+	}
+	if systems[1].factory != replacement {
+		t.Error("RegisterFactoryFor did not replace the bbb core")
+	}
+
+	systems, factory = nil, nil
+	if SupportedSystemsJSON() != "[]" {
+		t.Errorf("SupportedSystemsJSON = %s with no cores", SupportedSystemsJSON())
+	}
+}
+
+func TestSupportedExtensionsJSON(t *testing.T) {
+	useSystems(t, ".aaa", ".BBB", ".aaa")
+
+	var got struct {
+		ROM     []string `json:"rom"`
+		Archive []string `json:"archive"`
+		All     []string `json:"all"`
+	}
+	if err := json.Unmarshal([]byte(SupportedExtensionsJSON()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got.ROM, []string{".aaa", ".bbb"}) {
+		t.Errorf("rom = %v", got.ROM)
+	}
+	if !slices.Equal(got.Archive, archiveExtensions) {
+		t.Errorf("archive = %v", got.Archive)
+	}
+	if want := append([]string{".aaa", ".bbb"}, archiveExtensions...); !slices.Equal(got.All, want) {
+		t.Errorf("all = %v, want %v", got.All, want)
+	}
+}
+
+func TestInstanceKeepsItsCore(t *testing.T) {
+	fs := useSystems(t, ".aaa", ".bbb")
+	created := map[string]int{}
+	for name, f := range fs {
+		f.create = func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			created[name]++
+			return newMockEmulator(rom, region), nil
+		}
+	}
+
+	if !Init(writeROM(t, "game.aaa", []byte{1}), 0) {
+		t.Fatalf("Init failed: %s", LastError())
+	}
+	h := CreateInstance(writeROM(t, "other.bbb", []byte{2}), 0)
+	if h == 0 {
+		t.Fatalf("CreateInstance failed: %s", LastError())
+	}
+	defer CloseInstance(h)
+	if ActiveSystem() != "aaa" {
+		t.Errorf("CreateInstance made %q active", ActiveSystem())
+	}
+
+	if !Reset(true) || created["aaa"] != 2 || created["bbb"] != 1 {
+		t.Errorf("hard reset created %v, want the aaa core again", created)
+	}
+	if !ResetFor(h, true) || created["bbb"] != 2 {
+		t.Errorf("instance reset created %v, want the bbb core again", created)
+	}
+}
+
+func TestInitFromDataDispatchesBySystem(t *testing.T) {
+	useSystems(t, ".aaa", ".bbb")
+	if !InitFromData([]byte{1}, "game.bbb", 0) || ActiveSystem() != "bbb" {
+		t.Errorf("InitFromData did not pick the bbb core: %s", LastError())
+	}
+	h := CreateInstanceFromData([]byte{1}, "game.aaa", 0)
+	if h == 0 {
+		t.Fatalf("CreateInstanceFromData failed: %s", LastError())
+	}
+	CloseInstance(h)
+
+	var got storeResult
+	result, err := ExtractAndStoreROMFromData([]byte{1}, "game.aaa", t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("ExtractAndStoreROMFromData: %v", err)
+	}
+	if err := json.Unmarshal([]byte(result), &got); err != nil || got.Filename != "game.aaa" {
+		t.Errorf("stored %s, want game.aaa", result)
+	}
+}
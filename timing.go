@@ -0,0 +1,80 @@
+package ios
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// PreciseTiming is an optional emulator interface for cores whose frame
+// rate is not a whole number, such as 60.0988 fps NTSC.
+type PreciseTiming interface {
+	// FrameRate returns the exact frames per second for the current
+	// region and options.
+	FrameRate() float64
+}
+
+// GetFPSFloat returns the exact frames per second for the current
+// emulator state.
+func GetFPSFloat() float64 {
+	return def.fpsFloat()
+}
+
+func (in *instance) fpsFloat() float64 {
+	if in.emu == nil {
+		return 60
+	}
+	if p, ok := in.emu.(PreciseTiming); ok {
+		if fps := p.FrameRate(); fps > 0 {
+			return fps
+		}
+	}
+	return float64(in.emu.GetTiming().FPS)
+}
+
+// outputSampleRate returns the rate GetAudioData is delivered at.
+func (in *instance) outputSampleRate() int {
+	if in.audioOut.rate > 0 {
+		return in.audioOut.rate
+	}
+	rate, _ := in.sourceAudioFormat()
+	return rate
+}
+
+// GetTimingJSON returns the current frame timing, re-read from the core
+// on each call:
+// {"fps", "frameDurationNs", "scanlines", "sampleRate", "samplesPerFrame"}.
+// sampleRate is the rate GetAudioData is delivered at and samplesPerFrame
+// the fractional number of sample frames per video frame at that rate.
+func GetTimingJSON() string {
+	return def.timingJSON()
+}
+
+func (in *instance) timingJSON() string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	fps := in.fpsFloat()
+	timing := struct {
+		FPS             float64 `json:"fps"`
+		FrameDurationNs int64   `json:"frameDurationNs"`
+		Scanlines       int     `json:"scanlines"`
+		SampleRate      int     `json:"sampleRate"`
+		SamplesPerFrame float64 `json:"samplesPerFrame"`
+	}{
+		FPS:        fps,
+		SampleRate: in.outputSampleRate(),
+	}
+	if fps > 0 {
+		timing.FrameDurationNs = int64(math.Round(float64(time.Second) / fps))
+		timing.SamplesPerFrame = float64(timing.SampleRate) / fps
+	}
+	if in.emu != nil {
+		timing.Scanlines = in.emu.GetTiming().Scanlines
+	}
+
+	data, err := json.Marshal(timing)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
@@ -0,0 +1,78 @@
+package ios
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+// mockPreciseEmulator runs at the real NTSC and PAL rates.
+type mockPreciseEmulator struct {
+	*mockEmulator
+}
+
+func (m *mockPreciseEmulator) FrameRate() float64 {
+	if m.region == emucore.RegionPAL {
+		return 50.007
+	}
+	return 60.0988
+}
+
+func TestTimingJSON(t *testing.T) {
+	var e *mockPreciseEmulator
+	useMockFactory(t, &mockFactory{
+		create: func(rom []byte, region emucore.Region) (emucore.Emulator, error) {
+			e = &mockPreciseEmulator{newMockEmulator(rom, region)}
+			return e, nil
+		},
+		modify: func(info *emucore.SystemInfo) { info.SampleRate = 48000 },
+	})
+	if !Init(writeROM(t, "rom.bin", []byte{0}), 0) {
+		t.Fatal("Init failed")
+	}
+
+	tests := []struct {
+		region    emucore.Region
+		fps       float64
+		rounded   int
+		scanlines int
+	}{
+		{emucore.RegionNTSC, 60.0988, 60, 262},
+		{emucore.RegionPAL, 50.007, 50, 313},
+	}
+	for _, tt := range tests {
+		e.SetRegion(tt.region)
+
+		var fields map[string]float64
+		if err := json.Unmarshal([]byte(GetTimingJSON()), &fields); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"fps", "frameDurationNs", "scanlines", "sampleRate", "samplesPerFrame"} {
+			if _, ok := fields[key]; !ok {
+				t.Errorf("region %d: missing %s", tt.region, key)
+			}
+		}
+		if fields["fps"] != tt.fps || GetFPSFloat() != tt.fps || GetFPS() != tt.rounded {
+			t.Errorf("region %d: fps = %v / %d", tt.region, fields["fps"], GetFPS())
+		}
+		if want := math.Round(1e9 / tt.fps); fields["frameDurationNs"] != want {
+			t.Errorf("region %d: frameDurationNs = %v, want %v", tt.region, fields["frameDurationNs"], want)
+		}
+		if want := 48000 / tt.fps; math.Abs(fields["samplesPerFrame"]-want) > 1e-9 {
+			t.Errorf("region %d: samplesPerFrame = %v, want %v", tt.region, fields["samplesPerFrame"], want)
+		}
+		if int(fields["scanlines"]) != tt.scanlines {
+			t.Errorf("region %d: scanlines = %v", tt.region, fields["scanlines"])
+		}
+	}
+}
+
+func TestTimingFallsBackToIntegerFPS(t *testing.T) {
+	e := useMockEmulator(t)
+	e.SetRegion(emucore.RegionPAL)
+	if GetFPSFloat() != 50 || GetFPS() != 50 {
+		t.Errorf("fps = %v", GetFPSFloat())
+	}
+}
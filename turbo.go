@@ -0,0 +1,114 @@
+package ios
+
+// Default turbo rate: two frames pressed, two released, 15 presses a
+// second at 60 fps.
+const (
+	defaultTurboOn  = 2
+	defaultTurboOff = 2
+)
+
+// turbo holds the autofire configuration and phase.
+type turbo struct {
+	// masks holds the turbo buttons per player.
+	masks map[int]uint32
+	// on and off are the frames turbo buttons spend pressed and
+	// released; zero means the default.
+	on, off int
+	// frame counts the frames turbo has been applied on.
+	frame int64
+}
+
+// SetTurboButton enables or disables autofire for the buttons in
+// buttonMask, in core bits after any SetButtonMapping, for a player.
+// While held, turbo buttons are pressed and released automatically at
+// the SetTurboRate rate, each emulated frame, before the input reaches
+// the core. Turbo buttons are cleared by Close.
+func SetTurboButton(player int, buttonMask int, enabled bool) {
+	def.setTurboButton(player, buttonMask, enabled)
+}
+
+func (in *instance) setTurboButton(player int, buttonMask int, enabled bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	t := &in.turbo
+	if t.masks == nil {
+		t.masks = map[int]uint32{}
+	}
+	if enabled {
+		t.masks[player] |= uint32(buttonMask)
+	} else {
+		t.masks[player] &^= uint32(buttonMask)
+		in.restoreHeldInput(player)
+	}
+	if t.masks[player] == 0 {
+		delete(t.masks, player)
+	}
+}
+
+// restoreHeldInput queues a player's held buttons for the next frame, in
+// case turbo last sent them released, unless newer input is pending.
+func (in *instance) restoreHeldInput(player int) {
+	held, ok := in.heldInputs[player]
+	if !ok {
+		return
+	}
+	in.inputMu.Lock()
+	defer in.inputMu.Unlock()
+	if _, pending := in.pendingInputs[player]; pending {
+		return
+	}
+	if in.pendingInputs == nil {
+		in.pendingInputs = map[int]uint32{}
+	}
+	in.pendingInputs[player] = held
+}
+
+// GetTurboButtons returns the turbo button mask for a player.
+func GetTurboButtons(player int) int {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return int(def.turbo.masks[player])
+}
+
+// SetTurboRate sets how many frames turbo buttons stay pressed and then
+// released. Defaults to 2 and 2. The rate stays in effect across Init.
+// Returns false, leaving the rate unchanged, if either is less than 1.
+func SetTurboRate(framesOn, framesOff int) bool {
+	return def.setTurboRate(framesOn, framesOff)
+}
+
+func (in *instance) setTurboRate(framesOn, framesOff int) bool {
+	if framesOn < 1 || framesOff < 1 {
+		setLastError("turbo rate %d/%d: frames must be at least 1", framesOn, framesOff)
+		return false
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.turbo.on, in.turbo.off = framesOn, framesOff
+	return true
+}
+
+// hasTurbo returns whether turbo buttons should be applied this frame.
+func (in *instance) hasTurbo() bool {
+	return len(in.turbo.masks) > 0 && (in.movie == nil || !in.movie.playing)
+}
+
+// applyTurbo presents each turbo player's held buttons with the turbo
+// buttons released during the off part of the cycle.
+func (in *instance) applyTurbo() {
+	t := &in.turbo
+	on, off := t.on, t.off
+	if on == 0 {
+		on, off = defaultTurboOn, defaultTurboOff
+	}
+	pressed := t.frame%int64(on+off) < int64(on)
+	t.frame++
+
+	for player, mask := range t.masks {
+		buttons := in.heldInputs[player]
+		if !pressed {
+			buttons &^= mask
+		}
+		in.presentInput(player, buttons)
+	}
+}
@@ -0,0 +1,52 @@
+package ios
+
+import "testing"
+
+func TestTurboButtonToggles(t *testing.T) {
+	e := useMockEmulator(t)
+	SetTurboButton(0, 0x1, true)
+	if GetTurboButtons(0) != 0x1 {
+		t.Fatalf("GetTurboButtons = %#x, want 0x1", GetTurboButtons(0))
+	}
+	if SetTurboRate(0, 1) {
+		t.Error("SetTurboRate accepted 0 frames")
+	}
+	SetTurboRate(1, 2)
+	SetInput(0, 0x3)
+
+	var got []uint32
+	for i := 0; i < 6; i++ {
+		RunFrame()
+		got = append(got, e.inputs[0])
+	}
+	want := []uint32{0x3, 0x2, 0x2, 0x3, 0x2, 0x2}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("inputs = %#x, want %#x", got, want)
+		}
+	}
+
+	// Each frame of RunFrames advances the cycle.
+	RunFrames(2, true)
+	if e.inputs[0] != 0x2 {
+		t.Errorf("input after RunFrames = %#x, want 0x2", e.inputs[0])
+	}
+
+	SetTurboButton(0, 0x1, false)
+	RunFrame()
+	RunFrame()
+	if e.inputs[0] != 0x3 || GetTurboButtons(0) != 0 {
+		t.Errorf("input with turbo off = %#x, want 0x3", e.inputs[0])
+	}
+}
+
+func TestTurboReleasedButtonStaysReleased(t *testing.T) {
+	e := useMockEmulator(t)
+	SetTurboButton(1, 0x4, true)
+	for i := 0; i < 4; i++ {
+		RunFrame()
+		if e.inputs[1] != 0 {
+			t.Fatalf("frame %d: unheld turbo button pressed", i)
+		}
+	}
+}
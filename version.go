@@ -0,0 +1,72 @@
+package ios
+
+import (
+	"encoding/json"
+	"runtime"
+	"runtime/debug"
+)
+
+// bridgeModule is this package's module path, looked up in the build
+// info for VersionJSON.
+const bridgeModule = "github.com/user-none/eblitui-ios"
+
+// bridgeVersion returns the bridge module's version from the build info,
+// or "devel" for a local build.
+func bridgeVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	mods := append([]*debug.Module{&bi.Main}, bi.Deps...)
+	for _, m := range mods {
+		if m.Path != bridgeModule {
+			continue
+		}
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		if m.Version != "" && m.Version != "(devel)" {
+			return m.Version
+		}
+		break
+	}
+	return "devel"
+}
+
+// VersionJSON reports what the bridge was built from, for support
+// requests, save compatibility checks and credits: {"bridge", "go",
+// "stateFormat", "movieFormat", "cores"}. cores lists every registered
+// core as [{"system", "name", "version", "active"}]. A state is only
+// loadable by the same core name and version.
+func VersionJSON() string {
+	type coreVersion struct {
+		System  string `json:"system"`
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Active  bool   `json:"active"`
+	}
+	cores := []coreVersion{}
+	list, active := registeredSystems()
+	for _, s := range list {
+		info := s.factory.SystemInfo()
+		cores = append(cores, coreVersion{System: s.name, Name: info.CoreName, Version: info.CoreVersion, Active: s.factory == active})
+	}
+
+	data, err := json.Marshal(struct {
+		Bridge      string        `json:"bridge"`
+		Go          string        `json:"go"`
+		StateFormat int           `json:"stateFormat"`
+		MovieFormat int           `json:"movieFormat"`
+		Cores       []coreVersion `json:"cores"`
+	}{
+		Bridge:      bridgeVersion(),
+		Go:          runtime.Version(),
+		StateFormat: stateFileVersion,
+		MovieFormat: movieVersion,
+		Cores:       cores,
+	})
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
@@ -0,0 +1,41 @@
+package ios
+
+import (
+	"encoding/json"
+	"testing"
+
+	emucore "github.com/user-none/eblitui/api"
+)
+
+func TestVersionJSON(t *testing.T) {
+	fs := useSystems(t, ".aaa", ".bbb")
+	inner := fs["bbb"].modify
+	fs["bbb"].modify = func(info *emucore.SystemInfo) {
+		inner(info)
+		info.CoreName, info.CoreVersion = "bcore", "1.2.3"
+	}
+	SelectSystem("bbb")
+
+	var got struct {
+		Bridge      string `json:"bridge"`
+		Go          string `json:"go"`
+		StateFormat int    `json:"stateFormat"`
+		MovieFormat int    `json:"movieFormat"`
+		Cores       []struct {
+			System  string `json:"system"`
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			Active  bool   `json:"active"`
+		} `json:"cores"`
+	}
+	if err := json.Unmarshal([]byte(VersionJSON()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Bridge == "" || got.Go == "" || got.StateFormat != stateFileVersion || got.MovieFormat != movieVersion {
+		t.Errorf("VersionJSON = %+v", got)
+	}
+	if len(got.Cores) != 2 || got.Cores[0].Active || got.Cores[1].System != "bbb" ||
+		got.Cores[1].Name != "bcore" || got.Cores[1].Version != "1.2.3" || !got.Cores[1].Active {
+		t.Errorf("cores = %+v", got.Cores)
+	}
+}